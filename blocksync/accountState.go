@@ -0,0 +1,853 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	utilis "github.com/airchains-network/decentralized-sequencer/utils"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// stateTrackerInterval is how often the state tracker looks for new final transactions.
+const stateTrackerInterval = time.Second
+
+var (
+	// stateSeqKey in the transaction database is the last transaction applied to the
+	// account state.
+	stateSeqKey = []byte("stateSeq")
+	// stateErrKey holds why the state tracker failed to apply the transaction after
+	// stateSeq, until it applies it.
+	stateErrKey = []byte("stateErr")
+	// accountStateMu is held while the account state is written, so that a rollback of
+	// transactions does not interleave with the tracker.
+	accountStateMu sync.Mutex
+)
+
+// accountStateKey holds the latest tracked state of address.
+func accountStateKey(address string) []byte {
+	return []byte("acct-" + normalizeTxnIndexValue(address))
+}
+
+// txnAccountStatesKey holds the state of the accounts of transaction seq before it.
+func txnAccountStatesKey(seq int) []byte {
+	return []byte(fmt.Sprintf("acctpre-%d", seq))
+}
+
+// AccountState is the balance and nonce of a station account. Balances are decimal
+// amounts keyed by denomination; EVM stations keep their single balance under "".
+type AccountState struct {
+	Balances map[string]string `json:"balances,omitempty"`
+	Nonce    uint64            `json:"nonce"`
+	// Untrusted is set once the account would have spent more than it holds, because
+	// value reached it in ways that are not tracked. Its balances are then unknown and
+	// must be looked up on the station.
+	Untrusted bool `json:"untrusted,omitempty"`
+}
+
+// Balance returns the amount of denom held by the account.
+func (s AccountState) Balance(denom string) string {
+	if amount, ok := s.Balances[denom]; ok {
+		return amount
+	}
+	return "0"
+}
+
+func (s AccountState) clone() AccountState {
+	c := AccountState{Nonce: s.Nonce, Untrusted: s.Untrusted}
+	if len(s.Balances) > 0 {
+		c.Balances = make(map[string]string, len(s.Balances))
+		for denom, amount := range s.Balances {
+			c.Balances[denom] = amount
+		}
+	}
+	return c
+}
+
+// AccountStates are the states of the accounts of a transaction, keyed by address.
+type AccountStates map[string]AccountState
+
+// Of returns the state of address. Accounts the transaction does not involve have the
+// zero state.
+func (s AccountStates) Of(address string) AccountState {
+	return s[normalizeTxnIndexValue(address)]
+}
+
+// TxnAccountStates returns the state of the accounts of transaction seq before it was
+// executed. It returns leveldb.ErrNotFound until the state tracker applied seq.
+func TxnAccountStates(ldt *leveldb.DB, seq int) (AccountStates, error) {
+	data, err := ldt.Get(txnAccountStatesKey(seq), nil)
+	if err != nil {
+		return nil, err
+	}
+	var states AccountStates
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to decode account states of transaction %d: %w", seq, err)
+	}
+	return states, nil
+}
+
+// StateTrackerError returns why the state tracker failed to apply the transaction after
+// the last one it applied, or nil when it is not failing.
+func StateTrackerError(ldt *leveldb.DB) error {
+	data, err := ldt.Get(stateErrKey, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return errors.New(string(data))
+}
+
+// recordStateTrackerError stores failure, or clears the failure stored before when it
+// is nil.
+func recordStateTrackerError(ldt *leveldb.DB, failure error) error {
+	if failure != nil {
+		return ldt.Put(stateErrKey, []byte(failure.Error()), nil)
+	}
+	if ok, err := ldt.Has(stateErrKey, nil); err != nil || !ok {
+		return err
+	}
+	return ldt.Delete(stateErrKey, nil)
+}
+
+// readAccountState returns the latest tracked state of address, the zero state if the
+// account was never seen.
+func readAccountState(ldt *leveldb.DB, address string) (AccountState, error) {
+	var state AccountState
+	data, err := ldt.Get(accountStateKey(address), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to decode account state of %s: %w", address, err)
+	}
+	return state, nil
+}
+
+// StateTrackerOptions configures the local account state tracker.
+type StateTrackerOptions struct {
+	Enabled bool
+	// Genesis is the station genesis file the state is seeded from: a geth genesis with
+	// an alloc on evm stations, a cosmos genesis on wasm stations.
+	Genesis string
+	// CheckRate is the share of the accounts of each block that are compared with the
+	// station. Zero disables the checks.
+	CheckRate float64
+}
+
+// validate checks that the options are complete and supported by stationType.
+func (o StateTrackerOptions) validate(stationType string, ldt *leveldb.DB) error {
+	if !o.Enabled {
+		return nil
+	}
+	if stationType != StationTypeEVM && stationType != StationTypeWASM {
+		return fmt.Errorf("the state tracker is only supported by evm and wasm stations")
+	}
+	if o.CheckRate < 0 || o.CheckRate > 1 {
+		return fmt.Errorf("state tracker check rate must be between 0 and 1, got %g", o.CheckRate)
+	}
+	if o.Genesis == "" && !stateSeeded(ldt) {
+		return fmt.Errorf("the state tracker needs a genesis file to seed the account state")
+	}
+	return nil
+}
+
+// stateSeeded reports whether the account state was seeded from a genesis file.
+func stateSeeded(ldt *leveldb.DB) bool {
+	if ldt == nil {
+		return false
+	}
+	ok, _ := ldt.Has(stateSeqKey, nil)
+	return ok
+}
+
+// seedAccountState stores the accounts of the genesis file as the state before the
+// first transaction. It does nothing once the state is seeded.
+func seedAccountState(ldt *leveldb.DB, stationType, genesis string) error {
+	if stateSeeded(ldt) {
+		return nil
+	}
+	if pruned := readCounter(ldt, string(prunedTxnKey)); pruned > 0 {
+		return fmt.Errorf("transactions up to %d are pruned, the account state can only be seeded before the first transaction", pruned)
+	}
+	data, err := os.ReadFile(genesis)
+	if err != nil {
+		return fmt.Errorf("failed to read state tracker genesis: %w", err)
+	}
+	var accounts map[string]AccountState
+	if stationType == StationTypeEVM {
+		accounts, err = parseEVMGenesis(data)
+	} else {
+		accounts, err = parseCosmosGenesis(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	for address, state := range accounts {
+		value, err := json.Marshal(state)
+		if err != nil {
+			return fmt.Errorf("error marshalling genesis state of %s: %w", address, err)
+		}
+		batch.Put(accountStateKey(address), value)
+	}
+	batch.Put(stateSeqKey, []byte("0"))
+	if err := ldt.Write(batch, syncWrite); err != nil {
+		return fmt.Errorf("failed to store genesis account state: %w", err)
+	}
+	log.Info().Str("module", "blocksync").Msg(fmt.Sprintf("Seeded the account state with %d genesis accounts", len(accounts)))
+	return nil
+}
+
+// parseEVMGenesis returns the accounts of the alloc of a geth genesis file.
+func parseEVMGenesis(data []byte) (map[string]AccountState, error) {
+	var genesis struct {
+		Alloc map[string]struct {
+			Balance *math.HexOrDecimal256 `json:"balance"`
+			Nonce   math.HexOrDecimal64   `json:"nonce"`
+		} `json:"alloc"`
+	}
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return nil, fmt.Errorf("failed to decode geth genesis: %w", err)
+	}
+	if genesis.Alloc == nil {
+		return nil, errors.New("geth genesis has no alloc")
+	}
+	accounts := make(map[string]AccountState, len(genesis.Alloc))
+	for address, account := range genesis.Alloc {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("geth genesis alloc has malformed address %q", address)
+		}
+		state := AccountState{Nonce: uint64(account.Nonce)}
+		if account.Balance != nil {
+			state.Balances = map[string]string{"": (*big.Int)(account.Balance).String()}
+		}
+		accounts[common.HexToAddress(address).Hex()] = state
+	}
+	return accounts, nil
+}
+
+// parseCosmosGenesis returns the bank balances and auth sequences of a cosmos genesis
+// file.
+func parseCosmosGenesis(data []byte) (map[string]AccountState, error) {
+	type genesisAccount struct {
+		Address  string `json:"address"`
+		Sequence string `json:"sequence"`
+	}
+	var genesis struct {
+		AppState struct {
+			Auth struct {
+				Accounts []struct {
+					genesisAccount
+					// Module and vesting accounts nest their base account.
+					BaseAccount *genesisAccount `json:"base_account"`
+				} `json:"accounts"`
+			} `json:"auth"`
+			Bank struct {
+				Balances []struct {
+					Address string              `json:"address"`
+					Coins   []types.BatchAmount `json:"coins"`
+				} `json:"balances"`
+			} `json:"bank"`
+		} `json:"app_state"`
+	}
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return nil, fmt.Errorf("failed to decode cosmos genesis: %w", err)
+	}
+
+	accounts := make(map[string]AccountState)
+	for _, balance := range genesis.AppState.Bank.Balances {
+		state := AccountState{Balances: make(map[string]string, len(balance.Coins))}
+		for _, coin := range balance.Coins {
+			state.Balances[coin.Denom] = coin.Amount
+		}
+		accounts[balance.Address] = state
+	}
+	for _, account := range genesis.AppState.Auth.Accounts {
+		base := account.genesisAccount
+		if account.BaseAccount != nil {
+			base = *account.BaseAccount
+		}
+		if base.Address == "" || base.Sequence == "" {
+			continue
+		}
+		sequence, err := strconv.ParseUint(base.Sequence, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cosmos genesis account %s has malformed sequence %q", base.Address, base.Sequence)
+		}
+		state := accounts[base.Address]
+		state.Nonce = sequence
+		accounts[base.Address] = state
+	}
+	if len(accounts) == 0 {
+		return nil, errors.New("cosmos genesis has no bank balances or auth accounts")
+	}
+	return accounts, nil
+}
+
+// balanceChange moves amount of denom into account; a negative amount is spent.
+type balanceChange struct {
+	account string
+	denom   string
+	amount  *big.Int
+}
+
+// txnStateChange is what a transaction does to the account state.
+type txnStateChange struct {
+	height int
+	// accounts are recorded with their state before the transaction, together with the
+	// accounts of balances and nonces.
+	accounts []string
+	balances []balanceChange
+	// nonces are the account nonces after the transaction.
+	nonces map[string]uint64
+}
+
+// evmStateChange returns the state change of a stored EVM transaction: the sender pays
+// the fee, the block producer earns the priority fee and, when it succeeds, the value
+// goes to the recipient or created contract. The base fee and blob fee are burnt.
+//
+// Value moved by contract code, such as internal calls and self-destructs, withdrawals
+// and block rewards are not tracked, nor is the priority fee of transactions stored
+// without their coinbase. Accounts they pay end up with less than they hold, and those
+// they drain are marked untrusted once they would go below zero.
+func evmStateChange(data []byte) (txnStateChange, error) {
+	var tx types.TransactionStruct
+	if err := json.Unmarshal(data, &tx); err != nil {
+		return txnStateChange{}, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	if tx.Receipt == nil {
+		return txnStateChange{}, fmt.Errorf("%s was stored without a receipt, backfill it to track account state", tx.Hash)
+	}
+	nonce, err := strconv.ParseUint(tx.Nonce, 10, 64)
+	if err != nil {
+		return txnStateChange{}, fmt.Errorf("%s has malformed nonce %q", tx.Hash, tx.Nonce)
+	}
+	gasPrice := tx.Receipt.EffectiveGasPrice
+	if gasPrice == "" {
+		gasPrice = tx.GasPrice
+	}
+	gasUsed, ok := new(big.Int).SetString(tx.Receipt.GasUsed, 10)
+	price, priceOK := new(big.Int).SetString(gasPrice, 10)
+	if !ok || !priceOK {
+		return txnStateChange{}, fmt.Errorf("%s has malformed gas used %q or gas price %q", tx.Hash, tx.Receipt.GasUsed, gasPrice)
+	}
+	fee := new(big.Int).Mul(gasUsed, price)
+	if tx.Receipt.BlobGasUsed != "" {
+		blobGas, ok := new(big.Int).SetString(tx.Receipt.BlobGasUsed, 10)
+		blobPrice, priceOK := new(big.Int).SetString(tx.Receipt.BlobGasPrice, 10)
+		if !ok || !priceOK {
+			return txnStateChange{}, fmt.Errorf("%s has malformed blob gas used %q or blob gas price %q", tx.Hash, tx.Receipt.BlobGasUsed, tx.Receipt.BlobGasPrice)
+		}
+		fee.Add(fee, blobGas.Mul(blobGas, blobPrice))
+	}
+	value, ok := new(big.Int).SetString(tx.Value, 10)
+	if !ok {
+		return txnStateChange{}, fmt.Errorf("%s has malformed value %q", tx.Hash, tx.Value)
+	}
+
+	change := txnStateChange{
+		height:   int(tx.BlockNumber),
+		accounts: []string{tx.From, tx.To},
+		balances: []balanceChange{{account: tx.From, amount: fee.Neg(fee)}},
+		nonces:   map[string]uint64{tx.From: nonce + 1},
+	}
+	if tx.Coinbase != "" {
+		tip := price
+		if tx.BaseFeePerGas != "" {
+			baseFee, ok := new(big.Int).SetString(tx.BaseFeePerGas, 10)
+			if !ok {
+				return txnStateChange{}, fmt.Errorf("%s has malformed base fee %q", tx.Hash, tx.BaseFeePerGas)
+			}
+			tip = new(big.Int).Sub(price, baseFee)
+		}
+		if tip.Sign() > 0 {
+			change.balances = append(change.balances, balanceChange{account: tx.Coinbase, amount: tip.Mul(tip, gasUsed)})
+		}
+	}
+	to := tx.To
+	if EVMPodCandidate(0, tx).ContractCreation && tx.Receipt.ContractAddress != "" {
+		to = tx.Receipt.ContractAddress
+	}
+	if tx.Receipt.Status == 1 && value.Sign() > 0 {
+		change.balances = append(change.balances,
+			balanceChange{account: tx.From, amount: new(big.Int).Neg(value)},
+			balanceChange{account: to, amount: value},
+		)
+	}
+	return change, nil
+}
+
+// wasmStateChange returns the state change of a stored cosmos transaction from its
+// coin_spent and coin_received events, which cover the fee as well as the messages, and
+// the account sequences of its tx events.
+func wasmStateChange(data []byte) (txnStateChange, error) {
+	var txn types.BatchTransaction
+	if err := json.Unmarshal(data, &txn); err != nil {
+		return txnStateChange{}, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	height, err := strconv.Atoi(txn.TxResponse.Height)
+	if err != nil {
+		return txnStateChange{}, fmt.Errorf("%s has malformed height %q", txn.TxResponse.TxHash, txn.TxResponse.Height)
+	}
+
+	change := txnStateChange{height: height, nonces: make(map[string]uint64)}
+	for _, event := range txn.TxResponse.Events {
+		switch event.Type {
+		case "coin_spent", "coin_received":
+			var account, amount string
+			for _, attr := range event.Attributes {
+				switch attr.Key {
+				case "spender", "receiver":
+					account = attr.Value
+				case "amount":
+					amount = attr.Value
+				}
+			}
+			coins, err := sdk.ParseCoinsNormalized(amount)
+			if err != nil || account == "" {
+				return txnStateChange{}, fmt.Errorf("%s has a malformed %s event", txn.TxResponse.TxHash, event.Type)
+			}
+			for _, coin := range coins {
+				moved := coin.Amount.BigInt()
+				if event.Type == "coin_spent" {
+					moved.Neg(moved)
+				}
+				change.balances = append(change.balances, balanceChange{account: account, denom: coin.Denom, amount: moved})
+			}
+		case "tx":
+			for _, attr := range event.Attributes {
+				if attr.Key != "acc_seq" {
+					continue
+				}
+				// The sequence the signer used: <address>/<sequence>.
+				i := strings.LastIndex(attr.Value, "/")
+				sequence, err := strconv.ParseUint(attr.Value[i+1:], 10, 64)
+				if i < 0 || err != nil {
+					return txnStateChange{}, fmt.Errorf("%s has malformed account sequence %q", txn.TxResponse.TxHash, attr.Value)
+				}
+				change.nonces[attr.Value[:i]] = sequence + 1
+			}
+		}
+	}
+	// Pods read the state of every transfer, even of failed transactions.
+	entries, skipped := WasmPodEntries(txn)
+	for _, skip := range skipped {
+		// The station rejects malformed transfers, so a successful one means the pod
+		// entries do not match the coins its events moved.
+		if skip.Malformed() && txn.TxResponse.Code == 0 {
+			return txnStateChange{}, fmt.Errorf("%s succeeded with a malformed message %d: %s", txn.TxResponse.TxHash, skip.Index, skip.Reason)
+		}
+	}
+	for _, entry := range entries {
+		change.accounts = append(change.accounts, entry.From, entry.To)
+	}
+	return change, nil
+}
+
+// stateChecker returns the station state of address at the end of block height, with
+// the balances of denoms.
+type stateChecker func(ctx context.Context, height int, address string, denoms []string) (AccountState, error)
+
+// newEVMStateChecker looks account state up with eth_getBalance and
+// eth_getTransactionCount.
+func newEVMStateChecker(station *stationclient.Client) stateChecker {
+	states := utilis.NewEVMStateCache(station)
+	return func(ctx context.Context, height int, address string, _ []string) (AccountState, error) {
+		at := utilis.EVMAccountAt{Address: address, Block: uint64(height)}
+		balances, nonces, err := states.Fetch(ctx, []utilis.EVMAccountAt{at}, []utilis.EVMAccountAt{at})
+		if err != nil {
+			return AccountState{}, err
+		}
+		nonce, err := strconv.ParseUint(strings.TrimPrefix(nonces[at], "0x"), 16, 64)
+		if err != nil {
+			return AccountState{}, fmt.Errorf("malformed nonce %q of %s", nonces[at], address)
+		}
+		return AccountState{Balances: map[string]string{"": balances[at]}, Nonce: nonce}, nil
+	}
+}
+
+// newWasmStateChecker looks account state up with the bank and auth REST queries.
+func newWasmStateChecker(station *stationclient.Client) stateChecker {
+	return func(ctx context.Context, height int, address string, denoms []string) (AccountState, error) {
+		state := AccountState{Balances: make(map[string]string, len(denoms))}
+		for _, denom := range denoms {
+			body, err := station.Get(ctx, fmt.Sprintf("/cosmos/bank/v1beta1/balances/%s/by_denom?denom=%s&height=%d", address, url.QueryEscape(denom), height))
+			if err != nil {
+				return state, err
+			}
+			var res struct {
+				Balance *types.BatchAmount `json:"balance"`
+			}
+			if err := json.Unmarshal(body, &res); err != nil {
+				return state, fmt.Errorf("error decoding %s balance of %s: %w", denom, address, err)
+			}
+			state.Balances[denom] = "0"
+			if res.Balance != nil && res.Balance.Amount != "" {
+				state.Balances[denom] = res.Balance.Amount
+			}
+		}
+
+		body, err := station.Get(ctx, fmt.Sprintf("/cosmos/auth/v1beta1/accounts/%s?height=%d", address, height))
+		var statusErr *stationclient.StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			// Accounts that only received coins have no auth account yet.
+			return state, nil
+		}
+		if err != nil {
+			return state, err
+		}
+		var res struct {
+			Account struct {
+				Sequence    string `json:"sequence"`
+				BaseAccount *struct {
+					Sequence string `json:"sequence"`
+				} `json:"base_account"`
+			} `json:"account"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return state, fmt.Errorf("error decoding account %s: %w", address, err)
+		}
+		sequence := res.Account.Sequence
+		if res.Account.BaseAccount != nil {
+			sequence = res.Account.BaseAccount.Sequence
+		}
+		if sequence != "" {
+			if state.Nonce, err = strconv.ParseUint(sequence, 10, 64); err != nil {
+				return state, fmt.Errorf("malformed sequence %q of %s", sequence, address)
+			}
+		}
+		return state, nil
+	}
+}
+
+// stateTracker applies the final indexed transactions, block by block and in order, to
+// the account state, and records the state of the accounts of each transaction before
+// it for the pod builders.
+type stateTracker struct {
+	ldt    *leveldb.DB
+	change func(data []byte) (txnStateChange, error)
+	// check is nil when the cross-checks are disabled.
+	check     stateChecker
+	checkRate float64
+	sample    func() float64
+}
+
+func newStateTracker(stationType string, ldt *leveldb.DB, check stateChecker, checkRate float64) *stateTracker {
+	t := &stateTracker{ldt: ldt, change: wasmStateChange, check: check, checkRate: checkRate, sample: rand.Float64}
+	if stationType == StationTypeEVM {
+		t.change = evmStateChange
+	}
+	return t
+}
+
+// startStateTracker seeds the account state and applies new transactions to it until
+// ctx is cancelled. It does nothing when the tracker is disabled.
+func startStateTracker(ctx context.Context, stationType string, opts IndexerOptions) error {
+	if !opts.StateTracker.Enabled {
+		return nil
+	}
+	stationType = NormalizeStationType(stationType)
+	if err := seedAccountState(opts.TxnDB, stationType, opts.StateTracker.Genesis); err != nil {
+		return err
+	}
+
+	var check stateChecker
+	if opts.StateTracker.CheckRate > 0 {
+		endpoints := opts.StationAPI
+		if stationType == StationTypeEVM {
+			endpoints = opts.StationRPC
+		}
+		station, err := stationclient.New(stationclient.ParseEndpoints(endpoints), opts.StationClient)
+		if err != nil {
+			return err
+		}
+		if stationType == StationTypeEVM {
+			check = newEVMStateChecker(station)
+		} else {
+			check = newWasmStateChecker(station)
+		}
+	}
+
+	t := newStateTracker(stationType, opts.TxnDB, check, opts.StateTracker.CheckRate)
+	go func() {
+		ticker := time.NewTicker(stateTrackerInterval)
+		defer ticker.Stop()
+		for {
+			_, err := t.apply(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Error().Str("module", "blocksync").Err(err).Msg("Failed to apply transactions to the account state")
+			}
+			// Pod builders waiting on the state fail the pod instead of waiting forever.
+			if err := recordStateTrackerError(t.ldt, err); err != nil {
+				log.Error().Str("module", "blocksync").Err(err).Msg("Failed to record the state tracker status")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// appliedBlock sums up a block applied to the account state.
+type appliedBlock struct {
+	height  int
+	lastSeq int
+	txns    int
+	// touched maps the accounts whose state changed to the denominations they moved.
+	touched map[string]map[string]bool
+	// suspect are the accounts that would have spent more than they hold.
+	suspect map[string]bool
+}
+
+// apply applies every final transaction after stateSeq and returns how many there were.
+func (t *stateTracker) apply(ctx context.Context) (int, error) {
+	applied := 0
+	for ctx.Err() == nil {
+		block, err := t.applyBlock()
+		if err != nil || block.txns == 0 {
+			return applied, err
+		}
+		applied += block.txns
+		t.crossCheck(ctx, block)
+	}
+	return applied, ctx.Err()
+}
+
+// applyBlock applies the final transactions of the next block in a single write. Blocks
+// are committed with all their transactions and the finality boundary is at a block
+// end, so the transactions found are always a whole block.
+func (t *stateTracker) applyBlock() (appliedBlock, error) {
+	accountStateMu.Lock()
+	defer accountStateMu.Unlock()
+
+	block := appliedBlock{
+		height:  -1,
+		lastSeq: readCounter(t.ldt, string(stateSeqKey)),
+		touched: make(map[string]map[string]bool),
+		suspect: make(map[string]bool),
+	}
+	current := make(map[string]AccountState)
+	state := func(address string) (AccountState, error) {
+		key := normalizeTxnIndexValue(address)
+		if s, ok := current[key]; ok {
+			return s, nil
+		}
+		s, err := readAccountState(t.ldt, key)
+		current[key] = s
+		return s, err
+	}
+
+	batch := new(leveldb.Batch)
+	txnCount := readCounter(t.ldt, "txnCount")
+	for seq := block.lastSeq + 1; seq <= txnCount && IsTxnFinal(t.ldt, seq); seq++ {
+		data, err := t.ldt.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+		if err != nil {
+			return appliedBlock{}, fmt.Errorf("failed to read txns-%d: %w", seq, err)
+		}
+		change, err := t.change(data)
+		if err != nil {
+			return appliedBlock{}, fmt.Errorf("transaction %d: %w", seq, err)
+		}
+		if block.height != -1 && change.height != block.height {
+			break
+		}
+		block.height = change.height
+
+		accounts := append([]string(nil), change.accounts...)
+		for _, c := range change.balances {
+			accounts = append(accounts, c.account)
+		}
+		for account := range change.nonces {
+			accounts = append(accounts, account)
+		}
+		before := make(AccountStates, len(accounts))
+		for _, account := range accounts {
+			if account == "" {
+				continue
+			}
+			s, err := state(account)
+			if err != nil {
+				return appliedBlock{}, err
+			}
+			before[normalizeTxnIndexValue(account)] = s.clone()
+		}
+
+		for _, c := range change.balances {
+			key := normalizeTxnIndexValue(c.account)
+			s, _ := state(key)
+			s = s.clone()
+			balance, ok := new(big.Int).SetString(s.Balance(c.denom), 10)
+			if !ok {
+				return appliedBlock{}, fmt.Errorf("malformed %q balance %q of %s", c.denom, s.Balance(c.denom), key)
+			}
+			balance.Add(balance, c.amount)
+			if balance.Sign() < 0 {
+				// Value moved in ways that are not tracked, such as internal transfers.
+				block.suspect[key] = true
+				s.Untrusted = true
+				balance.SetInt64(0)
+			}
+			if s.Balances == nil {
+				s.Balances = make(map[string]string)
+			}
+			s.Balances[c.denom] = balance.String()
+			current[key] = s
+			if block.touched[key] == nil {
+				block.touched[key] = make(map[string]bool)
+			}
+			block.touched[key][c.denom] = true
+		}
+		for account, nonce := range change.nonces {
+			key := normalizeTxnIndexValue(account)
+			s, _ := state(key)
+			s = s.clone()
+			s.Nonce = nonce
+			current[key] = s
+			if block.touched[key] == nil {
+				block.touched[key] = make(map[string]bool)
+			}
+		}
+
+		value, err := json.Marshal(before)
+		if err != nil {
+			return appliedBlock{}, fmt.Errorf("error marshalling account states of transaction %d: %w", seq, err)
+		}
+		batch.Put(txnAccountStatesKey(seq), value)
+		block.lastSeq = seq
+		block.txns++
+	}
+	if block.txns == 0 {
+		return block, nil
+	}
+
+	for key := range block.touched {
+		value, err := json.Marshal(current[key])
+		if err != nil {
+			return appliedBlock{}, fmt.Errorf("error marshalling account state of %s: %w", key, err)
+		}
+		batch.Put(accountStateKey(key), value)
+	}
+	batch.Put(stateSeqKey, []byte(strconv.Itoa(block.lastSeq)))
+	if err := t.ldt.Write(batch, syncWrite); err != nil {
+		return appliedBlock{}, fmt.Errorf("failed to store account state of block %d: %w", block.height, err)
+	}
+	return block, nil
+}
+
+// crossCheck compares a sample of the accounts of block, and every suspect one, with the
+// station at the end of the block and logs where they differ. The tracked state is never
+// changed: the sample differs between tracks, and pods built from corrected states
+// would differ too.
+func (t *stateTracker) crossCheck(ctx context.Context, block appliedBlock) {
+	if t.check == nil {
+		return
+	}
+	for address, denoms := range block.touched {
+		if !block.suspect[address] && t.sample() >= t.checkRate {
+			continue
+		}
+		denomList := make([]string, 0, len(denoms))
+		for denom := range denoms {
+			denomList = append(denomList, denom)
+		}
+		station, err := t.check(ctx, block.height, address, denomList)
+		if err != nil {
+			log.Warn().Str("module", "blocksync").Err(err).Msg(fmt.Sprintf("Failed to cross-check the account state of %s at block %d", address, block.height))
+			continue
+		}
+		if err := t.report(block, address, denomList, station); err != nil {
+			log.Error().Str("module", "blocksync").Err(err).Msg("Failed to compare the account state")
+		}
+	}
+}
+
+// report logs where the tracked balances of denoms and the nonce of address differ from
+// the station ones, unless the state moved past block in the meantime.
+func (t *stateTracker) report(block appliedBlock, address string, denoms []string, station AccountState) error {
+	accountStateMu.Lock()
+	defer accountStateMu.Unlock()
+	if readCounter(t.ldt, string(stateSeqKey)) != block.lastSeq {
+		return nil
+	}
+	local, err := readAccountState(t.ldt, address)
+	if err != nil {
+		return err
+	}
+	diffs := stateDiffs(local, station, denoms)
+	if len(diffs) == 0 {
+		return nil
+	}
+	log.Warn().Str("module", "blocksync").Msg(fmt.Sprintf("Tracked state of %s at block %d differs from the station: %s", address, block.height, strings.Join(diffs, ", ")))
+	return nil
+}
+
+// stateDiffs describes where local differs from station in the balances of denoms and
+// the nonce.
+func stateDiffs(local, station AccountState, denoms []string) []string {
+	var diffs []string
+	for _, denom := range denoms {
+		if local.Balance(denom) != station.Balance(denom) {
+			diffs = append(diffs, fmt.Sprintf("balance %q %s instead of %s", denom, local.Balance(denom), station.Balance(denom)))
+		}
+	}
+	if local.Nonce != station.Nonce {
+		diffs = append(diffs, fmt.Sprintf("nonce %d instead of %d", local.Nonce, station.Nonce))
+	}
+	return diffs
+}
+
+// revertAccountStates adds to batch the restoration of the account state from before
+// every applied transaction after seq, for the removal of those transactions. The
+// caller holds accountStateMu.
+func revertAccountStates(ldt *leveldb.DB, batch *leveldb.Batch, seq int) error {
+	applied := readCounter(ldt, string(stateSeqKey))
+	if applied <= seq {
+		return nil
+	}
+	// Later writes of a batch win, so the earliest state of an account is written last.
+	for next := applied; next > seq; next-- {
+		states, err := TxnAccountStates(ldt, next)
+		if err != nil {
+			return fmt.Errorf("failed to revert account state of transaction %d: %w", next, err)
+		}
+		for address, state := range states {
+			value, err := json.Marshal(state)
+			if err != nil {
+				return err
+			}
+			batch.Put(accountStateKey(address), value)
+		}
+		batch.Delete(txnAccountStatesKey(next))
+	}
+	batch.Put(stateSeqKey, []byte(strconv.Itoa(seq)))
+	return nil
+}
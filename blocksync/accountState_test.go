@@ -0,0 +1,264 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const (
+	testAlice = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+	testBob   = "0xe7f1725e7734ce288f8367e1bb143e90bb3f0512"
+)
+
+// commitTestTxns stores txns, marshalled, as the transactions of block height.
+func commitTestTxns(t *testing.T, ldb, ldt *leveldb.DB, height int, txns ...interface{}) {
+	t.Helper()
+	w := blockWrite{height: height, blockKey: blockKey(height), blockData: []byte("{}")}
+	for _, txn := range txns {
+		data, err := json.Marshal(txn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.txns = append(w.txns, data)
+	}
+	if err := commitBlock(ldb, ldt, w); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seedTestState seeds the account state of ldt from genesis.
+func seedTestState(t *testing.T, ldt *leveldb.DB, stationType, genesis string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	if err := os.WriteFile(path, []byte(genesis), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := seedAccountState(ldt, stationType, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testEVMTransfer(block uint64, nonce int, value string, status uint64) types.TransactionStruct {
+	return types.TransactionStruct{
+		BlockNumber: block,
+		Hash:        fmt.Sprintf("0x%064x", nonce),
+		From:        testAlice,
+		To:          testBob,
+		Nonce:       fmt.Sprint(nonce),
+		Value:       value,
+		GasPrice:    "10",
+		Receipt:     &types.ReceiptStruct{Status: status, GasUsed: "21", EffectiveGasPrice: "10"},
+	}
+}
+
+func TestStateTrackerAppliesEVMTransactions(t *testing.T) {
+	blockDB, txnDB := newTestDBs(t)
+	seedTestState(t, txnDB, StationTypeEVM, `{"alloc": {"5FbDB2315678afecb367f032d93F642f64180aa3": {"balance": "0xf4240"}}}`)
+	// Alice pays Bob twice in block 1, then sends more than she holds in block 2.
+	commitTestTxns(t, blockDB, txnDB, 1, testEVMTransfer(1, 0, "100", 1), testEVMTransfer(1, 1, "50", 1))
+	commitTestTxns(t, blockDB, txnDB, 2, testEVMTransfer(2, 2, "5000000", 0))
+
+	tracker := newStateTracker(StationTypeEVM, txnDB, nil, 0)
+	if applied, err := tracker.apply(context.Background()); err != nil || applied != 3 {
+		t.Fatalf("apply() = %d, %v, want 3 transactions", applied, err)
+	}
+
+	second, err := TxnAccountStates(txnDB, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice := second.Of(testAlice); alice.Balance("") != "999690" || alice.Nonce != 1 {
+		t.Errorf("Alice before transaction 2 = %+v, want the first transfer and its fee spent", alice)
+	}
+	if bob := second.Of(testBob); bob.Balance("") != "100" {
+		t.Errorf("Bob before transaction 2 = %+v, want the first transfer received", bob)
+	}
+	alice, _ := readAccountState(txnDB, testAlice)
+	if alice.Balance("") != "999220" || alice.Nonce != 3 {
+		t.Errorf("Alice = %+v, want the failed transfer to cost its fee only", alice)
+	}
+
+	if _, err := TxnAccountStates(txnDB, 4); !errors.Is(err, leveldb.ErrNotFound) {
+		t.Errorf("TxnAccountStates() of a transaction not applied yet error = %v", err)
+	}
+
+	// Removing block 2 goes back to the state after block 1.
+	if err := truncateTxns(txnDB, 2); err != nil {
+		t.Fatal(err)
+	}
+	alice, _ = readAccountState(txnDB, testAlice)
+	if alice.Balance("") != "999430" || alice.Nonce != 2 {
+		t.Errorf("Alice after the rollback = %+v", alice)
+	}
+	if seq := readCounter(txnDB, string(stateSeqKey)); seq != 2 {
+		t.Errorf("stateSeq after the rollback = %d, want 2", seq)
+	}
+}
+
+func TestStateTrackerSplitsEVMFees(t *testing.T) {
+	const coinbase = "0x0000000000000000000000000000000000000c0b"
+	blockDB, txnDB := newTestDBs(t)
+	seedTestState(t, txnDB, StationTypeEVM, `{"alloc": {"5FbDB2315678afecb367f032d93F642f64180aa3": {"balance": "0xf4240"}}}`)
+	blob := testEVMTransfer(1, 0, "100", 1)
+	blob.Coinbase, blob.BaseFeePerGas = coinbase, "7"
+	blob.Receipt.BlobGasUsed, blob.Receipt.BlobGasPrice = "131072", "1"
+	// Bob spends more than the tracker knows he holds.
+	overdraft := testEVMTransfer(1, 0, "500", 1)
+	overdraft.From, overdraft.To, overdraft.Hash = testBob, testAlice, "0xbob"
+	commitTestTxns(t, blockDB, txnDB, 1, blob, overdraft)
+
+	if _, err := newStateTracker(StationTypeEVM, txnDB, nil, 0).apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	alice, _ := readAccountState(txnDB, testAlice)
+	if alice.Balance("") != "869118" {
+		t.Errorf("Alice = %+v, want the gas and blob fees paid and Bob's transfer received", alice)
+	}
+	if producer, _ := readAccountState(txnDB, coinbase); producer.Balance("") != "63" {
+		t.Errorf("coinbase = %+v, want the priority fee of 21 gas at 3", producer)
+	}
+	if bob, _ := readAccountState(txnDB, testBob); !bob.Untrusted {
+		t.Errorf("Bob = %+v, want an untrusted state after the overdraft", bob)
+	}
+	if before, _ := TxnAccountStates(txnDB, 2); before.Of(testBob).Untrusted {
+		t.Error("the state before the overdraft is untrusted")
+	}
+}
+
+func TestStateTrackerErrorIsRecorded(t *testing.T) {
+	_, txnDB := newTestDBs(t)
+	if err := recordStateTrackerError(txnDB, errors.New("transaction 3: malformed nonce")); err != nil {
+		t.Fatal(err)
+	}
+	if err := StateTrackerError(txnDB); err == nil || err.Error() != "transaction 3: malformed nonce" {
+		t.Errorf("StateTrackerError() = %v", err)
+	}
+	if err := recordStateTrackerError(txnDB, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := StateTrackerError(txnDB); err != nil {
+		t.Errorf("StateTrackerError() after a success = %v", err)
+	}
+}
+
+func TestStateTrackerAppliesWasmEvents(t *testing.T) {
+	from, to := testWasmAddress(t, 1), testWasmAddress(t, 2)
+	blockDB, txnDB := newTestDBs(t)
+	seedTestState(t, txnDB, StationTypeWASM, `{"app_state": {
+		"auth": {"accounts": [{"@type": "/cosmos.auth.v1beta1.BaseAccount", "address": "`+from+`", "sequence": "4"}]},
+		"bank": {"balances": [{"address": "`+from+`", "coins": [{"denom": "stake", "amount": "1000"}, {"denom": "uatom", "amount": "7"}]}]}
+	}}`)
+
+	attr := func(key, value string) types.EventAttribute { return types.EventAttribute{Key: key, Value: value} }
+	var txn types.BatchTransaction
+	txn.TxResponse.Height = "5"
+	txn.Tx.Body.Messages = []types.Message{{
+		Type:        "/cosmos.bank.v1beta1.MsgSend",
+		FromAddress: from,
+		ToAddress:   to,
+		Amount:      []types.BatchAmount{{Denom: "stake", Amount: "10"}},
+	}}
+	txn.TxResponse.Events = []types.Event{
+		{Type: "tx", Attributes: []types.EventAttribute{attr("acc_seq", from+"/4")}},
+		{Type: "coin_spent", Attributes: []types.EventAttribute{attr("spender", from), attr("amount", "2stake")}},
+		{Type: "coin_spent", Attributes: []types.EventAttribute{attr("spender", from), attr("amount", "10stake")}},
+		{Type: "coin_received", Attributes: []types.EventAttribute{attr("receiver", to), attr("amount", "10stake")}},
+	}
+	commitTestTxns(t, blockDB, txnDB, 5, txn)
+
+	if _, err := newStateTracker(StationTypeWASM, txnDB, nil, 0).apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	before, err := TxnAccountStates(txnDB, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender := before.Of(from); sender.Balance("stake") != "1000" || sender.Nonce != 4 {
+		t.Errorf("sender before the transaction = %+v", sender)
+	}
+	if _, ok := before[to]; !ok {
+		t.Error("the recipient state before the transaction is not recorded")
+	}
+	sender, _ := readAccountState(txnDB, from)
+	if sender.Balance("stake") != "988" || sender.Balance("uatom") != "7" || sender.Nonce != 5 {
+		t.Errorf("sender = %+v, want the fee and transfer spent and the sequence used", sender)
+	}
+	if recipient, _ := readAccountState(txnDB, to); recipient.Balance("stake") != "10" {
+		t.Errorf("recipient = %+v", recipient)
+	}
+}
+
+func TestStateTrackerCrossCheck(t *testing.T) {
+	blockDB, txnDB := newTestDBs(t)
+	seedTestState(t, txnDB, StationTypeEVM, `{"alloc": {"`+testAlice+`": {"balance": "1000000"}}}`)
+	// Bob received value through an internal transfer that is not tracked.
+	tx := testEVMTransfer(1, 0, "100", 1)
+	tx.From, tx.To = testBob, testAlice
+	commitTestTxns(t, blockDB, txnDB, 1, tx)
+
+	var checked []string
+	check := func(_ context.Context, height int, address string, denoms []string) (AccountState, error) {
+		checked = append(checked, address)
+		if height != 1 || len(denoms) != 1 || denoms[0] != "" {
+			t.Errorf("check(%d, %s, %q)", height, address, denoms)
+		}
+		return AccountState{Balances: map[string]string{"": "5000"}, Nonce: 1}, nil
+	}
+	tracker := newStateTracker(StationTypeEVM, txnDB, check, 0)
+	if _, err := tracker.apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Only Bob is checked: he spent more than the tracked balance.
+	if len(checked) != 1 || checked[0] != normalizeTxnIndexValue(testBob) {
+		t.Errorf("checked %v, want Bob only", checked)
+	}
+	// The difference is only reported, every track keeps the state it derived itself.
+	if bob, _ := readAccountState(txnDB, testBob); bob.Balance("") != "0" || bob.Nonce != 1 {
+		t.Errorf("Bob = %+v, want the tracked state", bob)
+	}
+	if alice, _ := readAccountState(txnDB, testAlice); alice.Balance("") != "1000100" {
+		t.Errorf("Alice = %+v, want her own state", alice)
+	}
+}
+
+func TestStateTrackerOptionsValidate(t *testing.T) {
+	seeded := newMemDB(t)
+	seeded.Put(stateSeqKey, []byte("0"), nil)
+	tests := []struct {
+		name        string
+		opts        StateTrackerOptions
+		stationType string
+		ldt         *leveldb.DB
+		wantErr     bool
+	}{
+		{"disabled", StateTrackerOptions{}, StationTypeSVM, nil, false},
+		{"evm", StateTrackerOptions{Enabled: true, Genesis: "genesis.json", CheckRate: 0.05}, StationTypeEVM, nil, false},
+		{"svm", StateTrackerOptions{Enabled: true, Genesis: "genesis.json"}, StationTypeSVM, nil, true},
+		{"check rate above 1", StateTrackerOptions{Enabled: true, Genesis: "genesis.json", CheckRate: 2}, StationTypeWASM, nil, true},
+		{"no genesis", StateTrackerOptions{Enabled: true}, StationTypeWASM, newMemDB(t), true},
+		{"no genesis once seeded", StateTrackerOptions{Enabled: true}, StationTypeWASM, seeded, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.validate(tt.stationType, tt.ldt); (err != nil) != tt.wantErr {
+				t.Errorf("validate(%q) error = %v, wantErr %v", tt.stationType, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSeedAccountStateAfterPruning(t *testing.T) {
+	txnDB := newMemDB(t)
+	txnDB.Put(prunedTxnKey, []byte("25"), nil)
+	if err := seedAccountState(txnDB, StationTypeEVM, "genesis.json"); err == nil {
+		t.Error("seeded the account state after transactions were pruned")
+	}
+}
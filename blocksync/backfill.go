@@ -0,0 +1,443 @@
+package blocksync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// skippedSlotHash stands in for the hash of an SVM slot that produced no block.
+const skippedSlotHash = "skipped"
+
+// blockSource fetches stored station blocks again for Backfill and VerifyBlocks.
+type blockSource interface {
+	// fetchBlock returns height as the indexer would store it.
+	fetchBlock(ctx context.Context, height int) (blockWrite, error)
+	// stationHash returns the hash the station reports for height.
+	stationHash(ctx context.Context, height int) (string, error)
+	// storedHash returns the hash of a block stored by fetchBlock.
+	storedHash(blockData []byte) (string, error)
+	// blockKeys returns the keys height may be stored under.
+	blockKeys(height int) []string
+}
+
+// BlockError is a block Backfill or VerifyBlocks could not process.
+type BlockError struct {
+	Height int
+	Err    error
+}
+
+// BackfillReport lists what Backfill did with every block of a range.
+type BackfillReport struct {
+	Rewritten []int
+	Unchanged int
+	Failed    []BlockError
+}
+
+// BlockMismatch is a stored block whose hash differs from the one the station reports.
+type BlockMismatch struct {
+	Height  int
+	Stored  string
+	Station string
+}
+
+// VerifyReport lists the blocks of a range whose stored hash does not match the station.
+type VerifyReport struct {
+	Checked    int
+	Mismatches []BlockMismatch
+	Failed     []BlockError
+}
+
+// newBlockSource builds the block source of stationType from the station endpoints in
+// opts.
+func newBlockSource(ctx context.Context, stationType string, opts IndexerOptions) (blockSource, error) {
+	stationType = NormalizeStationType(stationType)
+	if !IsStationTypeSupported(stationType) {
+		return nil, fmt.Errorf("unsupported station type %q, must be one of: %s", stationType, strings.Join(SupportedStationTypes(), ", "))
+	}
+	stationRPC, err := stationclient.New(stationclient.ParseEndpoints(opts.StationRPC), opts.StationClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid station rpc: %w", err)
+	}
+	switch stationType {
+	case StationTypeEVM:
+		client, err := newFailoverEVMClient(ctx, stationRPC)
+		if err != nil {
+			return nil, fmt.Errorf("error in connecting to the evm station: %w", err)
+		}
+		return &evmBlockSource{client: client}, nil
+	case StationTypeWASM:
+		return &wasmBlockSource{stationRPC: stationRPC}, nil
+	case StationTypeSVM:
+		return svmBlockSource{rpc: newSVMRPC(stationRPC, opts.Finality.Commitment)}, nil
+	}
+	return nil, fmt.Errorf("station type %q does not support backfill", stationType)
+}
+
+// Backfill fetches blocks from to to again and rewrites the stored blocks and
+// transactions that differ from the station in place. Transactions keep their sequence
+// numbers, so a block whose transaction count changed is reported as failed and needs a
+// rollback instead, and transactions already in a pod are never rewritten. A missing
+// block without transactions is stored.
+func Backfill(ctx context.Context, stationType string, opts IndexerOptions, from, to int) (BackfillReport, error) {
+	source, err := newBlockSource(ctx, stationType, opts)
+	if err != nil {
+		return BackfillReport{}, err
+	}
+	return backfillBlocks(ctx, source, opts, from, to)
+}
+
+func backfillBlocks(ctx context.Context, source blockSource, opts IndexerOptions, from, to int) (BackfillReport, error) {
+	var report BackfillReport
+	if err := checkStoredRange(opts.BlockDB, from, to); err != nil {
+		return report, err
+	}
+	podBoundary := podBoundaryTxn(opts.StaticDB, opts.StateDB)
+	for height := from; height <= to; height++ {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		rewritten, err := backfillBlock(ctx, source, opts.BlockDB, opts.TxnDB, height, podBoundary)
+		switch {
+		case err != nil:
+			log.Warn().Str("module", "blocksync").Err(err).Msg(fmt.Sprintf("Failed to backfill block %d", height))
+			report.Failed = append(report.Failed, BlockError{Height: height, Err: err})
+		case rewritten:
+			log.Info().Str("module", "blocksync").Msg(fmt.Sprintf("Rewrote block %d", height))
+			report.Rewritten = append(report.Rewritten, height)
+		default:
+			report.Unchanged++
+		}
+	}
+	return report, nil
+}
+
+// backfillBlock rewrites height if the station returns anything other than what is
+// stored, and reports whether it did.
+func backfillBlock(ctx context.Context, source blockSource, ldb, ldt *leveldb.DB, height, podBoundary int) (bool, error) {
+	w, err := source.fetchBlock(ctx, height)
+	if err != nil {
+		return false, err
+	}
+
+	commit, ok := readBlockCommit(ldb, height)
+	if !ok {
+		if commit, err = unmarkedBlockCommit(ldb, ldt, w); err != nil {
+			return false, err
+		}
+	} else if unchanged, err := storedBlockEquals(ldb, ldt, w, commit); err != nil || unchanged {
+		return false, err
+	}
+
+	for i, txn := range w.txns {
+		seq := commit.FirstTxn + i
+		if seq > podBoundary {
+			break
+		}
+		stored, err := ldt.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+		if err != nil || !bytes.Equal(stored, txn) {
+			return false, fmt.Errorf("transaction %d of block %d differs from the station but is already part of a pod", seq, height)
+		}
+	}
+	if err := rewriteBlock(ldb, ldt, w, commit); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// unmarkedBlockCommit returns where the transactions of w are stored when its block has
+// no commit marker, because it is missing or was stored before markers existed. Blocks
+// without transactions go after the transactions of the block before. The transactions
+// of other blocks are found by their hash index, and must all be stored, in order.
+func unmarkedBlockCommit(ldb, ldt *leveldb.DB, w blockWrite) (blockCommit, error) {
+	if len(w.txns) == 0 {
+		txnCount, found := finalTxnCount(ldb, w.height-1)
+		if !found {
+			return blockCommit{}, fmt.Errorf("block %d is stored without a commit marker", w.height)
+		}
+		return blockCommit{BlockKey: w.blockKey, FirstTxn: txnCount + 1, TxnCount: txnCount}, nil
+	}
+	first, found := indexedTxnSeq(ldt, w, 0)
+	for i := 1; found && i < len(w.txns); i++ {
+		seq, ok := indexedTxnSeq(ldt, w, i)
+		found = ok && seq == first+i
+	}
+	if !found {
+		return blockCommit{}, fmt.Errorf("block %d is stored without a commit marker and its %d transactions at the station are not stored in order", w.height, len(w.txns))
+	}
+	return blockCommit{BlockKey: w.blockKey, FirstTxn: first, TxnCount: first + len(w.txns) - 1}, nil
+}
+
+// indexedTxnSeq returns the number of the stored transaction with the hash of the i-th
+// transaction of w.
+func indexedTxnSeq(ldt *leveldb.DB, w blockWrite, i int) (int, bool) {
+	if w.txnIndexes == nil {
+		return 0, false
+	}
+	hashPrefix := string(txnHashKey(""))
+	for key := range w.txnIndexes(i, 0) {
+		if !strings.HasPrefix(key, hashPrefix) {
+			continue
+		}
+		value, err := ldt.Get([]byte(key), nil)
+		if err != nil {
+			return 0, false
+		}
+		seq, err := strconv.Atoi(string(value))
+		return seq, err == nil
+	}
+	return 0, false
+}
+
+// storedBlockEquals reports whether the stored block and transactions of commit are
+// exactly w.
+func storedBlockEquals(ldb, ldt *leveldb.DB, w blockWrite, commit blockCommit) (bool, error) {
+	if commit.BlockKey != w.blockKey || commit.TxnCount-commit.FirstTxn+1 != len(w.txns) {
+		return false, nil
+	}
+	stored, err := ldb.Get([]byte(commit.BlockKey), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(stored, w.blockData) {
+		return false, nil
+	}
+	for i, txn := range w.txns {
+		stored, err := ldt.Get([]byte(fmt.Sprintf("txns-%d", commit.FirstTxn+i)), nil)
+		if errors.Is(err, leveldb.ErrNotFound) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(stored, txn) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// VerifyBlocks compares the hash of every stored block from to to with the hash the
+// station reports for it.
+func VerifyBlocks(ctx context.Context, stationType string, opts IndexerOptions, from, to int) (VerifyReport, error) {
+	source, err := newBlockSource(ctx, stationType, opts)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	return verifyBlocks(ctx, source, opts.BlockDB, from, to)
+}
+
+func verifyBlocks(ctx context.Context, source blockSource, ldb *leveldb.DB, from, to int) (VerifyReport, error) {
+	var report VerifyReport
+	if err := checkStoredRange(ldb, from, to); err != nil {
+		return report, err
+	}
+	for height := from; height <= to; height++ {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		stored, station, err := compareBlockHash(ctx, source, ldb, height)
+		if err != nil {
+			report.Failed = append(report.Failed, BlockError{Height: height, Err: err})
+			continue
+		}
+		report.Checked++
+		if !strings.EqualFold(stored, station) {
+			report.Mismatches = append(report.Mismatches, BlockMismatch{Height: height, Stored: stored, Station: station})
+		}
+	}
+	return report, nil
+}
+
+func compareBlockHash(ctx context.Context, source blockSource, ldb *leveldb.DB, height int) (string, string, error) {
+	stored, err := readStoredBlockHash(source, ldb, height)
+	if err != nil {
+		return "", "", err
+	}
+	station, err := source.stationHash(ctx, height)
+	if err != nil {
+		return "", "", err
+	}
+	return stored, station, nil
+}
+
+// StoredBlockHash returns the hash of the block stored at height by a stationType
+// indexer.
+func StoredBlockHash(ldb *leveldb.DB, stationType string, height int) (string, error) {
+	var source blockSource
+	switch NormalizeStationType(stationType) {
+	case StationTypeEVM:
+		source = &evmBlockSource{}
+	case StationTypeWASM:
+		source = &wasmBlockSource{}
+	case StationTypeSVM:
+		source = svmBlockSource{}
+	default:
+		return "", fmt.Errorf("unsupported station type %q, must be one of: %s", stationType, strings.Join(SupportedStationTypes(), ", "))
+	}
+	return readStoredBlockHash(source, ldb, height)
+}
+
+func readStoredBlockHash(source blockSource, ldb *leveldb.DB, height int) (string, error) {
+	keys := source.blockKeys(height)
+	if commit, ok := readBlockCommit(ldb, height); ok {
+		keys = []string{commit.BlockKey}
+	}
+	var data []byte
+	for _, key := range keys {
+		if value, err := ldb.Get([]byte(key), nil); err == nil {
+			data = value
+			break
+		}
+	}
+	if data == nil {
+		return "", fmt.Errorf("block %d is not stored", height)
+	}
+	stored, err := source.storedHash(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode stored block %d: %w", height, err)
+	}
+	return stored, nil
+}
+
+// LatestIndexedBlock returns the height of the last stored block, or -1 if there is
+// none.
+func LatestIndexedBlock(ldb *leveldb.DB) int {
+	return readCounter(ldb, "blockCount") - 1
+}
+
+// checkStoredRange checks that from to to is a range of indexed heights.
+func checkStoredRange(ldb *leveldb.DB, from, to int) error {
+	latest := LatestIndexedBlock(ldb)
+	switch {
+	case from < 0 || to < from:
+		return fmt.Errorf("invalid block range %d to %d", from, to)
+	case to > latest:
+		return fmt.Errorf("block %d is not indexed yet, the latest indexed block is %d", to, latest)
+	case from < firstStoredBlock(ldb):
+		return fmt.Errorf("block %d was pruned, the first stored block is %d", from, firstStoredBlock(ldb))
+	}
+	return nil
+}
+
+type evmBlockSource struct {
+	client EVMClient
+	signer ethTypes.Signer
+}
+
+func (s *evmBlockSource) fetchBlock(ctx context.Context, height int) (blockWrite, error) {
+	if s.signer == nil {
+		chainID, err := s.client.NetworkID(ctx)
+		if err != nil {
+			return blockWrite{}, fmt.Errorf("failed to get the network ID: %w", err)
+		}
+		s.signer = ethTypes.LatestSignerForChainID(chainID)
+	}
+	fetched, err := fetchEVMBlock(ctx, s.client, height)
+	if err != nil {
+		return blockWrite{}, err
+	}
+	return evmBlockWrite(s.signer, fetched)
+}
+
+func (s *evmBlockSource) stationHash(ctx context.Context, height int) (string, error) {
+	header, err := s.client.HeaderByNumber(ctx, big.NewInt(int64(height)))
+	if err != nil {
+		return "", fmt.Errorf("failed to get the header of block %d: %w", height, err)
+	}
+	return header.Hash().String(), nil
+}
+
+func (s *evmBlockSource) storedHash(blockData []byte) (string, error) {
+	var block types.BlockStruct
+	if err := json.Unmarshal(blockData, &block); err != nil {
+		return "", err
+	}
+	return block.Hash, nil
+}
+
+func (s *evmBlockSource) blockKeys(height int) []string {
+	return []string{blockKey(height)}
+}
+
+type wasmBlockSource struct {
+	stationRPC *stationclient.Client
+}
+
+func (s *wasmBlockSource) fetchBlock(ctx context.Context, height int) (blockWrite, error) {
+	return fetchWasmBlockWrite(ctx, s.stationRPC, height)
+}
+
+func (s *wasmBlockSource) stationHash(ctx context.Context, height int) (string, error) {
+	_, block, err := fetchWasmBlock(ctx, s.stationRPC, height)
+	if err != nil {
+		return "", err
+	}
+	return block.Result.BlockID.Hash, nil
+}
+
+func (s *wasmBlockSource) storedHash(blockData []byte) (string, error) {
+	var result struct {
+		BlockID struct {
+			Hash string `json:"hash"`
+		} `json:"block_id"`
+	}
+	if err := json.Unmarshal(blockData, &result); err != nil {
+		return "", err
+	}
+	return result.BlockID.Hash, nil
+}
+
+func (s *wasmBlockSource) blockKeys(height int) []string {
+	return []string{blockKey(height)}
+}
+
+type svmBlockSource struct {
+	rpc svmRPC
+}
+
+func (s svmBlockSource) fetchBlock(_ context.Context, slot int) (blockWrite, error) {
+	return fetchSVMSlotWrite(s.rpc, slot)
+}
+
+func (s svmBlockSource) stationHash(_ context.Context, slot int) (string, error) {
+	res, err := s.rpc.SVMBlockCall(slot)
+	var rpcErr *SVMRPCError
+	if errors.As(err, &rpcErr) && rpcErr.SlotSkipped() {
+		return skippedSlotHash, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return res.Result.Blockhash, nil
+}
+
+func (svmBlockSource) storedHash(blockData []byte) (string, error) {
+	var block struct {
+		Blockhash string `json:"blockhash"`
+		Skipped   bool   `json:"skipped"`
+	}
+	if err := json.Unmarshal(blockData, &block); err != nil {
+		return "", err
+	}
+	if block.Skipped {
+		return skippedSlotHash, nil
+	}
+	return block.Blockhash, nil
+}
+
+func (svmBlockSource) blockKeys(slot int) []string {
+	return []string{blockKey(slot), svmSkippedSlotKey(slot)}
+}
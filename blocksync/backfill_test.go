@@ -0,0 +1,212 @@
+package blocksync
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// storeTestEVMChain indexes every block of client and returns the stored transactions
+// by sequence.
+func storeTestEVMChain(t *testing.T, client *fakeEVMClient, opts IndexerOptions) map[int][]byte {
+	t.Helper()
+	for height := 0; height < len(client.blocks); height++ {
+		if err := StoreEVMBlock(context.Background(), client, height, opts.BlockDB, opts.TxnDB); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored := make(map[int][]byte)
+	for seq := 1; seq <= readCounter(opts.TxnDB, "txnCount"); seq++ {
+		stored[seq], _ = opts.TxnDB.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+	}
+	return stored
+}
+
+func TestBackfillRewritesBlocksInPlace(t *testing.T) {
+	client := newFakeEVMClient(t, 6, 2)
+	blockDB, txnDB := newTestDBs(t)
+	opts := IndexerOptions{BlockDB: blockDB, TxnDB: txnDB, StaticDB: newMemDB(t)}
+	stored := storeTestEVMChain(t, client, opts)
+	block2, _ := blockDB.Get([]byte("Block2"), nil)
+
+	// Block 1 holds transactions 3 and 4.
+	txnDB.Put([]byte("txns-3"), []byte(`{"hash":"garbage"}`), nil)
+	blockDB.Put([]byte("Block2"), []byte("{}"), nil)
+
+	source := &evmBlockSource{client: client}
+	report, err := backfillBlocks(context.Background(), source, opts, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Rewritten, []int{1, 2}) || report.Unchanged != 4 || len(report.Failed) != 0 {
+		t.Fatalf("Backfill() = %+v, want blocks 1 and 2 rewritten", report)
+	}
+	if got, _ := txnDB.Get([]byte("txns-3"), nil); string(got) != string(stored[3]) {
+		t.Errorf("txns-3 = %s, want the station transaction", got)
+	}
+	if got, _ := blockDB.Get([]byte("Block2"), nil); string(got) != string(block2) {
+		t.Errorf("Block2 = %s, want the station block", got)
+	}
+	if got := readCounter(txnDB, "txnCount"); got != 12 {
+		t.Errorf("txnCount = %d, want 12", got)
+	}
+	if seq, err := GetTxnSeqByHash(txnDB, client.blocks[1].Transactions()[0].Hash().Hex()); err != nil || seq != 3 {
+		t.Errorf("GetTxnSeqByHash() = %d, %v, want 3", seq, err)
+	}
+
+	// Transactions already in a pod are left alone.
+	opts.StaticDB.Put([]byte("batchStartIndex"), []byte("4"), nil)
+	txnDB.Put([]byte("txns-4"), []byte(`{"hash":"garbage"}`), nil)
+	report, err = backfillBlocks(context.Background(), source, opts, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Failed) != 1 || !strings.Contains(report.Failed[0].Err.Error(), "already part of a pod") {
+		t.Errorf("Backfill() = %+v, want block 1 refused", report)
+	}
+
+	if _, err := backfillBlocks(context.Background(), source, opts, 4, 9); err == nil {
+		t.Error("Backfill() accepted blocks that are not indexed")
+	}
+}
+
+func TestVerifyBlocksReportsMismatches(t *testing.T) {
+	client := newFakeEVMClient(t, 6, 2)
+	blockDB, txnDB := newTestDBs(t)
+	opts := IndexerOptions{BlockDB: blockDB, TxnDB: txnDB}
+	storeTestEVMChain(t, client, opts)
+	source := &evmBlockSource{client: client}
+
+	report, err := verifyBlocks(context.Background(), source, blockDB, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked != 6 || len(report.Mismatches) != 0 || len(report.Failed) != 0 {
+		t.Fatalf("VerifyBlocks() = %+v, want 6 matching blocks", report)
+	}
+
+	// The station replaces blocks 4 and 5 with blocks carrying three transactions.
+	client.reorg(t, 4, 2, 3)
+	report, err = verifyBlocks(context.Background(), source, blockDB, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var heights []int
+	for _, mismatch := range report.Mismatches {
+		heights = append(heights, mismatch.Height)
+		if mismatch.Station != client.blocks[mismatch.Height].Hash().String() {
+			t.Errorf("block %d station hash = %s, want %s", mismatch.Height, mismatch.Station, client.blocks[mismatch.Height].Hash())
+		}
+	}
+	if !reflect.DeepEqual(heights, []int{4, 5}) {
+		t.Errorf("mismatches at %v, want blocks 4 and 5", heights)
+	}
+
+	// Their transactions can not keep their sequence numbers.
+	backfill, err := backfillBlocks(context.Background(), source, opts, 4, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backfill.Failed) != 1 || !strings.Contains(backfill.Failed[0].Err.Error(), "3 transactions at the station but 2 are stored") {
+		t.Errorf("Backfill() = %+v, want a transaction count mismatch", backfill)
+	}
+}
+
+func TestBackfillStoresMissingEmptyBlock(t *testing.T) {
+	blockDB, txnDB := newTestDBs(t)
+	commitTestBlocks(t, blockDB, txnDB, 2, 0, 1)
+	// Block 1 went missing together with its commit marker.
+	blockDB.Delete([]byte("Block1"), nil)
+	blockDB.Delete(blockCommitKey(1), nil)
+
+	source := &stubBlockSource{blocks: map[int]blockWrite{
+		0: {height: 0, blockKey: "Block0", blockData: []byte("{}"), txns: [][]byte{[]byte(`{"block":0,"index":0}`), []byte(`{"block":0,"index":1}`)}},
+		1: {height: 1, blockKey: "Block1", blockData: []byte(`{"hash":"0x01"}`)},
+		2: {height: 2, blockKey: "Block2", blockData: []byte("{}"), txns: [][]byte{[]byte(`{"block":2,"index":0}`)}},
+	}}
+	report, err := backfillBlocks(context.Background(), source, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB}, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Rewritten, []int{1}) || report.Unchanged != 2 {
+		t.Fatalf("Backfill() = %+v, want block 1 stored", report)
+	}
+	if commit, ok := readBlockCommit(blockDB, 1); !ok || commit.FirstTxn != 3 || commit.TxnCount != 2 {
+		t.Errorf("commit marker of block 1 = %+v, %v, want an empty block after transaction 2", commit, ok)
+	}
+	if err := checkBlockContiguity(blockDB, "Block", 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBackfillBlockStoredBeforeCommitMarkers(t *testing.T) {
+	blockDB, txnDB := newTestDBs(t)
+	hashIndexed := func(block int) func(i, seq int) map[string][]byte {
+		return func(i, seq int) map[string][]byte {
+			return txnIndexes(seq, fmt.Sprintf("0x%02x%02x", block, i))
+		}
+	}
+	blocks := make(map[int]blockWrite)
+	for height, count := range []int{1, 2} {
+		w := blockWrite{height: height, blockKey: blockKey(height), blockData: []byte("{}"), txnIndexes: hashIndexed(height)}
+		for i := 0; i < count; i++ {
+			w.txns = append(w.txns, []byte(fmt.Sprintf(`{"block":%d,"index":%d}`, height, i)))
+		}
+		if err := commitBlock(blockDB, txnDB, w); err != nil {
+			t.Fatal(err)
+		}
+		blocks[height] = w
+	}
+	// Both blocks were stored before commit markers, and block 1 changed at the station.
+	blockDB.Delete(blockCommitKey(0), nil)
+	blockDB.Delete(blockCommitKey(1), nil)
+	changed := blocks[1]
+	changed.blockData = []byte(`{"hash":"0x01"}`)
+	blocks[1] = changed
+
+	report, err := backfillBlocks(context.Background(), &stubBlockSource{blocks: blocks}, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB}, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Rewritten, []int{1}) {
+		t.Fatalf("Backfill() = %+v, want block 1 rewritten", report)
+	}
+	if commit, ok := readBlockCommit(blockDB, 1); !ok || commit.FirstTxn != 2 || commit.TxnCount != 3 {
+		t.Errorf("commit marker of block 1 = %+v, %v, want transactions 2 to 3", commit, ok)
+	}
+
+	// Transactions that are not stored cannot be placed.
+	missing := blocks[0]
+	missing.txnIndexes = hashIndexed(9)
+	source := &stubBlockSource{blocks: map[int]blockWrite{0: missing}}
+	if report, _ := backfillBlocks(context.Background(), source, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB}, 0, 0); len(report.Failed) != 1 {
+		t.Errorf("Backfill() = %+v, want block 0 failed", report)
+	}
+}
+
+// stubBlockSource serves fixed block writes.
+type stubBlockSource struct {
+	blocks map[int]blockWrite
+}
+
+func (s *stubBlockSource) fetchBlock(_ context.Context, height int) (blockWrite, error) {
+	w, ok := s.blocks[height]
+	if !ok {
+		return w, fmt.Errorf("block %d not available", height)
+	}
+	return w, nil
+}
+
+func (s *stubBlockSource) stationHash(_ context.Context, height int) (string, error) {
+	return fmt.Sprint(height), nil
+}
+
+func (s *stubBlockSource) storedHash(blockData []byte) (string, error) {
+	return string(blockData), nil
+}
+
+func (s *stubBlockSource) blockKeys(height int) []string {
+	return []string{blockKey(height)}
+}
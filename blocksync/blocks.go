@@ -3,36 +3,48 @@ package blocksync
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"math/big"
-	"net/http"
-	"os"
 	"strconv"
 	"time"
 
 	logs "github.com/airchains-network/decentralized-sequencer/log"
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
 	"github.com/airchains-network/decentralized-sequencer/types"
 	"github.com/airchains-network/decentralized-sequencer/utils"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/rs/zerolog"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/rs/zerolog/log"
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
-func StoreEVMBlock(client *ethclient.Client, ctx context.Context, blockIndex int, ldb *leveldb.DB, ldt *leveldb.DB) {
-	zerolog.TimeFieldFormat = time.RFC3339
-	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
-	blockData, err := client.BlockByNumber(ctx, big.NewInt(int64(blockIndex)))
-	if err != nil {
+const (
+	evmRetryInterval  = 3 * time.Second
+	wasmPollInterval  = 7 * time.Second
+	wasmRetryInterval = 2 * time.Second
+	svmPollInterval   = 2 * time.Second
+	svmRetryInterval  = 2 * time.Second
+	// svmBlocksRange is the number of slots requested per getBlocks call.
+	svmBlocksRange = 1000
+)
 
-		// errMessage := fmt.Sprintf("Failed to get block data for block number %d: %s", blockIndex, err)
-		// log.Warn().Str("module", "blocksync").Err(err).Msg(errMessage)
-		time.Sleep(3 * time.Second)
-		StoreEVMBlock(client, ctx, blockIndex, ldb, ldt)
+// StoreEVMBlock fetches a single block from the station and stores it together with its
+// transactions. It returns an error when the block is not available yet so the caller
+// can retry, and an *ErrReorgDetected when the block does not extend the stored chain.
+func StoreEVMBlock(ctx context.Context, client EVMClient, blockIndex int, ldb *leveldb.DB, ldt *leveldb.DB) error {
+	chainID, err := client.NetworkID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the network ID: %w", err)
+	}
+	fetched, err := fetchEVMBlock(ctx, client, blockIndex)
+	if err != nil {
+		return err
 	}
+	return commitEVMBlock(ldb, ldt, ethTypes.LatestSignerForChainID(chainID), fetched)
+}
 
-	var block = types.BlockStruct{
+// evmBlockStruct converts a station block into the format stored under Block<n>.
+func evmBlockStruct(blockData *ethTypes.Block) types.BlockStruct {
+	return types.BlockStruct{
 		BaseFeePerGas:    utils.ToString(blockData.Header().BaseFee),
 		Difficulty:       utils.ToString(blockData.Difficulty().String()),
 		ExtraData:        utils.ToString(blockData.Extra()),
@@ -51,303 +63,288 @@ func StoreEVMBlock(client *ethclient.Client, ctx context.Context, blockIndex int
 		StateRoot:        utils.ToString(blockData.Root().String()),
 		Timestamp:        utils.ToString(blockData.Time()),
 		TotalDifficulty:  utils.ToString(blockData.Difficulty().String()),
-		TransactionCount: blockData.Transactions().Len(), // Assuming transactionCount is already defined and holds the appropriate value
+		TransactionCount: blockData.Transactions().Len(),
 		TransactionsRoot: utils.ToString(blockData.TxHash().String()),
 		Uncles:           utils.ToString(blockData.Uncles()),
 	}
-	data, err := json.Marshal(block)
-	if err != nil {
-		errMessage := fmt.Sprintf("Error marshalling block data: %s", err)
-		log.Error().Str("module", "blocksync").Err(err).Msg(errMessage)
+}
 
+// StoreWasmBlock stores every station block after the last stored one, in height order,
+// and then follows the chain until ctx is cancelled.
+func StoreWasmBlock(ctx context.Context, heads *HeadNotifier, ldb *leveldb.DB, ldt *leveldb.DB, stationRPC, stationAPI *stationclient.Client) error {
+	if err := checkBlockContiguity(ldb, "Block", 1); err != nil {
+		log.Warn().Str("module", "blocksync").Err(err).Msg("Stored wasm blocks are not contiguous, transactions of the missing blocks are not indexed")
 	}
-	key := fmt.Sprintf("block_%s", block.Number)
-	err = ldb.Put([]byte(key), data, nil)
-	if err != nil {
-		errMessage := fmt.Sprintf("Error inserting block data into database: %s", err)
-		log.Error().Str("module", "blocksync").Err(err).Msg(errMessage)
+	nextHeight := readCounter(ldb, "blockCount")
+	if nextHeight == 0 {
+		nextHeight = 1
 	}
+	watchWasmBlocks(ctx, heads, stationRPC, stationAPI, nextHeight, ldb, ldt)
+	return nil
+}
 
-	transactions := blockData.Transactions()
-	if transactions == nil {
-		log.Info().Str("module", "blocksync").Msg(fmt.Sprintf("No Txn In  %s", block.Number))
+func GetWasmCurrentBlock(ctx context.Context, stationAPI *stationclient.Client) (BlockObject, error) {
+	res, err := stationAPI.Get(ctx, "/cosmos/base/tendermint/v1beta1/blocks/latest")
+	if err != nil {
+		return BlockObject{}, err
 	}
 
-	for i := 0; i < block.TransactionCount; i++ {
-		StoreEVMTransactions(client, ctx, ldt, transactions[i].Hash().String(), blockIndex, block.Hash)
+	var data BlockObject
+	if err := json.Unmarshal(res, &data); err != nil {
+		return BlockObject{}, err
 	}
 
-	blockCount := blockIndex + 1
-	err = ldb.Put([]byte("blockCount"), []byte(strconv.Itoa(blockCount)), nil)
-	if err != nil {
-		errMessage := fmt.Sprintf("Error inserting block count into database: %s", err)
-		log.Error().Str("module", "blocksync").Err(err).Msg(errMessage)
-	}
-	StoreEVMBlock(client, ctx, blockIndex+1, ldb, ldt)
+	return data, nil
 }
 
-func getLastProcessedBlock(db *leveldb.DB) int {
-	lastBlockKey := []byte("lastProcessedBlock")
-	data, err := db.Get(lastBlockKey, nil)
-	if err != nil {
-		if err == leveldb.ErrNotFound {
-			// If not found, return 0 indicating start from the beginning
-			return 0
+// watchWasmBlocks stores every height from nextHeight up to the latest station block,
+// retrying a height until it is stored, and then waits for the next head.
+func watchWasmBlocks(ctx context.Context, heads *HeadNotifier, stationRPC, stationAPI *stationclient.Client, nextHeight int, db *leveldb.DB, txnDB *leveldb.DB) {
+	for ctx.Err() == nil {
+		latestBlock, err := GetWasmCurrentBlock(ctx, stationAPI)
+		if err != nil {
+			logs.Log.Debug(err.Error())
+			sleepContext(ctx, wasmRetryInterval)
+			continue
+		}
+		latestHeight, err := strconv.Atoi(latestBlock.Block.Header.Height)
+		if err != nil {
+			log.Error().Str("module", "blocksync").Err(err).Msg("Invalid latest wasm block height")
+			sleepContext(ctx, wasmRetryInterval)
+			continue
 		}
 
+		for nextHeight <= latestHeight && ctx.Err() == nil {
+			if err := storeWasmBlockAt(ctx, db, txnDB, stationRPC, nextHeight); err != nil {
+				log.Warn().Str("module", "blocksync").Err(err).Msg(fmt.Sprintf("Failed to store wasm block %d, retrying", nextHeight))
+				sleepContext(ctx, wasmRetryInterval)
+				continue
+			}
+			nextHeight++
+		}
+		heads.Wait(ctx)
 	}
-	lastBlockNum, _ := strconv.Atoi(string(data))
-	return lastBlockNum
 }
 
-func StoreWasmBlock(ldb *leveldb.DB, ldt *leveldb.DB, JsonRPC string, JsonAPI string) {
-	rpcUrl := fmt.Sprintf("%s/cosmos/base/tendermint/v1beta1/blocks/latest", JsonAPI)
-	res, resErr := http.Get(rpcUrl)
-	if resErr != nil {
-		log.Error().Str("module", "blocksync").Err(resErr).Msg("")
+// storeWasmBlockAt fetches block height and the results of its transactions from the
+// station RPC and commits them. The block must directly follow the last stored one.
+func storeWasmBlockAt(ctx context.Context, db *leveldb.DB, txnDB *leveldb.DB, stationRPC *stationclient.Client, height int) error {
+	if blockCount := readCounter(db, "blockCount"); blockCount != 0 && blockCount != height {
+		return fmt.Errorf("wasm block %d does not follow stored block %d", height, blockCount-1)
 	}
-	defer res.Body.Close()
-	bodyBlockHeight, bodyBlockHeightErr := io.ReadAll(res.Body)
-	if bodyBlockHeightErr != nil {
-		log.Error().Str("module", "blocksync").Err(bodyBlockHeightErr).Msg("")
+	w, err := fetchWasmBlockWrite(ctx, stationRPC, height)
+	if err != nil {
+		return err
 	}
+	return commitBlock(db, txnDB, w)
+}
 
-	var blockHeight BlockObject
-	error := json.Unmarshal(bodyBlockHeight, &blockHeight)
-	if error != nil {
-		log.Error().Str("module", "blocksync").Err(error).Msg("")
+// fetchWasmBlock returns block height as stored under Block<height>, together with the
+// decoded response.
+func fetchWasmBlock(ctx context.Context, stationRPC *stationclient.Client, height int) ([]byte, Response, error) {
+	var blockData Response
+	body, err := stationRPC.Get(ctx, fmt.Sprintf("/block?height=%d", height))
+	if err != nil {
+		return nil, blockData, fmt.Errorf("failed to fetch block %d: %w", height, err)
 	}
-	latestBlock := blockHeight.Block.Header.Height
 
-	numLatestBlock, err := strconv.Atoi(latestBlock)
+	if err := json.Unmarshal(body, &blockData); err != nil {
+		return nil, blockData, fmt.Errorf("failed to decode block %d: %w", height, err)
+	}
+	if blockData.Result.Block.Header.Height != strconv.Itoa(height) {
+		return nil, blockData, fmt.Errorf("station did not return block %d: %s", height, body)
+	}
+	var responseMap map[string]json.RawMessage
+	if err := json.Unmarshal(body, &responseMap); err != nil {
+		return nil, blockData, fmt.Errorf("failed to decode block %d: %w", height, err)
+	}
+	var result interface{}
+	if err := json.Unmarshal(responseMap["result"], &result); err != nil {
+		return nil, blockData, fmt.Errorf("failed to decode block %d: %w", height, err)
+	}
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		log.Error().Str("module", "blocksync").Err(err).Msg("")
+		return nil, blockData, err
 	}
-	lastBlock := getLastProcessedBlock(ldb)
-	startBlock := lastBlock + 1
-
-	OldWasmBlocks(JsonRPC, JsonAPI, startBlock, numLatestBlock, ldb, ldt)
-	NewWasmBlocks(JsonRPC, JsonAPI, numLatestBlock, ldb, ldt)
-
+	return resultJSON, blockData, nil
 }
 
-func OldWasmBlocks(JsonRPC string, JsonAPI string, startBlock int, numLatestBlock int, db *leveldb.DB, txnDB *leveldb.DB) {
-	for i := startBlock; i <= numLatestBlock; i++ {
-		rpcUrl := fmt.Sprintf("%s/block?height=%d", JsonRPC, i)
-		resp, err := http.Get(rpcUrl)
-		if err != nil {
-			logs.Log.Error(err.Error())
-
-		}
+// fetchWasmBlockWrite fetches block height and the results of its transactions and
+// returns the entries stored for them.
+func fetchWasmBlockWrite(ctx context.Context, stationRPC *stationclient.Client, height int) (blockWrite, error) {
+	resultJSON, blockData, err := fetchWasmBlock(ctx, stationRPC, height)
+	if err != nil {
+		return blockWrite{}, err
+	}
 
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close() // Close the response body here
+	var txns [][]byte
+	if txs := blockData.Result.Block.Data.Txs; len(txs) > 0 {
+		results, err := fetchWasmBlockResults(ctx, stationRPC, height)
 		if err != nil {
-			logs.Log.Error(err.Error())
+			return blockWrite{}, err
 		}
-
-		var blockData Response
-
-		jsonErr := json.Unmarshal(body, &blockData)
-		if jsonErr != nil {
-			log.Error().Str("module", "blocksync").Err(jsonErr).Msg("")
-		}
-		if len(blockData.Result.Block.Data.Txs) > 0 {
-			StoreWasmTransaction(blockData.Result.Block.Data.Txs, txnDB, JsonAPI)
-		}
-
-		var responseMap map[string]interface{}
-		if err := json.Unmarshal(body, &responseMap); err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in response: %v", err))
-			continue
-		}
-		if result, ok := responseMap["result"]; ok {
-			resultJSON, err := json.MarshalIndent(result, "", "  ")
-			if err != nil {
-				log.Error().Str("module", "blocksync").Err(err).Msg("")
-			}
-
-			blockKey := []byte("Block" + strconv.Itoa(i))
-
-			if err = db.Put(blockKey, resultJSON, nil); err != nil {
-				log.Error().Str("module", "blocksync").Err(err).Msg("")
-			}
-
-			blockCount := i + 1
-			err = db.Put([]byte("blockCount"), []byte(strconv.Itoa(blockCount)), nil)
-			if err != nil {
-				log.Error().Str("module", "blocksync").Err(err).Msg("")
-			}
+		header := blockData.Result.Block.Header
+		if txns, err = decodeWasmTransactions(txs, results, header.Height, header.Time); err != nil {
+			return blockWrite{}, err
 		}
-
 	}
+	var blockTime int64
+	if t, err := time.Parse(time.RFC3339Nano, blockData.Result.Block.Header.Time); err == nil {
+		blockTime = t.Unix()
+	}
+	return blockWrite{
+		height:    height,
+		blockKey:  blockKey(height),
+		blockData: resultJSON,
+		txns:      txns,
+		time:      blockTime,
+		txnIndexes: func(i, seq int) map[string][]byte {
+			return wasmTxnIndexes(txns[i], seq)
+		},
+	}, nil
 }
 
-func GetWasmCurrentBlock(JsonAPI string) (BlockObject, error) {
-	rpcUrl := fmt.Sprintf("%s/cosmos/base/tendermint/v1beta1/blocks/latest", JsonAPI)
-	res, err := http.Get(rpcUrl)
-	if err != nil {
-		return BlockObject{}, err
-	}
-	defer res.Body.Close()
+// * SVM chain
 
-	var data BlockObject
-	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
-		return BlockObject{}, err
+// StoreSVMBlock stores every slot after the last stored one, in slot order, and then
+// follows the chain until ctx is cancelled. Slots are heights: blockCount is the last
+// stored slot + 1, and slots that produced no block are recorded as skipped.
+func StoreSVMBlock(ctx context.Context, heads *HeadNotifier, ldb *leveldb.DB, ldt *leveldb.DB, stationRPC *stationclient.Client, commitment string) error {
+	rpc := newSVMRPC(stationRPC, commitment)
+	if _, err := rpc.SVMLatestBlockCheck(); err != nil {
+		return fmt.Errorf("error while fetching latest block: %w", err)
 	}
 
-	return data, nil
+	nextSlot := readCounter(ldb, "blockCount")
+	if nextSlot == 0 {
+		nextSlot = 1
+	}
+	watchSVMBlocks(ctx, rpc, heads, nextSlot, ldb, ldt)
+	return nil
 }
 
-func watchWasmBlocks(JsonRPC string, JsonAPI string, currentBlockHeight int, db *leveldb.DB, txnDB *leveldb.DB) {
-	var currentBlock BlockObject
-	for {
-		latestBlock, err := GetWasmCurrentBlock(JsonAPI)
+// watchSVMBlocks stores every slot from nextSlot up to the latest station slot, at most
+// svmBlocksRange slots per getBlocks call, and then waits for the next head.
+func watchSVMBlocks(ctx context.Context, rpc svmRPC, heads *HeadNotifier, nextSlot int, ldb, ldt *leveldb.DB) {
+	for ctx.Err() == nil {
+		latestSlot, err := rpc.SVMLatestBlockCheck()
 		if err != nil {
 			logs.Log.Debug(err.Error())
-			time.Sleep(2 * time.Second)
+			sleepContext(ctx, svmRetryInterval)
 			continue
 		}
 
-		if currentBlock.Block.Header.Height == latestBlock.Block.Header.Height {
-			time.Sleep(7 * time.Second)
-			continue
-		}
-		log.Info().Str("module", "blocksync").Msg("New Block Found")
-		rpcUrl := fmt.Sprintf("%s/block?height=%s", JsonRPC, latestBlock.Block.Header.Height)
-		resp, err := http.Get(rpcUrl)
-		if err != nil {
-			logs.Log.Error(err.Error())
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			logs.Log.Error(err.Error())
-			continue
-		}
-
-		var blockData Response
-
-		err = json.Unmarshal(body, &blockData)
-		if err != nil {
-			logs.Log.Error(err.Error())
-		}
-
-		if len(blockData.Result.Block.Data.Txs) > 0 {
-
-			StoreWasmTransaction(blockData.Result.Block.Data.Txs, txnDB, JsonAPI)
-		}
-
-		var responseMap map[string]interface{}
-		if err := json.Unmarshal(body, &responseMap); err != nil {
-			log.Fatal().Str("body", string(body)).Msg(err.Error())
-		}
-
-		if result, ok := responseMap["result"]; ok {
-			resultJSON, err := json.MarshalIndent(result, "", "  ")
-			if err != nil {
-				log.Fatal().Str("body", string(body)).Msg(err.Error())
-			}
-
-			blockKey := []byte("Block" + latestBlock.Block.Header.Height)
-			if err = db.Put(blockKey, resultJSON, nil); err != nil {
-
-			}
-
-			height, err := strconv.Atoi(latestBlock.Block.Header.Height)
+		for nextSlot <= latestSlot && ctx.Err() == nil {
+			endSlot := min(latestSlot, nextSlot+svmBlocksRange-1)
+			stored, err := storeSVMSlots(rpc, ldb, ldt, nextSlot, endSlot)
+			nextSlot += stored
 			if err != nil {
-				log.Error().Str("module", "blocksync").Err(err).Msg("")
-				continue
-			}
-			blockCount := height + 1
-			err = db.Put([]byte("blockCount"), []byte(strconv.Itoa(blockCount)), nil)
-			if err != nil {
-
+				log.Warn().Str("module", "blocksync").Err(err).Msg(fmt.Sprintf("Failed to store svm slot %d, retrying", nextSlot))
+				sleepContext(ctx, svmRetryInterval)
 			}
-		} else {
-			fmt.Println("Result key not found in response")
 		}
-
-		currentBlock = latestBlock
+		heads.Wait(ctx)
 	}
 }
 
-func NewWasmBlocks(JsonRPC string, JsonAPI string, currentBlock int, db *leveldb.DB, txnDB *leveldb.DB) {
-	watchWasmBlocks(JsonRPC, JsonAPI, currentBlock, db, txnDB)
-}
-
-// * SVM chain
-
-func StoreSVMBlock(ldb *leveldb.DB, ldt *leveldb.DB, JsonRPC, JsonAPI string) {
-	initSVMRPC(JsonRPC)
-
-	latestIndex, latestIndexErr := SVMLatestBlockCheck()
-	if latestIndexErr != nil {
-		log.Error().Str("module", "blocksync").Err(latestIndexErr).Msg("")
-		//return fmt.Errorf("error while fetching latest block: %v", latestIndexErr)
+// storeSVMSlots stores slots start to end and returns how many of them were stored
+// before the first error.
+func storeSVMSlots(rpc svmRPC, ldb, ldt *leveldb.DB, start, end int) (int, error) {
+	blocks, err := rpc.SVMBlocksCall(start, end)
+	if err != nil {
+		return 0, err
 	}
-
-	lastBlock := getLastProcessedBlock(ldb)
-	startBlock := lastBlock + 1
-
-	OldSVMBlock(startBlock, latestIndex, ldb, ldt)
-	NewSVMBlock(latestIndex, ldb, ldt)
-}
-
-func OldSVMBlock(startIndex, latestIndex int, ldb, ldt *leveldb.DB) {
-	for i := startIndex; i <= latestIndex; i++ {
-		SVMBlockStore(i, ldb, ldt)
-		fmt.Println("old block store : ", i)
+	produced := make(map[int]bool, len(blocks))
+	for _, slot := range blocks {
+		produced[slot] = true
 	}
-}
-
-func NewSVMBlock(currentIndex int, ldb, ldt *leveldb.DB) {
-	for {
-		latestIndex, latestIndexErr := SVMLatestBlockCheck()
-		if latestIndexErr != nil {
-			log.Error().Str("module", "blocksync").Err(latestIndexErr).Msg("")
-			//return fmt.Errorf("error while fetching latest block: %v", latestIndexErr)
-		}
 
-		if currentIndex == latestIndex {
-			fmt.Println("wait for new block...")
-			time.Sleep(2 * time.Second)
-			continue
+	for slot := start; slot <= end; slot++ {
+		if produced[slot] {
+			err = storeSVMSlot(rpc, ldb, ldt, slot)
 		} else {
-			for i := currentIndex; i < latestIndex; i++ {
-				SVMBlockStore(latestIndex, ldb, ldt)
-				fmt.Println("new block store : ", i)
-			}
-			currentIndex = latestIndex
+			err = storeSkippedSVMSlot(ldb, ldt, slot)
+		}
+		if err != nil {
+			return slot - start, err
 		}
 	}
+	return end - start + 1, nil
+}
 
+// storeSVMSlot fetches the block of slot and commits it with its transactions. The slot
+// must directly follow the last stored one.
+func storeSVMSlot(rpc svmRPC, ldb, ldt *leveldb.DB, slot int) error {
+	if blockCount := readCounter(ldb, "blockCount"); blockCount != 0 && blockCount != slot {
+		return fmt.Errorf("svm slot %d does not follow stored slot %d", slot, blockCount-1)
+	}
+	w, err := fetchSVMSlotWrite(rpc, slot)
+	if err != nil {
+		return err
+	}
+	return commitBlock(ldb, ldt, w)
 }
 
-func SVMBlockStore(blockNumber int, ldb, ldt *leveldb.DB) {
-	res, resErr := SVMBlockCall(blockNumber)
-	if resErr != nil {
-		log.Error().Str("module", "blocksync").Err(resErr).Msg("")
+// fetchSVMSlotWrite fetches the block of slot and returns the entries stored for it. A
+// slot the station reports as skipped is returned as a skipped slot.
+func fetchSVMSlotWrite(rpc svmRPC, slot int) (blockWrite, error) {
+	res, err := rpc.SVMBlockCall(slot)
+	var rpcErr *SVMRPCError
+	if errors.As(err, &rpcErr) && rpcErr.SlotSkipped() {
+		return skippedSVMSlotWrite(slot), nil
+	}
+	if err != nil {
+		return blockWrite{}, err
 	}
 
 	resJson, err := json.Marshal(res.Result)
 	if err != nil {
-		log.Error().Str("module", "blocksync").Err(err).Msg("")
+		return blockWrite{}, fmt.Errorf("error marshalling slot %d: %w", slot, err)
 	}
-
-	blockKey := []byte("Block" + strconv.Itoa(blockNumber))
-	if err = ldb.Put(blockKey, resJson, nil); err != nil {
-		log.Error().Str("module", "blocksync").Err(err).Msg("")
+	txns := make([][]byte, 0, len(res.Result.Transactions))
+	for i := range res.Result.Transactions {
+		txn, err := json.Marshal(res.Result.Transactions[i])
+		if err != nil {
+			return blockWrite{}, fmt.Errorf("error marshalling transaction %d of slot %d: %w", i, slot, err)
+		}
+		txns = append(txns, txn)
 	}
 
-	for i := 0; i < len(res.Result.Transactions); i++ {
-		StoreSVMTransaction(ldt, res.Result.Transactions[i])
+	return blockWrite{
+		height:    slot,
+		blockKey:  blockKey(slot),
+		blockData: resJson,
+		txns:      txns,
+		time:      int64(res.Result.BlockTime),
+		txnIndexes: func(i, seq int) map[string][]byte {
+			return svmTxnIndexes(res.Result.Transactions[i], seq)
+		},
+	}, nil
+}
+
+// svmSkippedSlotKey marks a slot that produced no block. It takes the place of
+// Block<slot> for that height.
+func svmSkippedSlotKey(slot int) string {
+	return "SkippedSlot" + strconv.Itoa(slot)
+}
+
+func storeSkippedSVMSlot(ldb, ldt *leveldb.DB, slot int) error {
+	if blockCount := readCounter(ldb, "blockCount"); blockCount != 0 && blockCount != slot {
+		return fmt.Errorf("svm slot %d does not follow stored slot %d", slot, blockCount-1)
 	}
+	return commitBlock(ldb, ldt, skippedSVMSlotWrite(slot))
+}
 
-	blockCount := blockNumber + 1
-	err = ldb.Put([]byte("blockCount"), []byte(strconv.Itoa(blockCount)), nil)
-	if err != nil {
-		log.Error().Str("module", "blocksync").Err(err).Msg("")
+func skippedSVMSlotWrite(slot int) blockWrite {
+	return blockWrite{
+		height:    slot,
+		blockKey:  svmSkippedSlotKey(slot),
+		blockData: []byte(fmt.Sprintf(`{"slot":%d,"skipped":true}`, slot)),
 	}
 }
+
+// IsSVMSlotSkipped reports whether slot was stored as a slot without a block.
+func IsSVMSlotSkipped(ldb *leveldb.DB, slot int) bool {
+	ok, _ := ldb.Has([]byte(svmSkippedSlotKey(slot)), nil)
+	return ok
+}
@@ -4,33 +4,97 @@ import (
 	"context"
 	"fmt"
 	"github.com/airchains-network/decentralized-sequencer/config"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 	"github.com/syndtr/goleveldb/leveldb"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
-func StartIndexer(wg *sync.WaitGroup, client *ethclient.Client, ctx context.Context, blockDatabaseConnection *leveldb.DB, txnDatabaseConnection *leveldb.DB, latestBlock int) {
+// StartIndexer builds the indexer registered for the configured station type and runs it
+// until ctx is cancelled.
+func StartIndexer(wg *sync.WaitGroup, ctx context.Context, blockDatabaseConnection *leveldb.DB, txnDatabaseConnection *leveldb.DB, latestBlock int) {
 	wg.Done()
+	zerolog.TimeFieldFormat = time.RFC3339
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+
 	bsgConfig, err := LoadConfig()
 	if err != nil {
 		fmt.Println(err)
+		return
+	}
+
+	repairedBlock, err := repairIndexedState(blockDatabaseConnection, txnDatabaseConnection)
+	if err != nil {
+		log.Error().Str("module", "blocksync").Err(err).Msg("Failed to repair indexed blocks")
+		return
+	}
+	if repairedBlock < latestBlock {
+		latestBlock = repairedBlock
+	}
+
+	opts := IndexerOptionsFromConfig(bsgConfig.Station, blockDatabaseConnection, txnDatabaseConnection)
+	opts.StartBlock = latestBlock
+	indexer, err := NewStationIndexer(bsgConfig.Station.StationType, opts)
+	if err != nil {
+		log.Error().Str("module", "blocksync").Err(err).Msg("Failed to create station indexer")
+		return
+	}
+	if err := startStateTracker(ctx, bsgConfig.Station.StationType, opts); err != nil {
+		log.Error().Str("module", "blocksync").Err(err).Msg("Failed to start the account state tracker")
+		return
 	}
+	startPruner(ctx, opts)
 
-	if bsgConfig.Station.StationType == "EVM" || bsgConfig.Station.StationType == "evm" {
-		StoreEVMBlock(client, ctx, latestBlock, blockDatabaseConnection, txnDatabaseConnection)
-	} else if bsgConfig.Station.StationType == "WASM" || bsgConfig.Station.StationType == "wasm" {
-		JsonRPC := bsgConfig.Station.StationRPC
-		JsonAPI := bsgConfig.Station.StationAPI
-		StoreWasmBlock(blockDatabaseConnection, txnDatabaseConnection, JsonRPC, JsonAPI)
-	} else if bsgConfig.Station.StationType == "SVM" || bsgConfig.Station.StationType == "svm" {
-		JsonRPC := bsgConfig.Station.StationRPC
-		JsonAPI := bsgConfig.Station.StationAPI
-		StoreSVMBlock(blockDatabaseConnection, txnDatabaseConnection, JsonRPC, JsonAPI)
+	if err := indexer.Start(ctx); err != nil {
+		log.Error().Str("module", "blocksync").Err(err).Msg("Station indexer stopped")
 	}
+}
 
+// IndexerOptionsFromConfig returns the indexer options set in the [station] section of
+// sequencer.toml for the given block and transaction databases.
+func IndexerOptionsFromConfig(conf *config.StationConfig, blockDB, txnDB *leveldb.DB) IndexerOptions {
+	return IndexerOptions{
+		StationRPC:    conf.StationRPC,
+		StationAPI:    conf.StationAPI,
+		StationWS:     conf.StationWS,
+		StationClient: StationClientOptions(conf),
+		BlockDB:       blockDB,
+		TxnDB:         txnDB,
+		StaticDB:      GetStaticDbInstance(),
+		StateDB:       GetStateDbInstance(),
+		Concurrency:   conf.IndexerConcurrency,
+		Finality: FinalityPolicy{
+			Mode:          conf.Finality,
+			Confirmations: conf.FinalityConfirmations,
+			Tag:           conf.FinalityTag,
+			Commitment:    conf.FinalityCommitment,
+		},
+		Pruning: PruningPolicy{
+			Mode:       conf.Pruning,
+			KeepRecent: conf.PruningKeepRecent,
+			Interval:   conf.PruningInterval,
+		},
+		StateTracker: StateTrackerOptions{
+			Enabled:   conf.StateTracker,
+			Genesis:   conf.StateTrackerGenesis,
+			CheckRate: conf.StateTrackerCheckRate,
+		},
+	}
+}
+
+// StationClientOptions returns the station client options set in the [station] section
+// of sequencer.toml.
+func StationClientOptions(conf *config.StationConfig) stationclient.Options {
+	return stationclient.Options{
+		Timeout:   conf.StationRPCTimeout,
+		Retries:   conf.StationRPCRetries,
+		RateLimit: conf.StationRPCRateLimit,
+	}
 }
 
 func LoadConfig() (config config.Config, err error) {
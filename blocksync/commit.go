@@ -0,0 +1,296 @@
+package blocksync
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// syncWrite makes every commit durable before the next block is written.
+var syncWrite = &opt.WriteOptions{Sync: true}
+
+// blockWrite is everything stored for one station block.
+type blockWrite struct {
+	height    int
+	blockKey  string
+	blockData []byte
+	txns      [][]byte
+	// time is the station block time in unix seconds, or 0 when the station reports none.
+	time int64
+	// txnIndexes returns the secondary index entries of the i-th transaction once it is
+	// numbered seq. It may be nil.
+	txnIndexes func(i, seq int) map[string][]byte
+}
+
+// blockCommit is stored under commit_<height> in the block database once a block and
+// its transactions are durable. TxnCount is the value of txnCount after the block, and
+// Time the station block time in unix seconds.
+type blockCommit struct {
+	BlockKey string `json:"blockKey"`
+	FirstTxn int    `json:"firstTxn"`
+	TxnCount int    `json:"txnCount"`
+	Time     int64  `json:"time,omitempty"`
+}
+
+// blockKey is the key of the block at height, or of the slot on SVM stations. EVM blocks
+// were stored under block_<height> before schema version 4.
+func blockKey(height int) string {
+	return "Block" + strconv.Itoa(height)
+}
+
+func blockCommitKey(height int) []byte {
+	return []byte(fmt.Sprintf("commit_%d", height))
+}
+
+// txnTimeKey stores the station time of the block of transaction seq, which decides
+// when a pod that is not full is sealed.
+func txnTimeKey(seq int) []byte {
+	return []byte(fmt.Sprintf("txntime-%d", seq))
+}
+
+// txnIndexListKey lists the secondary index keys written for txns-<seq> so they can be
+// removed together with the transaction.
+func txnIndexListKey(seq int) []byte {
+	return []byte(fmt.Sprintf("txnidx-%d", seq))
+}
+
+// readBlockCommit returns the commit marker of height, or false if the block was stored
+// without one.
+func readBlockCommit(ldb *leveldb.DB, height int) (blockCommit, bool) {
+	var commit blockCommit
+	data, err := ldb.Get(blockCommitKey(height), nil)
+	if err != nil {
+		return commit, false
+	}
+	if err := json.Unmarshal(data, &commit); err != nil {
+		return commit, false
+	}
+	return commit, true
+}
+
+// commitBlock stores the transactions of a block and then the block itself, each in a
+// single synced batch. The block batch carries the commit marker, so a crash between
+// the two batches leaves extra transactions that repairIndexedState removes.
+func commitBlock(ldb *leveldb.DB, ldt *leveldb.DB, w blockWrite) error {
+	firstTxn := readCounter(ldt, "txnCount") + 1
+	txnCount := firstTxn - 1
+
+	txnBatch := new(leveldb.Batch)
+	for i := range w.txns {
+		txnCount++
+		if err := putTxn(txnBatch, w, i, txnCount); err != nil {
+			return err
+		}
+	}
+	txnBatch.Put([]byte("txnCount"), []byte(strconv.Itoa(txnCount)))
+	if err := ldt.Write(txnBatch, syncWrite); err != nil {
+		return fmt.Errorf("failed to store transactions of block %d: %w", w.height, err)
+	}
+
+	commit, err := json.Marshal(blockCommit{BlockKey: w.blockKey, FirstTxn: firstTxn, TxnCount: txnCount, Time: w.time})
+	if err != nil {
+		return fmt.Errorf("error marshalling commit marker of block %d: %w", w.height, err)
+	}
+	blockBatch := new(leveldb.Batch)
+	blockBatch.Put([]byte(w.blockKey), w.blockData)
+	blockBatch.Put(blockCommitKey(w.height), commit)
+	blockBatch.Put([]byte("blockCount"), []byte(strconv.Itoa(w.height+1)))
+	if err := ldb.Write(blockBatch, syncWrite); err != nil {
+		return fmt.Errorf("error inserting block %d into database: %w", w.height, err)
+	}
+	return nil
+}
+
+// putTxn adds the i-th transaction of w to batch as txns-<seq>, with its secondary
+// indexes, its block time and the list of them.
+func putTxn(batch *leveldb.Batch, w blockWrite, i, seq int) error {
+	batch.Put([]byte(fmt.Sprintf("txns-%d", seq)), w.txns[i])
+	indexes := make(map[string][]byte)
+	if w.txnIndexes != nil {
+		for key, value := range w.txnIndexes(i, seq) {
+			indexes[key] = value
+		}
+	}
+	if w.time > 0 {
+		indexes[string(txnTimeKey(seq))] = []byte(strconv.FormatInt(w.time, 10))
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(indexes))
+	for key, value := range indexes {
+		batch.Put([]byte(key), value)
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	indexList, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("error marshalling indexes of transaction %d: %w", seq, err)
+	}
+	batch.Put(txnIndexListKey(seq), indexList)
+	return nil
+}
+
+// deleteTxnIndexes adds the removal of the secondary indexes of transaction seq, and of
+// their list, to batch.
+func deleteTxnIndexes(ldt *leveldb.DB, batch *leveldb.Batch, seq int) error {
+	indexList, err := ldt.Get(txnIndexListKey(seq), nil)
+	if err != nil {
+		return nil
+	}
+	var indexKeys []string
+	if err := json.Unmarshal(indexList, &indexKeys); err != nil {
+		return fmt.Errorf("failed to decode indexes of transaction %d: %w", seq, err)
+	}
+	for _, indexKey := range indexKeys {
+		batch.Delete([]byte(indexKey))
+	}
+	batch.Delete(txnIndexListKey(seq))
+	return nil
+}
+
+// rewriteBlock replaces a stored block and its transactions with w in place. The
+// transactions keep the sequence numbers recorded in commit, so w must hold as many of
+// them as the stored block.
+func rewriteBlock(ldb *leveldb.DB, ldt *leveldb.DB, w blockWrite, commit blockCommit) error {
+	if stored := commit.TxnCount - commit.FirstTxn + 1; len(w.txns) != stored {
+		return fmt.Errorf("block %d has %d transactions at the station but %d are stored", w.height, len(w.txns), stored)
+	}
+
+	txnBatch := new(leveldb.Batch)
+	for i := range w.txns {
+		seq := commit.FirstTxn + i
+		if err := deleteTxnIndexes(ldt, txnBatch, seq); err != nil {
+			return err
+		}
+		if err := putTxn(txnBatch, w, i, seq); err != nil {
+			return err
+		}
+	}
+	if err := ldt.Write(txnBatch, syncWrite); err != nil {
+		return fmt.Errorf("failed to rewrite transactions of block %d: %w", w.height, err)
+	}
+
+	marker, err := json.Marshal(blockCommit{BlockKey: w.blockKey, FirstTxn: commit.FirstTxn, TxnCount: commit.TxnCount, Time: w.time})
+	if err != nil {
+		return fmt.Errorf("error marshalling commit marker of block %d: %w", w.height, err)
+	}
+	blockBatch := new(leveldb.Batch)
+	if commit.BlockKey != w.blockKey {
+		blockBatch.Delete([]byte(commit.BlockKey))
+	}
+	blockBatch.Put([]byte(w.blockKey), w.blockData)
+	blockBatch.Put(blockCommitKey(w.height), marker)
+	if err := ldb.Write(blockBatch, syncWrite); err != nil {
+		return fmt.Errorf("failed to rewrite block %d: %w", w.height, err)
+	}
+	return nil
+}
+
+// truncateTxns deletes every transaction after seq, together with its secondary
+// indexes, and sets txnCount to seq. The finality boundary is lowered to match, and the
+// tracked account state goes back to before the deleted transactions.
+func truncateTxns(ldt *leveldb.DB, seq int) error {
+	accountStateMu.Lock()
+	defer accountStateMu.Unlock()
+	batch := new(leveldb.Batch)
+	if err := revertAccountStates(ldt, batch, seq); err != nil {
+		return err
+	}
+	for next := seq + 1; ; next++ {
+		key := []byte(fmt.Sprintf("txns-%d", next))
+		if ok, _ := ldt.Has(key, nil); !ok {
+			break
+		}
+		batch.Delete(key)
+		batch.Delete(txnPodKey(next))
+		if err := deleteTxnIndexes(ldt, batch, next); err != nil {
+			return err
+		}
+	}
+	batch.Put([]byte("txnCount"), []byte(strconv.Itoa(seq)))
+	if readCounter(ldt, string(finalizedTxnCountKey)) > seq {
+		batch.Put(finalizedTxnCountKey, []byte(strconv.Itoa(seq)))
+		batch.Delete(finalizedTimeKey)
+	}
+	return ldt.Write(batch, syncWrite)
+}
+
+// repairIndexedState makes the block and transaction databases agree after an unclean
+// shutdown. It keeps the highest block whose commit marker is covered by txnCount,
+// removes any block or transaction written after it and returns the repaired
+// blockCount. Databases written before commit markers existed are left untouched.
+func repairIndexedState(ldb *leveldb.DB, ldt *leveldb.DB) (int, error) {
+	blockCount := readCounter(ldb, "blockCount")
+	txnCount := readCounter(ldt, "txnCount")
+
+	height := blockCount - 1
+	keptTxns := 0
+	for ; height >= 0; height-- {
+		commit, ok := readBlockCommit(ldb, height)
+		if !ok {
+			if height == blockCount-1 {
+				// No marker on the latest block: nothing to verify against.
+				return blockCount, nil
+			}
+			keptTxns = txnCount
+			break
+		}
+		if hasBlock, _ := ldb.Has([]byte(commit.BlockKey), nil); hasBlock && commit.TxnCount <= txnCount {
+			keptTxns = commit.TxnCount
+			break
+		}
+	}
+
+	if keptTxns != txnCount {
+		log.Warn().Str("module", "blocksync").Msg(fmt.Sprintf("Removing transactions %d to %d written after block %d", keptTxns+1, txnCount, height))
+		if err := truncateTxns(ldt, keptTxns); err != nil {
+			return 0, fmt.Errorf("failed to remove half-written transactions: %w", err)
+		}
+	}
+
+	repaired := height + 1
+	if repaired < blockCount {
+		log.Warn().Str("module", "blocksync").Msg(fmt.Sprintf("Removing blocks %d to %d without stored transactions", repaired, blockCount-1))
+		batch := new(leveldb.Batch)
+		for h := repaired; h < blockCount; h++ {
+			if commit, ok := readBlockCommit(ldb, h); ok {
+				batch.Delete([]byte(commit.BlockKey))
+			}
+			batch.Delete(blockCommitKey(h))
+		}
+		clampFinalizedBlock(ldb, batch, repaired)
+		batch.Put([]byte("blockCount"), []byte(strconv.Itoa(repaired)))
+		if err := ldb.Write(batch, syncWrite); err != nil {
+			return 0, fmt.Errorf("failed to remove half-written blocks: %w", err)
+		}
+	}
+	return repaired, nil
+}
+
+// checkBlockContiguity reports an error if any height from first to blockCount-1 is
+// missing from the block database. keyPrefix is the block key without its height.
+// Pruned heights are not checked.
+func checkBlockContiguity(ldb *leveldb.DB, keyPrefix string, first int) error {
+	blockCount := readCounter(ldb, "blockCount")
+	first = max(first, firstStoredBlock(ldb))
+	missing, firstMissing := 0, 0
+	for height := first; height < blockCount; height++ {
+		if ok, _ := ldb.Has([]byte(keyPrefix+strconv.Itoa(height)), nil); ok {
+			continue
+		}
+		if missing == 0 {
+			firstMissing = height
+		}
+		missing++
+	}
+	if missing > 0 {
+		return fmt.Errorf("%d of blocks %d to %d are missing, the first is %d", missing, first, blockCount-1, firstMissing)
+	}
+	return nil
+}
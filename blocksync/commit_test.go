@@ -0,0 +1,126 @@
+package blocksync
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// commitTestBlocks commits one block per entry of txnsPerBlock, starting at height 0.
+func commitTestBlocks(t *testing.T, ldb, ldt *leveldb.DB, txnsPerBlock ...int) {
+	t.Helper()
+	for height, count := range txnsPerBlock {
+		var txns [][]byte
+		for i := 0; i < count; i++ {
+			txns = append(txns, []byte(fmt.Sprintf(`{"block":%d,"index":%d}`, height, i)))
+		}
+		err := commitBlock(ldb, ldt, blockWrite{
+			height:    height,
+			blockKey:  blockKey(height),
+			blockData: []byte("{}"),
+			txns:      txns,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRepairIndexedState(t *testing.T) {
+	tests := []struct {
+		name           string
+		txnsPerBlock   []int
+		corrupt        func(t *testing.T, ldb, ldt *leveldb.DB)
+		wantBlockCount int
+		wantTxnCount   int
+	}{
+		{
+			name:           "consistent",
+			txnsPerBlock:   []int{2, 0, 3},
+			wantBlockCount: 3,
+			wantTxnCount:   5,
+		},
+		{
+			name:         "crash after transaction batch",
+			txnsPerBlock: []int{2, 3},
+			corrupt: func(t *testing.T, ldb, ldt *leveldb.DB) {
+				batch := new(leveldb.Batch)
+				batch.Put([]byte("txns-6"), []byte("{}"))
+				batch.Put([]byte("txns-7"), []byte("{}"))
+				batch.Put([]byte("txnCount"), []byte("7"))
+				if err := ldt.Write(batch, nil); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantBlockCount: 2,
+			wantTxnCount:   5,
+		},
+		{
+			name:         "transaction database behind",
+			txnsPerBlock: []int{2, 3, 1},
+			corrupt: func(t *testing.T, ldb, ldt *leveldb.DB) {
+				if err := truncateTxns(ldt, 3); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantBlockCount: 1,
+			wantTxnCount:   2,
+		},
+		{
+			name: "transactions without blocks",
+			corrupt: func(t *testing.T, ldb, ldt *leveldb.DB) {
+				if err := ldt.Put([]byte("txns-1"), []byte("{}"), nil); err != nil {
+					t.Fatal(err)
+				}
+				if err := ldt.Put([]byte("txnCount"), []byte("1"), nil); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantBlockCount: 0,
+			wantTxnCount:   0,
+		},
+		{
+			name: "blocks without commit markers",
+			corrupt: func(t *testing.T, ldb, ldt *leveldb.DB) {
+				if err := ldb.Put([]byte("Block0"), []byte("{}"), nil); err != nil {
+					t.Fatal(err)
+				}
+				if err := ldb.Put([]byte("blockCount"), []byte("1"), nil); err != nil {
+					t.Fatal(err)
+				}
+				if err := ldt.Put([]byte("txnCount"), []byte("4"), nil); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantBlockCount: 1,
+			wantTxnCount:   4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ldb, ldt := newTestDBs(t)
+			commitTestBlocks(t, ldb, ldt, tt.txnsPerBlock...)
+			if tt.corrupt != nil {
+				tt.corrupt(t, ldb, ldt)
+			}
+
+			got, err := repairIndexedState(ldb, ldt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.wantBlockCount || readCounter(ldb, "blockCount") != tt.wantBlockCount {
+				t.Errorf("blockCount = %d (stored %d), want %d", got, readCounter(ldb, "blockCount"), tt.wantBlockCount)
+			}
+			if got := readCounter(ldt, "txnCount"); got != tt.wantTxnCount {
+				t.Errorf("txnCount = %d, want %d", got, tt.wantTxnCount)
+			}
+			if ok, _ := ldt.Has([]byte(fmt.Sprintf("txns-%d", tt.wantTxnCount+1)), nil); ok {
+				t.Errorf("txns-%d still present after repair", tt.wantTxnCount+1)
+			}
+			if ok, _ := ldb.Has([]byte(blockKey(tt.wantBlockCount)), nil); ok {
+				t.Errorf("Block%d still present after repair", tt.wantBlockCount)
+			}
+		})
+	}
+}
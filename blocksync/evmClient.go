@@ -0,0 +1,120 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// failoverEVMClient is an EVMClient over every endpoint of a station client. Calls go
+// through the station client, so they fail over, retry and are rate limited like the
+// other station requests.
+type failoverEVMClient struct {
+	station *stationclient.Client
+	clients map[string]*ethclient.Client
+}
+
+func newFailoverEVMClient(ctx context.Context, station *stationclient.Client) (*failoverEVMClient, error) {
+	clients := make(map[string]*ethclient.Client)
+	for _, endpoint := range station.Endpoints() {
+		client, err := rpc.DialOptions(ctx, endpoint, rpc.WithHTTPClient(station.HTTPClient()))
+		if err != nil {
+			return nil, fmt.Errorf("error in connecting to %s: %w", endpoint, err)
+		}
+		clients[endpoint] = ethclient.NewClient(client)
+	}
+	return &failoverEVMClient{station: station, clients: clients}, nil
+}
+
+// evmCall runs call against the first endpoint that answers it.
+func evmCall[T any](ctx context.Context, c *failoverEVMClient, call func(ctx context.Context, client *ethclient.Client) (T, error)) (T, error) {
+	var result T
+	err := c.station.Do(ctx, func(ctx context.Context, endpoint string) error {
+		r, err := call(ctx, c.clients[endpoint])
+		if err != nil {
+			return evmCallError(err)
+		}
+		result = r
+		return nil
+	})
+	return result, err
+}
+
+// evmCallError marks the answers of a working endpoint, such as a block that does not
+// exist yet or an execution error, as permanent so they are not retried on the other
+// endpoints. Other JSON-RPC errors, such as a rate limit, fail over.
+func evmCallError(err error) error {
+	var rpcErr rpc.Error
+	var httpErr rpc.HTTPError
+	switch {
+	case errors.Is(err, ethereum.NotFound), errors.Is(err, types.ErrTxTypeNotSupported):
+		return stationclient.Permanent(err)
+	case errors.As(err, &rpcErr):
+		if stationclient.PermanentRPCCode(rpcErr.ErrorCode()) {
+			return stationclient.Permanent(err)
+		}
+	case errors.As(err, &httpErr):
+		if httpErr.StatusCode < 500 && httpErr.StatusCode != http.StatusRequestTimeout && httpErr.StatusCode != http.StatusTooManyRequests {
+			return stationclient.Permanent(err)
+		}
+	}
+	return err
+}
+
+func (c *failoverEVMClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return evmCall(ctx, c, func(ctx context.Context, client *ethclient.Client) (*types.Block, error) {
+		return client.BlockByNumber(ctx, number)
+	})
+}
+
+func (c *failoverEVMClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return evmCall(ctx, c, func(ctx context.Context, client *ethclient.Client) (*types.Header, error) {
+		return client.HeaderByNumber(ctx, number)
+	})
+}
+
+func (c *failoverEVMClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	var isPending bool
+	tx, err := evmCall(ctx, c, func(ctx context.Context, client *ethclient.Client) (*types.Transaction, error) {
+		tx, pending, err := client.TransactionByHash(ctx, hash)
+		isPending = pending
+		return tx, err
+	})
+	return tx, isPending, err
+}
+
+func (c *failoverEVMClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return evmCall(ctx, c, func(ctx context.Context, client *ethclient.Client) (*types.Receipt, error) {
+		return client.TransactionReceipt(ctx, txHash)
+	})
+}
+
+func (c *failoverEVMClient) RawBlockByNumber(ctx context.Context, number *big.Int) (json.RawMessage, error) {
+	return evmCall(ctx, c, func(ctx context.Context, client *ethclient.Client) (json.RawMessage, error) {
+		var raw json.RawMessage
+		if err := client.Client().CallContext(ctx, &raw, "eth_getBlockByNumber", hexutil.EncodeBig(number), true); err != nil {
+			return nil, err
+		}
+		if len(raw) == 0 || string(raw) == "null" {
+			return nil, ethereum.NotFound
+		}
+		return raw, nil
+	})
+}
+
+func (c *failoverEVMClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	return evmCall(ctx, c, func(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+		return client.NetworkID(ctx)
+	})
+}
@@ -0,0 +1,101 @@
+package blocksync
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// stubEVMStation answers net_version, and eth_getBlockByNumber with no block.
+type stubEVMStation struct {
+	blockCalls atomic.Int32
+}
+
+type stubNetAPI struct{}
+
+func (stubNetAPI) Version() string { return "42" }
+
+type stubEthAPI struct {
+	station *stubEVMStation
+}
+
+func (api stubEthAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	api.station.blockCalls.Add(1)
+	return nil, nil
+}
+
+func newStubEVMServer(t *testing.T, station *stubEVMStation) *httptest.Server {
+	t.Helper()
+	server := rpc.NewServer()
+	if err := server.RegisterName("net", stubNetAPI{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("eth", stubEthAPI{station: station}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	return httptest.NewServer(server)
+}
+
+func TestFailoverEVMClient(t *testing.T) {
+	down := httptest.NewServer(nil)
+	down.Close()
+	station := &stubEVMStation{}
+	up := newStubEVMServer(t, station)
+	defer up.Close()
+
+	stationClient := newTestStation(t, down.URL, up.URL)
+	client, err := newFailoverEVMClient(context.Background(), stationClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chainID, err := client.NetworkID(context.Background())
+	if err != nil || chainID.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("NetworkID() = %v, %v, want 42 from the second endpoint", chainID, err)
+	}
+	if status := stationClient.Status(); status[0].Healthy || !status[1].Healthy {
+		t.Errorf("endpoint status = %+v, want the first endpoint down", status)
+	}
+
+	// A block the station does not have yet is an answer, not an endpoint failure.
+	if _, err := client.BlockByNumber(context.Background(), big.NewInt(100)); !errors.Is(err, ethereum.NotFound) {
+		t.Errorf("BlockByNumber() error = %v, want ethereum.NotFound", err)
+	}
+	if calls := station.blockCalls.Load(); calls != 1 {
+		t.Errorf("eth_getBlockByNumber called %d times, want 1", calls)
+	}
+	if status := stationClient.Status(); !status[1].Healthy {
+		t.Errorf("endpoint status = %+v, want the second endpoint healthy", status)
+	}
+}
+
+type testRPCError struct{ code int }
+
+func (e testRPCError) Error() string  { return "rpc error" }
+func (e testRPCError) ErrorCode() int { return e.code }
+
+func TestEVMCallErrorFailsOverRateLimits(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		err           error
+		wantPermanent bool
+	}{
+		{name: "not found", err: ethereum.NotFound, wantPermanent: true},
+		{name: "execution reverted", err: testRPCError{code: 3}, wantPermanent: true},
+		{name: "missing trie node", err: testRPCError{code: -32000}, wantPermanent: true},
+		{name: "rate limited", err: testRPCError{code: -32005}},
+		{name: "internal error", err: testRPCError{code: -32603}},
+	} {
+		if got := stationclient.IsPermanent(evmCallError(tt.err)); got != tt.wantPermanent {
+			t.Errorf("%s: permanent = %v, want %v", tt.name, got, tt.wantPermanent)
+		}
+	}
+}
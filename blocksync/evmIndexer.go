@@ -0,0 +1,164 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	logs "github.com/airchains-network/decentralized-sequencer/log"
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog/log"
+)
+
+// EVMClient is the subset of the ethclient API used to index an EVM station. It lets
+// the indexer run against a fake RPC in tests.
+type EVMClient interface {
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	NetworkID(ctx context.Context) (*big.Int, error)
+	// RawBlockByNumber returns the eth_getBlockByNumber object of a block with its full
+	// transactions, for blocks BlockByNumber cannot decode.
+	RawBlockByNumber(ctx context.Context, number *big.Int) (json.RawMessage, error)
+}
+
+func init() {
+	RegisterIndexer(StationTypeEVM, newEVMIndexer)
+}
+
+type evmIndexer struct {
+	indexerState
+	client EVMClient
+	opts   IndexerOptions
+	// subscribeHeads is nil when no websocket endpoint is configured.
+	subscribeHeads headSubscriber
+}
+
+func newEVMIndexer(opts IndexerOptions) (StationIndexer, error) {
+	station, err := stationclient.Shared(opts.StationRPC, opts.StationClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid evm station rpc: %w", err)
+	}
+	client, err := newFailoverEVMClient(context.Background(), station)
+	if err != nil {
+		return nil, fmt.Errorf("error in connecting to the evm station: %w", err)
+	}
+	indexer := newEVMIndexerWithClient(client, opts)
+	if opts.StationWS != "" {
+		indexer.subscribeHeads = evmHeadSubscriber(opts.StationWS)
+	}
+	return indexer, nil
+}
+
+func newEVMIndexerWithClient(client EVMClient, opts IndexerOptions) *evmIndexer {
+	return &evmIndexer{
+		indexerState: newIndexerState(StationTypeEVM, opts.BlockDB),
+		client:       client,
+		opts:         opts,
+	}
+}
+
+func (e *evmIndexer) Start(ctx context.Context) error {
+	ctx, err := e.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer e.end()
+
+	signer, ok := e.waitForSigner(ctx)
+	if !ok {
+		return nil
+	}
+
+	heads := startHeadNotifier(ctx, StationTypeEVM, e.subscribeHeads, evmRetryInterval)
+	finality := newFinalityTracker(e.opts.Finality, e.opts.BlockDB, e.opts.TxnDB, e.stationFinalized())
+	finality.start(ctx)
+	pipeline := newEVMPipeline(e.client, e.opts.Concurrency)
+	blockIndex := e.opts.StartBlock
+	window := pipeline.workers
+	for ctx.Err() == nil {
+		blocks, fetchErrs := pipeline.fetch(ctx, blockIndex, window)
+		committed := 0
+		var err error
+		for i, fetched := range blocks {
+			if err = fetchErrs[i]; err != nil {
+				break
+			}
+			if err = commitEVMBlock(e.opts.BlockDB, e.opts.TxnDB, signer, fetched); err != nil {
+				break
+			}
+			committed++
+		}
+		blockIndex += committed
+		window = pipeline.nextWindow(window, committed)
+
+		var reorg *ErrReorgDetected
+		if errors.As(err, &reorg) {
+			log.Warn().Str("module", "blocksync").Msg(reorg.Error())
+			finality.mu.Lock()
+			nextBlock, rollbackErr := rollbackEVMReorg(ctx, e.client, e.opts, reorg)
+			finality.mu.Unlock()
+			if rollbackErr != nil {
+				e.setError(rollbackErr)
+				if isReorgAlert(rollbackErr) {
+					logs.Log.Error("ALERT: " + rollbackErr.Error())
+				} else {
+					log.Error().Str("module", "blocksync").Err(rollbackErr).Msg("Failed to roll back reorg")
+				}
+				sleepContext(ctx, evmRetryInterval)
+				continue
+			}
+			log.Info().Str("module", "blocksync").Msg(fmt.Sprintf("Rolled back to block %d after reorg at block %d", nextBlock-1, reorg.Height))
+			blockIndex = nextBlock
+			window = 1
+			continue
+		}
+		if err != nil && committed == 0 {
+			e.setError(err)
+			log.Debug().Str("module", "blocksync").Err(err).Msg(fmt.Sprintf("Waiting for block %d", blockIndex))
+			heads.Wait(ctx)
+			continue
+		}
+		e.setError(nil)
+	}
+	return nil
+}
+
+// stationFinalized returns the lookup of the finalized or safe block when the finality
+// policy follows a block tag.
+func (e *evmIndexer) stationFinalized() func(ctx context.Context) (int, error) {
+	if e.opts.Finality.Mode != FinalityTag {
+		return nil
+	}
+	tag := rpc.FinalizedBlockNumber
+	if e.opts.Finality.Tag == "safe" {
+		tag = rpc.SafeBlockNumber
+	}
+	return func(ctx context.Context) (int, error) {
+		header, err := e.client.HeaderByNumber(ctx, big.NewInt(int64(tag)))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get the %s block: %w", e.opts.Finality.Tag, err)
+		}
+		return int(header.Number.Int64()), nil
+	}
+}
+
+// waitForSigner asks the station for its chain ID until it answers or ctx is cancelled.
+func (e *evmIndexer) waitForSigner(ctx context.Context) (types.Signer, bool) {
+	for ctx.Err() == nil {
+		chainID, err := e.client.NetworkID(ctx)
+		if err == nil {
+			return types.LatestSignerForChainID(chainID), true
+		}
+		e.setError(err)
+		log.Debug().Str("module", "blocksync").Err(err).Msg("Failed to get the network ID")
+		sleepContext(ctx, evmRetryInterval)
+	}
+	return nil, false
+}
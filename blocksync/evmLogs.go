@@ -0,0 +1,128 @@
+package blocksync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// maxEVMLogResults bounds the number of logs returned by a single GetEVMLogs call.
+const maxEVMLogResults = 10000
+
+// Log index keys end in <seq>-<position>, zero padded so that a prefix scan returns the
+// logs in chain order. position is the index of the log inside its receipt.
+func evmLogAddressPrefix(address string) string {
+	return "logaddr-" + strings.ToLower(address) + "-"
+}
+
+func evmLogTopicPrefix(topic string) string {
+	return "logtopic-" + strings.ToLower(topic) + "-"
+}
+
+func evmLogPosition(seq, position int) string {
+	return fmt.Sprintf("%012d-%04d", seq, position)
+}
+
+// evmLogIndexes returns the address and topic0 index entries of a stored transaction.
+func evmLogIndexes(tx types.TransactionStruct, seq int) map[string][]byte {
+	if tx.Receipt == nil || len(tx.Receipt.Logs) == 0 {
+		return nil
+	}
+	indexes := make(map[string][]byte)
+	for position, receiptLog := range tx.Receipt.Logs {
+		suffix := evmLogPosition(seq, position)
+		indexes[evmLogAddressPrefix(receiptLog.Address)+suffix] = nil
+		if len(receiptLog.Topics) > 0 {
+			indexes[evmLogTopicPrefix(receiptLog.Topics[0])+suffix] = nil
+		}
+	}
+	return indexes
+}
+
+// EVMLogFilter selects stored logs by emitting contract and/or first topic. ToBlock 0
+// means no upper bound.
+type EVMLogFilter struct {
+	Address   string `json:"address"`
+	Topic0    string `json:"topic0"`
+	FromBlock uint64 `json:"fromBlock"`
+	ToBlock   uint64 `json:"toBlock"`
+}
+
+// GetEVMLogs returns the logs matching filter in chain order, using the address index
+// when an address is given and the topic0 index otherwise.
+func GetEVMLogs(ldt *leveldb.DB, filter EVMLogFilter) ([]types.LogStruct, error) {
+	var prefix string
+	switch {
+	case filter.Address != "":
+		prefix = evmLogAddressPrefix(filter.Address)
+	case filter.Topic0 != "":
+		prefix = evmLogTopicPrefix(filter.Topic0)
+	default:
+		return nil, errors.New("log filter needs an address or topic0")
+	}
+
+	iter := ldt.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	var (
+		result    []types.LogStruct
+		cachedTx  types.TransactionStruct
+		cachedSeq int
+	)
+	for iter.Next() {
+		seq, position, err := parseEVMLogPosition(strings.TrimPrefix(string(iter.Key()), prefix))
+		if err != nil {
+			return nil, err
+		}
+		if seq != cachedSeq {
+			data, err := ldt.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read txns-%d: %w", seq, err)
+			}
+			cachedTx = types.TransactionStruct{}
+			if err := json.Unmarshal(data, &cachedTx); err != nil {
+				return nil, fmt.Errorf("failed to decode txns-%d: %w", seq, err)
+			}
+			cachedSeq = seq
+		}
+		if cachedTx.BlockNumber < filter.FromBlock {
+			continue
+		}
+		if filter.ToBlock != 0 && cachedTx.BlockNumber > filter.ToBlock {
+			// Sequence numbers grow with block numbers, so nothing later can match.
+			break
+		}
+		if cachedTx.Receipt == nil || position >= len(cachedTx.Receipt.Logs) {
+			return nil, fmt.Errorf("log index points to missing log %d of txns-%d", position, seq)
+		}
+		receiptLog := cachedTx.Receipt.Logs[position]
+		if filter.Topic0 != "" && (len(receiptLog.Topics) == 0 || !strings.EqualFold(receiptLog.Topics[0], filter.Topic0)) {
+			continue
+		}
+		if len(result) == maxEVMLogResults {
+			return nil, fmt.Errorf("query returned more than %d logs, narrow the block range", maxEVMLogResults)
+		}
+		result = append(result, receiptLog)
+	}
+	return result, iter.Error()
+}
+
+func parseEVMLogPosition(suffix string) (seq int, position int, err error) {
+	seqPart, positionPart, ok := strings.Cut(suffix, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("malformed log index key suffix %q", suffix)
+	}
+	if seq, err = strconv.Atoi(seqPart); err != nil {
+		return 0, 0, fmt.Errorf("malformed log index key suffix %q: %w", suffix, err)
+	}
+	if position, err = strconv.Atoi(positionPart); err != nil {
+		return 0, 0, fmt.Errorf("malformed log index key suffix %q: %w", suffix, err)
+	}
+	return seq, position, nil
+}
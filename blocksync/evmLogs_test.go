@@ -0,0 +1,108 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	tokenA        = common.HexToAddress("0x000000000000000000000000000000000000000a")
+	tokenB        = common.HexToAddress("0x000000000000000000000000000000000000000b")
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+// newLoggingEVMClient mines 3 blocks of 2 transactions. Even nonces emit a Transfer log
+// from tokenA, odd nonces from tokenB, and the transaction with nonce 3 fails.
+func newLoggingEVMClient(t *testing.T) *fakeEVMClient {
+	client := newFakeEVMClient(t, 0, 0)
+	client.receiptHook = func(tx *ethTypes.Transaction, receipt *ethTypes.Receipt) {
+		address := tokenA
+		if tx.Nonce()%2 == 1 {
+			address = tokenB
+		}
+		receipt.Logs = []*ethTypes.Log{{Address: address, Topics: []common.Hash{transferTopic}, Data: []byte{byte(tx.Nonce())}}}
+		if tx.Nonce() == 3 {
+			receipt.Status = ethTypes.ReceiptStatusFailed
+		}
+	}
+	client.mine(t, 3, 2)
+	return client
+}
+
+func TestEVMIndexerStoresReceiptsAndLogs(t *testing.T) {
+	client := newLoggingEVMClient(t)
+	blockDB, txnDB := newTestDBs(t)
+	indexer := newEVMIndexerWithClient(client, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.Start(ctx)
+	waitFor(t, 5*time.Second, func() bool { return indexer.LatestIndexed() == 2 })
+	indexer.Stop()
+
+	raw, err := txnDB.Get([]byte("txns-4"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx types.TransactionStruct
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Receipt == nil {
+		t.Fatal("txns-4 stored without receipt")
+	}
+	if tx.Receipt.Status != ethTypes.ReceiptStatusFailed || tx.Receipt.GasUsed != "21000" || len(tx.Receipt.Logs) != 1 {
+		t.Errorf("txns-4 receipt = %+v, want failed with 21000 gas and one log", tx.Receipt)
+	}
+
+	tests := []struct {
+		name       string
+		filter     EVMLogFilter
+		wantBlocks []uint64
+	}{
+		{name: "address", filter: EVMLogFilter{Address: tokenA.Hex()}, wantBlocks: []uint64{0, 1, 2}},
+		{name: "lowercase address", filter: EVMLogFilter{Address: "0x000000000000000000000000000000000000000b"}, wantBlocks: []uint64{0, 1, 2}},
+		{name: "address and block range", filter: EVMLogFilter{Address: tokenA.Hex(), FromBlock: 1, ToBlock: 1}, wantBlocks: []uint64{1}},
+		{name: "topic0", filter: EVMLogFilter{Topic0: transferTopic.Hex(), FromBlock: 2}, wantBlocks: []uint64{2, 2}},
+		{name: "address and other topic0", filter: EVMLogFilter{Address: tokenA.Hex(), Topic0: common.BytesToHash(tokenA.Bytes()).Hex()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs, err := GetEVMLogs(txnDB, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(logs) != len(tt.wantBlocks) {
+				t.Fatalf("GetEVMLogs() returned %d logs, want %d", len(logs), len(tt.wantBlocks))
+			}
+			for i, log := range logs {
+				if log.BlockNumber != tt.wantBlocks[i] || log.BlockHash != client.blocks[log.BlockNumber].Hash().Hex() {
+					t.Errorf("log %d = %+v, want block %d", i, log, tt.wantBlocks[i])
+				}
+			}
+		})
+	}
+
+	if _, err := GetEVMLogs(txnDB, EVMLogFilter{}); err == nil {
+		t.Error("GetEVMLogs() expected error without address or topic0")
+	}
+
+	// Rolling back transactions must drop their log indexes too.
+	if err := truncateTxns(txnDB, 2); err != nil {
+		t.Fatal(err)
+	}
+	logs, err := GetEVMLogs(txnDB, EVMLogFilter{Topic0: transferTopic.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Errorf("GetEVMLogs() after truncate returned %d logs, want 2", len(logs))
+	}
+}
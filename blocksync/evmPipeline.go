@@ -0,0 +1,280 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	stationTypes "github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// defaultIndexerConcurrency is used when the station config does not set
+// indexerConcurrency.
+const defaultIndexerConcurrency = 8
+
+// evmReceiptConcurrency bounds the receipts of one block fetched in parallel.
+const evmReceiptConcurrency = 16
+
+// evmFetchedBlock is a station block together with the receipts of its transactions,
+// ready to be committed.
+type evmFetchedBlock struct {
+	height int
+	block  *types.Block
+	// setCodeTxs are the set code transactions of the block by their index in it. They
+	// are not part of block.Transactions().
+	setCodeTxs map[int]*evmSetCodeTx
+	// receipts are in block order, set code transactions included.
+	receipts []*types.Receipt
+}
+
+// transactionCount is the number of transactions in the block.
+func (f *evmFetchedBlock) transactionCount() int {
+	return f.block.Transactions().Len() + len(f.setCodeTxs)
+}
+
+// eachTransaction calls fn with every transaction of the block in block order: tx for
+// the decoded ones and setCodeTx for the others.
+func (f *evmFetchedBlock) eachTransaction(fn func(i int, tx *types.Transaction, setCodeTx *evmSetCodeTx) error) error {
+	decoded := f.block.Transactions()
+	for i := 0; i < f.transactionCount(); i++ {
+		if setCodeTx, ok := f.setCodeTxs[i]; ok {
+			if err := fn(i, nil, setCodeTx); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(i, decoded[0], nil); err != nil {
+			return err
+		}
+		decoded = decoded[1:]
+	}
+	return nil
+}
+
+// fetchEVMBlock downloads the block at height and the receipt of every transaction in
+// it. The transactions themselves come with the block body.
+func fetchEVMBlock(ctx context.Context, client EVMClient, height int) (*evmFetchedBlock, error) {
+	fetched := &evmFetchedBlock{height: height}
+	var err error
+	fetched.block, err = client.BlockByNumber(ctx, big.NewInt(int64(height)))
+	if errors.Is(err, types.ErrTxTypeNotSupported) {
+		// go-ethereum v1.13 decodes transaction types up to blob transactions (type 3).
+		fetched.block, fetched.setCodeTxs, err = fetchEVMSetCodeBlock(ctx, client, height)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block data for block number %d: %w", height, err)
+	}
+	hashes := make([]common.Hash, 0, fetched.transactionCount())
+	fetched.eachTransaction(func(_ int, tx *types.Transaction, setCodeTx *evmSetCodeTx) error {
+		if setCodeTx != nil {
+			hashes = append(hashes, setCodeTx.Hash)
+		} else {
+			hashes = append(hashes, tx.Hash())
+		}
+		return nil
+	})
+	if fetched.receipts, err = fetchEVMReceipts(ctx, client, hashes); err != nil {
+		return nil, err
+	}
+	return fetched, nil
+}
+
+// fetchEVMSetCodeBlock fetches the block at height as JSON and decodes it without the
+// go-ethereum transaction decoder for its set code transactions (type 4), which are
+// returned by their index in the block.
+func fetchEVMSetCodeBlock(ctx context.Context, client EVMClient, height int) (*types.Block, map[int]*evmSetCodeTx, error) {
+	raw, err := client.RawBlockByNumber(ctx, big.NewInt(int64(height)))
+	if err != nil {
+		return nil, nil, err
+	}
+	var header types.Header
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode the header: %w", err)
+	}
+	var body struct {
+		Transactions []json.RawMessage `json:"transactions"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode the transactions: %w", err)
+	}
+
+	var txs types.Transactions
+	setCodeTxs := make(map[int]*evmSetCodeTx)
+	for i, rawTx := range body.Transactions {
+		var typed struct {
+			Type hexutil.Uint64 `json:"type"`
+		}
+		if err := json.Unmarshal(rawTx, &typed); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode transaction %d: %w", i, err)
+		}
+		if typed.Type == setCodeTxType {
+			setCodeTx := new(evmSetCodeTx)
+			if err := json.Unmarshal(rawTx, setCodeTx); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode set code transaction %d: %w", i, err)
+			}
+			setCodeTxs[i] = setCodeTx
+			continue
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalJSON(rawTx); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode transaction %d: %w", i, err)
+		}
+		txs = append(txs, tx)
+	}
+	return types.NewBlockWithHeader(&header).WithBody(txs, nil), setCodeTxs, nil
+}
+
+// fetchEVMReceipts downloads the receipts of the transactions with hashes, at most
+// evmReceiptConcurrency at a time, and returns them in transaction order. The remaining
+// requests are abandoned after the first error.
+func fetchEVMReceipts(ctx context.Context, client EVMClient, hashes []common.Hash) ([]*types.Receipt, error) {
+	receipts := make([]*types.Receipt, len(hashes))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		errOnce  sync.Once
+		firstErr error
+	)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(evmReceiptConcurrency, len(hashes)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					continue
+				}
+				receipt, err := client.TransactionReceipt(ctx, hashes[i])
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("failed to fetch the receipt of transaction %s: %w", hashes[i].Hex(), err)
+						cancel()
+					})
+					continue
+				}
+				receipts[i] = receipt
+			}
+		}()
+	}
+	for i := range hashes {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return receipts, nil
+}
+
+// commitEVMBlock stores a fetched block and its transactions. Blocks must be committed
+// in height order because transactions are numbered sequentially.
+func commitEVMBlock(ldb *leveldb.DB, ldt *leveldb.DB, signer types.Signer, fetched *evmFetchedBlock) error {
+	if err := checkEVMParent(ldb, fetched.height, fetched.block.ParentHash().String()); err != nil {
+		return err
+	}
+	w, err := evmBlockWrite(signer, fetched)
+	if err != nil {
+		return err
+	}
+	return commitBlock(ldb, ldt, w)
+}
+
+// evmBlockWrite converts a fetched block into the entries stored for it.
+func evmBlockWrite(signer types.Signer, fetched *evmFetchedBlock) (blockWrite, error) {
+	block := evmBlockStruct(fetched.block)
+	block.TransactionCount = fetched.transactionCount()
+	blockData, err := json.Marshal(block)
+	if err != nil {
+		return blockWrite{}, fmt.Errorf("error marshalling block %d: %w", fetched.height, err)
+	}
+
+	txDatas := make([]stationTypes.TransactionStruct, fetched.transactionCount())
+	txns := make([][]byte, fetched.transactionCount())
+	err = fetched.eachTransaction(func(i int, tx *types.Transaction, setCodeTx *evmSetCodeTx) error {
+		var err error
+		if setCodeTx != nil {
+			txDatas[i], err = evmSetCodeTransactionStruct(setCodeTx, fetched.receipts[i], fetched.block.Header())
+		} else {
+			txDatas[i], err = evmTransactionStruct(signer, tx, fetched.receipts[i], fetched.block.Header())
+		}
+		if err != nil {
+			return err
+		}
+		if txns[i], err = json.Marshal(txDatas[i]); err != nil {
+			return fmt.Errorf("error marshalling transaction %s: %w", txDatas[i].Hash, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return blockWrite{}, err
+	}
+
+	return blockWrite{
+		height:    fetched.height,
+		blockKey:  blockKey(fetched.height),
+		blockData: blockData,
+		txns:      txns,
+		time:      int64(fetched.block.Time()),
+		txnIndexes: func(i, seq int) map[string][]byte {
+			return evmTxnIndexes(txDatas[i], seq)
+		},
+	}, nil
+}
+
+// evmPipeline fetches a window of consecutive blocks with a bounded pool of workers.
+// The caller commits the results in order, so the stored chain never has gaps.
+type evmPipeline struct {
+	client  EVMClient
+	workers int
+}
+
+func newEVMPipeline(client EVMClient, workers int) *evmPipeline {
+	if workers <= 0 {
+		workers = defaultIndexerConcurrency
+	}
+	return &evmPipeline{client: client, workers: workers}
+}
+
+// fetch downloads count blocks starting at from. The result and error for each height
+// are stored at offset height-from.
+func (p *evmPipeline) fetch(ctx context.Context, from, count int) ([]*evmFetchedBlock, []error) {
+	blocks := make([]*evmFetchedBlock, count)
+	errs := make([]error, count)
+
+	offsets := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(p.workers, count); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for offset := range offsets {
+				blocks[offset], errs[offset] = fetchEVMBlock(ctx, p.client, from+offset)
+			}
+		}()
+	}
+	for offset := 0; offset < count; offset++ {
+		offsets <- offset
+	}
+	close(offsets)
+	wg.Wait()
+	return blocks, errs
+}
+
+// nextWindow grows the fetch window while the station has blocks to catch up on and
+// drops back to a single block once the indexer reaches the tip.
+func (p *evmPipeline) nextWindow(window, committed int) int {
+	if committed < window {
+		return 1
+	}
+	return min(window*2, p.workers)
+}
@@ -0,0 +1,123 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog"
+)
+
+func TestEVMPipelineCommitsInOrder(t *testing.T) {
+	client := newFakeEVMClient(t, 40, 3)
+	client.latency = time.Millisecond
+	blockDB, txnDB := newTestDBs(t)
+	indexer := newEVMIndexerWithClient(client, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB, Concurrency: 6})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.Start(ctx)
+	waitFor(t, 10*time.Second, func() bool { return indexer.LatestIndexed() == 39 })
+	indexer.Stop()
+
+	if got := readCounter(txnDB, "txnCount"); got != 120 {
+		t.Fatalf("txnCount = %d, want 120", got)
+	}
+	for seq := 1; seq <= 120; seq++ {
+		raw, err := txnDB.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+		if err != nil {
+			t.Fatalf("txns-%d missing: %v", seq, err)
+		}
+		var tx types.TransactionStruct
+		if err := json.Unmarshal(raw, &tx); err != nil {
+			t.Fatal(err)
+		}
+		height := (seq - 1) / 3
+		want := client.blocks[height].Transactions()[(seq-1)%3].Hash().Hex()
+		if tx.BlockNumber != uint64(height) || tx.Hash != want {
+			t.Fatalf("txns-%d = block %d hash %s, want block %d hash %s", seq, tx.BlockNumber, tx.Hash, height, want)
+		}
+	}
+}
+
+// BenchmarkEVMCatchUp measures how fast the indexer catches up with a station that
+// already has 200 blocks, with every RPC call taking about a millisecond.
+func BenchmarkEVMCatchUp(b *testing.B) {
+	const blocks = 200
+	defer zerolog.SetGlobalLevel(zerolog.GlobalLevel())
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	client := newFakeEVMClient(b, blocks, 4)
+	client.latency = time.Millisecond
+
+	for _, concurrency := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				blockDB, txnDB := newTestDBs(b)
+				indexer := newEVMIndexerWithClient(client, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB, Concurrency: concurrency})
+				done := make(chan error, 1)
+				go func() { done <- indexer.Start(context.Background()) }()
+				for indexer.LatestIndexed() < blocks-1 {
+					time.Sleep(time.Millisecond)
+				}
+				indexer.Stop()
+				<-done
+			}
+			b.ReportMetric(float64(blocks*b.N)/b.Elapsed().Seconds(), "blocks/s")
+		})
+	}
+}
+
+// receiptCountingClient records how many receipts are fetched at the same time.
+type receiptCountingClient struct {
+	*fakeEVMClient
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (c *receiptCountingClient) TransactionReceipt(ctx context.Context, hash common.Hash) (*ethTypes.Receipt, error) {
+	c.mu.Lock()
+	c.inFlight++
+	c.maxInFlight = max(c.maxInFlight, c.inFlight)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+	return c.fakeEVMClient.TransactionReceipt(ctx, hash)
+}
+
+func TestFetchEVMBlockReceiptsConcurrently(t *testing.T) {
+	client := &receiptCountingClient{fakeEVMClient: newFakeEVMClient(t, 1, 40)}
+	client.latency = time.Millisecond
+
+	fetched, err := fetchEVMBlock(context.Background(), client, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tx := range fetched.block.Transactions() {
+		if fetched.receipts[i].TxHash != tx.Hash() {
+			t.Fatalf("receipt %d belongs to %s, want %s", i, fetched.receipts[i].TxHash, tx.Hash())
+		}
+	}
+	if client.maxInFlight < 2 || client.maxInFlight > evmReceiptConcurrency {
+		t.Errorf("%d receipts fetched at once, want between 2 and %d", client.maxInFlight, evmReceiptConcurrency)
+	}
+
+	missing := fetched.block.Transactions()[7].Hash()
+	client.mu.Lock()
+	delete(client.receipts, missing)
+	client.mu.Unlock()
+	if _, err := fetchEVMBlock(context.Background(), client, 0); err == nil || !strings.Contains(err.Error(), missing.Hex()) {
+		t.Errorf("fetchEVMBlock() = %v, want the missing receipt of %s", err, missing.Hex())
+	}
+}
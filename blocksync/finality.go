@@ -0,0 +1,233 @@
+package blocksync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Finality modes of a FinalityPolicy.
+const (
+	// FinalityConfirmations makes a block final once Confirmations blocks are stored on
+	// top of it.
+	FinalityConfirmations = "confirmations"
+	// FinalityTag follows the finalized or safe block tag of an EVM station.
+	FinalityTag = "tag"
+	// FinalityCommitment fetches Solana blocks at a commitment level, so every stored
+	// slot is final.
+	FinalityCommitment = "commitment"
+)
+
+// finalityInterval is how often the finality boundary is refreshed.
+const finalityInterval = 2 * time.Second
+
+var (
+	finalizedBlockKey    = []byte("finalizedBlock")
+	finalizedTxnCountKey = []byte("finalizedTxnCount")
+	// finalizedTimeKey holds the station time of the finalized block in unix seconds.
+	finalizedTimeKey = []byte("finalizedTime")
+)
+
+// FinalityPolicy decides when an indexed station block is final. Blocks above the
+// boundary are tentative: they are stored, but their transactions are kept out of pods.
+type FinalityPolicy struct {
+	Mode string
+	// Confirmations is used by FinalityConfirmations.
+	Confirmations int
+	// Tag is "finalized" or "safe" and is used by FinalityTag.
+	Tag string
+	// Commitment is "finalized" or "confirmed" and is used by FinalityCommitment.
+	Commitment string
+}
+
+// validate checks that the policy is complete and supported by stationType. The mode
+// must be set: a sequencer.toml written before finality was configurable has none.
+func (p FinalityPolicy) validate(stationType string) error {
+	switch p.Mode {
+	case "":
+		return fmt.Errorf("finality mode is not set, set finality in the [station] section of sequencer.toml to %s, %s or %s", FinalityConfirmations, FinalityTag, FinalityCommitment)
+	case FinalityConfirmations:
+		if p.Confirmations < 0 {
+			return fmt.Errorf("finality confirmations must not be negative, got %d", p.Confirmations)
+		}
+	case FinalityTag:
+		if stationType != StationTypeEVM {
+			return fmt.Errorf("finality mode %q is only supported by evm stations", p.Mode)
+		}
+		if p.Tag != "finalized" && p.Tag != "safe" {
+			return fmt.Errorf("finality tag must be finalized or safe, got %q", p.Tag)
+		}
+	case FinalityCommitment:
+		if stationType != StationTypeSVM {
+			return fmt.Errorf("finality mode %q is only supported by svm stations", p.Mode)
+		}
+		// getBlock does not serve processed slots.
+		if p.Commitment != "finalized" && p.Commitment != "confirmed" {
+			return fmt.Errorf("finality commitment must be finalized or confirmed, got %q", p.Commitment)
+		}
+	default:
+		return fmt.Errorf("unknown finality mode %q, must be one of: %s, %s, %s", p.Mode, FinalityConfirmations, FinalityTag, FinalityCommitment)
+	}
+	return nil
+}
+
+// finalityTracker keeps finalizedBlock in the block database and finalizedTxnCount in
+// the transaction database in line with a FinalityPolicy.
+type finalityTracker struct {
+	policy FinalityPolicy
+	ldb    *leveldb.DB
+	ldt    *leveldb.DB
+	// stationFinalized returns the height the station reports as final. It is nil when
+	// finality follows from confirmations or from the commitment blocks are fetched at.
+	stationFinalized func(ctx context.Context) (int, error)
+
+	// mu is held while the boundary is written, and by rollbacks that may lower it.
+	mu sync.Mutex
+}
+
+func newFinalityTracker(policy FinalityPolicy, ldb, ldt *leveldb.DB, stationFinalized func(ctx context.Context) (int, error)) *finalityTracker {
+	return &finalityTracker{policy: policy, ldb: ldb, ldt: ldt, stationFinalized: stationFinalized}
+}
+
+// start records the initial boundary before any block is indexed and then refreshes it
+// until ctx is cancelled.
+func (t *finalityTracker) start(ctx context.Context) {
+	if err := t.update(ctx); err != nil {
+		log.Debug().Str("module", "blocksync").Err(err).Msg("Failed to update the finality boundary")
+	}
+	go func() {
+		ticker := time.NewTicker(finalityInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.update(ctx); err != nil {
+					log.Debug().Str("module", "blocksync").Err(err).Msg("Failed to update the finality boundary")
+				}
+			}
+		}
+	}()
+}
+
+// update moves the boundary up to the highest stored block that is final. It never
+// moves it down; rollbacks do that.
+func (t *finalityTracker) update(ctx context.Context) error {
+	stationFinal := -1
+	if t.stationFinalized != nil {
+		var err error
+		if stationFinal, err = t.stationFinalized(ctx); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	latest := readCounter(t.ldb, "blockCount") - 1
+	boundary := latest
+	if t.stationFinalized != nil {
+		boundary = min(stationFinal, latest)
+	} else if t.policy.Mode == "" || t.policy.Mode == FinalityConfirmations {
+		boundary = latest - t.policy.Confirmations
+	}
+
+	current, ok := readFinalizedBlock(t.ldb)
+	if ok && boundary <= current {
+		return nil
+	}
+	txnCount, found := finalTxnCount(t.ldb, boundary)
+	if !found {
+		if ok {
+			// Legacy blocks without commit markers: keep the boundary until a marked
+			// block becomes final.
+			return nil
+		}
+		// Nothing has been indexed since the upgrade, so every stored transaction is
+		// from a block stored before the tracker started.
+		txnCount = readCounter(t.ldt, "txnCount")
+	}
+	return writeFinalized(t.ldb, t.ldt, boundary, txnCount)
+}
+
+// finalTxnCount returns the number of transactions stored up to and including height.
+func finalTxnCount(ldb *leveldb.DB, height int) (int, bool) {
+	if height < 0 {
+		return 0, true
+	}
+	if commit, ok := readBlockCommit(ldb, height); ok {
+		return commit.TxnCount, true
+	}
+	// Blocks stored before commit markers existed precede every marked block, so the
+	// first marked block above height tells where they end.
+	blockCount := readCounter(ldb, "blockCount")
+	for h := height + 1; h < blockCount; h++ {
+		if commit, ok := readBlockCommit(ldb, h); ok {
+			return commit.FirstTxn - 1, true
+		}
+	}
+	return 0, false
+}
+
+func writeFinalized(ldb, ldt *leveldb.DB, height, txnCount int) error {
+	// The transaction side goes first: pods only look at finalizedTxnCount and
+	// finalizedTime.
+	batch := new(leveldb.Batch)
+	batch.Put(finalizedTxnCountKey, []byte(strconv.Itoa(txnCount)))
+	if commit, ok := readBlockCommit(ldb, height); ok && commit.Time > 0 {
+		batch.Put(finalizedTimeKey, []byte(strconv.FormatInt(commit.Time, 10)))
+	}
+	if err := ldt.Write(batch, syncWrite); err != nil {
+		return fmt.Errorf("failed to store the finalized transaction count: %w", err)
+	}
+	if err := ldb.Put(finalizedBlockKey, []byte(strconv.Itoa(height)), syncWrite); err != nil {
+		return fmt.Errorf("failed to store the finalized block: %w", err)
+	}
+	return nil
+}
+
+// readFinalizedBlock returns the highest final block, or false if finality has never
+// been tracked. The height is -1 while no block is final.
+func readFinalizedBlock(ldb *leveldb.DB) (int, bool) {
+	if ldb == nil {
+		return 0, false
+	}
+	value, err := ldb.Get(finalizedBlockKey, nil)
+	if err != nil {
+		return 0, false
+	}
+	height, err := strconv.Atoi(strings.TrimSpace(string(value)))
+	if err != nil {
+		return 0, false
+	}
+	return height, true
+}
+
+// clampFinalizedBlock adds to batch whatever is needed to keep the finalized block below
+// fromBlock, which is about to be removed.
+func clampFinalizedBlock(ldb *leveldb.DB, batch *leveldb.Batch, fromBlock int) {
+	if finalized, ok := readFinalizedBlock(ldb); ok && finalized >= fromBlock {
+		log.Warn().Str("module", "blocksync").Msg(fmt.Sprintf("Removing finalized blocks %d to %d", fromBlock, finalized))
+		batch.Put(finalizedBlockKey, []byte(strconv.Itoa(fromBlock-1)))
+	}
+}
+
+// IsTxnFinal reports whether transaction seq belongs to a final block. Databases
+// written before finality was tracked treat every transaction as final.
+func IsTxnFinal(ldt *leveldb.DB, seq int) bool {
+	value, err := ldt.Get(finalizedTxnCountKey, nil)
+	if err != nil {
+		return true
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(value)))
+	if err != nil {
+		return true
+	}
+	return seq <= count
+}
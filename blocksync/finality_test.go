@@ -0,0 +1,129 @@
+package blocksync
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFinalityPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      FinalityPolicy
+		stationType string
+		wantErr     bool
+	}{
+		{"unset mode", FinalityPolicy{}, StationTypeWASM, true},
+		{"confirmations", FinalityPolicy{Mode: FinalityConfirmations, Confirmations: 12}, StationTypeEVM, false},
+		{"negative confirmations", FinalityPolicy{Mode: FinalityConfirmations, Confirmations: -1}, StationTypeEVM, true},
+		{"evm finalized tag", FinalityPolicy{Mode: FinalityTag, Tag: "finalized"}, StationTypeEVM, false},
+		{"evm safe tag", FinalityPolicy{Mode: FinalityTag, Tag: "safe"}, StationTypeEVM, false},
+		{"evm latest tag", FinalityPolicy{Mode: FinalityTag, Tag: "latest"}, StationTypeEVM, true},
+		{"tag on wasm", FinalityPolicy{Mode: FinalityTag, Tag: "finalized"}, StationTypeWASM, true},
+		{"svm confirmed", FinalityPolicy{Mode: FinalityCommitment, Commitment: "confirmed"}, StationTypeSVM, false},
+		{"svm processed", FinalityPolicy{Mode: FinalityCommitment, Commitment: "processed"}, StationTypeSVM, true},
+		{"commitment on evm", FinalityPolicy{Mode: FinalityCommitment, Commitment: "finalized"}, StationTypeEVM, true},
+		{"unknown mode", FinalityPolicy{Mode: "instant"}, StationTypeEVM, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.validate(tt.stationType); (err != nil) != tt.wantErr {
+				t.Errorf("validate(%q) error = %v, wantErr %v", tt.stationType, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFinalityTrackerConfirmations(t *testing.T) {
+	blockDB, txnDB := newTestDBs(t)
+	tracker := newFinalityTracker(FinalityPolicy{Mode: FinalityConfirmations, Confirmations: 3}, blockDB, txnDB, nil)
+	ctx := context.Background()
+
+	if err := tracker.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if finalized, _ := readFinalizedBlock(blockDB); finalized >= 0 {
+		t.Errorf("finalized block of an empty database = %d, want none", finalized)
+	}
+
+	// Blocks 0 to 9 with two transactions each.
+	commitTestBlocks(t, blockDB, txnDB, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2)
+	if IsTxnFinal(txnDB, 1) {
+		t.Error("transaction 1 is final before the tracker saw its block")
+	}
+	if err := tracker.update(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if finalized, _ := readFinalizedBlock(blockDB); finalized != 6 {
+		t.Errorf("finalized block = %d, want 6", finalized)
+	}
+	if !IsTxnFinal(txnDB, 14) || IsTxnFinal(txnDB, 15) {
+		t.Errorf("final transactions end at %d, want 14", readCounter(txnDB, string(finalizedTxnCountKey)))
+	}
+
+	// Removing final transactions lowers the boundary with them.
+	if err := truncateTxns(txnDB, 10); err != nil {
+		t.Fatal(err)
+	}
+	if !IsTxnFinal(txnDB, 10) || IsTxnFinal(txnDB, 11) {
+		t.Errorf("final transactions end at %d after truncation, want 10", readCounter(txnDB, string(finalizedTxnCountKey)))
+	}
+}
+
+func TestFinalityTrackerLegacyBlocks(t *testing.T) {
+	blockDB, txnDB := newTestDBs(t)
+	// Five blocks and seven transactions stored before commit markers existed.
+	for height := 0; height < 5; height++ {
+		blockDB.Put([]byte(blockKey(height)), []byte("{}"), nil)
+	}
+	blockDB.Put([]byte("blockCount"), []byte("5"), nil)
+	txnDB.Put([]byte("txnCount"), []byte("7"), nil)
+
+	tracker := newFinalityTracker(FinalityPolicy{Mode: FinalityConfirmations, Confirmations: 2}, blockDB, txnDB, nil)
+	if err := tracker.update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !IsTxnFinal(txnDB, 7) {
+		t.Error("legacy transactions are not final")
+	}
+
+	if err := commitBlock(blockDB, txnDB, blockWrite{height: 5, blockKey: "Block5", blockData: []byte("{}"), txns: [][]byte{[]byte("{}")}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if IsTxnFinal(txnDB, 8) {
+		t.Error("transaction of a block with one confirmation is final")
+	}
+}
+
+func TestEVMIndexerFollowsFinalizedTag(t *testing.T) {
+	client := newFakeEVMClient(t, 10, 1)
+	client.finalized = 4
+	blockDB, txnDB := newTestDBs(t)
+	indexer := newEVMIndexerWithClient(client, IndexerOptions{
+		BlockDB:  blockDB,
+		TxnDB:    txnDB,
+		Finality: FinalityPolicy{Mode: FinalityTag, Tag: "finalized"},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.Start(ctx)
+	waitFor(t, 5*time.Second, func() bool {
+		return indexer.LatestIndexed() == 9 && indexer.Status().FinalizedBlock == 4
+	})
+	// Every block holds one transaction, so blocks 0 to 4 hold transactions 1 to 5.
+	if !IsTxnFinal(txnDB, 5) || IsTxnFinal(txnDB, 6) {
+		t.Errorf("final transactions end at %d, want 5", readCounter(txnDB, string(finalizedTxnCountKey)))
+	}
+
+	client.mu.Lock()
+	client.finalized = 8
+	client.mu.Unlock()
+	waitFor(t, 5*time.Second, func() bool { return indexer.Status().FinalizedBlock == 8 })
+	if !IsTxnFinal(txnDB, 9) || IsTxnFinal(txnDB, 10) {
+		t.Errorf("final transactions end at %d, want 9", readCounter(txnDB, string(finalizedTxnCountKey)))
+	}
+}
@@ -0,0 +1,202 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// headResubscribeInterval is how long a notifier polls before it tries to restore a
+	// dropped subscription.
+	headResubscribeInterval = 30 * time.Second
+	// headReadTimeout closes a websocket that has been silent for too long, so a dead
+	// connection falls back to polling instead of hanging.
+	headReadTimeout = 90 * time.Second
+)
+
+// headSubscriber connects to a push feed of the station and calls notify for every new
+// head. It blocks until the feed fails or ctx is cancelled.
+type headSubscriber func(ctx context.Context, notify func()) error
+
+// HeadNotifier wakes an indexer when the station may have a new block. It follows a
+// push subscription when one is configured and polls at a fixed interval otherwise,
+// including while a dropped subscription is being restored.
+type HeadNotifier struct {
+	c          chan struct{}
+	subscribed atomic.Bool
+}
+
+// startHeadNotifier runs the notifier until ctx is cancelled. subscribe may be nil for a
+// polling-only notifier.
+func startHeadNotifier(ctx context.Context, name string, subscribe headSubscriber, pollInterval time.Duration) *HeadNotifier {
+	n := &HeadNotifier{c: make(chan struct{}, 1)}
+	go n.run(ctx, name, subscribe, pollInterval)
+	return n
+}
+
+func (n *HeadNotifier) run(ctx context.Context, name string, subscribe headSubscriber, pollInterval time.Duration) {
+	for ctx.Err() == nil {
+		if subscribe != nil {
+			n.subscribed.Store(true)
+			err := subscribe(ctx, n.notify)
+			n.subscribed.Store(false)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn().Str("module", "blocksync").Err(err).Msg(fmt.Sprintf("%s head subscription unavailable, falling back to polling", name))
+			// Blocks may have been produced while the subscription was down.
+			n.notify()
+		}
+		if !n.poll(ctx, subscribe == nil, pollInterval) {
+			return
+		}
+	}
+}
+
+// poll emits a tick every interval, forever or until it is time to resubscribe. It
+// reports false once ctx is cancelled.
+func (n *HeadNotifier) poll(ctx context.Context, forever bool, interval time.Duration) bool {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	resubscribe := time.After(headResubscribeInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			n.notify()
+		case <-resubscribe:
+			if !forever {
+				return true
+			}
+		}
+	}
+}
+
+func (n *HeadNotifier) notify() {
+	select {
+	case n.c <- struct{}{}:
+	default:
+	}
+}
+
+// Wait blocks until the next head notification and reports false if ctx was cancelled
+// first.
+func (n *HeadNotifier) Wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-n.c:
+		return true
+	}
+}
+
+// Subscribed reports whether the notifier currently follows a push subscription.
+func (n *HeadNotifier) Subscribed() bool {
+	return n.subscribed.Load()
+}
+
+// evmHeadSubscriber follows eth_subscribe newHeads on an EVM websocket endpoint.
+func evmHeadSubscriber(wsURL string) headSubscriber {
+	return func(ctx context.Context, notify func()) error {
+		client, err := ethclient.DialContext(ctx, wsURL)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		heads := make(chan *types.Header, 16)
+		sub, err := client.SubscribeNewHead(ctx, heads)
+		if err != nil {
+			return err
+		}
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case err := <-sub.Err():
+				return err
+			case <-heads:
+				notify()
+			}
+		}
+	}
+}
+
+// cometHeadSubscriber follows NewBlock events on a CometBFT websocket endpoint such as
+// ws://localhost:26657/websocket.
+func cometHeadSubscriber(wsURL string) headSubscriber {
+	request := `{"jsonrpc":"2.0","id":1,"method":"subscribe","params":{"query":"tm.event='NewBlock'"}}`
+	return jsonRPCHeadSubscriber(wsURL, request, func(msg jsonRPCMessage) bool {
+		var result struct {
+			Data json.RawMessage `json:"data"`
+		}
+		return json.Unmarshal(msg.Result, &result) == nil && len(result.Data) > 0
+	})
+}
+
+// svmHeadSubscriber follows slotSubscribe on a Solana websocket endpoint such as
+// ws://localhost:8900.
+func svmHeadSubscriber(wsURL string) headSubscriber {
+	request := `{"jsonrpc":"2.0","id":1,"method":"slotSubscribe"}`
+	return jsonRPCHeadSubscriber(wsURL, request, func(msg jsonRPCMessage) bool {
+		return msg.Method == "slotNotification"
+	})
+}
+
+type jsonRPCMessage struct {
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// jsonRPCHeadSubscriber sends request over a websocket and calls notify for every
+// message accepted by isHead.
+func jsonRPCHeadSubscriber(wsURL string, request string, isHead func(msg jsonRPCMessage) bool) headSubscriber {
+	return func(ctx context.Context, notify func()) error {
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+		defer stop()
+
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			return err
+		}
+		conn.SetPingHandler(func(data string) error {
+			conn.SetReadDeadline(time.Now().Add(headReadTimeout))
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		for {
+			conn.SetReadDeadline(time.Now().Add(headReadTimeout))
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return err
+			}
+			var msg jsonRPCMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+			if len(msg.Error) > 0 && string(msg.Error) != "null" {
+				return fmt.Errorf("subscription rejected: %s", msg.Error)
+			}
+			if isHead(msg) {
+				notify()
+			}
+		}
+	}
+}
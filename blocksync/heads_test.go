@@ -0,0 +1,155 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHeadNotifierFallsBackToPolling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts atomic.Int32
+	failing := func(ctx context.Context, notify func()) error {
+		attempts.Add(1)
+		return errors.New("connection refused")
+	}
+	heads := startHeadNotifier(ctx, "test", failing, 10*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		waitCtx, waitCancel := context.WithTimeout(ctx, time.Second)
+		if !heads.Wait(waitCtx) {
+			t.Fatalf("Wait() #%d timed out while polling", i)
+		}
+		waitCancel()
+	}
+	if heads.Subscribed() {
+		t.Error("Subscribed() = true after the subscription failed")
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("subscription attempts = %d, want 1 before the resubscribe interval", attempts.Load())
+	}
+}
+
+func TestJSONRPCHeadSubscribers(t *testing.T) {
+	tests := []struct {
+		name      string
+		subscribe func(wsURL string) headSubscriber
+		method    string
+		messages  []string
+	}{
+		{
+			name:      "cometbft",
+			subscribe: cometHeadSubscriber,
+			method:    "subscribe",
+			messages: []string{
+				`{"jsonrpc":"2.0","id":1,"result":{}}`,
+				`{"jsonrpc":"2.0","id":1,"result":{"query":"tm.event='NewBlock'","data":{"type":"tendermint/event/NewBlock"}}}`,
+				`{"jsonrpc":"2.0","id":1,"result":{"query":"tm.event='NewBlock'","data":{"type":"tendermint/event/NewBlock"}}}`,
+			},
+		},
+		{
+			name:      "solana",
+			subscribe: svmHeadSubscriber,
+			method:    "slotSubscribe",
+			messages: []string{
+				`{"jsonrpc":"2.0","result":23784,"id":1}`,
+				`{"jsonrpc":"2.0","method":"slotNotification","params":{"result":{"parent":75,"root":44,"slot":76},"subscription":23784}}`,
+				`{"jsonrpc":"2.0","method":"slotNotification","params":{"result":{"parent":76,"root":45,"slot":77},"subscription":23784}}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upgrader := websocket.Upgrader{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				conn, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				var request struct {
+					Method string `json:"method"`
+				}
+				if err := conn.ReadJSON(&request); err != nil || request.Method != tt.method {
+					t.Errorf("subscription request method = %q, %v, want %q", request.Method, err, tt.method)
+					return
+				}
+				for _, msg := range tt.messages {
+					conn.WriteMessage(websocket.TextMessage, []byte(msg))
+				}
+				// Closing the connection ends the subscription.
+			}))
+			defer server.Close()
+
+			var notified atomic.Int32
+			wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+			err := tt.subscribe(wsURL)(context.Background(), func() { notified.Add(1) })
+			if err == nil {
+				t.Error("subscriber returned nil after the connection closed")
+			}
+			if notified.Load() != 2 {
+				t.Errorf("notify called %d times, want 2", notified.Load())
+			}
+		})
+	}
+}
+
+func TestJSONRPCHeadSubscriberRejected(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var request json.RawMessage
+		conn.ReadJSON(&request)
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	err := svmHeadSubscriber(wsURL)(context.Background(), func() {})
+	if err == nil || !strings.Contains(err.Error(), "Method not found") {
+		t.Errorf("subscriber error = %v, want rejection", err)
+	}
+}
+
+func TestEVMIndexerFollowsHeadSubscription(t *testing.T) {
+	client := newFakeEVMClient(t, 1, 1)
+	blockDB, txnDB := newTestDBs(t)
+	indexer := newEVMIndexerWithClient(client, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB})
+	newHeads := make(chan struct{})
+	indexer.subscribeHeads = func(ctx context.Context, notify func()) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-newHeads:
+				notify()
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.Start(ctx)
+	waitFor(t, 5*time.Second, func() bool { return indexer.LatestIndexed() == 0 })
+
+	client.mine(t, 1, 1)
+	newHeads <- struct{}{}
+	// Well below evmRetryInterval, so the block can only have been picked up through the
+	// subscription.
+	waitFor(t, time.Second, func() bool { return indexer.LatestIndexed() == 1 })
+}
@@ -0,0 +1,315 @@
+package blocksync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/airchains-network/decentralized-sequencer/config"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/airchains-network/decentralized-sequencer/types/svmTypes"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Reasons recorded for a transaction left out of its pod.
+const (
+	// ExcludedContractCreation is a contract deployment under a value-transfers-only policy.
+	ExcludedContractCreation = "contract-creation"
+	// ExcludedContractCall is a contract call under a value-transfers-only policy.
+	ExcludedContractCall = "contract-call"
+	// ExcludedFailed is a reverted or failed transaction under a successful-only policy.
+	ExcludedFailed = "failed"
+	// ExcludedContractDenied is a call to or deployment of a denied contract.
+	ExcludedContractDenied = "contract-denied"
+	// ExcludedContractNotAllowed is a call to or deployment of a contract missing from
+	// the allow list.
+	ExcludedContractNotAllowed = "contract-not-allowed"
+	// ExcludedUnsupported is a transaction the pod builder cannot represent, whatever
+	// the policy.
+	ExcludedUnsupported = "unsupported"
+	// ExcludedPodFull is a transaction with more transfers than the entries of a whole
+	// pod.
+	ExcludedPodFull = "pod-full"
+	// ExcludedMessages is an included transaction some of whose messages have no pod
+	// entry.
+	ExcludedMessages = "excluded-messages"
+)
+
+// PodCandidate describes a stored transaction to an InclusionPolicy.
+type PodCandidate struct {
+	Seq  int
+	Hash string
+	// Failed is set when the station reports the transaction as reverted or failed.
+	Failed bool
+	// ContractCreation is set for contract deployments.
+	ContractCreation bool
+	// Contract is the called or created contract, empty for value transfers.
+	Contract string
+	// Unsupported explains why the pod builder cannot represent the transaction.
+	Unsupported string
+	// Messages are the messages the pod builder leaves out of an included transaction.
+	Messages []MessageExclusion
+}
+
+// evmCreationTo is the recipient stored for contract deployments.
+const evmCreationTo = "0x0000000000000000000000000000000000000000"
+
+// evmSetCodeTxType is the stored type of set code transactions (EIP-7702). They are
+// stored without their authorization list, which may change the code of any account, so
+// pods leave them out.
+const evmSetCodeTxType = "4"
+
+// EVMPodCandidate describes EVM transaction seq. A transaction stored without a receipt
+// counts as successful, and one with input data as a contract call.
+func EVMPodCandidate(seq int, tx types.TransactionStruct) PodCandidate {
+	c := PodCandidate{Seq: seq, Hash: tx.Hash}
+	if tx.Receipt != nil {
+		c.Failed = tx.Receipt.Status == 0
+	}
+	if tx.Type == evmSetCodeTxType {
+		c.Unsupported = "set code transaction"
+	}
+	switch {
+	case evmContractCreation(tx):
+		c.ContractCreation = true
+		if tx.Receipt != nil {
+			c.Contract = tx.Receipt.ContractAddress
+		}
+	case tx.Input != "" && tx.Input != "0x":
+		c.Contract = tx.To
+	}
+	return c
+}
+
+// evmContractCreation reports whether tx deployed a contract. Deployments are stored with
+// evmCreationTo as recipient, like transfers to the zero address, so only the receipt
+// tells them apart; transactions stored without a receipt fall back to the recipient.
+func evmContractCreation(tx types.TransactionStruct) bool {
+	if tx.Receipt != nil {
+		return tx.Receipt.ContractAddress != ""
+	}
+	return tx.To == "" || tx.To == evmCreationTo
+}
+
+// WasmPodCandidate describes cosmos transaction seq together with its pod entries. A
+// transaction without a single entry is unsupported, and the messages without entries
+// are listed in Messages.
+func WasmPodCandidate(seq int, txn types.BatchTransaction) (PodCandidate, []WasmPodEntry) {
+	c := PodCandidate{Seq: seq, Hash: txn.TxResponse.TxHash, Failed: txn.TxResponse.Code != 0}
+	messages := txn.Tx.Body.Messages
+	for _, msg := range messages {
+		switch {
+		case strings.HasSuffix(msg.Type, ".MsgInstantiateContract"), strings.HasSuffix(msg.Type, ".MsgInstantiateContract2"), strings.HasSuffix(msg.Type, ".MsgStoreCode"):
+			c.ContractCreation = true
+		case msg.Type == wasmExecuteContractURL:
+			c.Contract = msg.Contract
+		}
+	}
+	entries, skipped := WasmPodEntries(txn)
+	c.Messages = skipped
+	switch {
+	case len(messages) == 0:
+		c.Unsupported = "no messages"
+	case len(entries) == 0:
+		c.Unsupported = "no supported messages"
+	}
+	return c, entries
+}
+
+// SVMPodCandidate describes solana transaction seq together with its pod entries. The
+// contract of a transaction is the first program it invokes other than the system and
+// compute budget programs, and loader instructions count as deployments.
+func SVMPodCandidate(seq int, txn svmTypes.SVMTransactionStruct) (PodCandidate, []SVMPodEntry) {
+	c := PodCandidate{Seq: seq, Failed: txn.Meta.Err != nil}
+	if len(txn.Transaction.Signatures) > 0 {
+		c.Hash = txn.Transaction.Signatures[0]
+	}
+	instructions := txn.Transaction.Message.Instructions
+	for _, instruction := range instructions {
+		switch {
+		case instruction.ProgramID == svmSystemProgram, instruction.ProgramID == svmComputeBudgetProgram:
+		case svmLoaderPrograms[instruction.ProgramID]:
+			c.ContractCreation = true
+		case c.Contract == "":
+			c.Contract = instruction.ProgramID
+		}
+	}
+	entries, skipped := SVMPodEntries(txn)
+	c.Messages = skipped
+	switch {
+	case len(instructions) == 0:
+		c.Unsupported = "no instructions"
+	case len(entries) == 0:
+		c.Unsupported = "no system transfers"
+	}
+	return c, entries
+}
+
+// InclusionPolicy decides which indexed transactions go into a pod. A pod still covers
+// the same transaction sequence numbers; excluded ones leave empty entries, so every
+// track of a station must run the same policy to build the same pods.
+type InclusionPolicy struct {
+	// ValueTransfersOnly excludes contract deployments and contract calls.
+	ValueTransfersOnly bool
+	// SuccessfulOnly excludes reverted and failed transactions.
+	SuccessfulOnly bool
+	// AllowContracts, when set, excludes calls to and deployments of other contracts.
+	AllowContracts []string
+	// DenyContracts excludes calls to and deployments of these contracts.
+	DenyContracts []string
+}
+
+// InclusionPolicyFromConfig returns the policy set in the [station] section of
+// sequencer.toml.
+func InclusionPolicyFromConfig(conf *config.StationConfig) InclusionPolicy {
+	return InclusionPolicy{
+		ValueTransfersOnly: conf.InclusionValueTransfersOnly,
+		SuccessfulOnly:     conf.InclusionSuccessfulOnly,
+		AllowContracts:     conf.InclusionAllowContracts,
+		DenyContracts:      conf.InclusionDenyContracts,
+	}
+}
+
+// Validate checks that the contract lists hold addresses and do not contradict each other.
+func (p InclusionPolicy) Validate() error {
+	allowed := make(map[string]bool)
+	for _, contract := range p.AllowContracts {
+		if strings.TrimSpace(contract) == "" {
+			return fmt.Errorf("inclusion allow list holds an empty contract address")
+		}
+		allowed[normalizeTxnIndexValue(contract)] = true
+	}
+	for _, contract := range p.DenyContracts {
+		if strings.TrimSpace(contract) == "" {
+			return fmt.Errorf("inclusion deny list holds an empty contract address")
+		}
+		if allowed[normalizeTxnIndexValue(contract)] {
+			return fmt.Errorf("contract %s is both allowed and denied for inclusion", contract)
+		}
+	}
+	return nil
+}
+
+// String sums up the policy for logs.
+func (p InclusionPolicy) String() string {
+	return fmt.Sprintf("valueTransfersOnly=%t successfulOnly=%t allowContracts=%d denyContracts=%d", p.ValueTransfersOnly, p.SuccessfulOnly, len(p.AllowContracts), len(p.DenyContracts))
+}
+
+// inclusionRule returns the reason c is excluded, or "" to let the next rule decide.
+type inclusionRule func(p InclusionPolicy, c PodCandidate) string
+
+// inclusionRules are checked in order; the first reason is the one recorded.
+var inclusionRules = []inclusionRule{
+	func(p InclusionPolicy, c PodCandidate) string {
+		if p.SuccessfulOnly && c.Failed {
+			return ExcludedFailed
+		}
+		return ""
+	},
+	func(p InclusionPolicy, c PodCandidate) string {
+		switch {
+		case !p.ValueTransfersOnly:
+		case c.ContractCreation:
+			return ExcludedContractCreation
+		case c.Contract != "":
+			return ExcludedContractCall
+		}
+		return ""
+	},
+	func(p InclusionPolicy, c PodCandidate) string {
+		if c.Contract == "" && !c.ContractCreation {
+			return ""
+		}
+		if containsAddress(p.DenyContracts, c.Contract) {
+			return ExcludedContractDenied
+		}
+		if len(p.AllowContracts) > 0 && !containsAddress(p.AllowContracts, c.Contract) {
+			return ExcludedContractNotAllowed
+		}
+		return ""
+	},
+	func(_ InclusionPolicy, c PodCandidate) string {
+		if c.Unsupported != "" {
+			return ExcludedUnsupported
+		}
+		return ""
+	},
+}
+
+// Exclude returns the reason c is left out of its pod, or "" if it is included.
+func (p InclusionPolicy) Exclude(c PodCandidate) string {
+	for _, rule := range inclusionRules {
+		if reason := rule(p, c); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+func containsAddress(addresses []string, address string) bool {
+	if address == "" {
+		return false
+	}
+	address = normalizeTxnIndexValue(address)
+	for _, a := range addresses {
+		if normalizeTxnIndexValue(a) == address {
+			return true
+		}
+	}
+	return false
+}
+
+// TxnExclusion records why a transaction was left out of a pod.
+type TxnExclusion struct {
+	Seq    int    `json:"seq"`
+	Hash   string `json:"hash"`
+	Pod    int    `json:"pod"`
+	Reason string `json:"reason"`
+	// Detail is set for unsupported transactions.
+	Detail string `json:"detail,omitempty"`
+	// Messages lists the messages without a pod entry.
+	Messages []MessageExclusion `json:"messages,omitempty"`
+}
+
+func txnExclusionKey(seq int) []byte {
+	return []byte("txexcl-" + strconv.Itoa(seq))
+}
+
+// Exclusion returns the record of c being left out of podNumber for reason.
+func (c PodCandidate) Exclusion(podNumber int, reason string) TxnExclusion {
+	exclusion := TxnExclusion{Seq: c.Seq, Hash: c.Hash, Pod: podNumber, Reason: reason, Messages: c.Messages}
+	if reason == ExcludedUnsupported {
+		exclusion.Detail = c.Unsupported
+	}
+	return exclusion
+}
+
+// RecordPodExclusions replaces the exclusion records of transactions firstSeq to lastSeq
+// with excluded, so that rebuilding a pod under another policy leaves no stale record.
+func RecordPodExclusions(ldt *leveldb.DB, firstSeq, lastSeq int, excluded []TxnExclusion) error {
+	batch := new(leveldb.Batch)
+	for seq := firstSeq; seq <= lastSeq; seq++ {
+		batch.Delete(txnExclusionKey(seq))
+	}
+	for _, exclusion := range excluded {
+		value, err := json.Marshal(exclusion)
+		if err != nil {
+			return err
+		}
+		batch.Put(txnExclusionKey(exclusion.Seq), value)
+	}
+	return ldt.Write(batch, syncWrite)
+}
+
+// GetTxnExclusion returns why transaction seq was left out of its pod, or
+// leveldb.ErrNotFound if it was included or its pod is not built yet.
+func GetTxnExclusion(ldt *leveldb.DB, seq int) (TxnExclusion, error) {
+	var exclusion TxnExclusion
+	value, err := ldt.Get(txnExclusionKey(seq), nil)
+	if err != nil {
+		return exclusion, err
+	}
+	err = json.Unmarshal(value, &exclusion)
+	return exclusion, err
+}
@@ -0,0 +1,120 @@
+package blocksync
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+)
+
+func TestInclusionPolicyExclude(t *testing.T) {
+	transfer := EVMPodCandidate(1, types.TransactionStruct{To: "0xBB", Input: "0x", Receipt: &types.ReceiptStruct{Status: 1}})
+	failed := EVMPodCandidate(2, types.TransactionStruct{To: "0xBB", Receipt: &types.ReceiptStruct{Status: 0}})
+	call := EVMPodCandidate(3, types.TransactionStruct{To: "0xCC", Input: "0xa9059cbb", Receipt: &types.ReceiptStruct{Status: 1}})
+	deploy := EVMPodCandidate(4, types.TransactionStruct{Input: "0x6080", Receipt: &types.ReceiptStruct{Status: 1, ContractAddress: "0xDD"}})
+	noReceipt := EVMPodCandidate(5, types.TransactionStruct{To: "0xBB"})
+	if !EVMPodCandidate(6, types.TransactionStruct{To: evmCreationTo, Input: "0x6080"}).ContractCreation {
+		t.Error("a deployment stored without a receipt is not a contract creation")
+	}
+	if burn := EVMPodCandidate(8, types.TransactionStruct{To: evmCreationTo, Input: "0x", Receipt: &types.ReceiptStruct{Status: 1}}); burn.ContractCreation {
+		t.Error("a transfer to the zero address is a contract creation")
+	}
+	if setCode := EVMPodCandidate(7, types.TransactionStruct{To: "0xBB", Type: evmSetCodeTxType, Receipt: &types.ReceiptStruct{Status: 1}}); (InclusionPolicy{}).Exclude(setCode) != ExcludedUnsupported {
+		t.Errorf("set code transaction %+v is not excluded as unsupported", setCode)
+	}
+	if !deploy.ContractCreation || deploy.Contract != "0xDD" || call.Contract != "0xCC" || transfer.Contract != "" || !failed.Failed || noReceipt.Failed {
+		t.Fatalf("EVMPodCandidate() = %+v, %+v, %+v, %+v, %+v", transfer, failed, call, deploy, noReceipt)
+	}
+
+	tests := []struct {
+		name   string
+		policy InclusionPolicy
+		want   []string
+	}{
+		{name: "everything", policy: InclusionPolicy{}, want: []string{"", "", "", "", ""}},
+		{name: "successful only", policy: InclusionPolicy{SuccessfulOnly: true}, want: []string{"", ExcludedFailed, "", "", ""}},
+		{name: "value transfers only", policy: InclusionPolicy{ValueTransfersOnly: true}, want: []string{"", "", ExcludedContractCall, ExcludedContractCreation, ""}},
+		{name: "deny list", policy: InclusionPolicy{DenyContracts: []string{"0xcc"}}, want: []string{"", "", ExcludedContractDenied, "", ""}},
+		{name: "allow list", policy: InclusionPolicy{AllowContracts: []string{"0xcc"}}, want: []string{"", "", "", ExcludedContractNotAllowed, ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range []PodCandidate{transfer, failed, call, deploy, noReceipt} {
+				got = append(got, tt.policy.Exclude(c))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Exclude() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWasmPodCandidate(t *testing.T) {
+	send := types.Message{Type: "/cosmos.bank.v1beta1.MsgSend", FromAddress: "wasm1a", ToAddress: "wasm1b", Amount: []types.BatchAmount{{Denom: "stake", Amount: "5"}}}
+	execute := types.Message{Type: wasmExecuteContractURL, Contract: "wasm1c", Msg: []byte(`{"transfer":{"recipient":"wasm1b","amount":"7"}}`)}
+	wasmTxn := func(code int, messages ...types.Message) types.BatchTransaction {
+		var txn types.BatchTransaction
+		txn.Tx.Body.Messages = messages
+		txn.TxResponse.Code = code
+		return txn
+	}
+
+	tests := []struct {
+		name   string
+		txn    types.BatchTransaction
+		policy InclusionPolicy
+		want   string
+	}{
+		{name: "bank send", txn: wasmTxn(0, send), want: ""},
+		{name: "failed bank send", txn: wasmTxn(5, send), policy: InclusionPolicy{SuccessfulOnly: true}, want: ExcludedFailed},
+		{name: "multi-message", txn: wasmTxn(0, send, send), want: ""},
+		{name: "unsupported message", txn: wasmTxn(0, types.Message{Type: "/ibc.applications.transfer.v1.MsgTransfer"}), want: ExcludedUnsupported},
+		{name: "fee only", txn: wasmTxn(0), want: ExcludedUnsupported},
+		{name: "contract call", txn: wasmTxn(0, execute), policy: InclusionPolicy{ValueTransfersOnly: true}, want: ExcludedContractCall},
+		{name: "denied contract", txn: wasmTxn(0, execute), policy: InclusionPolicy{DenyContracts: []string{"wasm1c"}}, want: ExcludedContractDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidate, _ := WasmPodCandidate(1, tt.txn)
+			if got := tt.policy.Exclude(candidate); got != tt.want {
+				t.Errorf("Exclude() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInclusionPolicyValidate(t *testing.T) {
+	if err := (InclusionPolicy{AllowContracts: []string{"0xAA"}, DenyContracts: []string{"0xbb"}}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (InclusionPolicy{AllowContracts: []string{"0xAA"}, DenyContracts: []string{"0xaa"}}).Validate(); err == nil {
+		t.Error("Validate() accepted a contract that is both allowed and denied")
+	}
+	if err := (InclusionPolicy{DenyContracts: []string{" "}}).Validate(); err == nil {
+		t.Error("Validate() accepted an empty contract address")
+	}
+}
+
+func TestRecordPodExclusions(t *testing.T) {
+	txnDB := newMemDB(t)
+	unsupported := PodCandidate{Seq: 3, Hash: "0x03", Unsupported: "2 messages"}
+	first := []TxnExclusion{{Seq: 2, Hash: "0x02", Pod: 1, Reason: ExcludedFailed}, unsupported.Exclusion(1, ExcludedUnsupported)}
+	if err := RecordPodExclusions(txnDB, 1, 25, first); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := GetTxnExclusion(txnDB, 3); err != nil || got.Detail != "2 messages" || got.Pod != 1 {
+		t.Errorf("GetTxnExclusion(3) = %+v, %v", got, err)
+	}
+
+	// Rebuilding the pod under another policy drops the records it no longer holds.
+	if err := RecordPodExclusions(txnDB, 1, 25, first[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetTxnExclusion(txnDB, 2); err == nil {
+		t.Error("a stale exclusion record was kept after rebuilding the pod")
+	}
+	if _, err := GetTxnExclusion(txnDB, 3); err != nil {
+		t.Error(err)
+	}
+}
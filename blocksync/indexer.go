@@ -0,0 +1,296 @@
+package blocksync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const (
+	StationTypeEVM  = "evm"
+	StationTypeWASM = "wasm"
+	StationTypeSVM  = "svm"
+)
+
+// StationIndexer follows one station chain and persists its blocks and transactions
+// into the block and transaction databases. Each station family provides its own
+// implementation and registers it with RegisterIndexer.
+type StationIndexer interface {
+	// Start indexes the station until ctx is cancelled or Stop is called.
+	Start(ctx context.Context) error
+	// Stop asks a running indexer to return from Start.
+	Stop()
+	// LatestIndexed returns the height of the last block that was fully stored.
+	LatestIndexed() int
+	// Status returns a snapshot of the indexer progress.
+	Status() IndexerStatus
+}
+
+// IndexerStatus is a point-in-time view of a StationIndexer.
+type IndexerStatus struct {
+	StationType   string
+	Running       bool
+	LatestIndexed int
+	// FinalizedBlock is the highest block whose transactions may be included in pods, or
+	// -1 if there is none yet.
+	FinalizedBlock int
+	LastError      string
+	UpdatedAt      time.Time
+}
+
+// IndexerOptions carries everything an indexer needs to talk to the station and the
+// local databases.
+type IndexerOptions struct {
+	// StationRPC and StationAPI are comma separated endpoint lists; later endpoints are
+	// used when the earlier ones are down.
+	StationRPC string
+	StationAPI string
+	// StationClient configures the requests sent to the station endpoints.
+	StationClient stationclient.Options
+	// StationWS is the optional websocket endpoint used to subscribe to new blocks.
+	StationWS string
+	BlockDB   *leveldb.DB
+	TxnDB     *leveldb.DB
+	// StaticDB and StateDB are read to find the transactions already included in a pod.
+	StaticDB   *leveldb.DB
+	StateDB    *leveldb.DB
+	StartBlock int
+	// Concurrency bounds the number of blocks fetched in parallel. Zero uses the default.
+	Concurrency int
+	// Finality decides which indexed blocks may be included in pods.
+	Finality FinalityPolicy
+	// Pruning decides which blocks and transactions of verified pods are deleted.
+	Pruning PruningPolicy
+	// StateTracker keeps the account state of the station from its indexed transactions.
+	StateTracker StateTrackerOptions
+}
+
+// IndexerFactory builds a StationIndexer for a single station family.
+type IndexerFactory func(opts IndexerOptions) (StationIndexer, error)
+
+// PodBuilder assembles the next pod of a station family from the transactions indexed
+// in ldt, starting after batchStartIndex and taking at most limit transactions.
+type PodBuilder func(ldt *leveldb.DB, batchStartIndex []byte, limit []byte) (witness []byte, unverifiedProof []byte, MRH []byte, podData *types.BatchStruct, err error)
+
+// stationDrivers are the implementations registered for one station type.
+type stationDrivers struct {
+	indexer    IndexerFactory
+	podBuilder PodBuilder
+}
+
+var (
+	indexerRegistryMu sync.RWMutex
+	indexerRegistry   = make(map[string]*stationDrivers)
+)
+
+// RegisterIndexer makes an indexer available for the given station type. It panics if
+// the station type is registered twice, so it is meant to be called from init.
+func RegisterIndexer(stationType string, factory IndexerFactory) {
+	indexerRegistryMu.Lock()
+	defer indexerRegistryMu.Unlock()
+
+	key := NormalizeStationType(stationType)
+	if factory == nil {
+		panic("blocksync: RegisterIndexer factory is nil for " + key)
+	}
+	if _, exists := indexerRegistry[key]; exists {
+		panic("blocksync: RegisterIndexer called twice for " + key)
+	}
+	indexerRegistry[key] = &stationDrivers{indexer: factory}
+}
+
+// RegisterPodBuilder makes a pod builder available for a station type registered with
+// RegisterIndexer. Station types without one are indexed but do not produce pods. Like
+// RegisterIndexer it panics on misuse and is meant to be called from init.
+func RegisterPodBuilder(stationType string, builder PodBuilder) {
+	indexerRegistryMu.Lock()
+	defer indexerRegistryMu.Unlock()
+
+	key := NormalizeStationType(stationType)
+	if builder == nil {
+		panic("blocksync: RegisterPodBuilder builder is nil for " + key)
+	}
+	drivers, ok := indexerRegistry[key]
+	if !ok {
+		panic("blocksync: RegisterPodBuilder called for unregistered station type " + key)
+	}
+	if drivers.podBuilder != nil {
+		panic("blocksync: RegisterPodBuilder called twice for " + key)
+	}
+	drivers.podBuilder = builder
+}
+
+// StationPodBuilder returns the pod builder registered for stationType, or false if the
+// station type does not produce pods.
+func StationPodBuilder(stationType string) (PodBuilder, bool) {
+	indexerRegistryMu.RLock()
+	defer indexerRegistryMu.RUnlock()
+	drivers, ok := indexerRegistry[NormalizeStationType(stationType)]
+	if !ok || drivers.podBuilder == nil {
+		return nil, false
+	}
+	return drivers.podBuilder, true
+}
+
+// NewStationIndexer builds the registered indexer for stationType.
+func NewStationIndexer(stationType string, opts IndexerOptions) (StationIndexer, error) {
+	indexerRegistryMu.RLock()
+	drivers, ok := indexerRegistry[NormalizeStationType(stationType)]
+	indexerRegistryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported station type %q, must be one of: %s", stationType, strings.Join(SupportedStationTypes(), ", "))
+	}
+	if err := opts.Finality.validate(NormalizeStationType(stationType)); err != nil {
+		return nil, err
+	}
+	if err := opts.Pruning.validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.StateTracker.validate(NormalizeStationType(stationType), opts.TxnDB); err != nil {
+		return nil, err
+	}
+	return drivers.indexer(opts)
+}
+
+// IsStationTypeSupported reports whether an indexer is registered for stationType.
+func IsStationTypeSupported(stationType string) bool {
+	indexerRegistryMu.RLock()
+	defer indexerRegistryMu.RUnlock()
+	_, ok := indexerRegistry[NormalizeStationType(stationType)]
+	return ok
+}
+
+// SupportedStationTypes returns the registered station types in sorted order.
+func SupportedStationTypes() []string {
+	indexerRegistryMu.RLock()
+	defer indexerRegistryMu.RUnlock()
+	stationTypes := make([]string, 0, len(indexerRegistry))
+	for stationType := range indexerRegistry {
+		stationTypes = append(stationTypes, stationType)
+	}
+	sort.Strings(stationTypes)
+	return stationTypes
+}
+
+// NormalizeStationType maps the station type from sequencer.toml ("EVM", "evm", ...)
+// to the key used by the registry.
+func NormalizeStationType(stationType string) string {
+	return strings.ToLower(strings.TrimSpace(stationType))
+}
+
+// indexerState holds the bookkeeping shared by every StationIndexer implementation.
+type indexerState struct {
+	mu          sync.Mutex
+	stationType string
+	blockDB     *leveldb.DB
+	running     bool
+	lastErr     error
+	updatedAt   time.Time
+	cancel      context.CancelFunc
+}
+
+func newIndexerState(stationType string, blockDB *leveldb.DB) indexerState {
+	return indexerState{stationType: stationType, blockDB: blockDB}
+}
+
+// begin marks the indexer as running and returns a context that Stop cancels.
+func (s *indexerState) begin(ctx context.Context) (context.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return nil, fmt.Errorf("%s indexer is already running", s.stationType)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	s.running = true
+	s.cancel = cancel
+	s.updatedAt = time.Now()
+	return runCtx, nil
+}
+
+func (s *indexerState) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	s.running = false
+	s.updatedAt = time.Now()
+}
+
+func (s *indexerState) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+	s.updatedAt = time.Now()
+}
+
+func (s *indexerState) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+func (s *indexerState) LatestIndexed() int {
+	return readCounter(s.blockDB, "blockCount") - 1
+}
+
+func (s *indexerState) Status() IndexerStatus {
+	latest := s.LatestIndexed()
+	finalized, ok := readFinalizedBlock(s.blockDB)
+	if !ok {
+		finalized = -1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := IndexerStatus{
+		StationType:    s.stationType,
+		Running:        s.running,
+		LatestIndexed:  latest,
+		FinalizedBlock: finalized,
+		UpdatedAt:      s.updatedAt,
+	}
+	if s.lastErr != nil {
+		status.LastError = s.lastErr.Error()
+	}
+	return status
+}
+
+// readCounter reads a decimal counter such as blockCount or txnCount, returning 0 when
+// the key is missing or malformed.
+func readCounter(db *leveldb.DB, key string) int {
+	if db == nil {
+		return 0
+	}
+	value, err := db.Get([]byte(key), nil)
+	if err != nil {
+		return 0
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(value)))
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// sleepContext waits for d and reports false if ctx was cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,340 @@
+package blocksync
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+// fakeEVMClient serves a fixed chain from memory. When latency is set, block and
+// receipt lookups are delayed to mimic RPC round trips.
+type fakeEVMClient struct {
+	latency time.Duration
+	// receiptHook, when set, can add logs to or change the status of every new receipt.
+	receiptHook func(tx *ethTypes.Transaction, receipt *ethTypes.Receipt)
+	// finalized is the height returned for the finalized and safe block tags.
+	finalized int64
+
+	mu       sync.Mutex
+	chainID  *big.Int
+	key      *ecdsa.PrivateKey
+	nonce    uint64
+	extra    []byte
+	blocks   []*ethTypes.Block
+	txs      map[common.Hash]*ethTypes.Transaction
+	receipts map[common.Hash]*ethTypes.Receipt
+}
+
+func newFakeEVMClient(t testing.TB, blockCount, txsPerBlock int) *fakeEVMClient {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeEVMClient{
+		chainID:  big.NewInt(1337),
+		key:      key,
+		txs:      make(map[common.Hash]*ethTypes.Transaction),
+		receipts: make(map[common.Hash]*ethTypes.Receipt),
+	}
+	f.mine(t, blockCount, txsPerBlock)
+	return f
+}
+
+// mine appends blockCount blocks carrying txsPerBlock value transfers each.
+func (f *fakeEVMClient) mine(t testing.TB, blockCount, txsPerBlock int) {
+	t.Helper()
+	for height := 0; height < blockCount; height++ {
+		var txs []*ethTypes.Transaction
+		for i := 0; i < txsPerBlock; i++ {
+			txs = append(txs, f.signedTransfer(t, f.key, f.nonce))
+			f.nonce++
+		}
+		f.appendBlock(txs)
+	}
+}
+
+// reorg drops every block from height onwards and mines blockCount replacement blocks
+// with different hashes.
+func (f *fakeEVMClient) reorg(t testing.TB, height, blockCount, txsPerBlock int) {
+	t.Helper()
+	f.mu.Lock()
+	f.blocks = f.blocks[:height]
+	f.extra = []byte("fork")
+	f.mu.Unlock()
+	f.mine(t, blockCount, txsPerBlock)
+}
+
+func (f *fakeEVMClient) signedTransfer(t testing.TB, key *ecdsa.PrivateKey, nonce uint64) *ethTypes.Transaction {
+	t.Helper()
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tx, err := ethTypes.SignNewTx(key, ethTypes.LatestSignerForChainID(f.chainID), &ethTypes.DynamicFeeTx{
+		ChainID:   f.chainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func (f *fakeEVMClient) appendBlock(txs []*ethTypes.Transaction) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	header := &ethTypes.Header{
+		Number:     big.NewInt(int64(len(f.blocks))),
+		Difficulty: big.NewInt(0),
+		BaseFee:    big.NewInt(1),
+		GasLimit:   30_000_000,
+		Time:       uint64(len(f.blocks)),
+		Extra:      f.extra,
+	}
+	if len(f.blocks) > 0 {
+		header.ParentHash = f.blocks[len(f.blocks)-1].Hash()
+	}
+	var receipts []*ethTypes.Receipt
+	for i, tx := range txs {
+		receipt := &ethTypes.Receipt{
+			Type:              tx.Type(),
+			Status:            ethTypes.ReceiptStatusSuccessful,
+			GasUsed:           21000,
+			CumulativeGasUsed: uint64(21000 * (i + 1)),
+			TxHash:            tx.Hash(),
+			TransactionIndex:  uint(i),
+		}
+		if f.receiptHook != nil {
+			f.receiptHook(tx, receipt)
+		}
+		receipts = append(receipts, receipt)
+		f.txs[tx.Hash()] = tx
+		f.receipts[tx.Hash()] = receipt
+	}
+	block := ethTypes.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
+	for _, receipt := range receipts {
+		receipt.BlockHash = block.Hash()
+		receipt.BlockNumber = block.Number()
+	}
+	f.blocks = append(f.blocks, block)
+}
+
+// delay sleeps for the configured latency, varied by key so that concurrent lookups
+// complete out of order.
+func (f *fakeEVMClient) delay(key uint64) {
+	if f.latency > 0 {
+		time.Sleep(f.latency * time.Duration(1+key%3))
+	}
+}
+
+func (f *fakeEVMClient) BlockByNumber(_ context.Context, number *big.Int) (*ethTypes.Block, error) {
+	if number != nil {
+		f.delay(number.Uint64())
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if number == nil || number.Int64() >= int64(len(f.blocks)) {
+		return nil, ethereum.NotFound
+	}
+	return f.blocks[number.Int64()], nil
+}
+
+func (f *fakeEVMClient) HeaderByNumber(_ context.Context, number *big.Int) (*ethTypes.Header, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	height := f.finalized
+	if number != nil && number.Sign() >= 0 {
+		height = number.Int64()
+	}
+	if number == nil || height >= int64(len(f.blocks)) {
+		return nil, ethereum.NotFound
+	}
+	return f.blocks[height].Header(), nil
+}
+
+func (f *fakeEVMClient) TransactionByHash(_ context.Context, hash common.Hash) (*ethTypes.Transaction, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tx, ok := f.txs[hash]
+	if !ok {
+		return nil, false, ethereum.NotFound
+	}
+	return tx, false, nil
+}
+
+func (f *fakeEVMClient) TransactionReceipt(_ context.Context, hash common.Hash) (*ethTypes.Receipt, error) {
+	f.delay(uint64(hash[0]))
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	receipt, ok := f.receipts[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (f *fakeEVMClient) NetworkID(_ context.Context) (*big.Int, error) {
+	return f.chainID, nil
+}
+
+// RawBlockByNumber is not served: every transaction of the fake chain can be decoded.
+func (f *fakeEVMClient) RawBlockByNumber(_ context.Context, _ *big.Int) (json.RawMessage, error) {
+	return nil, errors.New("raw blocks are not served by the fake chain")
+}
+
+func newMemDB(t testing.TB) *leveldb.DB {
+	t.Helper()
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestDBs(t testing.TB) (*leveldb.DB, *leveldb.DB) {
+	t.Helper()
+	blockDB, txnDB := newMemDB(t), newMemDB(t)
+	if err := blockDB.Put([]byte("blockCount"), []byte("0"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := txnDB.Put([]byte("txnCount"), []byte("0"), nil); err != nil {
+		t.Fatal(err)
+	}
+	return blockDB, txnDB
+}
+
+// waitFor polls cond until it holds or the timeout expires.
+func waitFor(t testing.TB, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newTestStation returns a station client for endpoints that backs off briefly.
+func newTestStation(t testing.TB, endpoints ...string) *stationclient.Client {
+	t.Helper()
+	station, err := stationclient.New(endpoints, stationclient.Options{BackoffBase: 10 * time.Millisecond, BackoffMax: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return station
+}
+
+func TestStationIndexerRegistry(t *testing.T) {
+	tests := []struct {
+		stationType string
+		want        bool
+	}{
+		{stationType: "evm", want: true},
+		{stationType: "EVM", want: true},
+		{stationType: " wasm ", want: true},
+		{stationType: "svm", want: true},
+		{stationType: "move", want: false},
+		{stationType: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stationType, func(t *testing.T) {
+			if got := IsStationTypeSupported(tt.stationType); got != tt.want {
+				t.Errorf("IsStationTypeSupported(%q) = %v, want %v", tt.stationType, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewStationIndexer("move", IndexerOptions{}); err == nil {
+		t.Error("NewStationIndexer() expected error for unregistered station type")
+	}
+
+	if _, ok := StationPodBuilder("move"); ok {
+		t.Error("StationPodBuilder() returned a builder for an unregistered station type")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("RegisterPodBuilder() accepted an unregistered station type")
+			}
+		}()
+		RegisterPodBuilder("move", func(*leveldb.DB, []byte, []byte) ([]byte, []byte, []byte, *types.BatchStruct, error) {
+			return nil, nil, nil, nil, nil
+		})
+	}()
+
+	want := []string{StationTypeEVM, StationTypeSVM, StationTypeWASM}
+	got := SupportedStationTypes()
+	if len(got) != len(want) {
+		t.Fatalf("SupportedStationTypes() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("SupportedStationTypes() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestEVMIndexerAgainstFakeRPC(t *testing.T) {
+	client := newFakeEVMClient(t, 3, 2)
+	blockDB, txnDB := newTestDBs(t)
+	indexer := newEVMIndexerWithClient(client, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB})
+
+	done := make(chan error, 1)
+	go func() { done <- indexer.Start(context.Background()) }()
+
+	waitFor(t, 5*time.Second, func() bool { return indexer.LatestIndexed() == 2 })
+	if status := indexer.Status(); !status.Running || status.StationType != StationTypeEVM {
+		t.Errorf("Status() = %+v, want running evm indexer", status)
+	}
+
+	indexer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start() returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start() did not return after Stop()")
+	}
+	if indexer.Status().Running {
+		t.Error("Status().Running = true after Stop()")
+	}
+
+	if got := readCounter(txnDB, "txnCount"); got != 6 {
+		t.Fatalf("txnCount = %d, want 6", got)
+	}
+	raw, err := txnDB.Get([]byte("txns-6"), nil)
+	if err != nil {
+		t.Fatalf("txns-6 missing: %v", err)
+	}
+	var tx types.TransactionStruct
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.BlockNumber != 2 || tx.Value != "1000" {
+		t.Errorf("txns-6 = %+v, want value 1000 in block 2", tx)
+	}
+	if _, err := blockDB.Get([]byte("Block2"), nil); err != nil {
+		t.Errorf("Block2 missing: %v", err)
+	}
+}
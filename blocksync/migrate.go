@@ -0,0 +1,299 @@
+package blocksync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/airchains-network/decentralized-sequencer/types/svmTypes"
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// schemaVersionKey in the static database is the version of the last migration applied
+// to the databases.
+var schemaVersionKey = []byte("schemaVersion")
+
+// Databases are the databases a migration may rewrite.
+type Databases struct {
+	BlockDB  *leveldb.DB
+	TxnDB    *leveldb.DB
+	StaticDB *leveldb.DB
+	StateDB  *leveldb.DB
+	// PodsDB holds the pods built by the node under pod-<n>.
+	PodsDB *leveldb.DB
+	// StationType decides how stored blocks and transactions are decoded.
+	StationType string
+}
+
+// LocalDatabases returns the databases opened by InitDb.
+func LocalDatabases(stationType string) Databases {
+	return Databases{
+		BlockDB:     GetBlockDbInstance(),
+		TxnDB:       GetTxDbInstance(),
+		StaticDB:    GetStaticDbInstance(),
+		StateDB:     GetStateDbInstance(),
+		PodsDB:      GetBatchesDbInstance(),
+		StationType: stationType,
+	}
+}
+
+// migration upgrades the databases from Version-1 to Version. Migrate must be
+// idempotent: it runs again if the node stops before the version is recorded.
+type migration struct {
+	Version     int
+	Description string
+	Migrate     func(dbs Databases, progress func(done, total int)) error
+}
+
+// migrations are applied in order. Append new migrations at the end and never change
+// the version of a released one.
+var migrations = []migration{
+	{Version: 1, Description: "store the pod state with every field", Migrate: migratePodState},
+	{Version: 2, Description: "index transactions stored before the hash and address indexes", Migrate: migrateTxnIndexes},
+	{Version: 3, Description: "map the transactions of verified pods to their pod", Migrate: migrateTxnPods},
+	{Version: 4, Description: "store EVM blocks under Block<n> like the other stations", Migrate: migrateBlockKeys},
+}
+
+// migrationBatchSize is the number of entries a migration writes per batch.
+const migrationBatchSize = 1000
+
+// SchemaVersion is the storage schema written by this binary.
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// ReadSchemaVersion returns the schema version of the databases. Databases written
+// before the version was recorded are at version 0.
+func ReadSchemaVersion(staticDB *leveldb.DB) int {
+	return readCounter(staticDB, string(schemaVersionKey))
+}
+
+// CheckSchemaVersion reports an error unless the databases are at SchemaVersion.
+func CheckSchemaVersion(staticDB *leveldb.DB) error {
+	switch version := ReadSchemaVersion(staticDB); {
+	case version > SchemaVersion():
+		return fmt.Errorf("database schema version %d is newer than version %d supported by this binary, upgrade tracks", version, SchemaVersion())
+	case version < SchemaVersion():
+		return fmt.Errorf("database schema version %d is older than version %d, run tracks start to migrate it", version, SchemaVersion())
+	}
+	return nil
+}
+
+// MigrateDatabases applies every migration newer than the recorded schema version, in
+// order, and records the version after each one. It refuses databases written by a newer
+// binary.
+func MigrateDatabases(dbs Databases) error {
+	return runMigrations(dbs, migrations)
+}
+
+func runMigrations(dbs Databases, migrations []migration) error {
+	current := ReadSchemaVersion(dbs.StaticDB)
+	latest := migrations[len(migrations)-1].Version
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than version %d supported by this binary, upgrade tracks", current, latest)
+	}
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		log.Info().Str("module", "blocksync").Msg(fmt.Sprintf("Migrating databases to schema version %d: %s", m.Version, m.Description))
+		if err := m.Migrate(dbs, migrationProgress(m.Version)); err != nil {
+			return fmt.Errorf("migration to schema version %d failed: %w", m.Version, err)
+		}
+		if err := dbs.StaticDB.Put(schemaVersionKey, []byte(strconv.Itoa(m.Version)), syncWrite); err != nil {
+			return fmt.Errorf("failed to record schema version %d: %w", m.Version, err)
+		}
+	}
+	return nil
+}
+
+// migrationProgress logs every tenth of the work of a migration.
+func migrationProgress(version int) func(done, total int) {
+	logged := 0
+	return func(done, total int) {
+		if total == 0 {
+			return
+		}
+		if step := done * 10 / total; step > logged || done == total {
+			logged = step
+			log.Info().Str("module", "blocksync").Msg(fmt.Sprintf("Schema version %d: %d of %d done", version, done, total))
+		}
+	}
+}
+
+// migratePodState rewrites the stored pod state so that it carries every field of
+// types.PodState, with a vote map and a transaction state.
+func migratePodState(dbs Databases, progress func(done, total int)) error {
+	data, err := dbs.StateDB.Get([]byte("podState"), nil)
+	if err == leveldb.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var podState types.PodState
+	if err := json.Unmarshal(data, &podState); err != nil {
+		return fmt.Errorf("failed to decode the pod state: %w", err)
+	}
+	if podState.Votes == nil {
+		podState.Votes = make(map[string]types.Votes)
+	}
+	if podState.LatestTxState == "" {
+		podState.LatestTxState = "PreInit"
+	}
+	if podState.LatestPodHeight == 0 {
+		podState.LatestPodHeight = 1
+	}
+	migrated, err := json.Marshal(podState)
+	if err != nil {
+		return fmt.Errorf("failed to encode the pod state: %w", err)
+	}
+	if err := dbs.StateDB.Put([]byte("podState"), migrated, syncWrite); err != nil {
+		return err
+	}
+	progress(1, 1)
+	return nil
+}
+
+// migrateTxnIndexes writes the hash, address and log indexes of every stored transaction
+// that has none.
+func migrateTxnIndexes(dbs Databases, progress func(done, total int)) error {
+	first := readCounter(dbs.TxnDB, string(prunedTxnKey)) + 1
+	txnCount := readCounter(dbs.TxnDB, "txnCount")
+	stationType := NormalizeStationType(dbs.StationType)
+	for start := first; start <= txnCount; start += migrationBatchSize {
+		batch := new(leveldb.Batch)
+		end := min(start+migrationBatchSize-1, txnCount)
+		for seq := start; seq <= end; seq++ {
+			if ok, _ := dbs.TxnDB.Has(txnIndexListKey(seq), nil); ok {
+				continue
+			}
+			raw, err := dbs.TxnDB.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+			if err != nil {
+				continue
+			}
+			w := blockWrite{
+				txns: [][]byte{raw},
+				txnIndexes: func(_, seq int) map[string][]byte {
+					return storedTxnIndexes(stationType, raw, seq)
+				},
+			}
+			if err := putTxn(batch, w, 0, seq); err != nil {
+				return err
+			}
+		}
+		if err := dbs.TxnDB.Write(batch, syncWrite); err != nil {
+			return fmt.Errorf("failed to index transactions %d to %d: %w", start, end, err)
+		}
+		progress(end-first+1, txnCount-first+1)
+	}
+	return nil
+}
+
+// storedTxnIndexes returns the index entries of a transaction stored as raw.
+func storedTxnIndexes(stationType string, raw []byte, seq int) map[string][]byte {
+	switch stationType {
+	case StationTypeEVM:
+		var tx types.TransactionStruct
+		if err := json.Unmarshal(raw, &tx); err != nil {
+			return nil
+		}
+		return evmTxnIndexes(tx, seq)
+	case StationTypeWASM:
+		return wasmTxnIndexes(raw, seq)
+	case StationTypeSVM:
+		var txn svmTypes.SVMTransactionStruct
+		if err := json.Unmarshal(raw, &txn); err != nil {
+			return nil
+		}
+		return svmTxnIndexes(txn, seq)
+	}
+	return nil
+}
+
+// migrateTxnPods records the pod of every transaction in a verified pod. The range of a
+// pod is read from the pod stored by the node, or from the last transaction recorded for
+// it and the pod before it.
+func migrateTxnPods(dbs Databases, progress func(done, total int)) error {
+	verifiedPods := readCounter(dbs.StaticDB, "batchCount")
+	prunedTxn := readCounter(dbs.TxnDB, string(prunedTxnKey))
+	for pod := 1; pod <= verifiedPods; pod++ {
+		firstSeq, lastSeq := storedPodTxns(dbs, pod)
+		if lastSeq <= prunedTxn {
+			continue
+		}
+		if ok, _ := dbs.TxnDB.Has(txnPodKey(lastSeq), nil); !ok {
+			if err := IndexPodTxns(dbs.TxnDB, pod, max(firstSeq, prunedTxn+1), lastSeq); err != nil {
+				return fmt.Errorf("failed to index the transactions of pod %d: %w", pod, err)
+			}
+		}
+		progress(pod, verifiedPods)
+	}
+	return nil
+}
+
+// storedPodTxns returns the first and last transaction of a verified pod.
+func storedPodTxns(dbs Databases, pod int) (int, int) {
+	if dbs.PodsDB != nil {
+		if data, err := dbs.PodsDB.Get([]byte(fmt.Sprintf("pod-%d", pod)), nil); err == nil {
+			var stored struct {
+				Batch *types.BatchStruct
+			}
+			if json.Unmarshal(data, &stored) == nil && stored.Batch != nil && stored.Batch.LastTxnSeq > 0 {
+				return stored.Batch.FirstTxnSeq, stored.Batch.LastTxnSeq
+			}
+		}
+	}
+	return PodLastTxn(dbs.TxnDB, pod-1) + 1, PodLastTxn(dbs.TxnDB, pod)
+}
+
+// legacyEVMBlockPrefix is the prefix EVM blocks were stored under before schema version 4.
+const legacyEVMBlockPrefix = "block_"
+
+// migrateBlockKeys moves the blocks stored under block_<n> to Block<n> and points their
+// commit markers at the new key.
+func migrateBlockKeys(dbs Databases, progress func(done, total int)) error {
+	var heights []int
+	iter := dbs.BlockDB.NewIterator(util.BytesPrefix([]byte(legacyEVMBlockPrefix)), nil)
+	for iter.Next() {
+		height, err := strconv.Atoi(strings.TrimPrefix(string(iter.Key()), legacyEVMBlockPrefix))
+		if err != nil {
+			continue
+		}
+		heights = append(heights, height)
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	for start := 0; start < len(heights); start += migrationBatchSize {
+		batch := new(leveldb.Batch)
+		end := min(start+migrationBatchSize, len(heights))
+		for _, height := range heights[start:end] {
+			legacyKey := []byte(legacyEVMBlockPrefix + strconv.Itoa(height))
+			data, err := dbs.BlockDB.Get(legacyKey, nil)
+			if err != nil {
+				return fmt.Errorf("failed to read block %d: %w", height, err)
+			}
+			batch.Put([]byte(blockKey(height)), data)
+			batch.Delete(legacyKey)
+			if commit, ok := readBlockCommit(dbs.BlockDB, height); ok && commit.BlockKey == string(legacyKey) {
+				commit.BlockKey = blockKey(height)
+				value, err := json.Marshal(commit)
+				if err != nil {
+					return err
+				}
+				batch.Put(blockCommitKey(height), value)
+			}
+		}
+		if err := dbs.BlockDB.Write(batch, syncWrite); err != nil {
+			return fmt.Errorf("failed to move blocks %d to %d: %w", heights[start], heights[end-1], err)
+		}
+		progress(end, len(heights))
+	}
+	return nil
+}
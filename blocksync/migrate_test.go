@@ -0,0 +1,119 @@
+package blocksync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+)
+
+func TestRunMigrations(t *testing.T) {
+	dbs := Databases{StaticDB: newMemDB(t)}
+	var applied []int
+	step := func(version int, err error) migration {
+		return migration{Version: version, Description: "test", Migrate: func(Databases, func(int, int)) error {
+			applied = append(applied, version)
+			return err
+		}}
+	}
+
+	failing := []migration{step(1, nil), step(2, errors.New("disk full")), step(3, nil)}
+	if err := runMigrations(dbs, failing); err == nil || !strings.Contains(err.Error(), "version 2") {
+		t.Fatalf("runMigrations() = %v, want the failure of version 2", err)
+	}
+	if got := ReadSchemaVersion(dbs.StaticDB); got != 1 {
+		t.Errorf("schema version = %d after a failed migration, want 1", got)
+	}
+
+	applied = nil
+	if err := runMigrations(dbs, []migration{step(1, nil), step(2, nil), step(3, nil)}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(applied, []int{2, 3}) || ReadSchemaVersion(dbs.StaticDB) != 3 {
+		t.Errorf("applied %v up to version %d, want 2 and 3 up to 3", applied, ReadSchemaVersion(dbs.StaticDB))
+	}
+
+	applied = nil
+	if err := runMigrations(dbs, []migration{step(1, nil), step(2, nil)}); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("runMigrations() = %v, want a refusal of the newer database", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("applied %v to a newer database", applied)
+	}
+}
+
+func TestMigrateDatabases(t *testing.T) {
+	blockDB, txnDB := newTestDBs(t)
+	dbs := Databases{BlockDB: blockDB, TxnDB: txnDB, StaticDB: newVerifiedPodsDB(t, 2), StateDB: newMemDB(t), PodsDB: newMemDB(t), StationType: "EVM"}
+	// A database written before the indexes existed: 30 bare transactions, pod 1 full, pod
+	// 2 sealed after 3 transactions, a pod state without votes and EVM blocks under block_<n>.
+	commitTestBlocks(t, blockDB, txnDB, 30, 0)
+	txnDB.Put([]byte("txns-3"), []byte(`{"hash":"0xAB","from":"0x01","to":"0x02"}`), nil)
+	dbs.PodsDB.Put([]byte("pod-2"), []byte(`{"Batch":{"TxnCount":3,"FirstTxnSeq":26,"LastTxnSeq":28}}`), nil)
+	for height := 0; height < 2; height++ {
+		block, _ := blockDB.Get([]byte(blockKey(height)), nil)
+		commit, _ := readBlockCommit(blockDB, height)
+		commit.BlockKey = fmt.Sprintf("block_%d", height)
+		marker, _ := json.Marshal(commit)
+		blockDB.Delete([]byte(blockKey(height)), nil)
+		blockDB.Put([]byte(commit.BlockKey), block, nil)
+		blockDB.Put(blockCommitKey(height), marker, nil)
+	}
+	dbs.StateDB.Put([]byte("podState"), []byte(`{"LatestPodHeight":2,"LatestTxState":"","VRFInitiationTxHash":"0xvrf"}`), nil)
+
+	for i := 0; i < 2; i++ {
+		if err := MigrateDatabases(dbs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := CheckSchemaVersion(dbs.StaticDB); err != nil {
+		t.Error(err)
+	}
+	if seq, err := GetTxnSeqByHash(txnDB, "0xab"); err != nil || seq != 3 {
+		t.Errorf("GetTxnSeqByHash() = %d, %v, want 3", seq, err)
+	}
+	if seqs, _ := GetTxnSeqsByAddress(txnDB, "0x02", 0); !reflect.DeepEqual(seqs, []int{3}) {
+		t.Errorf("GetTxnSeqsByAddress() = %v, want [3]", seqs)
+	}
+	if pod, err := GetTxnPod(txnDB, 25); err != nil || pod != 1 {
+		t.Errorf("GetTxnPod(25) = %d, %v, want pod 1", pod, err)
+	}
+	if pod, err := GetTxnPod(txnDB, 28); err != nil || pod != 2 {
+		t.Errorf("GetTxnPod(28) = %d, %v, want pod 2", pod, err)
+	}
+	if _, err := GetTxnPod(txnDB, 29); err == nil {
+		t.Error("a transaction of an unverified pod was mapped to a pod")
+	}
+	for height := 0; height < 2; height++ {
+		if ok, _ := blockDB.Has([]byte(fmt.Sprintf("block_%d", height)), nil); ok {
+			t.Errorf("block_%d still stored after the migration", height)
+		}
+		if commit, ok := readBlockCommit(blockDB, height); !ok || commit.BlockKey != blockKey(height) {
+			t.Errorf("commit marker of block %d = %+v, want block key %s", height, commit, blockKey(height))
+		}
+	}
+	if err := checkBlockContiguity(blockDB, "Block", 0); err != nil {
+		t.Error(err)
+	}
+
+	data, _ := dbs.StateDB.Get([]byte("podState"), nil)
+	var podState types.PodState
+	if err := json.Unmarshal(data, &podState); err != nil {
+		t.Fatal(err)
+	}
+	if podState.Votes == nil || podState.LatestTxState != "PreInit" || podState.LatestPodHeight != 2 || podState.VRFInitiationTxHash != "0xvrf" {
+		t.Errorf("migrated pod state = %s", data)
+	}
+
+	dbs.StaticDB.Put(schemaVersionKey, []byte("99"), nil)
+	if err := MigrateDatabases(dbs); err == nil {
+		t.Error("MigrateDatabases() accepted a database newer than the binary")
+	}
+	if err := CheckSchemaVersion(dbs.StaticDB); err == nil {
+		t.Error("CheckSchemaVersion() accepted a database newer than the binary")
+	}
+}
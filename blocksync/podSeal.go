@@ -0,0 +1,82 @@
+package blocksync
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/config"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func podLastTxnKey(pod int) []byte {
+	return []byte(fmt.Sprintf("podlast-%d", pod))
+}
+
+// readTime returns the unix time stored under key, or false if there is none.
+func readTime(ldt *leveldb.DB, key []byte) (int64, bool) {
+	value, err := ldt.Get(key, nil)
+	if err != nil {
+		return 0, false
+	}
+	t, err := strconv.ParseInt(strings.TrimSpace(string(value)), 10, 64)
+	if err != nil || t <= 0 {
+		return 0, false
+	}
+	return t, true
+}
+
+// SealPod decides which transactions go into the pod starting at transaction first. A
+// pod is sealed once its size transactions are final. When maxInterval is set, a pod
+// that is not full is sealed as well once the finalized station block is maxInterval
+// past the block of its first transaction; it then holds the final transactions of the
+// blocks before that cutoff. Sealing follows station block times rather than the clock
+// of the track, so every track seals a pod with the same transactions. Station time only
+// moves with new blocks: a station that produces no blocks while idle never reaches the
+// cutoff, and a first transaction stored without a time never seals early. It returns
+// the last transaction of the pod, or false if the pod cannot be sealed yet. A pod
+// always holds at least one transaction.
+func SealPod(ldt *leveldb.DB, first, size int, maxInterval time.Duration) (int, bool) {
+	full := first + size - 1
+	if ok, _ := ldt.Has([]byte(fmt.Sprintf("txns-%d", full)), nil); ok && IsTxnFinal(ldt, full) {
+		return full, true
+	}
+	if maxInterval <= 0 || !IsTxnFinal(ldt, first) {
+		return 0, false
+	}
+	if ok, _ := ldt.Has([]byte(fmt.Sprintf("txns-%d", first)), nil); !ok {
+		return 0, false
+	}
+	start, ok := readTime(ldt, txnTimeKey(first))
+	if !ok {
+		return 0, false
+	}
+	cutoff := start + int64(maxInterval/time.Second)
+	if finalized, ok := readTime(ldt, finalizedTimeKey); !ok || finalized < cutoff {
+		return 0, false
+	}
+	last := first
+	for seq := first + 1; seq < full && IsTxnFinal(ldt, seq); seq++ {
+		t, ok := readTime(ldt, txnTimeKey(seq))
+		if !ok || t >= cutoff {
+			break
+		}
+		last = seq
+	}
+	return last, true
+}
+
+// PodLastTxn returns the last transaction of verified pod, or 0 before the first pod.
+// Pods verified before their range was recorded hold config.PODSize transactions each.
+func PodLastTxn(ldt *leveldb.DB, pod int) int {
+	if pod < 1 {
+		return 0
+	}
+	if value, err := ldt.Get(podLastTxnKey(pod), nil); err == nil {
+		if last, err := strconv.Atoi(string(value)); err == nil {
+			return last
+		}
+	}
+	return config.PODSize * pod
+}
@@ -0,0 +1,93 @@
+package blocksync
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/config"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// commitTimedBlocks stores one block per entry of times, each with txnsPerBlock
+// transactions and the given station time.
+func commitTimedBlocks(t *testing.T, ldb, ldt *leveldb.DB, txnsPerBlock int, times ...int64) {
+	t.Helper()
+	for height, blockTime := range times {
+		w := blockWrite{height: height, blockKey: blockKey(height), blockData: []byte("{}"), time: blockTime}
+		for i := 0; i < txnsPerBlock; i++ {
+			w.txns = append(w.txns, []byte(fmt.Sprintf(`{"block":%d,"index":%d}`, height, i)))
+		}
+		if err := commitBlock(ldb, ldt, w); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSealPod(t *testing.T) {
+	tests := []struct {
+		name        string
+		times       []int64
+		final       int
+		maxInterval time.Duration
+		wantLast    int
+		wantOK      bool
+	}{
+		{"full pod", []int64{100, 101, 102, 103}, 3, 0, 4, true},
+		{"not final", []int64{100, 101, 102, 103}, 2, time.Hour, 0, false},
+		{"waits for full pod", []int64{100, 500, 900}, 2, 0, 0, false},
+		{"early seal", []int64{100, 130, 170}, 2, time.Minute, 2, true},
+		{"before cutoff", []int64{100, 130, 150}, 2, time.Minute, 0, false},
+		{"cutoff block not final", []int64{100, 130, 170}, 1, time.Minute, 0, false},
+		{"no block time", []int64{0, 0, 0}, 2, time.Second, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockDB, txnDB := newTestDBs(t)
+			commitTimedBlocks(t, blockDB, txnDB, 1, tt.times...)
+			if err := writeFinalized(blockDB, txnDB, tt.final, tt.final+1); err != nil {
+				t.Fatal(err)
+			}
+			last, ok := SealPod(txnDB, 1, 4, tt.maxInterval)
+			if last != tt.wantLast || ok != tt.wantOK {
+				t.Errorf("SealPod() = %d, %t, want %d, %t", last, ok, tt.wantLast, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSealPodKeepsBlocksTogether(t *testing.T) {
+	blockDB, txnDB := newTestDBs(t)
+	commitTimedBlocks(t, blockDB, txnDB, 2, 100, 110, 200)
+	if err := writeFinalized(blockDB, txnDB, 2, 6); err != nil {
+		t.Fatal(err)
+	}
+	// The first pod covers the blocks before the cutoff at 160, the next starts after them.
+	if last, ok := SealPod(txnDB, 1, 10, time.Minute); last != 4 || !ok {
+		t.Errorf("SealPod() = %d, %t, want the transactions of the first two blocks", last, ok)
+	}
+
+	// Removing the finalized block forgets its time until the boundary moves again.
+	if err := truncateTxns(txnDB, 4); err != nil {
+		t.Fatal(err)
+	}
+	if last, ok := SealPod(txnDB, 1, 10, time.Minute); ok {
+		t.Errorf("SealPod() after truncation = %d, want no seal", last)
+	}
+}
+
+func TestPodLastTxn(t *testing.T) {
+	txnDB := newMemDB(t)
+	if err := IndexPodTxns(txnDB, 3, 51, 57); err != nil {
+		t.Fatal(err)
+	}
+	if last := PodLastTxn(txnDB, 3); last != 57 {
+		t.Errorf("PodLastTxn(3) = %d, want the recorded range", last)
+	}
+	if last := PodLastTxn(txnDB, 2); last != 2*config.PODSize {
+		t.Errorf("PodLastTxn(2) = %d, want a full legacy pod", last)
+	}
+	if last := PodLastTxn(txnDB, 0); last != 0 {
+		t.Errorf("PodLastTxn(0) = %d", last)
+	}
+}
@@ -0,0 +1,258 @@
+package blocksync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/config"
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Pruning modes of a PruningPolicy.
+const (
+	// PruneNothing keeps every indexed block and transaction.
+	PruneNothing = "nothing"
+	// PruneKeepRecent keeps the blocks and transactions of the KeepRecent latest verified
+	// pods, and of every pod after them.
+	PruneKeepRecent = "keep-recent"
+	// PruneEverythingVerified deletes the blocks and transactions of every verified pod
+	// except the latest one.
+	PruneEverythingVerified = "everything-verified"
+)
+
+// defaultPruningInterval is used when a PruningPolicy has no Interval.
+const defaultPruningInterval = 10 * time.Minute
+
+var (
+	// prunedTxnKey in the transaction database is the highest pruned transaction.
+	prunedTxnKey = []byte("prunedTxn")
+	// prunedBlockKey in the block database is the lowest height that was not pruned.
+	prunedBlockKey = []byte("prunedBlock")
+)
+
+// PruningPolicy decides which indexed blocks and transactions are deleted once the pods
+// holding them are verified on the junction.
+type PruningPolicy struct {
+	Mode string
+	// KeepRecent is the number of latest verified pods kept by PruneKeepRecent.
+	KeepRecent int
+	// Interval is how often the pruner runs. Zero uses the default.
+	Interval time.Duration
+}
+
+// validate checks that the policy is complete.
+func (p PruningPolicy) validate() error {
+	switch p.Mode {
+	case "", PruneNothing, PruneEverythingVerified:
+	case PruneKeepRecent:
+		if p.KeepRecent < 1 {
+			return fmt.Errorf("pruning keep-recent must keep at least 1 pod, got %d", p.KeepRecent)
+		}
+	default:
+		return fmt.Errorf("unknown pruning mode %q, must be one of: %s, %s, %s", p.Mode, PruneNothing, PruneKeepRecent, PruneEverythingVerified)
+	}
+	if p.Interval < 0 {
+		return fmt.Errorf("pruning interval must not be negative, got %s", p.Interval)
+	}
+	return nil
+}
+
+// keptPods returns how many of the latest verified pods are kept. The latest one is
+// always kept: tracks that are behind may still ask about it before moving on.
+func (p PruningPolicy) keptPods() int {
+	if p.Mode == PruneKeepRecent {
+		return max(p.KeepRecent, 1)
+	}
+	return 1
+}
+
+// PruneReport sums up a pruning run.
+type PruneReport struct {
+	// LastPod is the latest pod whose blocks and transactions are pruned.
+	LastPod int
+	Blocks  int
+	Txns    int
+	// Bytes is the size of the deleted keys and values.
+	Bytes int64
+}
+
+// pruner deletes the blocks and transactions of verified pods, pod by pod. Only
+// transactions up to batchStartIndex, which saveVerifiedPOD moves once the junction
+// verified a pod, are ever deleted, and a block goes only once all its transactions
+// are gone. The latest block is never deleted.
+type pruner struct {
+	policy   PruningPolicy
+	ldb      *leveldb.DB
+	ldt      *leveldb.DB
+	staticDB *leveldb.DB
+}
+
+func newPruner(policy PruningPolicy, ldb, ldt, staticDB *leveldb.DB) *pruner {
+	return &pruner{policy: policy, ldb: ldb, ldt: ldt, staticDB: staticDB}
+}
+
+// startPruner runs the pruner of opts.Pruning until ctx is cancelled. It does nothing
+// when the policy keeps everything.
+func startPruner(ctx context.Context, opts IndexerOptions) {
+	if opts.Pruning.Mode == "" || opts.Pruning.Mode == PruneNothing || opts.StaticDB == nil {
+		return
+	}
+	interval := opts.Pruning.Interval
+	if interval == 0 {
+		interval = defaultPruningInterval
+	}
+	p := newPruner(opts.Pruning, opts.BlockDB, opts.TxnDB, opts.StaticDB)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			p.run(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (p *pruner) run(ctx context.Context) {
+	report, err := p.prune(ctx)
+	if err != nil {
+		log.Error().Str("module", "blocksync").Err(err).Msg("Failed to prune indexed blocks")
+	}
+	if report.Txns > 0 || report.Blocks > 0 {
+		log.Info().Str("module", "blocksync").Msg(fmt.Sprintf("Pruned %d blocks and %d transactions up to pod %d, reclaimed %d bytes", report.Blocks, report.Txns, report.LastPod, report.Bytes))
+	}
+}
+
+// prune deletes everything the policy allows and returns what was deleted.
+func (p *pruner) prune(ctx context.Context) (PruneReport, error) {
+	var report PruneReport
+	verifiedPods := readCounter(p.staticDB, "batchCount")
+	lastPod := verifiedPods - p.policy.keptPods()
+	if lastPod < 1 {
+		return report, nil
+	}
+	// batchStartIndex is the last transaction of the latest verified pod.
+	boundary := min(PodLastTxn(p.ldt, lastPod), readCounter(p.staticDB, "batchStartIndex"))
+
+	for seq := readCounter(p.ldt, string(prunedTxnKey)); seq < boundary; {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		last := min(seq+config.PODSize, boundary)
+		txns, bytes, err := p.pruneTxns(seq+1, last)
+		if err != nil {
+			return report, err
+		}
+		blocks, blockBytes, err := p.pruneBlocks(last)
+		if err != nil {
+			return report, err
+		}
+		report.Txns += txns
+		report.Blocks += blocks
+		report.Bytes += bytes + blockBytes
+		seq = last
+	}
+	if report.Txns > 0 || report.Blocks > 0 {
+		report.LastPod = lastPod
+		p.compact()
+	}
+	return report, nil
+}
+
+// pruneTxns deletes transactions first to last with their indexes and records last as
+// pruned.
+func (p *pruner) pruneTxns(first, last int) (int, int64, error) {
+	batch := new(leveldb.Batch)
+	deleted := 0
+	var bytes int64
+	for seq := first; seq <= last; seq++ {
+		key := []byte(fmt.Sprintf("txns-%d", seq))
+		if value, err := p.ldt.Get(key, nil); err == nil {
+			batch.Delete(key)
+			deleted++
+			bytes += int64(len(key) + len(value))
+		}
+		bytes += p.deleteIfPresent(p.ldt, batch, txnPodKey(seq))
+		bytes += p.deleteIfPresent(p.ldt, batch, txnIndexListKey(seq))
+		bytes += p.deleteIfPresent(p.ldt, batch, txnExclusionKey(seq))
+		bytes += p.deleteIfPresent(p.ldt, batch, txnAccountStatesKey(seq))
+		if err := deleteTxnIndexes(p.ldt, batch, seq); err != nil {
+			return 0, 0, err
+		}
+	}
+	batch.Put(prunedTxnKey, []byte(strconv.Itoa(last)))
+	if err := p.ldt.Write(batch, syncWrite); err != nil {
+		return 0, 0, fmt.Errorf("failed to prune transactions %d to %d: %w", first, last, err)
+	}
+	return deleted, bytes, nil
+}
+
+// pruneBlocks deletes the blocks after the last pruned one whose transactions all come
+// at or before boundary. It stops at a block stored without a commit marker, as its
+// transactions are unknown.
+func (p *pruner) pruneBlocks(boundary int) (int, int64, error) {
+	blockCount := readCounter(p.ldb, "blockCount")
+	batch := new(leveldb.Batch)
+	start := firstStoredBlock(p.ldb)
+	height := start
+	deleted := 0
+	var bytes int64
+	for ; height < blockCount-1; height++ {
+		commit, ok := readBlockCommit(p.ldb, height)
+		if !ok && height == 0 {
+			// WASM heights start at 1.
+			continue
+		}
+		if !ok || commit.TxnCount > boundary {
+			break
+		}
+		bytes += p.deleteIfPresent(p.ldb, batch, []byte(commit.BlockKey))
+		bytes += p.deleteIfPresent(p.ldb, batch, blockCommitKey(height))
+		deleted++
+	}
+	if height == start {
+		return 0, 0, nil
+	}
+	batch.Put(prunedBlockKey, []byte(strconv.Itoa(height)))
+	if err := p.ldb.Write(batch, syncWrite); err != nil {
+		return 0, 0, fmt.Errorf("failed to prune blocks before %d: %w", height, err)
+	}
+	return deleted, bytes, nil
+}
+
+// deleteIfPresent adds the removal of key to batch and returns the size it frees.
+func (p *pruner) deleteIfPresent(db *leveldb.DB, batch *leveldb.Batch, key []byte) int64 {
+	value, err := db.Get(key, nil)
+	if err != nil {
+		return 0
+	}
+	batch.Delete(key)
+	return int64(len(key) + len(value))
+}
+
+// compact rewrites the key ranges emptied by pruning so the space is returned to the
+// file system.
+func (p *pruner) compact() {
+	for _, prefix := range []string{"txns-", "txnidx-", "txpod-", "txexcl-", "acctpre-"} {
+		if err := p.ldt.CompactRange(*util.BytesPrefix([]byte(prefix))); err != nil {
+			log.Warn().Str("module", "blocksync").Err(err).Msg("Failed to compact pruned transactions")
+		}
+	}
+	for _, prefix := range []string{"Block", "SkippedSlot", "commit_"} {
+		if err := p.ldb.CompactRange(*util.BytesPrefix([]byte(prefix))); err != nil {
+			log.Warn().Str("module", "blocksync").Err(err).Msg("Failed to compact pruned blocks")
+		}
+	}
+}
+
+// firstStoredBlock returns the lowest height that has not been pruned.
+func firstStoredBlock(ldb *leveldb.DB) int {
+	return readCounter(ldb, string(prunedBlockKey))
+}
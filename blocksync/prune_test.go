@@ -0,0 +1,149 @@
+package blocksync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// newVerifiedPodsDB returns a static database in which pods 1 to pods are verified.
+func newVerifiedPodsDB(t *testing.T, pods int) *leveldb.DB {
+	t.Helper()
+	staticDB := newMemDB(t)
+	staticDB.Put([]byte("batchCount"), []byte(strconv.Itoa(pods)), nil)
+	staticDB.Put([]byte("batchStartIndex"), []byte(strconv.Itoa(25*pods)), nil)
+	return staticDB
+}
+
+func TestPrunerKeepsUnprunableData(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     PruningPolicy
+		pods       int
+		wantTxn    int
+		wantBlocks int
+	}{
+		{name: "keep recent", policy: PruningPolicy{Mode: PruneKeepRecent, KeepRecent: 2}, pods: 3, wantTxn: 25, wantBlocks: 2},
+		{name: "everything verified", policy: PruningPolicy{Mode: PruneEverythingVerified}, pods: 3, wantTxn: 50, wantBlocks: 5},
+		{name: "only the latest pod is verified", policy: PruningPolicy{Mode: PruneEverythingVerified}, pods: 1, wantTxn: 0, wantBlocks: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockDB, txnDB := newTestDBs(t)
+			// Ten blocks of ten transactions, pod n holds transactions 25n-24 to 25n.
+			commitTestBlocks(t, blockDB, txnDB, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10)
+			if err := IndexPodTxns(txnDB, 1, 1, 25); err != nil {
+				t.Fatal(err)
+			}
+			if err := RecordPodExclusions(txnDB, 1, 25, []TxnExclusion{{Seq: 1, Pod: 1, Reason: ExcludedFailed}}); err != nil {
+				t.Fatal(err)
+			}
+
+			p := newPruner(tt.policy, blockDB, txnDB, newVerifiedPodsDB(t, tt.pods))
+			report, err := p.prune(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Txns != tt.wantTxn || report.Blocks != tt.wantBlocks {
+				t.Errorf("prune() = %+v, want %d transactions and %d blocks", report, tt.wantTxn, tt.wantBlocks)
+			}
+			if tt.wantTxn > 0 && report.Bytes == 0 {
+				t.Error("prune() reported no reclaimed space")
+			}
+
+			for seq := 1; seq <= 100; seq++ {
+				ok, _ := txnDB.Has([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+				if ok != (seq > tt.wantTxn) {
+					t.Errorf("txns-%d stored = %v after pruning %d transactions", seq, ok, tt.wantTxn)
+				}
+			}
+			for height := 0; height < 10; height++ {
+				ok, _ := blockDB.Has([]byte(blockKey(height)), nil)
+				if ok != (height >= tt.wantBlocks) {
+					t.Errorf("Block%d stored = %v after pruning %d blocks", height, ok, tt.wantBlocks)
+				}
+			}
+			if tt.wantTxn > 0 {
+				if _, err := GetTxnPod(txnDB, 1); err == nil {
+					t.Error("pod index of a pruned transaction was kept")
+				}
+				if _, err := GetTxnExclusion(txnDB, 1); err == nil {
+					t.Error("exclusion record of a pruned transaction was kept")
+				}
+			}
+			if got := readCounter(txnDB, "txnCount"); got != 100 {
+				t.Errorf("txnCount = %d, want 100", got)
+			}
+			if err := checkBlockContiguity(blockDB, "Block", 0); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPrunerIsIncremental(t *testing.T) {
+	blockDB, txnDB := newTestDBs(t)
+	commitTestBlocks(t, blockDB, txnDB, 25, 25, 0)
+	err := commitBlock(blockDB, txnDB, blockWrite{
+		height:    3,
+		blockKey:  "Block3",
+		blockData: []byte("{}"),
+		txns:      [][]byte{[]byte(`{"hash":"0xab"}`)},
+		txnIndexes: func(i, seq int) map[string][]byte {
+			return txnIndexes(seq, "0xab", "0xfrom")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	staticDB := newVerifiedPodsDB(t, 2)
+	p := newPruner(PruningPolicy{Mode: PruneEverythingVerified}, blockDB, txnDB, staticDB)
+
+	report, err := p.prune(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.LastPod != 1 || report.Txns != 25 || report.Blocks != 1 {
+		t.Errorf("first prune() = %+v, want pod 1 pruned", report)
+	}
+	if report, _ := p.prune(context.Background()); report.Txns != 0 || report.Blocks != 0 {
+		t.Errorf("second prune() = %+v, want nothing left to prune", report)
+	}
+
+	// Pod 3 holds the transaction of block 3, so verifying it frees pod 2 and block 2,
+	// but block 3 is the latest block and stays.
+	staticDB.Put([]byte("batchCount"), []byte("3"), nil)
+	staticDB.Put([]byte("batchStartIndex"), []byte("75"), nil)
+	report, err = p.prune(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.LastPod != 2 || report.Txns != 25 || report.Blocks != 2 {
+		t.Errorf("third prune() = %+v, want pod 2 and blocks 1 and 2 pruned", report)
+	}
+	if got := firstStoredBlock(blockDB); got != 3 {
+		t.Errorf("first stored block = %d, want 3", got)
+	}
+	if _, err := GetTxnSeqByHash(txnDB, "0xab"); err != nil {
+		t.Errorf("index of an unpruned transaction was removed: %v", err)
+	}
+	if err := checkStoredRange(blockDB, 2, 3); err == nil {
+		t.Error("checkStoredRange() accepted a pruned block")
+	}
+}
+
+func TestPruningPolicyValidate(t *testing.T) {
+	for _, policy := range []PruningPolicy{{}, {Mode: PruneNothing}, {Mode: PruneEverythingVerified}, {Mode: PruneKeepRecent, KeepRecent: 1}} {
+		if err := policy.validate(); err != nil {
+			t.Errorf("validate(%+v) = %v", policy, err)
+		}
+	}
+	for _, policy := range []PruningPolicy{{Mode: "archive"}, {Mode: PruneKeepRecent}} {
+		if err := policy.validate(); err == nil {
+			t.Errorf("validate(%+v) accepted an invalid policy", policy)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/airchains-network/decentralized-sequencer/config"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// ErrReorgDetected is returned by StoreEVMBlock when the parent hash of the fetched
+// block does not match the block stored at the previous height.
+type ErrReorgDetected struct {
+	Height          int
+	ParentHash      string
+	StoredBlockHash string
+}
+
+func (e *ErrReorgDetected) Error() string {
+	return fmt.Sprintf("reorg detected at block %d: parent hash %s does not match stored hash %s", e.Height, e.ParentHash, e.StoredBlockHash)
+}
+
+// ReorgAlert is raised when a reorg would remove transactions that are already part of
+// a pod. The indexer refuses to roll back and waits for an operator.
+type ReorgAlert struct {
+	ForkHeight     int
+	CommonAncestor int
+	FirstOrphanTxn int
+	PodBoundaryTxn int
+}
+
+func (a *ReorgAlert) Error() string {
+	return fmt.Sprintf("reorg at block %d rolls back to block %d, which would remove transaction %d already included in a pod (pod boundary: transaction %d)", a.ForkHeight, a.CommonAncestor, a.FirstOrphanTxn, a.PodBoundaryTxn)
+}
+
+// readEVMBlock returns the block stored at height, or false if it is missing.
+func readEVMBlock(ldb *leveldb.DB, height int) (types.BlockStruct, bool) {
+	var block types.BlockStruct
+	data, err := ldb.Get([]byte(blockKey(height)), nil)
+	if err != nil {
+		return block, false
+	}
+	if err := json.Unmarshal(data, &block); err != nil {
+		return block, false
+	}
+	return block, true
+}
+
+// checkEVMParent compares the parent hash of the block at height with the stored block
+// at height-1. Missing predecessors are not treated as a reorg.
+func checkEVMParent(ldb *leveldb.DB, height int, parentHash string) error {
+	if height == 0 {
+		return nil
+	}
+	previous, ok := readEVMBlock(ldb, height-1)
+	if !ok || previous.Hash == parentHash {
+		return nil
+	}
+	return &ErrReorgDetected{Height: height, ParentHash: parentHash, StoredBlockHash: previous.Hash}
+}
+
+// findEVMCommonAncestor walks back from height until the stored block hash matches the
+// station again. It returns -1 when no stored block is on the canonical chain.
+func findEVMCommonAncestor(ctx context.Context, client EVMClient, ldb *leveldb.DB, height int) (int, error) {
+	for ; height >= 0; height-- {
+		stored, ok := readEVMBlock(ldb, height)
+		if !ok {
+			return height, nil
+		}
+		canonical, err := client.BlockByNumber(ctx, big.NewInt(int64(height)))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get block %d while searching common ancestor: %w", height, err)
+		}
+		if canonical.Hash().String() == stored.Hash {
+			return height, nil
+		}
+	}
+	return -1, nil
+}
+
+// podBoundaryTxn returns the highest transaction sequence that is part of a pod, either
+// verified (batchStartIndex) or currently being processed by the tracks.
+func podBoundaryTxn(staticDB, stateDB *leveldb.DB) int {
+	boundary := readCounter(staticDB, "batchStartIndex")
+	if stateDB == nil {
+		return boundary
+	}
+	podStateBytes, err := stateDB.Get([]byte("podState"), nil)
+	if err != nil {
+		return boundary
+	}
+	var podState types.PodState
+	if err := json.Unmarshal(podStateBytes, &podState); err != nil {
+		return boundary
+	}
+	if podState.LatestTxState != "" && podState.LatestTxState != "PreInit" {
+		inFlight := config.PODSize * int(podState.LatestPodHeight)
+		if podState.Batch != nil && podState.Batch.LastTxnSeq > 0 {
+			inFlight = podState.Batch.LastTxnSeq
+		}
+		if inFlight > boundary {
+			boundary = inFlight
+		}
+	}
+	return boundary
+}
+
+// firstEVMTxnFromBlock returns the sequence of the first transaction stored for fromBlock
+// or later. Blocks stored without a commit marker are found by scanning the transaction
+// database backwards.
+func firstEVMTxnFromBlock(ldb, ldt *leveldb.DB, fromBlock int) (int, error) {
+	if commit, ok := readBlockCommit(ldb, fromBlock); ok {
+		return commit.FirstTxn, nil
+	}
+	seq := readCounter(ldt, "txnCount")
+	for ; seq > 0; seq-- {
+		data, err := ldt.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read txns-%d: %w", seq, err)
+		}
+		var tx types.TransactionStruct
+		if err := json.Unmarshal(data, &tx); err != nil {
+			return 0, fmt.Errorf("failed to decode txns-%d: %w", seq, err)
+		}
+		if tx.BlockNumber < uint64(fromBlock) {
+			break
+		}
+	}
+	return seq + 1, nil
+}
+
+// rollbackEVMReorg removes every block above the common ancestor of the reorg together
+// with its transactions and returns the next height to index.
+func rollbackEVMReorg(ctx context.Context, client EVMClient, opts IndexerOptions, reorg *ErrReorgDetected) (int, error) {
+	ancestor, err := findEVMCommonAncestor(ctx, client, opts.BlockDB, reorg.Height-1)
+	if err != nil {
+		return 0, err
+	}
+	fromBlock := ancestor + 1
+
+	firstOrphanTxn, err := firstEVMTxnFromBlock(opts.BlockDB, opts.TxnDB, fromBlock)
+	if err != nil {
+		return 0, err
+	}
+	txnCount := readCounter(opts.TxnDB, "txnCount")
+	boundary := podBoundaryTxn(opts.StaticDB, opts.StateDB)
+	if firstOrphanTxn <= txnCount && firstOrphanTxn <= boundary {
+		return 0, &ReorgAlert{
+			ForkHeight:     reorg.Height,
+			CommonAncestor: ancestor,
+			FirstOrphanTxn: firstOrphanTxn,
+			PodBoundaryTxn: boundary,
+		}
+	}
+
+	// Blocks go first: if the transaction batch is lost, repairIndexedState trims the
+	// transactions that no longer belong to a committed block.
+	blockBatch := new(leveldb.Batch)
+	for height := fromBlock; height < reorg.Height; height++ {
+		blockBatch.Delete([]byte(blockKey(height)))
+		blockBatch.Delete(blockCommitKey(height))
+	}
+	clampFinalizedBlock(opts.BlockDB, blockBatch, fromBlock)
+	blockBatch.Put([]byte("blockCount"), []byte(strconv.Itoa(fromBlock)))
+	if err := opts.BlockDB.Write(blockBatch, syncWrite); err != nil {
+		return 0, fmt.Errorf("failed to remove orphaned blocks: %w", err)
+	}
+
+	if err := truncateTxns(opts.TxnDB, firstOrphanTxn-1); err != nil {
+		return 0, fmt.Errorf("failed to remove orphaned transactions: %w", err)
+	}
+
+	return fromBlock, nil
+}
+
+// isReorgAlert reports whether err asks the indexer to halt for an operator.
+func isReorgAlert(err error) bool {
+	var alert *ReorgAlert
+	return errors.As(err, &alert)
+}
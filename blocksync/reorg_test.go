@@ -0,0 +1,87 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+)
+
+func TestEVMIndexerRollsBackReorg(t *testing.T) {
+	client := newFakeEVMClient(t, 3, 2)
+	blockDB, txnDB := newTestDBs(t)
+	indexer := newEVMIndexerWithClient(client, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.Start(ctx)
+	waitFor(t, 5*time.Second, func() bool { return indexer.LatestIndexed() == 2 })
+
+	// Blocks 1 and 2 are replaced by a longer fork carrying a single transaction each.
+	client.reorg(t, 1, 3, 1)
+	waitFor(t, 10*time.Second, func() bool { return indexer.LatestIndexed() == 3 })
+	indexer.Stop()
+
+	for height := 1; height <= 3; height++ {
+		stored, ok := readEVMBlock(blockDB, height)
+		if !ok {
+			t.Fatalf("Block%d missing after reorg", height)
+		}
+		if want := client.blocks[height].Hash().String(); stored.Hash != want {
+			t.Errorf("Block%d hash = %s, want %s", height, stored.Hash, want)
+		}
+	}
+
+	if got := readCounter(txnDB, "txnCount"); got != 5 {
+		t.Fatalf("txnCount = %d, want 5", got)
+	}
+	for seq := 3; seq <= 5; seq++ {
+		raw, err := txnDB.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+		if err != nil {
+			t.Fatalf("txns-%d missing: %v", seq, err)
+		}
+		var tx types.TransactionStruct
+		if err := json.Unmarshal(raw, &tx); err != nil {
+			t.Fatal(err)
+		}
+		if want := client.blocks[seq-2].Hash().String(); tx.BlockHash != want {
+			t.Errorf("txns-%d block hash = %s, want %s", seq, tx.BlockHash, want)
+		}
+	}
+	if _, err := txnDB.Get([]byte("txns-6"), nil); err == nil {
+		t.Error("orphaned txns-6 still present after reorg")
+	}
+}
+
+func TestEVMIndexerRefusesReorgBelowPod(t *testing.T) {
+	client := newFakeEVMClient(t, 3, 2)
+	blockDB, txnDB := newTestDBs(t)
+	staticDB := newMemDB(t)
+	// Transactions 1-4 (blocks 0 and 1) are already part of a verified pod.
+	if err := staticDB.Put([]byte("batchStartIndex"), []byte("4"), nil); err != nil {
+		t.Fatal(err)
+	}
+	indexer := newEVMIndexerWithClient(client, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB, StaticDB: staticDB})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.Start(ctx)
+	waitFor(t, 5*time.Second, func() bool { return indexer.LatestIndexed() == 2 })
+
+	client.reorg(t, 1, 3, 1)
+	waitFor(t, 10*time.Second, func() bool {
+		return strings.Contains(indexer.Status().LastError, "already included in a pod")
+	})
+	indexer.Stop()
+
+	if got := readCounter(txnDB, "txnCount"); got != 6 {
+		t.Errorf("txnCount = %d, want 6", got)
+	}
+	if got := indexer.LatestIndexed(); got != 2 {
+		t.Errorf("LatestIndexed() = %d, want 2", got)
+	}
+}
@@ -21,6 +21,9 @@ var publicWitnessDbInstance *leveldb.DB
 var daDbInstance *leveldb.DB
 var mockDbInstance *leveldb.DB
 
+// DatabaseNames are the directories under ~/.tracks/data/leveldb opened by InitDb.
+var DatabaseNames = []string{"tx", "blocks", "static", "state", "batches", "proof", "publicWitness", "da", "mock"}
+
 // InitTxDb This function initializes a LevelDB database for transactions and returns a boolean indicating
 // whether the initialization was successful.
 func InitTxDb() bool {
@@ -1,59 +1,81 @@
 package blocksync
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
 	"github.com/airchains-network/decentralized-sequencer/types/svmTypes"
 	"github.com/deadlium/deadlogs"
-	"io"
-	"net/http"
 	"sync"
 )
 
-var SVMChainRPCUrl string
+// svmRPC sends the JSON-RPC calls below to the station.
+type svmRPC struct {
+	station *stationclient.Client
+	// commitment is the level slots and blocks are requested at. Empty uses the RPC
+	// default, finalized.
+	commitment string
+}
 
-func initSVMRPC(JsonRPC string) {
-	SVMChainRPCUrl = JsonRPC
+func newSVMRPC(station *stationclient.Client, commitment string) svmRPC {
+	return svmRPC{station: station, commitment: commitment}
 }
 
-func svmRPCCall(method string, value any) ([]byte, error) {
-	payload := SVMPayLoad(method, value)
+// JSON-RPC error codes returned by getBlock for a slot that did not produce a block.
+const (
+	svmErrSlotSkipped                = -32007
+	svmErrLongTermStorageSlotSkipped = -32009
+)
 
-	jsonPayload, jsonPayloadErr := json.Marshal(payload)
-	if jsonPayloadErr != nil {
-		return nil, fmt.Errorf("error marshaling JSON: %v", jsonPayloadErr)
-	}
+// SVMRPCError is a JSON-RPC error returned by the station.
+type SVMRPCError struct {
+	Method  string
+	Code    int
+	Message string
+}
 
-	client := &http.Client{}
+func (e *SVMRPCError) Error() string {
+	return fmt.Sprintf("%s failed with code %d: %s", e.Method, e.Code, e.Message)
+}
+
+// SlotSkipped reports whether the error means the requested slot has no block.
+func (e *SVMRPCError) SlotSkipped() bool {
+	return e.Code == svmErrSlotSkipped || e.Code == svmErrLongTermStorageSlotSkipped
+}
 
-	req, reqErr := http.NewRequest("POST", SVMChainRPCUrl, bytes.NewBuffer(jsonPayload))
-	if reqErr != nil {
-		return nil, fmt.Errorf("error creating request: %v", reqErr)
+// svmRPCResponseError returns the JSON-RPC error carried by res, if any.
+func svmRPCResponseError(method string, res []byte) error {
+	var errorResponse svmTypes.ErrorResponse
+	if err := json.Unmarshal(res, &errorResponse); err != nil || errorResponse.Error.Code == 0 {
+		return nil
 	}
-	req.Header.Set("Content-Type", "application/json")
+	return &SVMRPCError{Method: method, Code: errorResponse.Error.Code, Message: errorResponse.Error.Message}
+}
+
+// commitmentConfig is the configuration object carrying the commitment.
+func (c svmRPC) commitmentConfig() map[string]string {
+	return map[string]string{"commitment": c.commitment}
+}
 
-	resp, respErr := client.Do(req)
-	if respErr != nil {
-		return nil, fmt.Errorf("error sending request: %v", respErr)
+func (c svmRPC) call(method string, value any) ([]byte, error) {
+	payload := c.payLoad(method, value)
+
+	jsonPayload, jsonPayloadErr := json.Marshal(payload)
+	if jsonPayloadErr != nil {
+		return nil, fmt.Errorf("error marshaling JSON: %v", jsonPayloadErr)
 	}
-	defer func(Body io.ReadCloser) {
-		bodyErr := Body.Close()
-		if bodyErr != nil {
-			deadlogs.Warn(fmt.Sprintf("error while closing body: %v", bodyErr))
-		}
-	}(resp.Body)
 
-	body, bodyErr := io.ReadAll(resp.Body)
-	if bodyErr != nil {
-		return nil, fmt.Errorf("error reading response: %v", bodyErr)
+	body, err := c.station.Post(context.Background(), "", "application/json", jsonPayload)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
 	}
 
 	return body, nil
 }
 
-func SVMLatestBlockCheck() (int, error) {
-	res, resErr := svmRPCCall("getSlot", nil)
+func (c svmRPC) SVMLatestBlockCheck() (int, error) {
+	res, resErr := c.call("getSlot", nil)
 	if resErr != nil {
 		return 0, fmt.Errorf("error rpc call: %v", resErr)
 	}
@@ -67,25 +89,54 @@ func SVMLatestBlockCheck() (int, error) {
 	return latestBlock.Result, nil
 }
 
-func SVMBlockCall(height int) (*svmTypes.BlockResponseStruct, error) {
+func (c svmRPC) SVMBlockCall(height int) (*svmTypes.BlockResponseStruct, error) {
 
-	res, resErr := svmRPCCall("getBlock", height)
+	res, resErr := c.call("getBlock", height)
 	if resErr != nil {
 		return nil, fmt.Errorf("error rpc call: %v", resErr)
 	}
+	if err := svmRPCResponseError("getBlock", res); err != nil {
+		return nil, err
+	}
 
 	var blockData svmTypes.BlockResponseStruct
 	blockDataErr := json.Unmarshal(res, &blockData)
 	if blockDataErr != nil {
 		return nil, fmt.Errorf("error decoding response: %v", blockDataErr)
 	}
+	if blockData.Result.Blockhash == "" {
+		return nil, fmt.Errorf("no block returned for slot %d", height)
+	}
 
 	return &blockData, nil
 }
 
-func SVMBlockLeaderCall(height int) (*svmTypes.SlotLeaderResponseStruct, error) {
+// SVMBlocksCall returns the slots between start and end, inclusive, that produced a
+// block. Slots missing from the list were skipped.
+func (c svmRPC) SVMBlocksCall(start, end int) ([]int, error) {
+	res, resErr := c.call("getBlocks", [2]int{start, end})
+	if resErr != nil {
+		return nil, fmt.Errorf("error rpc call: %v", resErr)
+	}
+	if err := svmRPCResponseError("getBlocks", res); err != nil {
+		return nil, err
+	}
+
+	var blocks struct {
+		Result *[]int `json:"result"`
+	}
+	if err := json.Unmarshal(res, &blocks); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+	if blocks.Result == nil {
+		return nil, fmt.Errorf("no blocks returned for slots %d to %d", start, end)
+	}
+	return *blocks.Result, nil
+}
+
+func (c svmRPC) SVMBlockLeaderCall(height int) (*svmTypes.SlotLeaderResponseStruct, error) {
 
-	res, resErr := svmRPCCall("getSlotLeaders", height)
+	res, resErr := c.call("getSlotLeaders", height)
 	if resErr != nil {
 		return nil, fmt.Errorf("error rpc call: %v", resErr)
 	}
@@ -99,7 +150,7 @@ func SVMBlockLeaderCall(height int) (*svmTypes.SlotLeaderResponseStruct, error)
 	return &leaderData, nil
 }
 
-func SVMAccountListCall() ([]svmTypes.AccountDetailsResponseStruct, error) {
+func (c svmRPC) SVMAccountListCall() ([]svmTypes.AccountDetailsResponseStruct, error) {
 
 	var leaderCircleData svmTypes.LargeAccountStruct
 	var leaderNonCircleData svmTypes.LargeAccountStruct
@@ -109,7 +160,7 @@ func SVMAccountListCall() ([]svmTypes.AccountDetailsResponseStruct, error) {
 
 	go func() {
 		defer wg.Done()
-		res, resErr := svmRPCCall("getLargestAccounts", "circulating")
+		res, resErr := c.call("getLargestAccounts", "circulating")
 		if resErr != nil {
 			deadlogs.Warn(fmt.Sprintf("error rpc call: %v", resErr))
 		}
@@ -122,7 +173,7 @@ func SVMAccountListCall() ([]svmTypes.AccountDetailsResponseStruct, error) {
 
 	go func() {
 		defer wg.Done()
-		res, resErr := svmRPCCall("getLargestAccounts", "nonCirculating")
+		res, resErr := c.call("getLargestAccounts", "nonCirculating")
 		if resErr != nil {
 			deadlogs.Warn(fmt.Sprintf("error rpc call: %v", resErr))
 		}
@@ -144,7 +195,7 @@ func SVMAccountListCall() ([]svmTypes.AccountDetailsResponseStruct, error) {
 		accountArray = append(accountArray, account.Address)
 	}
 
-	details, detailsErr := SVMAccountDetailsCall(accountArray)
+	details, detailsErr := c.SVMAccountDetailsCall(accountArray)
 	if detailsErr != nil {
 		return nil, fmt.Errorf("error fetching account details: %v", detailsErr)
 	}
@@ -161,9 +212,9 @@ func SVMAccountListCall() ([]svmTypes.AccountDetailsResponseStruct, error) {
 	return accountDetails, nil
 }
 
-func SVMAccountDetailsCall(address []string) (*svmTypes.AccountDetailsStruct, error) {
+func (c svmRPC) SVMAccountDetailsCall(address []string) (*svmTypes.AccountDetailsStruct, error) {
 
-	res, resErr := svmRPCCall("getMultipleAccounts", address)
+	res, resErr := c.call("getMultipleAccounts", address)
 	if resErr != nil {
 		return nil, fmt.Errorf("error rpc call: %v", resErr)
 	}
@@ -177,13 +228,17 @@ func SVMAccountDetailsCall(address []string) (*svmTypes.AccountDetailsStruct, er
 	return &leaderData, nil
 }
 
-func SVMPayLoad(method string, value any) svmTypes.PayloadStruct {
+func (c svmRPC) payLoad(method string, value any) svmTypes.PayloadStruct {
 	if method == "getSlot" {
+		params := make([]interface{}, 0)
+		if c.commitment != "" {
+			params = append(params, c.commitmentConfig())
+		}
 		return svmTypes.PayloadStruct{
 			JsonRPC: "2.0",
 			ID:      1,
 			Method:  method,
-			Params:  make([]interface{}, 0),
+			Params:  params,
 		}
 	}
 
@@ -199,6 +254,20 @@ func SVMPayLoad(method string, value any) svmTypes.PayloadStruct {
 		}
 	}
 
+	if method == "getBlocks" {
+		slots := value.([2]int)
+		params := []interface{}{slots[0], slots[1]}
+		if c.commitment != "" {
+			params = append(params, c.commitmentConfig())
+		}
+		return svmTypes.PayloadStruct{
+			JsonRPC: "2.0",
+			ID:      1,
+			Method:  method,
+			Params:  params,
+		}
+	}
+
 	if method == "getLargestAccounts" {
 		return svmTypes.PayloadStruct{
 			JsonRPC: "2.0",
@@ -225,6 +294,7 @@ func SVMPayLoad(method string, value any) svmTypes.PayloadStruct {
 				MaxSupportedTransactionVersion: 0,
 				TransactionDetails:             "full",
 				Rewards:                        true,
+				Commitment:                     c.commitment,
 			},
 		},
 	}
@@ -0,0 +1,114 @@
+package blocksync
+
+import (
+	"github.com/airchains-network/decentralized-sequencer/types/svmTypes"
+)
+
+// Programs the SVM pod builder looks at.
+const (
+	svmSystemProgram        = "11111111111111111111111111111111"
+	svmComputeBudgetProgram = "ComputeBudget111111111111111111111111111111"
+)
+
+// svmLoaderPrograms deploy programs.
+var svmLoaderPrograms = map[string]bool{
+	"BPFLoader2111111111111111111111111111111111": true,
+	"BPFLoaderUpgradeab1e11111111111111111111111": true,
+	"LoaderV411111111111111111111111111111111111": true,
+}
+
+// SVMPodEntry is a single system program transfer of a solana transaction, as it goes
+// into a pod. The balances are the lamports of the accounts right before and after the
+// transfer, replayed from the balances in the meta of the transaction after its fee.
+type SVMPodEntry struct {
+	// Instruction is the index of the instruction the transfer comes from.
+	Instruction     int
+	Type            string
+	From            string
+	To              string
+	Lamports        uint64
+	FromPreBalance  uint64
+	FromPostBalance uint64
+	ToPreBalance    uint64
+	ToPostBalance   uint64
+}
+
+// SVMPodEntries expands a solana transaction into one entry per system program transfer
+// among its top-level instructions. The other instructions are returned as skipped,
+// apart from compute budget instructions, which only set the fee.
+func SVMPodEntries(txn svmTypes.SVMTransactionStruct) (entries []SVMPodEntry, skipped []MessageExclusion) {
+	// The fee payer is the first account, and pays the fee before the instructions run.
+	balances := append([]uint64(nil), txn.Meta.PreBalances...)
+	if len(balances) > 0 && txn.Meta.Fee > 0 {
+		balances[0] -= min(balances[0], uint64(txn.Meta.Fee))
+	}
+	for i, instruction := range txn.Transaction.Message.Instructions {
+		if instruction.ProgramID == svmComputeBudgetProgram {
+			continue
+		}
+		entry, reason := svmInstructionEntry(txn, i, balances)
+		if reason != "" {
+			skipped = append(skipped, MessageExclusion{Index: i, Type: svmInstructionType(txn, i), Reason: reason})
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, skipped
+}
+
+// svmInstructionEntry returns the entry of instruction i, or why it has none, and applies
+// its transfer to balances.
+func svmInstructionEntry(txn svmTypes.SVMTransactionStruct, i int, balances []uint64) (SVMPodEntry, string) {
+	instruction := txn.Transaction.Message.Instructions[i]
+	parsed := instruction.Parsed
+	if instruction.ProgramID != svmSystemProgram || (parsed.Type != "transfer" && parsed.Type != "transferWithSeed") {
+		return SVMPodEntry{}, "unsupported instruction"
+	}
+	from, fromOK := svmAccountIndex(txn, parsed.Info.Source)
+	to, toOK := svmAccountIndex(txn, parsed.Info.Destination)
+	if !fromOK || !toOK {
+		return SVMPodEntry{}, "missing balances"
+	}
+	entry := SVMPodEntry{
+		Instruction:    i,
+		Type:           parsed.Type,
+		From:           parsed.Info.Source,
+		To:             parsed.Info.Destination,
+		Lamports:       parsed.Info.Lamports,
+		FromPreBalance: balances[from],
+	}
+	// Only a failed transaction can carry such a transfer, and the prover rejects it.
+	if entry.Lamports > entry.FromPreBalance {
+		return SVMPodEntry{}, "insufficient lamports"
+	}
+	balances[from] -= entry.Lamports
+	entry.FromPostBalance = balances[from]
+	entry.ToPreBalance = balances[to]
+	balances[to] += entry.Lamports
+	entry.ToPostBalance = balances[to]
+	return entry, ""
+}
+
+// svmAccountIndex returns the index of account in the transaction, provided its meta
+// holds the balances of that index.
+func svmAccountIndex(txn svmTypes.SVMTransactionStruct, account string) (int, bool) {
+	if account == "" {
+		return 0, false
+	}
+	for i, key := range txn.Transaction.Message.AccountKeys {
+		if key.Pubkey == account {
+			return i, i < len(txn.Meta.PreBalances) && i < len(txn.Meta.PostBalances)
+		}
+	}
+	return 0, false
+}
+
+// svmInstructionType names instruction i as <program>.<type> when the station parsed
+// it, and by its program id otherwise.
+func svmInstructionType(txn svmTypes.SVMTransactionStruct, i int) string {
+	instruction := txn.Transaction.Message.Instructions[i]
+	if instruction.Program != "" && instruction.Parsed.Type != "" {
+		return instruction.Program + "." + instruction.Parsed.Type
+	}
+	return instruction.ProgramID
+}
@@ -0,0 +1,143 @@
+package blocksync
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/airchains-network/decentralized-sequencer/types/svmTypes"
+)
+
+// testSVMTransaction is a jsonParsed solana transaction of payer moving lamports to
+// receiver, next to a compute budget and a token program instruction.
+const testSVMTransaction = `{
+	"meta": {"err": null, "fee": 5000, "preBalances": [1000000, 10, 1, 1, 1], "postBalances": [595000, 400010, 1, 1, 1]},
+	"transaction": {
+		"message": {
+			"accountKeys": [
+				{"pubkey": "payer111111111111111111111111111111111111111", "signer": true, "writable": true},
+				{"pubkey": "receiver1111111111111111111111111111111111111", "signer": false, "writable": true},
+				{"pubkey": "11111111111111111111111111111111"},
+				{"pubkey": "ComputeBudget111111111111111111111111111111"},
+				{"pubkey": "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"}
+			],
+			"instructions": [
+				{"parsed": {"info": {"microLamports": 1}, "type": "setComputeUnitPrice"}, "program": "compute-budget", "programId": "ComputeBudget111111111111111111111111111111"},
+				{"parsed": {"info": {"destination": "receiver1111111111111111111111111111111111111", "lamports": 400000, "source": "payer111111111111111111111111111111111111111"}, "type": "transfer"}, "program": "system", "programId": "11111111111111111111111111111111"},
+				{"parsed": {"info": {}, "type": "closeAccount"}, "program": "spl-token", "programId": "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"}
+			]
+		},
+		"signatures": ["5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnb"]
+	}
+}`
+
+func decodeTestSVMTransaction(t *testing.T, raw string) svmTypes.SVMTransactionStruct {
+	t.Helper()
+	var txn svmTypes.SVMTransactionStruct
+	if err := json.Unmarshal([]byte(raw), &txn); err != nil {
+		t.Fatal(err)
+	}
+	// Transactions are read back from their stored form.
+	stored, err := json.Marshal(txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn = svmTypes.SVMTransactionStruct{}
+	if err := json.Unmarshal(stored, &txn); err != nil {
+		t.Fatal(err)
+	}
+	return txn
+}
+
+func TestSVMPodCandidate(t *testing.T) {
+	txn := decodeTestSVMTransaction(t, testSVMTransaction)
+	c, entries := SVMPodCandidate(4, txn)
+
+	want := []SVMPodEntry{{
+		Instruction:     1,
+		Type:            "transfer",
+		From:            "payer111111111111111111111111111111111111111",
+		To:              "receiver1111111111111111111111111111111111111",
+		Lamports:        400000,
+		FromPreBalance:  995000,
+		FromPostBalance: 595000,
+		ToPreBalance:    10,
+		ToPostBalance:   400010,
+	}}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+	if c.Hash != "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnb" || c.Failed || c.Unsupported != "" {
+		t.Errorf("candidate = %+v", c)
+	}
+	if c.Contract != "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA" {
+		t.Errorf("contract = %q, want the token program", c.Contract)
+	}
+	if len(c.Messages) != 1 || c.Messages[0].Index != 2 || c.Messages[0].Type != "spl-token.closeAccount" {
+		t.Errorf("skipped instructions = %+v, want the token instruction only", c.Messages)
+	}
+	if reason := (InclusionPolicy{ValueTransfersOnly: true}).Exclude(c); reason != ExcludedContractCall {
+		t.Errorf("value transfers only policy reason = %q, want %q", reason, ExcludedContractCall)
+	}
+}
+
+func TestSVMPodEntriesReplayTransfers(t *testing.T) {
+	txn := decodeTestSVMTransaction(t, testSVMTransaction)
+	instructions := txn.Transaction.Message.Instructions
+	txn.Transaction.Message.Instructions = append(instructions[:2:2], instructions[1])
+	entries, _ := SVMPodEntries(txn)
+	if len(entries) != 2 {
+		t.Fatalf("entries = %+v, want both transfers", entries)
+	}
+	// The second transfer starts from the balances the first one left.
+	second := entries[1]
+	if second.FromPreBalance != 595000 || second.FromPostBalance != 195000 || second.ToPreBalance != 400010 || second.ToPostBalance != 800010 {
+		t.Errorf("second transfer = %+v", second)
+	}
+}
+
+func TestSVMPodCandidateWithoutTransfers(t *testing.T) {
+	tests := []struct {
+		name        string
+		edit        func(txn *svmTypes.SVMTransactionStruct)
+		unsupported string
+		failed      bool
+	}{
+		{
+			name: "failed transfer of more than the balance",
+			edit: func(txn *svmTypes.SVMTransactionStruct) {
+				txn.Meta.Err = map[string]interface{}{"InstructionError": []interface{}{1, "Custom"}}
+				txn.Transaction.Message.Instructions[1].Parsed.Info.Lamports = 2000000
+			},
+			unsupported: "no system transfers",
+			failed:      true,
+		},
+		{
+			name: "transfer without meta balances",
+			edit: func(txn *svmTypes.SVMTransactionStruct) {
+				txn.Meta.PreBalances = nil
+			},
+			unsupported: "no system transfers",
+		},
+		{
+			name: "no instructions",
+			edit: func(txn *svmTypes.SVMTransactionStruct) {
+				txn.Transaction.Message.Instructions = nil
+			},
+			unsupported: "no instructions",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn := decodeTestSVMTransaction(t, testSVMTransaction)
+			tt.edit(&txn)
+			c, entries := SVMPodCandidate(1, txn)
+			if len(entries) != 0 || c.Unsupported != tt.unsupported || c.Failed != tt.failed {
+				t.Errorf("candidate = %+v, entries = %+v", c, entries)
+			}
+			if reason := (InclusionPolicy{}).Exclude(c); reason != ExcludedUnsupported {
+				t.Errorf("reason = %q, want %q", reason, ExcludedUnsupported)
+			}
+		})
+	}
+}
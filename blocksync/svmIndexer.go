@@ -0,0 +1,54 @@
+package blocksync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+)
+
+func init() {
+	RegisterIndexer(StationTypeSVM, newSVMIndexer)
+}
+
+type svmIndexer struct {
+	indexerState
+	opts    IndexerOptions
+	station *stationclient.Client
+	// subscribeHeads is nil when no websocket endpoint is configured.
+	subscribeHeads headSubscriber
+}
+
+func newSVMIndexer(opts IndexerOptions) (StationIndexer, error) {
+	station, err := stationclient.Shared(opts.StationRPC, opts.StationClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid svm station rpc: %w", err)
+	}
+	indexer := &svmIndexer{
+		indexerState: newIndexerState(StationTypeSVM, opts.BlockDB),
+		opts:         opts,
+		station:      station,
+	}
+	if opts.StationWS != "" {
+		indexer.subscribeHeads = svmHeadSubscriber(opts.StationWS)
+	}
+	return indexer, nil
+}
+
+func (s *svmIndexer) Start(ctx context.Context) error {
+	ctx, err := s.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.end()
+
+	heads := startHeadNotifier(ctx, StationTypeSVM, s.subscribeHeads, svmPollInterval)
+	// Slots are fetched at the configured commitment, so only confirmations apply.
+	newFinalityTracker(s.opts.Finality, s.opts.BlockDB, s.opts.TxnDB, nil).start(ctx)
+
+	if err := StoreSVMBlock(ctx, heads, s.opts.BlockDB, s.opts.TxnDB, s.station, s.opts.Finality.Commitment); err != nil {
+		s.setError(err)
+		return err
+	}
+	return nil
+}
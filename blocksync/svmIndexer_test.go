@@ -0,0 +1,203 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/types/svmTypes"
+)
+
+// stubSVMChain serves the Solana JSON-RPC methods used by the svm indexer. Every slot
+// that is not skipped holds one transaction signed by sig<slot>.
+type stubSVMChain struct {
+	mu      sync.Mutex
+	slot    int
+	skipped map[int]bool
+	// lateSkipped slots are listed by getBlocks, but getBlock reports them as skipped.
+	lateSkipped map[int]bool
+	// unavailable is the number of times getBlock fails for a slot before it is served.
+	unavailable map[int]int
+	// blockCalls counts getBlock requests per slot.
+	blockCalls map[int]int
+}
+
+func (c *stubSVMChain) setSlot(slot int, skipped, lateSkipped map[int]bool, unavailable map[int]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slot = slot
+	for s := range skipped {
+		c.skipped[s] = true
+	}
+	c.lateSkipped = lateSkipped
+	c.unavailable = unavailable
+}
+
+func (c *stubSVMChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var request struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reply := func(result interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}
+	fail := func(code int, message string) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "error": map[string]interface{}{"code": code, "message": message}})
+	}
+
+	switch request.Method {
+	case "getSlot":
+		reply(c.slot)
+	case "getBlocks":
+		var start, end int
+		json.Unmarshal(request.Params[0], &start)
+		json.Unmarshal(request.Params[1], &end)
+		slots := []int{}
+		for slot := start; slot <= end && slot <= c.slot; slot++ {
+			if !c.skipped[slot] {
+				slots = append(slots, slot)
+			}
+		}
+		reply(slots)
+	case "getBlock":
+		var slot int
+		json.Unmarshal(request.Params[0], &slot)
+		c.blockCalls[slot]++
+		switch {
+		case slot > c.slot:
+			fail(-32004, fmt.Sprintf("Block not available for slot %d", slot))
+		case c.skipped[slot] || c.lateSkipped[slot]:
+			fail(svmErrSlotSkipped, fmt.Sprintf("Slot %d was skipped, or missing due to ledger jump to recent snapshot", slot))
+		case c.unavailable[slot] > 0:
+			c.unavailable[slot]--
+			fail(-32004, fmt.Sprintf("Block not available for slot %d", slot))
+		default:
+			reply(map[string]interface{}{
+				"blockHeight":       slot,
+				"blockhash":         fmt.Sprintf("hash%d", slot),
+				"parentSlot":        slot - 1,
+				"previousBlockhash": fmt.Sprintf("hash%d", slot-1),
+				"transactions": []interface{}{map[string]interface{}{
+					"transaction": map[string]interface{}{
+						"signatures": []string{fmt.Sprintf("sig%d", slot)},
+						"message": map[string]interface{}{
+							"accountKeys": []interface{}{map[string]interface{}{"pubkey": "Payer111", "signer": true, "writable": true}},
+						},
+					},
+				}},
+			})
+		}
+	default:
+		fail(-32601, "Method not found")
+	}
+}
+
+func TestSVMIndexerWalksEverySlot(t *testing.T) {
+	chain := &stubSVMChain{slot: 5, skipped: map[int]bool{3: true}, blockCalls: make(map[int]int)}
+	server := httptest.NewServer(chain)
+	defer server.Close()
+
+	blockDB, txnDB := newTestDBs(t)
+	indexer := &svmIndexer{
+		indexerState: newIndexerState(StationTypeSVM, blockDB),
+		opts:         IndexerOptions{StationRPC: server.URL, BlockDB: blockDB, TxnDB: txnDB},
+		station:      newTestStation(t, server.URL),
+	}
+	newHeads := make(chan struct{})
+	indexer.subscribeHeads = func(ctx context.Context, notify func()) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-newHeads:
+				notify()
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.Start(ctx)
+	waitFor(t, 5*time.Second, func() bool { return indexer.LatestIndexed() == 5 })
+
+	// Slot 10 is listed by getBlocks but not served right away, slot 11 turns out to be
+	// skipped only when its block is requested.
+	chain.setSlot(12, map[int]bool{8: true, 9: true}, map[int]bool{11: true}, map[int]int{10: 1})
+	newHeads <- struct{}{}
+	waitFor(t, 10*time.Second, func() bool { return indexer.LatestIndexed() == 12 })
+
+	skipped := map[int]bool{3: true, 8: true, 9: true, 11: true}
+	seq := 0
+	for slot := 1; slot <= 12; slot++ {
+		if skipped[slot] {
+			if !IsSVMSlotSkipped(blockDB, slot) {
+				t.Errorf("slot %d is not recorded as skipped", slot)
+			}
+			if ok, _ := blockDB.Has([]byte(fmt.Sprintf("Block%d", slot)), nil); ok {
+				t.Errorf("skipped slot %d has a block", slot)
+			}
+			continue
+		}
+		if IsSVMSlotSkipped(blockDB, slot) {
+			t.Errorf("slot %d is recorded as skipped", slot)
+		}
+		seq++
+		data, err := txnDB.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+		if err != nil {
+			t.Fatalf("txns-%d: %v", seq, err)
+		}
+		var txn svmTypes.SVMTransactionStruct
+		if err := json.Unmarshal(data, &txn); err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("sig%d", slot); len(txn.Transaction.Signatures) == 0 || txn.Transaction.Signatures[0] != want {
+			t.Errorf("txns-%d signatures = %v, want %s", seq, txn.Transaction.Signatures, want)
+		}
+	}
+	if got := readCounter(txnDB, "txnCount"); got != seq {
+		t.Errorf("txnCount = %d, want %d", got, seq)
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	for _, slot := range []int{3, 8, 9} {
+		if chain.blockCalls[slot] != 0 {
+			t.Errorf("getBlock called %d times for slot %d that getBlocks reported as skipped", chain.blockCalls[slot], slot)
+		}
+	}
+	for slot := 1; slot <= 12; slot++ {
+		if (!skipped[slot] || slot == 11) && slot != 10 && chain.blockCalls[slot] != 1 {
+			t.Errorf("getBlock called %d times for slot %d, want 1", chain.blockCalls[slot], slot)
+		}
+	}
+}
+
+func TestSVMRPCRequestsItsCommitment(t *testing.T) {
+	confirmed, finalized := newSVMRPC(nil, "confirmed"), newSVMRPC(nil, "")
+
+	if got := confirmed.payLoad("getSlot", nil).Params; len(got) != 1 || !reflect.DeepEqual(got[0], map[string]string{"commitment": "confirmed"}) {
+		t.Errorf("getSlot params = %v, want the confirmed commitment", got)
+	}
+	if got := finalized.payLoad("getSlot", nil).Params; len(got) != 0 {
+		t.Errorf("getSlot params = %v without a commitment, want none", got)
+	}
+	if got := confirmed.payLoad("getBlock", 5).Params[1].(svmTypes.Params).Commitment; got != "confirmed" {
+		t.Errorf("getBlock commitment = %q, want confirmed", got)
+	}
+	if got := finalized.payLoad("getBlock", 5).Params[1].(svmTypes.Params).Commitment; got != "" {
+		t.Errorf("getBlock commitment = %q, want the RPC default", got)
+	}
+}
@@ -0,0 +1,160 @@
+package blocksync
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/airchains-network/decentralized-sequencer/types/svmTypes"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+func txnHashKey(hash string) []byte {
+	return []byte("txhash-" + normalizeTxnIndexValue(hash))
+}
+
+func txnAddressPrefix(address string) string {
+	return "txaddr-" + normalizeTxnIndexValue(address) + "-"
+}
+
+func txnPodKey(seq int) []byte {
+	return []byte(fmt.Sprintf("txpod-%d", seq))
+}
+
+// normalizeTxnIndexValue lowercases hex hashes and addresses so lookups are case
+// insensitive. Other encodings, such as base58 on SVM, are case sensitive and kept as is.
+func normalizeTxnIndexValue(value string) string {
+	value = strings.TrimSpace(value)
+	hexValue := strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+	if hexValue == "" {
+		return value
+	}
+	for _, c := range hexValue {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return value
+		}
+	}
+	return strings.ToLower(value)
+}
+
+// txnIndexes returns the hash and address index entries of transaction seq.
+func txnIndexes(seq int, hash string, addresses ...string) map[string][]byte {
+	indexes := make(map[string][]byte)
+	if hash != "" {
+		indexes[string(txnHashKey(hash))] = []byte(strconv.Itoa(seq))
+	}
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+		indexes[txnAddressPrefix(address)+fmt.Sprintf("%012d", seq)] = nil
+	}
+	return indexes
+}
+
+// evmTxnIndexes indexes an EVM transaction by hash, sender, recipient and created
+// contract, together with the logs in its receipt.
+func evmTxnIndexes(tx types.TransactionStruct, seq int) map[string][]byte {
+	addresses := []string{tx.From}
+	if tx.Receipt != nil && tx.Receipt.ContractAddress != "" {
+		addresses = append(addresses, tx.Receipt.ContractAddress)
+	} else {
+		addresses = append(addresses, tx.To)
+	}
+	indexes := txnIndexes(seq, tx.Hash, addresses...)
+	for key, value := range evmLogIndexes(tx, seq) {
+		indexes[key] = value
+	}
+	return indexes
+}
+
+// wasmTxnIndexes indexes a cosmos transaction by hash and by the sender and recipient
+// fields of its messages.
+func wasmTxnIndexes(raw []byte, seq int) map[string][]byte {
+	var txn Transaction
+	if err := json.Unmarshal(raw, &txn); err != nil {
+		return nil
+	}
+	var addresses []string
+	for _, msg := range txn.TxResponse.Tx.Body.Messages {
+		fields, ok := msg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, field := range []string{"from_address", "to_address", "sender"} {
+			if address, ok := fields[field].(string); ok {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	return txnIndexes(seq, txn.TxResponse.TxHash, addresses...)
+}
+
+// svmTxnIndexes indexes a solana transaction by its first signature and by the accounts
+// that signed it or were written to.
+func svmTxnIndexes(txn svmTypes.SVMTransactionStruct, seq int) map[string][]byte {
+	var hash string
+	if len(txn.Transaction.Signatures) > 0 {
+		hash = txn.Transaction.Signatures[0]
+	}
+	var addresses []string
+	for _, account := range txn.Transaction.Message.AccountKeys {
+		if account.Signer || account.Writable {
+			addresses = append(addresses, account.Pubkey)
+		}
+	}
+	return txnIndexes(seq, hash, addresses...)
+}
+
+// GetTxnSeqByHash returns the sequence number of the transaction stored under
+// txns-<seq> for hash, or leveldb.ErrNotFound.
+func GetTxnSeqByHash(ldt *leveldb.DB, hash string) (int, error) {
+	value, err := ldt.Get(txnHashKey(hash), nil)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(value))
+}
+
+// GetTxnSeqsByAddress returns up to limit sequence numbers of transactions touching
+// address, oldest first. A limit of 0 returns all of them.
+func GetTxnSeqsByAddress(ldt *leveldb.DB, address string, limit int) ([]int, error) {
+	prefix := txnAddressPrefix(address)
+	iter := ldt.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	var seqs []int
+	for iter.Next() && (limit == 0 || len(seqs) < limit) {
+		seq, err := strconv.Atoi(strings.TrimPrefix(string(iter.Key()), prefix))
+		if err != nil {
+			return nil, fmt.Errorf("malformed address index key %q: %w", iter.Key(), err)
+		}
+		seqs = append(seqs, seq)
+	}
+	return seqs, iter.Error()
+}
+
+// IndexPodTxns records that transactions firstSeq to lastSeq are part of podNumber, and
+// that lastSeq is its last transaction.
+func IndexPodTxns(ldt *leveldb.DB, podNumber, firstSeq, lastSeq int) error {
+	batch := new(leveldb.Batch)
+	pod := []byte(strconv.Itoa(podNumber))
+	for seq := firstSeq; seq <= lastSeq; seq++ {
+		batch.Put(txnPodKey(seq), pod)
+	}
+	batch.Put(podLastTxnKey(podNumber), []byte(strconv.Itoa(lastSeq)))
+	return ldt.Write(batch, syncWrite)
+}
+
+// GetTxnPod returns the verified pod containing transaction seq, or leveldb.ErrNotFound
+// if it is not part of a verified pod yet.
+func GetTxnPod(ldt *leveldb.DB, seq int) (int, error) {
+	value, err := ldt.Get(txnPodKey(seq), nil)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(value))
+}
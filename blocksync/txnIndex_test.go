@@ -0,0 +1,107 @@
+package blocksync
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/types/svmTypes"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestEVMTxnIndexes(t *testing.T) {
+	client := newFakeEVMClient(t, 3, 2)
+	blockDB, txnDB := newTestDBs(t)
+	indexer := newEVMIndexerWithClient(client, IndexerOptions{BlockDB: blockDB, TxnDB: txnDB})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.Start(ctx)
+	waitFor(t, 5*time.Second, func() bool { return indexer.LatestIndexed() == 2 })
+	indexer.Stop()
+
+	hash := client.blocks[1].Transactions()[1].Hash().Hex()
+	for _, lookup := range []string{hash, "0x" + strings.ToUpper(hash[2:])} {
+		seq, err := GetTxnSeqByHash(txnDB, lookup)
+		if err != nil || seq != 4 {
+			t.Errorf("GetTxnSeqByHash(%s) = %d, %v, want 4", lookup, seq, err)
+		}
+	}
+
+	sender := crypto.PubkeyToAddress(client.key.PublicKey).Hex()
+	seqs, err := GetTxnSeqsByAddress(txnDB, sender, 0)
+	if err != nil || len(seqs) != 6 || seqs[0] != 1 || seqs[5] != 6 {
+		t.Errorf("GetTxnSeqsByAddress(sender) = %v, %v, want 1..6", seqs, err)
+	}
+	seqs, err = GetTxnSeqsByAddress(txnDB, "0x00000000000000000000000000000000000000AA", 2)
+	if err != nil || len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 2 {
+		t.Errorf("GetTxnSeqsByAddress(recipient, 2) = %v, %v, want [1 2]", seqs, err)
+	}
+
+	if err := IndexPodTxns(txnDB, 1, 1, 4); err != nil {
+		t.Fatal(err)
+	}
+	if pod, err := GetTxnPod(txnDB, 3); err != nil || pod != 1 {
+		t.Errorf("GetTxnPod(3) = %d, %v, want 1", pod, err)
+	}
+	if _, err := GetTxnPod(txnDB, 5); !errors.Is(err, leveldb.ErrNotFound) {
+		t.Errorf("GetTxnPod(5) error = %v, want not found", err)
+	}
+
+	if err := truncateTxns(txnDB, 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetTxnSeqByHash(txnDB, hash); !errors.Is(err, leveldb.ErrNotFound) {
+		t.Errorf("GetTxnSeqByHash() after truncate error = %v, want not found", err)
+	}
+	if seqs, _ := GetTxnSeqsByAddress(txnDB, sender, 0); len(seqs) != 3 {
+		t.Errorf("GetTxnSeqsByAddress() after truncate = %v, want 3 entries", seqs)
+	}
+	if _, err := GetTxnPod(txnDB, 4); !errors.Is(err, leveldb.ErrNotFound) {
+		t.Errorf("GetTxnPod(4) after truncate error = %v, want not found", err)
+	}
+}
+
+func TestStationTxnIndexes(t *testing.T) {
+	wasmTx := []byte(`{"tx_response":{"txhash":"ABCDEF01","tx":{"body":{"messages":[` +
+		`{"@type":"/cosmos.bank.v1beta1.MsgSend","from_address":"wasm1sender","to_address":"wasm1receiver"}]}}}}`)
+	wasm := wasmTxnIndexes(wasmTx, 7)
+	if string(wasm["txhash-abcdef01"]) != "7" {
+		t.Errorf("wasm hash index = %v, want lowercased hash pointing to 7", wasm)
+	}
+	for _, key := range []string{"txaddr-wasm1sender-000000000007", "txaddr-wasm1receiver-000000000007"} {
+		if _, ok := wasm[key]; !ok {
+			t.Errorf("wasm indexes missing %s: %v", key, wasm)
+		}
+	}
+
+	var svmTx svmTypes.SVMTransactionStruct
+	svmTx.Transaction.Signatures = []string{"5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnb"}
+	svmTx.Transaction.Message.AccountKeys = append(svmTx.Transaction.Message.AccountKeys,
+		struct {
+			Pubkey   string `json:"pubkey"`
+			Signer   bool   `json:"signer"`
+			Source   string `json:"source"`
+			Writable bool   `json:"writable"`
+		}{Pubkey: "Vote111111111111111111111111111111111111111", Signer: true},
+		struct {
+			Pubkey   string `json:"pubkey"`
+			Signer   bool   `json:"signer"`
+			Source   string `json:"source"`
+			Writable bool   `json:"writable"`
+		}{Pubkey: "SysvarC1ock11111111111111111111111111111111"},
+	)
+	svm := svmTxnIndexes(svmTx, 9)
+	if string(svm["txhash-5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnb"]) != "9" {
+		t.Errorf("svm hash index = %v, want case preserved signature pointing to 9", svm)
+	}
+	if _, ok := svm["txaddr-Vote111111111111111111111111111111111111111-000000000009"]; !ok {
+		t.Errorf("svm indexes missing signer account: %v", svm)
+	}
+	if len(svm) != 2 {
+		t.Errorf("svm indexes = %v, want hash and signer only", svm)
+	}
+}
@@ -1,148 +1,40 @@
 package blocksync
 
 import (
-	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"os"
-	"strconv"
-	"strings"
-	"time"
 
-	logs "github.com/airchains-network/decentralized-sequencer/log"
 	stationTypes "github.com/airchains-network/decentralized-sequencer/types"
-	"github.com/airchains-network/decentralized-sequencer/types/svmTypes"
 	utilis "github.com/airchains-network/decentralized-sequencer/utils"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
-func insertTxnEVM(db *leveldb.DB, txns stationTypes.TransactionStruct, transactionNumber int) error {
-	data, err := json.Marshal(txns)
+// evmTransactionStruct converts a transaction of the EVM block of header into the format
+// stored under txns-<n>.
+func evmTransactionStruct(signer types.Signer, tx *types.Transaction, receipt *types.Receipt, header *types.Header) (stationTypes.TransactionStruct, error) {
+	blockNumber, blockHash := int(header.Number.Int64()), header.Hash().String()
+	from, err := types.Sender(signer, tx)
 	if err != nil {
-		return err
-	}
-
-	txnsKey := fmt.Sprintf("txns-%d", transactionNumber+1)
-	err = db.Put([]byte(txnsKey), data, nil)
-	if err != nil {
-		return err
-	}
-
-	err = db.Put([]byte("txnCount"), []byte(strconv.Itoa(transactionNumber+1)), nil)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func insertTxnWASM(db *leveldb.DB, txns []byte, transactionNumber int) error {
-
-	txnsKey := fmt.Sprintf("txns-%d", transactionNumber+1)
-	err := db.Put([]byte(txnsKey), txns, nil)
-	if err != nil {
-		return err
-	}
-
-	err = db.Put([]byte("txnCount"), []byte(strconv.Itoa(transactionNumber+1)), nil)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func insertTxnSVM(db *leveldb.DB, txn svmTypes.SVMTransactionStruct, transactionNumber int) error {
-	data, err := json.Marshal(txn)
-	if err != nil {
-		return err
-	}
-
-	txnsKey := fmt.Sprintf("txns-%d", transactionNumber+1)
-	if err := db.Put([]byte(txnsKey), data, nil); err != nil {
-		return err
-	}
-
-	err = db.Put([]byte("txnCount"), []byte(strconv.Itoa(transactionNumber+1)), nil)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func StoreEVMTransactions(client *ethclient.Client, ctx context.Context, ldt *leveldb.DB, transactionHash string, blockNumber int, blockHash string) {
-	blockNumberUint64, err := strconv.ParseUint(strconv.Itoa(blockNumber), 10, 64)
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("error parsing block number to uint64:", err))
-		time.Sleep(2 * time.Second)
-		logs.Log.Info("Retrying in 2s...")
-		StoreEVMTransactions(client, ctx, ldt, transactionHash, blockNumber, blockHash)
-	}
-	var (
-		tx        *types.Transaction
-		isPending bool
-	)
-
-	txHash := common.HexToHash(transactionHash)
-	for {
-		tx, isPending, err = client.TransactionByHash(ctx, txHash)
-		if err != nil {
-			logs.Log.Debug(fmt.Sprintf("Failed to get transaction by hash: %s", err))
-			// Retry transaction
-			log.Println("Retrying the transaction after 10 seconds...")
-			time.Sleep(time.Second * 10) // Wait for 10 seconds before retrying
-			continue
-		}
-		if isPending {
-			logs.Log.Debug("Transaction is pending, waiting for 5 seconds for tx Approval in blockchain")
-			logs.Log.Info(fmt.Sprintf("Transaction type: %d\n", tx.Type()))
-			// Retry after 5 seconds if transaction is pending
-			time.Sleep(time.Second * 5)
-			continue
-		}
-		break
-	}
-
-	chainID, err := client.NetworkID(context.Background())
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to get the network ID: %v", err))
-		os.Exit(0)
-	}
-	msg, err := types.Sender(types.NewLondonSigner(chainID), tx)
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to derive the sender address: %v", err))
-		os.Exit(0)
-	}
-
-	receipt, err := client.TransactionReceipt(context.Background(), txHash)
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to fetch the transaction receipt: %v", err))
-		os.Exit(0)
+		return stationTypes.TransactionStruct{}, fmt.Errorf("failed to derive the sender address of %s: %w", tx.Hash().Hex(), err)
 	}
 
 	v, r, s := tx.RawSignatureValues()
 
 	var toAddress string
 	if tx.To() == nil {
-		toAddress = "0x0000000000000000000000000000000000000000"
+		toAddress = evmCreationTo
 	} else {
 		toAddress = tx.To().Hex()
 	}
 
-	var txData = stationTypes.TransactionStruct{
+	txData := stationTypes.TransactionStruct{
 		BlockHash:        blockHash,
-		BlockNumber:      blockNumberUint64,
-		From:             msg.Hex(),
+		BlockNumber:      uint64(blockNumber),
+		From:             from.Hex(),
 		Gas:              utilis.ToString(tx.Gas()),
 		GasPrice:         tx.GasPrice().String(),
 		Hash:             tx.Hash().Hex(),
@@ -155,84 +47,148 @@ func StoreEVMTransactions(client *ethclient.Client, ctx context.Context, ldt *le
 		Type:             fmt.Sprintf("%d", tx.Type()),
 		V:                v.String(),
 		Value:            tx.Value().String(),
+		Receipt:          evmReceiptStruct(tx.Hash(), tx.To(), receipt, blockNumber, blockHash),
+		Coinbase:         header.Coinbase.Hex(),
 	}
-
-	// get transaction number from database
-	transactionNumberBytes, err := ldt.Get([]byte("txnCount"), nil)
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to get transaction number: %s" + err.Error()))
-		os.Exit(0)
+	if header.BaseFee != nil {
+		txData.BaseFeePerGas = header.BaseFee.String()
 	}
-
-	transactionNumber, err := strconv.Atoi(strings.TrimSpace(string(transactionNumberBytes)))
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Invalid transaction number : %s" + err.Error()))
-		os.Exit(0)
+	if tx.Protected() {
+		txData.ChainID = tx.ChainId().String()
 	}
-
-	insetTxnErr := insertTxnEVM(ldt, txData, transactionNumber)
-	if insetTxnErr != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to insert transaction: %s" + insetTxnErr.Error()))
-		os.Exit(0)
+	if tx.Type() >= types.AccessListTxType {
+		txData.AccessList = evmAccessListStruct(tx.AccessList())
 	}
-
-}
-
-func StoreWasmTransaction(txn []interface{}, db *leveldb.DB, JsonAPI string) {
-	for _, tx := range txn {
-		hash, err := ComputeTransactionHash(tx.(string))
-		if err != nil {
-			log.Println("Error computing transaction hash:", err)
-			continue
-		}
-		rpcUrl := fmt.Sprintf("%s/cosmos/tx/v1beta1/txs/%s", JsonAPI, hash)
-		respo, err := http.Get(rpcUrl)
-		if err != nil {
-			log.Println("HTTP request failed for transaction hash:", err)
-			continue
+	if tx.Type() >= types.DynamicFeeTxType {
+		txData.MaxFeePerGas = tx.GasFeeCap().String()
+		txData.MaxPriorityFeePerGas = tx.GasTipCap().String()
+	}
+	if tx.Type() == types.BlobTxType {
+		txData.MaxFeePerBlobGas = tx.BlobGasFeeCap().String()
+		txData.BlobVersionedHashes = make([]string, len(tx.BlobHashes()))
+		for i, hash := range tx.BlobHashes() {
+			txData.BlobVersionedHashes[i] = hash.Hex()
 		}
-		if respo != nil {
-			bodyTxnHash, err := io.ReadAll(respo.Body)
-			err = respo.Body.Close()
-			if err != nil {
-				return
-			}
+	}
+	return txData, nil
+}
 
-			var txns Transaction
-			err = json.Unmarshal(bodyTxnHash, &txns)
-			if err != nil {
-				logs.Log.Error(fmt.Sprintf("Failed to unmarshal transaction: %s", err))
-				os.Exit(0)
-			}
+// setCodeTxType is the type of set code transactions (EIP-7702).
+const setCodeTxType = 4
+
+// evmSetCodeTx is a set code transaction as returned by eth_getBlockByNumber. go-ethereum
+// v1.13 cannot decode it, so its fields are read here, without the authorization list.
+type evmSetCodeTx struct {
+	Hash                 common.Hash      `json:"hash"`
+	From                 *common.Address  `json:"from"`
+	To                   common.Address   `json:"to"`
+	ChainID              *hexutil.Big     `json:"chainId"`
+	Nonce                hexutil.Uint64   `json:"nonce"`
+	Gas                  hexutil.Uint64   `json:"gas"`
+	MaxFeePerGas         *hexutil.Big     `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big     `json:"maxPriorityFeePerGas"`
+	Value                *hexutil.Big     `json:"value"`
+	Input                hexutil.Bytes    `json:"input"`
+	AccessList           types.AccessList `json:"accessList"`
+	V                    *hexutil.Big     `json:"v"`
+	R                    *hexutil.Big     `json:"r"`
+	S                    *hexutil.Big     `json:"s"`
+}
 
-			if len(txns.TxResponse.Tx.Body.Messages) == 0 {
-				logs.Log.Warn(fmt.Sprintf("Failed to unmarshal transaction: %s", err))
-				time.Sleep(2 * time.Second)
-				continue
-			}
+// evmSetCodeTransactionStruct converts a set code transaction of the EVM block of header
+// into the format stored under txns-<n>. The sender is the one reported by the station.
+func evmSetCodeTransactionStruct(tx *evmSetCodeTx, receipt *types.Receipt, header *types.Header) (stationTypes.TransactionStruct, error) {
+	if tx.From == nil || tx.ChainID == nil || tx.MaxFeePerGas == nil || tx.MaxPriorityFeePerGas == nil || tx.Value == nil || tx.V == nil || tx.R == nil || tx.S == nil {
+		return stationTypes.TransactionStruct{}, fmt.Errorf("set code transaction %s is missing fields", tx.Hash.Hex())
+	}
+	blockNumber, blockHash := int(header.Number.Int64()), header.Hash().String()
+	to := tx.To
+	txData := stationTypes.TransactionStruct{
+		BlockHash:            blockHash,
+		BlockNumber:          uint64(blockNumber),
+		From:                 tx.From.Hex(),
+		Gas:                  utilis.ToString(uint64(tx.Gas)),
+		GasPrice:             tx.MaxFeePerGas.ToInt().String(),
+		Hash:                 tx.Hash.Hex(),
+		Input:                string(tx.Input),
+		Nonce:                utilis.ToString(uint64(tx.Nonce)),
+		R:                    tx.R.ToInt().String(),
+		S:                    tx.S.ToInt().String(),
+		To:                   to.Hex(),
+		TransactionIndex:     utilis.ToString(receipt.TransactionIndex),
+		Type:                 evmSetCodeTxType,
+		V:                    tx.V.ToInt().String(),
+		Value:                tx.Value.ToInt().String(),
+		ChainID:              tx.ChainID.ToInt().String(),
+		AccessList:           evmAccessListStruct(tx.AccessList),
+		MaxFeePerGas:         tx.MaxFeePerGas.ToInt().String(),
+		MaxPriorityFeePerGas: tx.MaxPriorityFeePerGas.ToInt().String(),
+		Receipt:              evmReceiptStruct(tx.Hash, &to, receipt, blockNumber, blockHash),
+		Coinbase:             header.Coinbase.Hex(),
+	}
+	if header.BaseFee != nil {
+		txData.BaseFeePerGas = header.BaseFee.String()
+	}
+	return txData, nil
+}
 
-			// get transaction number from database
-			transactionNumberBytes, err := db.Get([]byte("txnCount"), nil)
-			if err != nil {
-				logs.Log.Error(fmt.Sprintf("Failed to get transaction number: %s" + err.Error()))
-				os.Exit(0)
-			}
+// evmAccessListStruct converts the access list of a transaction of type 1 or later.
+func evmAccessListStruct(accessList types.AccessList) []stationTypes.AccessTupleStruct {
+	tuples := make([]stationTypes.AccessTupleStruct, len(accessList))
+	for i, tuple := range accessList {
+		storageKeys := make([]string, len(tuple.StorageKeys))
+		for j, key := range tuple.StorageKeys {
+			storageKeys[j] = key.Hex()
+		}
+		tuples[i] = stationTypes.AccessTupleStruct{Address: tuple.Address.Hex(), StorageKeys: storageKeys}
+	}
+	return tuples
+}
 
-			transactionNumber, err := strconv.Atoi(strings.TrimSpace(string(transactionNumberBytes)))
-			if err != nil {
-				logs.Log.Error(fmt.Sprintf("Invalid transaction number : %s" + err.Error()))
-				os.Exit(0)
-			}
-			insetTxnErr := insertTxnWASM(db, bodyTxnHash, transactionNumber)
-			if insetTxnErr != nil {
-				logs.Log.Error(fmt.Sprintf("Failed to insert transaction: %s" + insetTxnErr.Error()))
-				os.Exit(0)
-			}
+// evmReceiptStruct keeps the execution result of the transaction with txHash sent to to,
+// including its logs.
+func evmReceiptStruct(txHash common.Hash, to *common.Address, receipt *types.Receipt, blockNumber int, blockHash string) *stationTypes.ReceiptStruct {
+	var contractAddress string
+	if to == nil {
+		contractAddress = receipt.ContractAddress.Hex()
+	}
+	var effectiveGasPrice string
+	if receipt.EffectiveGasPrice != nil {
+		effectiveGasPrice = receipt.EffectiveGasPrice.String()
+	}
 
-		} else {
-			log.Println("Received nil response for transaction hash:", hash)
+	receiptLogs := make([]stationTypes.LogStruct, len(receipt.Logs))
+	for i, receiptLog := range receipt.Logs {
+		topics := make([]string, len(receiptLog.Topics))
+		for j, topic := range receiptLog.Topics {
+			topics[j] = topic.Hex()
 		}
+		receiptLogs[i] = stationTypes.LogStruct{
+			Address:          receiptLog.Address.Hex(),
+			Topics:           topics,
+			Data:             hexutil.Encode(receiptLog.Data),
+			BlockNumber:      uint64(blockNumber),
+			BlockHash:        blockHash,
+			TransactionHash:  txHash.Hex(),
+			TransactionIndex: receipt.TransactionIndex,
+			LogIndex:         receiptLog.Index,
+		}
+	}
+
+	stored := &stationTypes.ReceiptStruct{
+		Status:            receipt.Status,
+		CumulativeGasUsed: utilis.ToString(receipt.CumulativeGasUsed),
+		GasUsed:           utilis.ToString(receipt.GasUsed),
+		EffectiveGasPrice: effectiveGasPrice,
+		ContractAddress:   contractAddress,
+		LogsBloom:         hexutil.Encode(receipt.Bloom.Bytes()),
+		Logs:              receiptLogs,
+	}
+	if receipt.BlobGasUsed > 0 && receipt.BlobGasPrice != nil {
+		stored.BlobGasUsed = utilis.ToString(receipt.BlobGasUsed)
+		stored.BlobGasPrice = receipt.BlobGasPrice.String()
 	}
+	return stored
 }
 
 func ComputeTransactionHash(base64Tx string) (string, error) {
@@ -244,23 +200,3 @@ func ComputeTransactionHash(base64Tx string) (string, error) {
 	txHash := hex.EncodeToString(hash[:])
 	return txHash, nil
 }
-
-func StoreSVMTransaction(db *leveldb.DB, txn svmTypes.SVMTransactionStruct) {
-	// get transaction number from database
-	transactionNumberBytes, err := db.Get([]byte("txnCount"), nil)
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Failed to get transaction number: %s" + err.Error()))
-		os.Exit(0)
-	}
-
-	transactionNumber, err := strconv.Atoi(strings.TrimSpace(string(transactionNumberBytes)))
-	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Invalid transaction number : %s" + err.Error()))
-		os.Exit(0)
-	}
-
-	if insertErr := insertTxnSVM(db, txn, transactionNumber); insertErr != nil {
-		logs.Log.Error(fmt.Sprintf("Invalid transaction number : %s" + err.Error()))
-		os.Exit(0)
-	}
-}
@@ -0,0 +1,125 @@
+// The simulated backend links github.com/fjl/memsize, which the linker of Go 1.23 and
+// later rejects, so this test only builds with the toolchain of go.mod.
+
+//go:build !go1.23
+
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	stationTypes "github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// simulatedEVMClient indexes a simulated go-ethereum chain.
+type simulatedEVMClient struct {
+	*backends.SimulatedBackend
+}
+
+func (c simulatedEVMClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	return c.ChainID(ctx)
+}
+
+// RawBlockByNumber is not served: the simulated chain only mines transactions
+// go-ethereum decodes.
+func (c simulatedEVMClient) RawBlockByNumber(_ context.Context, _ *big.Int) (json.RawMessage, error) {
+	return nil, errors.New("raw blocks are not served by the simulated chain")
+}
+
+// TestStoreEVMTransactionTypes mines one transaction of every type the simulated chain
+// accepts and checks the sender and the type-specific fields of the stored transactions.
+// The simulated chain of go-ethereum v1.13 does not activate Cancun, so blob and set code
+// transactions are covered by TestStoreEVMTransactionsOfNewerTypes.
+func TestStoreEVMTransactionTypes(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	sim := backends.NewSimulatedBackend(types.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}}, 30_000_000)
+	defer sim.Close()
+	chainID, err := sim.ChainID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	tip := big.NewInt(params.GWei)
+	feeCap := big.NewInt(2 * params.GWei)
+	accessList := types.AccessList{{Address: recipient, StorageKeys: []common.Hash{common.HexToHash("0x01")}}}
+
+	tests := []struct {
+		name  string
+		tx    types.TxData
+		check func(t *testing.T, stored stationTypes.TransactionStruct)
+	}{
+		{
+			name: "legacy",
+			tx:   &types.LegacyTx{Nonce: 0, GasPrice: feeCap, Gas: 21000, To: &recipient, Value: big.NewInt(1)},
+			check: func(t *testing.T, stored stationTypes.TransactionStruct) {
+				if stored.AccessList != nil || stored.MaxFeePerGas != "" {
+					t.Errorf("legacy transaction stored with type 1/2 fields: %+v", stored)
+				}
+			},
+		},
+		{
+			name: "access list",
+			tx:   &types.AccessListTx{ChainID: chainID, Nonce: 1, GasPrice: feeCap, Gas: 30000, To: &recipient, Value: big.NewInt(1), AccessList: accessList},
+			check: func(t *testing.T, stored stationTypes.TransactionStruct) {
+				if len(stored.AccessList) != 1 || stored.AccessList[0].Address != recipient.Hex() || len(stored.AccessList[0].StorageKeys) != 1 {
+					t.Errorf("access list = %+v", stored.AccessList)
+				}
+			},
+		},
+		{
+			name: "dynamic fee",
+			tx:   &types.DynamicFeeTx{ChainID: chainID, Nonce: 2, GasTipCap: tip, GasFeeCap: feeCap, Gas: 21000, To: &recipient, Value: big.NewInt(1)},
+			check: func(t *testing.T, stored stationTypes.TransactionStruct) {
+				if stored.MaxFeePerGas != feeCap.String() || stored.MaxPriorityFeePerGas != tip.String() {
+					t.Errorf("fees = %s, %s, want %s, %s", stored.MaxFeePerGas, stored.MaxPriorityFeePerGas, feeCap, tip)
+				}
+			},
+		},
+	}
+
+	signer := types.LatestSignerForChainID(chainID)
+	for _, tt := range tests {
+		tx, err := types.SignNewTx(key, signer, tt.tx)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := sim.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		sim.Commit()
+	}
+
+	blockDB, txnDB := newTestDBs(t)
+	for height := 0; height <= len(tests); height++ {
+		if err := StoreEVMBlock(ctx, simulatedEVMClient{sim}, height, blockDB, txnDB); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := readStoredEVMTxn(t, txnDB, i+1)
+			if stored.From != sender.Hex() || stored.Type != fmt.Sprint(i) || stored.ChainID != chainID.String() {
+				t.Errorf("stored from %s, type %s, chain %s", stored.From, stored.Type, stored.ChainID)
+			}
+			if stored.Receipt == nil || stored.Receipt.Status != types.ReceiptStatusSuccessful {
+				t.Errorf("receipt = %+v", stored.Receipt)
+			}
+			tt.check(t, stored)
+		})
+	}
+}
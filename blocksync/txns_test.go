@@ -0,0 +1,243 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	stationTypes "github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func readStoredEVMTxn(t *testing.T, txnDB *leveldb.DB, seq int) stationTypes.TransactionStruct {
+	t.Helper()
+	raw, err := txnDB.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored stationTypes.TransactionStruct
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+// jsonEVMStation serves station blocks and receipts as JSON-RPC objects, including
+// transactions go-ethereum v1.13 cannot mine or decode.
+type jsonEVMStation struct {
+	blocks   []json.RawMessage
+	receipts map[common.Hash]json.RawMessage
+}
+
+type jsonEVMEthAPI struct {
+	station *jsonEVMStation
+}
+
+func (api jsonEVMEthAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) (json.RawMessage, error) {
+	if int(number) < 0 || int(number) >= len(api.station.blocks) {
+		return nil, nil
+	}
+	return api.station.blocks[number], nil
+}
+
+func (api jsonEVMEthAPI) GetTransactionReceipt(hash common.Hash) (json.RawMessage, error) {
+	return api.station.receipts[hash], nil
+}
+
+// addBlock appends a block with header and the transactions txs to the station.
+func (s *jsonEVMStation) addBlock(t *testing.T, header *types.Header, txs ...json.RawMessage) {
+	t.Helper()
+	block := make(map[string]interface{})
+	data, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &block); err != nil {
+		t.Fatal(err)
+	}
+	block["transactions"] = append([]json.RawMessage{}, txs...)
+	block["uncles"] = []common.Hash{}
+	if data, err = json.Marshal(block); err != nil {
+		t.Fatal(err)
+	}
+	s.blocks = append(s.blocks, data)
+}
+
+func (s *jsonEVMStation) addReceipt(t *testing.T, receipt *types.Receipt) {
+	t.Helper()
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.receipts[receipt.TxHash] = data
+}
+
+// TestStoreEVMTransactionsOfNewerTypes stores a blob transaction and a set code
+// transaction, which go-ethereum v1.13 cannot decode, from a block served as JSON.
+func TestStoreEVMTransactionsOfNewerTypes(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	recipient := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	// The chain ID answered by stubNetAPI.
+	chainID := big.NewInt(42)
+	tip := big.NewInt(params.GWei)
+	feeCap := big.NewInt(2 * params.GWei)
+	blobFeeCap := big.NewInt(3 * params.GWei)
+	blobHash := common.HexToHash("0x01a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8")
+
+	blobTx, err := types.SignNewTx(key, types.LatestSignerForChainID(chainID), &types.BlobTx{
+		ChainID:    uint256.MustFromBig(chainID),
+		Nonce:      0,
+		GasTipCap:  uint256.MustFromBig(tip),
+		GasFeeCap:  uint256.MustFromBig(feeCap),
+		Gas:        21000,
+		To:         recipient,
+		Value:      uint256.NewInt(1),
+		BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+		BlobHashes: []common.Hash{blobHash},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobJSON, err := blobTx.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCodeHash := common.HexToHash("0x7702")
+	setCodeJSON, err := json.Marshal(map[string]interface{}{
+		"type":                 "0x4",
+		"hash":                 setCodeHash,
+		"from":                 sender,
+		"to":                   recipient,
+		"chainId":              (*hexutil.Big)(chainID),
+		"nonce":                "0x1",
+		"gas":                  "0x186a0",
+		"gasPrice":             (*hexutil.Big)(feeCap),
+		"maxFeePerGas":         (*hexutil.Big)(feeCap),
+		"maxPriorityFeePerGas": (*hexutil.Big)(tip),
+		"value":                "0x1",
+		"input":                "0x",
+		"accessList":           []interface{}{},
+		"authorizationList": []map[string]interface{}{
+			{"chainId": "0x2a", "address": recipient, "nonce": "0x0", "yParity": "0x0", "r": "0x1", "s": "0x1"},
+		},
+		"v":       "0x0",
+		"yParity": "0x0",
+		"r":       "0x1",
+		"s":       "0x2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	station := &jsonEVMStation{receipts: make(map[common.Hash]json.RawMessage)}
+	genesis := &types.Header{
+		Number:      big.NewInt(0),
+		Difficulty:  big.NewInt(0),
+		GasLimit:    30_000_000,
+		BaseFee:     big.NewInt(params.GWei),
+		UncleHash:   types.EmptyUncleHash,
+		TxHash:      types.EmptyTxsHash,
+		ReceiptHash: types.EmptyReceiptsHash,
+	}
+	station.addBlock(t, genesis)
+	header := &types.Header{
+		ParentHash:  genesis.Hash(),
+		Number:      big.NewInt(1),
+		Difficulty:  big.NewInt(0),
+		GasLimit:    30_000_000,
+		BaseFee:     big.NewInt(params.GWei),
+		Coinbase:    common.HexToAddress("0xc0ffee"),
+		UncleHash:   types.EmptyUncleHash,
+		TxHash:      common.HexToHash("0x01"),
+		ReceiptHash: common.HexToHash("0x02"),
+		Time:        1,
+	}
+	station.addBlock(t, header, blobJSON, setCodeJSON)
+	for i, hash := range []common.Hash{blobTx.Hash(), setCodeHash} {
+		station.addReceipt(t, &types.Receipt{
+			Type:              []uint8{types.BlobTxType, setCodeTxType}[i],
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(21000 * (i + 1)),
+			GasUsed:           21000,
+			Logs:              []*types.Log{},
+			TxHash:            hash,
+			BlockHash:         header.Hash(),
+			BlockNumber:       header.Number,
+			TransactionIndex:  uint(i),
+			EffectiveGasPrice: feeCap,
+			BlobGasUsed:       []uint64{params.BlobTxBlobGasPerBlob, 0}[i],
+			BlobGasPrice:      big.NewInt(1),
+		})
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("net", stubNetAPI{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("eth", jsonEVMEthAPI{station: station}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	client, err := newFailoverEVMClient(ctx, newTestStation(t, httpServer.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blockDB, txnDB := newTestDBs(t)
+	for height := 0; height <= 1; height++ {
+		if err := StoreEVMBlock(ctx, client, height, blockDB, txnDB); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	blob := readStoredEVMTxn(t, txnDB, 1)
+	if blob.Type != "3" || blob.From != sender.Hex() || blob.MaxFeePerBlobGas != blobFeeCap.String() || len(blob.BlobVersionedHashes) != 1 || blob.BlobVersionedHashes[0] != blobHash.Hex() {
+		t.Errorf("blob transaction stored as %+v", blob)
+	}
+	if blob.Receipt == nil || blob.Receipt.BlobGasUsed != fmt.Sprint(params.BlobTxBlobGasPerBlob) {
+		t.Errorf("blob receipt = %+v", blob.Receipt)
+	}
+
+	setCode := readStoredEVMTxn(t, txnDB, 2)
+	if setCode.Type != evmSetCodeTxType || setCode.Hash != setCodeHash.Hex() || setCode.From != sender.Hex() || setCode.To != recipient.Hex() || setCode.ChainID != chainID.String() || setCode.Nonce != "1" {
+		t.Errorf("set code transaction stored as %+v", setCode)
+	}
+	if setCode.MaxFeePerGas != feeCap.String() || setCode.MaxPriorityFeePerGas != tip.String() || setCode.Coinbase != header.Coinbase.Hex() {
+		t.Errorf("set code fees = %s, %s, coinbase %s", setCode.MaxFeePerGas, setCode.MaxPriorityFeePerGas, setCode.Coinbase)
+	}
+	if setCode.Receipt == nil || setCode.Receipt.Status != types.ReceiptStatusSuccessful || setCode.TransactionIndex != "1" {
+		t.Errorf("set code receipt = %+v at index %s", setCode.Receipt, setCode.TransactionIndex)
+	}
+	if candidate := EVMPodCandidate(2, setCode); candidate.Unsupported == "" {
+		t.Error("the set code transaction may be included in a pod")
+	}
+
+	data, err := blockDB.Get([]byte(blockKey(1)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block stationTypes.BlockStruct
+	if err := json.Unmarshal(data, &block); err != nil {
+		t.Fatal(err)
+	}
+	if block.TransactionCount != 2 || block.Hash != header.Hash().String() {
+		t.Errorf("block stored with %d transactions and hash %s, want 2 and %s", block.TransactionCount, block.Hash, header.Hash())
+	}
+}
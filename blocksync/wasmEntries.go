@@ -0,0 +1,118 @@
+package blocksync
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+)
+
+// WasmPodEntry is a single transfer of a cosmos transaction, as it goes into a pod.
+type WasmPodEntry struct {
+	// Message is the index of the message the transfer comes from.
+	Message int
+	From    string
+	To      string
+	Amount  string
+	// Denom is the bank denomination, or the token contract of a cw20 transfer.
+	Denom string
+	CW20  bool
+}
+
+// MessageExclusion records a message that has no pod entry.
+type MessageExclusion struct {
+	Index  int    `json:"index"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// reasonMultiSendInputs skips a multi-send the bank module rejects.
+const reasonMultiSendInputs = "multi-send without a single input"
+
+// Malformed reports whether the message was skipped because it is not a valid transfer,
+// rather than because pods do not carry it.
+func (e MessageExclusion) Malformed() bool {
+	return e.Reason == reasonMultiSendInputs
+}
+
+// WasmPodEntries expands a cosmos transaction into one entry per transferred coin of its
+// bank sends, multi-sends and cw20 transfer executions, in message order. The other
+// messages are returned as skipped.
+func WasmPodEntries(txn types.BatchTransaction) (entries []WasmPodEntry, skipped []MessageExclusion) {
+	for i, msg := range txn.Tx.Body.Messages {
+		msgEntries, reason := wasmMessageEntries(i, msg)
+		if reason != "" {
+			skipped = append(skipped, MessageExclusion{Index: i, Type: msg.Type, Reason: reason})
+			continue
+		}
+		entries = append(entries, msgEntries...)
+	}
+	return entries, skipped
+}
+
+// wasmMessageEntries returns the entries of message i, or why it has none.
+func wasmMessageEntries(i int, msg types.Message) ([]WasmPodEntry, string) {
+	var entries []WasmPodEntry
+	coinEntries := func(from, to string, coins []types.BatchAmount) {
+		for _, coin := range coins {
+			entries = append(entries, WasmPodEntry{Message: i, From: from, To: to, Amount: coin.Amount, Denom: coin.Denom})
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(msg.Type, "bank.v1beta1.MsgSend"):
+		coinEntries(msg.FromAddress, msg.ToAddress, msg.Amount)
+	case strings.HasSuffix(msg.Type, "bank.v1beta1.MsgMultiSend"):
+		// The bank module only accepts a single input since v0.46.
+		if len(msg.Inputs) != 1 {
+			return nil, reasonMultiSendInputs
+		}
+		for _, output := range msg.Outputs {
+			coinEntries(msg.Inputs[0].Address, output.Address, output.Coins)
+		}
+	case msg.Type == wasmExecuteContractURL:
+		transfer, ok := cw20Transfer(msg.Msg)
+		if !ok {
+			return nil, "contract call"
+		}
+		if len(msg.Funds) > 0 {
+			return nil, "cw20 transfer with funds"
+		}
+		entries = append(entries, WasmPodEntry{Message: i, From: msg.Sender, To: transfer.Recipient, Amount: transfer.Amount, Denom: msg.Contract, CW20: true})
+	default:
+		return nil, "unsupported message"
+	}
+	if len(entries) == 0 {
+		return nil, "no coins"
+	}
+	return entries, ""
+}
+
+type cw20TransferMsg struct {
+	Recipient string `json:"recipient"`
+	Amount    string `json:"amount"`
+}
+
+// cw20Transfer decodes a cw20 {"transfer":{"recipient":...,"amount":...}} execute
+// message, given as a JSON object or a base64 encoded string.
+func cw20Transfer(raw json.RawMessage) (cw20TransferMsg, bool) {
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err == nil {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return cw20TransferMsg{}, false
+		}
+		raw = decoded
+	}
+	var msg struct {
+		Transfer *cw20TransferMsg `json:"transfer"`
+	}
+	if err := json.Unmarshal(raw, &msg); err != nil || msg.Transfer == nil {
+		return cw20TransferMsg{}, false
+	}
+	if msg.Transfer.Recipient == "" || msg.Transfer.Amount == "" {
+		return cw20TransferMsg{}, false
+	}
+	return *msg.Transfer, true
+}
@@ -0,0 +1,96 @@
+package blocksync
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/airchains-network/decentralized-sequencer/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// testExecuteContract returns a MsgExecuteContract of sender on contract carrying msg.
+func testExecuteContract(sender, contract, msg string) *codectypes.Any {
+	var value []byte
+	value = protowire.AppendTag(value, 1, protowire.BytesType)
+	value = protowire.AppendString(value, sender)
+	value = protowire.AppendTag(value, 2, protowire.BytesType)
+	value = protowire.AppendString(value, contract)
+	value = protowire.AppendTag(value, 3, protowire.BytesType)
+	value = protowire.AppendBytes(value, []byte(msg))
+	return &codectypes.Any{TypeUrl: wasmExecuteContractURL, Value: value}
+}
+
+func TestWasmPodEntriesOfStoredTransaction(t *testing.T) {
+	from, to, token := testWasmAddress(t, 1), testWasmAddress(t, 2), testWasmAddress(t, 3)
+	encoded := encodeTestWasmTx(t, "",
+		testMsgSend(t, from, to),
+		testExecuteContract(from, token, `{"transfer":{"recipient":"`+to+`","amount":"42"}}`),
+		testExecuteContract(from, token, `{"increment":{}}`),
+	)
+	txns, err := decodeWasmTransactions([]interface{}{encoded}, []wasmTxResult{{}}, "7", "2024-03-01T10:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var txn types.BatchTransaction
+	if err := json.Unmarshal(txns[0], &txn); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, skipped := WasmPodEntries(txn)
+	want := []WasmPodEntry{
+		{Message: 0, From: from, To: to, Amount: "10", Denom: "stake"},
+		{Message: 1, From: from, To: to, Amount: "42", Denom: token, CW20: true},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("WasmPodEntries() = %+v, want %+v", entries, want)
+	}
+	if len(skipped) != 1 || skipped[0].Index != 2 || skipped[0].Reason != "contract call" {
+		t.Errorf("skipped = %+v, want the increment call", skipped)
+	}
+}
+
+func TestWasmPodEntries(t *testing.T) {
+	coins := func(amounts ...string) []types.BatchAmount {
+		var c []types.BatchAmount
+		for _, amount := range amounts {
+			c = append(c, types.BatchAmount{Denom: "stake", Amount: amount})
+		}
+		return c
+	}
+	base64Transfer, _ := json.Marshal(base64.StdEncoding.EncodeToString([]byte(`{"transfer":{"recipient":"b","amount":"3"}}`)))
+
+	tests := []struct {
+		name        string
+		msg         types.Message
+		wantAmounts []string
+		wantReason  string
+	}{
+		{name: "bank send of two coins", msg: types.Message{Type: "/cosmos.bank.v1beta1.MsgSend", FromAddress: "a", ToAddress: "b", Amount: coins("1", "2")}, wantAmounts: []string{"1", "2"}},
+		{name: "multi-send", msg: types.Message{Type: "/cosmos.bank.v1beta1.MsgMultiSend", Inputs: []types.BatchMultiSendIO{{Address: "a", Coins: coins("3")}}, Outputs: []types.BatchMultiSendIO{{Address: "b", Coins: coins("1")}, {Address: "c", Coins: coins("2")}}}, wantAmounts: []string{"1", "2"}},
+		{name: "multi-send with two inputs", msg: types.Message{Type: "/cosmos.bank.v1beta1.MsgMultiSend", Inputs: make([]types.BatchMultiSendIO, 2)}, wantReason: "multi-send without a single input"},
+		{name: "base64 cw20 transfer", msg: types.Message{Type: wasmExecuteContractURL, Sender: "a", Contract: "t", Msg: base64Transfer}, wantAmounts: []string{"3"}},
+		{name: "cw20 transfer with funds", msg: types.Message{Type: wasmExecuteContractURL, Msg: []byte(`{"transfer":{"recipient":"b","amount":"3"}}`), Funds: coins("1")}, wantReason: "cw20 transfer with funds"},
+		{name: "bank send without coins", msg: types.Message{Type: "/cosmos.bank.v1beta1.MsgSend"}, wantReason: "no coins"},
+		{name: "ibc transfer", msg: types.Message{Type: "/ibc.applications.transfer.v1.MsgTransfer"}, wantReason: "unsupported message"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var txn types.BatchTransaction
+			txn.Tx.Body.Messages = []types.Message{tt.msg}
+			entries, skipped := WasmPodEntries(txn)
+			var amounts []string
+			for _, entry := range entries {
+				amounts = append(amounts, entry.Amount)
+			}
+			if !reflect.DeepEqual(amounts, tt.wantAmounts) {
+				t.Errorf("entry amounts = %v, want %v", amounts, tt.wantAmounts)
+			}
+			if tt.wantReason != "" && (len(skipped) != 1 || skipped[0].Reason != tt.wantReason) {
+				t.Errorf("skipped = %+v, want %q", skipped, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package blocksync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+)
+
+func init() {
+	RegisterIndexer(StationTypeWASM, newWasmIndexer)
+}
+
+type wasmIndexer struct {
+	indexerState
+	opts       IndexerOptions
+	stationRPC *stationclient.Client
+	stationAPI *stationclient.Client
+	// subscribeHeads is nil when no websocket endpoint is configured.
+	subscribeHeads headSubscriber
+}
+
+func newWasmIndexer(opts IndexerOptions) (StationIndexer, error) {
+	stationRPC, err := stationclient.Shared(opts.StationRPC, opts.StationClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wasm station rpc: %w", err)
+	}
+	stationAPI, err := stationclient.Shared(opts.StationAPI, opts.StationClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wasm station api: %w", err)
+	}
+	indexer := &wasmIndexer{
+		indexerState: newIndexerState(StationTypeWASM, opts.BlockDB),
+		opts:         opts,
+		stationRPC:   stationRPC,
+		stationAPI:   stationAPI,
+	}
+	if opts.StationWS != "" {
+		indexer.subscribeHeads = cometHeadSubscriber(opts.StationWS)
+	}
+	return indexer, nil
+}
+
+func (w *wasmIndexer) Start(ctx context.Context) error {
+	ctx, err := w.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer w.end()
+
+	heads := startHeadNotifier(ctx, StationTypeWASM, w.subscribeHeads, wasmPollInterval)
+	// CometBFT blocks are final once committed, so only confirmations apply.
+	newFinalityTracker(w.opts.Finality, w.opts.BlockDB, w.opts.TxnDB, nil).start(ctx)
+
+	if err := StoreWasmBlock(ctx, heads, w.opts.BlockDB, w.opts.TxnDB, w.stationRPC, w.stationAPI); err != nil {
+		w.setError(err)
+		return err
+	}
+	return nil
+}
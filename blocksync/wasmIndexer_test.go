@@ -0,0 +1,191 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubCometChain serves the CometBFT RPC and cosmos REST endpoints used by the wasm
+// indexer. Every block holds one bank send.
+type stubCometChain struct {
+	t      testing.TB
+	mu     sync.Mutex
+	height int
+	// failures is the number of times a block request fails before it is served.
+	failures map[int]int
+	// restTxLookups counts requests for single transactions, which the indexer should
+	// not need.
+	restTxLookups int
+}
+
+func (c *stubCometChain) tx(height int) string {
+	return encodeTestWasmTx(c.t, fmt.Sprintf("tx-%d", height), testMsgSend(c.t, testWasmAddress(c.t, 1), testWasmAddress(c.t, 2)))
+}
+
+func (c *stubCometChain) setHeight(height int, failures map[int]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.height = height
+	c.failures = failures
+}
+
+func (c *stubCometChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/cosmos/base/tendermint/v1beta1/blocks/latest":
+		fmt.Fprintf(w, `{"block":{"header":{"chain_id":"stub","height":"%d"}}}`, c.height)
+	case r.URL.Path == "/block":
+		height, _ := strconv.Atoi(r.URL.Query().Get("height"))
+		if height > c.height {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":-1,"error":{"code":-32603,"message":"height %d must be less than or equal to the current blockchain height %d"}}`, height, c.height)
+			return
+		}
+		if c.failures[height] > 0 {
+			c.failures[height]--
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":-1,"result":{"block_id":{"hash":"%064X"},"block":{"header":{"chain_id":"stub","height":"%d","time":"2024-03-01T10:00:00Z"},"data":{"txs":["%s"]}}}}`, height, height, c.tx(height))
+	case r.URL.Path == "/block_results":
+		height, _ := strconv.Atoi(r.URL.Query().Get("height"))
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":-1,"result":{"height":"%d","txs_results":[{"code":0,"gas_wanted":"200000","gas_used":"51234","events":[]}]}}`, height)
+	case strings.HasPrefix(r.URL.Path, "/cosmos/tx/v1beta1/txs/"):
+		c.restTxLookups++
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestWasmIndexerStoresEveryHeight(t *testing.T) {
+	chain := &stubCometChain{t: t, height: 3}
+	server := httptest.NewServer(chain)
+	defer server.Close()
+
+	blockDB, txnDB := newTestDBs(t)
+	indexer := &wasmIndexer{
+		indexerState: newIndexerState(StationTypeWASM, blockDB),
+		opts:         IndexerOptions{StationRPC: server.URL, StationAPI: server.URL, BlockDB: blockDB, TxnDB: txnDB},
+		stationRPC:   newTestStation(t, server.URL),
+		stationAPI:   newTestStation(t, server.URL),
+	}
+	newHeads := make(chan struct{})
+	indexer.subscribeHeads = func(ctx context.Context, notify func()) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-newHeads:
+				notify()
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.Start(ctx)
+	waitFor(t, 5*time.Second, func() bool { return indexer.LatestIndexed() == 3 })
+
+	// Several blocks are produced before the indexer wakes up again, and one of them is
+	// briefly unavailable.
+	chain.setHeight(7, map[int]int{5: 1})
+	newHeads <- struct{}{}
+	waitFor(t, 10*time.Second, func() bool { return indexer.LatestIndexed() == 7 })
+
+	if err := checkBlockContiguity(blockDB, "Block", 1); err != nil {
+		t.Fatalf("checkBlockContiguity() = %v", err)
+	}
+	if got := readCounter(txnDB, "txnCount"); got != 7 {
+		t.Fatalf("txnCount = %d, want 7", got)
+	}
+	for height := 1; height <= 7; height++ {
+		data, err := txnDB.Get([]byte(fmt.Sprintf("txns-%d", height)), nil)
+		if err != nil {
+			t.Fatalf("txns-%d: %v", height, err)
+		}
+		var txn Transaction
+		if err := json.Unmarshal(data, &txn); err != nil {
+			t.Fatalf("txns-%d: %v", height, err)
+		}
+		wantHash, _ := ComputeTransactionHash(chain.tx(height))
+		if !strings.EqualFold(txn.TxResponse.TxHash, wantHash) {
+			t.Errorf("txns-%d hash = %s, want the transaction of block %d", height, txn.TxResponse.TxHash, height)
+		}
+	}
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	if chain.restTxLookups != 0 {
+		t.Errorf("indexer made %d per-transaction REST lookups, want 0", chain.restTxLookups)
+	}
+}
+
+func TestWasmIndexerSurvivesEndpointOutage(t *testing.T) {
+	chain := &stubCometChain{t: t, height: 2}
+	primary := httptest.NewServer(chain)
+	defer primary.Close()
+	fallback := httptest.NewServer(chain)
+	defer fallback.Close()
+
+	blockDB, txnDB := newTestDBs(t)
+	indexer := &wasmIndexer{
+		indexerState: newIndexerState(StationTypeWASM, blockDB),
+		opts:         IndexerOptions{BlockDB: blockDB, TxnDB: txnDB},
+		stationRPC:   newTestStation(t, primary.URL, fallback.URL),
+		stationAPI:   newTestStation(t, primary.URL, fallback.URL),
+	}
+	newHeads := make(chan struct{})
+	indexer.subscribeHeads = func(ctx context.Context, notify func()) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-newHeads:
+				notify()
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go indexer.Start(ctx)
+	waitFor(t, 5*time.Second, func() bool { return indexer.LatestIndexed() == 2 })
+
+	primary.CloseClientConnections()
+	primary.Close()
+	chain.setHeight(5, nil)
+	newHeads <- struct{}{}
+	waitFor(t, 5*time.Second, func() bool { return indexer.LatestIndexed() == 5 })
+
+	if status := indexer.stationRPC.Status(); status[0].Healthy || !status[1].Healthy {
+		t.Errorf("endpoint status = %+v, want the primary down and the fallback healthy", status)
+	}
+	if got := readCounter(txnDB, "txnCount"); got != 5 {
+		t.Errorf("txnCount = %d, want 5", got)
+	}
+}
+
+func TestCheckBlockContiguity(t *testing.T) {
+	blockDB := newMemDB(t)
+	for _, height := range []int{1, 2, 4, 6} {
+		blockDB.Put([]byte("Block"+strconv.Itoa(height)), []byte("{}"), nil)
+	}
+	blockDB.Put([]byte("blockCount"), []byte("7"), nil)
+
+	err := checkBlockContiguity(blockDB, "Block", 1)
+	if err == nil || !strings.Contains(err.Error(), "2 of blocks 1 to 6 are missing, the first is 3") {
+		t.Errorf("checkBlockContiguity() = %v, want 2 missing blocks starting at 3", err)
+	}
+	if err := checkBlockContiguity(blockDB, "Block", 6); err != nil {
+		t.Errorf("checkBlockContiguity() from 6 = %v, want nil", err)
+	}
+}
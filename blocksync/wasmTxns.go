@@ -0,0 +1,315 @@
+package blocksync
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	junctiontypes "github.com/airchains-network/decentralized-sequencer/junction/types"
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/std"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// wasmTxResult is the execution result of a transaction as returned by the CometBFT
+// block_results endpoint.
+type wasmTxResult struct {
+	Code      uint32  `json:"code"`
+	Data      string  `json:"data"`
+	Log       string  `json:"log"`
+	Info      string  `json:"info"`
+	GasWanted string  `json:"gas_wanted"`
+	GasUsed   string  `json:"gas_used"`
+	Events    []Event `json:"events"`
+	Codespace string  `json:"codespace"`
+}
+
+// wasmStoredTxn has the layout of the cosmos GetTx REST response, which WASM
+// transactions were stored as before they were decoded locally.
+type wasmStoredTxn struct {
+	Tx         json.RawMessage `json:"tx"`
+	TxResponse struct {
+		Height    string          `json:"height"`
+		TxHash    string          `json:"txhash"`
+		Codespace string          `json:"codespace"`
+		Code      uint32          `json:"code"`
+		Data      string          `json:"data"`
+		RawLog    string          `json:"raw_log"`
+		Logs      []interface{}   `json:"logs"`
+		Info      string          `json:"info"`
+		GasWanted string          `json:"gas_wanted"`
+		GasUsed   string          `json:"gas_used"`
+		Tx        json.RawMessage `json:"tx"`
+		Timestamp string          `json:"timestamp"`
+		Events    []Event         `json:"events"`
+	} `json:"tx_response"`
+}
+
+// wasmTxDecoder decodes station transactions with the cosmos-sdk tx decoder. Messages of
+// modules that are not registered with it are kept with their type URL only.
+type wasmTxDecoder struct {
+	cdc        *codec.ProtoCodec
+	decode     sdk.TxDecoder
+	encodeJSON sdk.TxEncoder
+}
+
+var defaultWasmTxDecoder = sync.OnceValue(newWasmTxDecoder)
+
+func newWasmTxDecoder() *wasmTxDecoder {
+	registry := codectypes.NewInterfaceRegistry()
+	std.RegisterInterfaces(registry)
+	authtypes.RegisterInterfaces(registry)
+	banktypes.RegisterInterfaces(registry)
+	junctiontypes.RegisterInterfaces(registry)
+	cdc := codec.NewProtoCodec(registry)
+	txConfig := authtx.NewTxConfig(cdc, authtx.DefaultSignModes)
+	return &wasmTxDecoder{
+		cdc:        cdc,
+		decode:     txConfig.TxDecoder(),
+		encodeJSON: txConfig.TxJSONEncoder(),
+	}
+}
+
+// txJSON returns the transaction in the JSON format of the cosmos REST API.
+func (d *wasmTxDecoder) txJSON(txBytes []byte) (json.RawMessage, error) {
+	if tx, err := d.decode(txBytes); err == nil {
+		if data, err := d.encodeJSON(tx); err == nil {
+			return data, nil
+		}
+	}
+	return d.rawTxJSON(txBytes)
+}
+
+// rawTxJSON is used for transactions carrying messages the decoder does not know. It
+// only needs the outer transaction to be well formed.
+func (d *wasmTxDecoder) rawTxJSON(txBytes []byte) (json.RawMessage, error) {
+	var raw txtypes.TxRaw
+	if err := raw.Unmarshal(txBytes); err != nil {
+		return nil, err
+	}
+	var body txtypes.TxBody
+	if err := body.Unmarshal(raw.BodyBytes); err != nil {
+		return nil, err
+	}
+
+	var tx struct {
+		Body struct {
+			Messages      []json.RawMessage `json:"messages"`
+			Memo          string            `json:"memo"`
+			TimeoutHeight string            `json:"timeout_height"`
+		} `json:"body"`
+		AuthInfo   json.RawMessage `json:"auth_info,omitempty"`
+		Signatures [][]byte        `json:"signatures"`
+	}
+	tx.Body.Messages = make([]json.RawMessage, 0, len(body.Messages))
+	for _, msg := range body.Messages {
+		tx.Body.Messages = append(tx.Body.Messages, d.messageJSON(msg))
+	}
+	tx.Body.Memo = body.Memo
+	tx.Body.TimeoutHeight = fmt.Sprint(body.TimeoutHeight)
+	var authInfo txtypes.AuthInfo
+	if err := authInfo.Unmarshal(raw.AuthInfoBytes); err == nil {
+		tx.AuthInfo, _ = d.cdc.MarshalJSON(&authInfo)
+	}
+	tx.Signatures = raw.Signatures
+	return json.Marshal(tx)
+}
+
+// wasmExecuteContractURL is the type URL of a CosmWasm contract execution. The wasm
+// module is not registered with the decoder, its messages are decoded by
+// decodeExecuteContract.
+const wasmExecuteContractURL = "/cosmwasm.wasm.v1.MsgExecuteContract"
+
+// messageJSON returns msg in the JSON format of the cosmos REST API when its type is
+// known, and its type URL only otherwise.
+func (d *wasmTxDecoder) messageJSON(msg *codectypes.Any) json.RawMessage {
+	if data, err := d.cdc.MarshalJSON(msg); err == nil {
+		return data
+	}
+	if msg.TypeUrl == wasmExecuteContractURL {
+		if data, err := decodeExecuteContract(msg.Value); err == nil {
+			return data
+		}
+	}
+	data, _ := json.Marshal(map[string]string{"@type": msg.TypeUrl})
+	return data
+}
+
+// decodeExecuteContract decodes a cosmwasm.wasm.v1.MsgExecuteContract: sender (1),
+// contract (2), msg (3) and funds (5).
+func decodeExecuteContract(value []byte) (json.RawMessage, error) {
+	type coin struct {
+		Denom  string `json:"denom"`
+		Amount string `json:"amount"`
+	}
+	msg := struct {
+		Type     string          `json:"@type"`
+		Sender   string          `json:"sender"`
+		Contract string          `json:"contract"`
+		Msg      json.RawMessage `json:"msg"`
+		Funds    []coin          `json:"funds"`
+	}{Type: wasmExecuteContractURL, Funds: []coin{}}
+
+	err := walkProtoFields(value, func(num protowire.Number, field []byte) error {
+		switch num {
+		case 1:
+			msg.Sender = string(field)
+		case 2:
+			msg.Contract = string(field)
+		case 3:
+			if json.Valid(field) {
+				msg.Msg = field
+			} else {
+				msg.Msg, _ = json.Marshal(field)
+			}
+		case 5:
+			var c coin
+			if err := walkProtoFields(field, func(num protowire.Number, field []byte) error {
+				switch num {
+				case 1:
+					c.Denom = string(field)
+				case 2:
+					c.Amount = string(field)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			msg.Funds = append(msg.Funds, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(msg)
+}
+
+// walkProtoFields calls fn with every length-delimited field of a protobuf message and
+// skips the other wire types.
+func walkProtoFields(b []byte, fn func(num protowire.Number, field []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		field, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := fn(num, field); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// fetchWasmBlockResults returns the execution results of the transactions in block
+// height, in block order.
+func fetchWasmBlockResults(ctx context.Context, stationRPC *stationclient.Client, height int) ([]wasmTxResult, error) {
+	body, err := stationRPC.Get(ctx, fmt.Sprintf("/block_results?height=%d", height))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch results of block %d: %w", height, err)
+	}
+
+	var results struct {
+		Result *struct {
+			TxsResults []wasmTxResult `json:"txs_results"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode results of block %d: %w", height, err)
+	}
+	if results.Result == nil {
+		return nil, fmt.Errorf("station did not return results of block %d: %s", height, body)
+	}
+	return results.Result.TxsResults, nil
+}
+
+// decodeWasmTransactions decodes the base64 transactions of a block and joins them with
+// their results from block_results. It returns them in block order in the stored format.
+func decodeWasmTransactions(txs []interface{}, results []wasmTxResult, height string, timestamp string) ([][]byte, error) {
+	if len(results) != len(txs) {
+		return nil, fmt.Errorf("block %s has %d transactions but %d results", height, len(txs), len(results))
+	}
+	decoder := defaultWasmTxDecoder()
+
+	rawTxns := make([][]byte, 0, len(txs))
+	for i, encoded := range txs {
+		encodedTx, ok := encoded.(string)
+		if !ok {
+			return nil, fmt.Errorf("transaction %d of block %s is not base64 encoded", i, height)
+		}
+		txBytes, err := base64.StdEncoding.DecodeString(encodedTx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d of block %s is not base64 encoded: %w", i, height, err)
+		}
+		hash, err := ComputeTransactionHash(encodedTx)
+		if err != nil {
+			return nil, err
+		}
+
+		txJSON, err := decoder.txJSON(txBytes)
+		if err != nil {
+			log.Warn().Str("module", "blocksync").Err(err).Msg(fmt.Sprintf("Failed to decode transaction %s, storing it without its body", hash))
+			txJSON = json.RawMessage(`{"body":{"messages":[]},"signatures":[]}`)
+		}
+
+		var stored wasmStoredTxn
+		stored.Tx = txJSON
+		stored.TxResponse.Height = height
+		stored.TxResponse.TxHash = strings.ToUpper(hash)
+		stored.TxResponse.Codespace = results[i].Codespace
+		stored.TxResponse.Code = results[i].Code
+		stored.TxResponse.Data = results[i].Data
+		stored.TxResponse.RawLog = results[i].Log
+		stored.TxResponse.Logs = []interface{}{}
+		stored.TxResponse.Info = results[i].Info
+		stored.TxResponse.GasWanted = results[i].GasWanted
+		stored.TxResponse.GasUsed = results[i].GasUsed
+		stored.TxResponse.Tx, err = withTxTypeURL(txJSON)
+		if err != nil {
+			return nil, err
+		}
+		stored.TxResponse.Timestamp = timestamp
+		stored.TxResponse.Events = results[i].Events
+
+		data, err := json.Marshal(stored)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling transaction %s: %w", hash, err)
+		}
+		rawTxns = append(rawTxns, data)
+	}
+	return rawTxns, nil
+}
+
+// withTxTypeURL adds the "@type" field the REST API puts on tx_response.tx.
+func withTxTypeURL(txJSON json.RawMessage) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(txJSON, &fields); err != nil {
+		return nil, err
+	}
+	fields["@type"] = json.RawMessage(`"/cosmos.tx.v1beta1.Tx"`)
+	return json.Marshal(fields)
+}
@@ -0,0 +1,135 @@
+package blocksync
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func testWasmAddress(t testing.TB, b byte) string {
+	t.Helper()
+	address, err := bech32.ConvertAndEncode("wasm", append(make([]byte, 19), b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return address
+}
+
+// encodeTestWasmTx returns a signed-looking transaction carrying msgs, base64 encoded as
+// in a CometBFT block.
+func encodeTestWasmTx(t testing.TB, memo string, msgs ...*codectypes.Any) string {
+	t.Helper()
+	body := txtypes.TxBody{Messages: msgs, Memo: memo}
+	bodyBytes, err := body.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authInfo := txtypes.AuthInfo{Fee: &txtypes.Fee{GasLimit: 200000}}
+	authInfoBytes, err := authInfo.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := txtypes.TxRaw{BodyBytes: bodyBytes, AuthInfoBytes: authInfoBytes, Signatures: [][]byte{{1, 2, 3}}}
+	txBytes, err := raw.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(txBytes)
+}
+
+func testMsgSend(t testing.TB, from, to string) *codectypes.Any {
+	t.Helper()
+	msg, err := codectypes.NewAnyWithValue(&banktypes.MsgSend{
+		FromAddress: from,
+		ToAddress:   to,
+		Amount:      sdk.NewCoins(sdk.NewInt64Coin("stake", 10)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestDecodeWasmTransactions(t *testing.T) {
+	from, to := testWasmAddress(t, 1), testWasmAddress(t, 2)
+	send := encodeTestWasmTx(t, "send", testMsgSend(t, from, to))
+	contract := encodeTestWasmTx(t, "execute", &codectypes.Any{TypeUrl: "/cosmwasm.wasm.v1.MsgExecuteContract", Value: []byte{0x0a, 0x01, 0x61}})
+	results := []wasmTxResult{
+		{Code: 0, GasWanted: "200000", GasUsed: "51234", Events: []Event{{Type: "transfer", Attributes: []EventAttribute{{Key: "recipient", Value: to, Index: true}}}}},
+		{Code: 5, Codespace: "wasm", Log: "out of gas", GasWanted: "200000", GasUsed: "200000"},
+	}
+
+	txns, err := decodeWasmTransactions([]interface{}{send, contract}, results, "12", "2024-03-01T10:00:00.123456789Z")
+	if err != nil {
+		t.Fatalf("decodeWasmTransactions() error = %v", err)
+	}
+	if len(txns) != 2 {
+		t.Fatalf("decodeWasmTransactions() returned %d transactions, want 2", len(txns))
+	}
+
+	var sendTxn Transaction
+	if err := json.Unmarshal(txns[0], &sendTxn); err != nil {
+		t.Fatalf("stored transaction does not decode as a REST transaction: %v", err)
+	}
+	wantHash, _ := ComputeTransactionHash(send)
+	if sendTxn.TxResponse.TxHash != strings.ToUpper(wantHash) || sendTxn.TxResponse.Height != "12" {
+		t.Errorf("tx_response hash, height = %s, %s, want %s, 12", sendTxn.TxResponse.TxHash, sendTxn.TxResponse.Height, strings.ToUpper(wantHash))
+	}
+	if sendTxn.TxResponse.GasUsed != "51234" || len(sendTxn.TxResponse.Events) != 1 {
+		t.Errorf("tx_response does not carry the block_results entry: %+v", sendTxn.TxResponse)
+	}
+	if sendTxn.TxResponse.Tx.Type != "/cosmos.tx.v1beta1.Tx" || sendTxn.TxResponse.Tx.Body.Memo != "send" {
+		t.Errorf("tx_response.tx = %+v, want the decoded transaction", sendTxn.TxResponse.Tx)
+	}
+
+	// Pods read the sender and recipient from the top level tx.
+	var podView struct {
+		Tx struct {
+			Body struct {
+				Messages []struct {
+					FromAddress string `json:"from_address"`
+					ToAddress   string `json:"to_address"`
+				} `json:"messages"`
+			} `json:"body"`
+		} `json:"tx"`
+	}
+	if err := json.Unmarshal(txns[0], &podView); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := podView.Tx.Body.Messages; len(msgs) != 1 || msgs[0].FromAddress != from || msgs[0].ToAddress != to {
+		t.Errorf("tx.body.messages = %+v, want a send from %s to %s", msgs, from, to)
+	}
+
+	indexes := wasmTxnIndexes(txns[0], 1)
+	for _, address := range []string{from, to} {
+		if _, ok := indexes[txnAddressPrefix(address)+"000000000001"]; !ok {
+			t.Errorf("wasmTxnIndexes() does not index %s", address)
+		}
+	}
+
+	var contractTxn Transaction
+	if err := json.Unmarshal(txns[1], &contractTxn); err != nil {
+		t.Fatal(err)
+	}
+	messages := contractTxn.TxResponse.Tx.Body.Messages
+	if len(messages) != 1 || messages[0].(map[string]interface{})["@type"] != "/cosmwasm.wasm.v1.MsgExecuteContract" {
+		t.Errorf("unregistered message = %v, want its type URL", messages)
+	}
+	if contractTxn.TxResponse.Code != 5 || contractTxn.TxResponse.Codespace != "wasm" || contractTxn.TxResponse.RawLog != "out of gas" {
+		t.Errorf("failed transaction result = %+v", contractTxn.TxResponse)
+	}
+}
+
+func TestDecodeWasmTransactionsResultMismatch(t *testing.T) {
+	send := encodeTestWasmTx(t, "", testMsgSend(t, testWasmAddress(t, 1), testWasmAddress(t, 2)))
+	if _, err := decodeWasmTransactions([]interface{}{send}, nil, "3", ""); err == nil {
+		t.Error("decodeWasmTransactions() accepted a block without transaction results")
+	}
+}
@@ -0,0 +1,116 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/airchains-network/decentralized-sequencer/config"
+	logger "github.com/airchains-network/decentralized-sequencer/log"
+	"github.com/spf13/cobra"
+)
+
+var BlocksyncCmd = &cobra.Command{
+	Use:   "blocksync",
+	Short: "Repair and check the indexed station data",
+	Run:   runBlocksyncCommand,
+}
+
+var BackfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Fetch a block range from the station again and rewrite the stored blocks and transactions in place",
+	Run:   runBackfillCommand,
+}
+
+var VerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Compare the stored block hashes of a range with the station",
+	Run:   runVerifyCommand,
+}
+
+func runBlocksyncCommand(cmd *cobra.Command, _ []string) {
+	if err := cmd.Help(); err != nil {
+		cmd.Println("Unable to display help:", err)
+	}
+}
+
+// initBlocksyncRange opens the databases and returns the station config with the
+// indexer options and the block range selected by the --from and --to flags. The node
+// must be stopped, as it holds the database locks.
+func initBlocksyncRange(cmd *cobra.Command) (*config.StationConfig, blocksync.IndexerOptions, int, int, error) {
+	var opts blocksync.IndexerOptions
+	conf, err := blocksync.LoadConfig()
+	if err != nil {
+		return nil, opts, 0, 0, fmt.Errorf("failed to load config: %w", err)
+	}
+	if conf.Station == nil {
+		return nil, opts, 0, 0, errors.New("station config is missing, run init first")
+	}
+	from, err := cmd.Flags().GetInt("from")
+	if err != nil {
+		return nil, opts, 0, 0, fmt.Errorf("failed to get flag 'from': %w", err)
+	}
+	to, err := cmd.Flags().GetInt("to")
+	if err != nil {
+		return nil, opts, 0, 0, fmt.Errorf("failed to get flag 'to': %w", err)
+	}
+
+	if success := blocksync.InitDb(); !success {
+		return nil, opts, 0, 0, errors.New("failed to initialize database, stop the node before running blocksync commands")
+	}
+	if err := blocksync.CheckSchemaVersion(blocksync.GetStaticDbInstance()); err != nil {
+		return nil, opts, 0, 0, err
+	}
+	opts = blocksync.IndexerOptionsFromConfig(conf.Station, blocksync.GetBlockDbInstance(), blocksync.GetTxDbInstance())
+	if to < 0 {
+		to = blocksync.LatestIndexedBlock(opts.BlockDB)
+	}
+	return conf.Station, opts, from, to, nil
+}
+
+func runBackfillCommand(cmd *cobra.Command, _ []string) {
+	station, opts, from, to, err := initBlocksyncRange(cmd)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return
+	}
+
+	report, err := blocksync.Backfill(context.Background(), station.StationType, opts, from, to)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Backfill failed: %s", err.Error()))
+		os.Exit(1)
+	}
+	for _, failed := range report.Failed {
+		logger.Log.Error(fmt.Sprintf("Block %d was not backfilled: %s", failed.Height, failed.Err.Error()))
+	}
+	logger.Log.Info(fmt.Sprintf("Backfilled blocks %d to %d: %d rewritten, %d unchanged, %d failed", from, to, len(report.Rewritten), report.Unchanged, len(report.Failed)))
+	if len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runVerifyCommand(cmd *cobra.Command, _ []string) {
+	station, opts, from, to, err := initBlocksyncRange(cmd)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return
+	}
+
+	report, err := blocksync.VerifyBlocks(context.Background(), station.StationType, opts, from, to)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Verify failed: %s", err.Error()))
+		os.Exit(1)
+	}
+	for _, mismatch := range report.Mismatches {
+		logger.Log.Warn(fmt.Sprintf("Block %d: stored hash %s, station hash %s", mismatch.Height, mismatch.Stored, mismatch.Station))
+	}
+	for _, failed := range report.Failed {
+		logger.Log.Error(fmt.Sprintf("Block %d was not verified: %s", failed.Height, failed.Err.Error()))
+	}
+	logger.Log.Info(fmt.Sprintf("Verified blocks %d to %d: %d checked, %d mismatches, %d failed", from, to, report.Checked, len(report.Mismatches), len(report.Failed)))
+	if len(report.Mismatches) > 0 || len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+}
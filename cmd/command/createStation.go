@@ -18,6 +18,7 @@ type StationArgs struct {
 	jsonRPC       string
 	tracks        []string
 	bootstrapNode []string
+	podSize       int
 }
 
 func parseCmdArgs(cmd *cobra.Command) (*StationArgs, error) {
@@ -35,6 +36,10 @@ func parseCmdArgs(cmd *cobra.Command) (*StationArgs, error) {
 	if err != nil {
 		return nil, fmt.Errorf(" Failed to get 'bootstarpNode' flag values: %w", err)
 	}
+	args.podSize, err = cmd.Flags().GetInt("podSize")
+	if err != nil {
+		return nil, fmt.Errorf(" Failed to get 'podSize' flag value: %w", err)
+	}
 
 	return args, nil
 }
@@ -55,7 +60,16 @@ var CreateStation = &cobra.Command{
 			return
 		}
 
-		_, verificationKey, err := v1.GetVkPk()
+		podSize := stationArgs.podSize
+		if podSize == 0 {
+			podSize = conf.Station.StationPodSize()
+		}
+		if err := checkCircuitKeys(conf.Station.StationType, podSize); err != nil {
+			logs.Log.Error(err.Error())
+			return
+		}
+
+		_, verificationKey, err := v1.GetVkPk(podSize)
 		//The Unused variable is the proving key
 		if err != nil {
 			logs.Log.Error("Failed to read Proving Key & Verification key: " + err.Error())
@@ -66,10 +80,13 @@ var CreateStation = &cobra.Command{
 			StationType: conf.Station.StationType,
 		}
 
-		extraArg := junctionTypes.StationArg{
-			TrackType: "Airchains Sequencer",
-			DaType:    conf.DA.DaType,
-			Prover:    "Airchains",
+		extraArg := types.StationArg{
+			StationArg: junctionTypes.StationArg{
+				TrackType: "Airchains Sequencer",
+				DaType:    conf.DA.DaType,
+				Prover:    "Airchains",
+			},
+			PodSize: podSize,
 		}
 
 		addressPrefix := "air"
@@ -2,14 +2,17 @@ package command
 
 import (
 	"fmt"
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
 	"github.com/airchains-network/decentralized-sequencer/config"
 	logs "github.com/airchains-network/decentralized-sequencer/log"
 
 	//logs "github.com/airchains-network/decentralized-sequencer/log"
 	"github.com/airchains-network/decentralized-sequencer/p2p"
+	"github.com/airchains-network/decentralized-sequencer/zk/keys"
 	"github.com/spf13/cobra"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type Configs struct {
@@ -20,6 +23,8 @@ type Configs struct {
 	daKey       string
 	stationRPC  string
 	stationAPI  string
+	stationWS   string
+	podSize     int
 }
 
 func InitConfigs(cmd *cobra.Command) (*Configs, error) {
@@ -36,6 +41,11 @@ func InitConfigs(cmd *cobra.Command) (*Configs, error) {
 		return nil, fmt.Errorf("failed to get flag 'stationType': %w", err)
 	}
 
+	if !blocksync.IsStationTypeSupported(configs.stationType) {
+		logs.Log.Error("invalid stationType. Must be one of: " + strings.Join(blocksync.SupportedStationTypes(), ", "))
+		return nil, fmt.Errorf("invalid stationType: %s", configs.stationType)
+	}
+
 	configs.daType, err = cmd.Flags().GetString("daType")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get flag 'daType': %w", err)
@@ -72,6 +82,19 @@ func InitConfigs(cmd *cobra.Command) (*Configs, error) {
 		return nil, fmt.Errorf("failed to get flag 'stationAPI': %w", err)
 	}
 
+	configs.stationWS, err = cmd.Flags().GetString("stationWS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flag 'stationWS': %w", err)
+	}
+
+	configs.podSize, err = cmd.Flags().GetInt("podSize")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flag 'podSize': %w", err)
+	}
+	if err := keys.ValidatePodSize(configs.podSize); err != nil {
+		return nil, err
+	}
+
 	return &configs, nil
 }
 
@@ -104,6 +127,8 @@ var InitCmd = &cobra.Command{
 		conf.Station.StationType = configs.stationType
 		conf.Station.StationRPC = configs.stationRPC
 		conf.Station.StationAPI = configs.stationAPI
+		conf.Station.StationWS = configs.stationWS
+		conf.Station.PodSize = configs.podSize
 		conf.P2P.NodeId = peerID
 		conf.SetRoot(conf.BaseConfig.RootDir)
 
@@ -1,6 +1,13 @@
 package command
 
 import (
+	"fmt"
+
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/airchains-network/decentralized-sequencer/zk/keys"
+	v1 "github.com/airchains-network/decentralized-sequencer/zk/v1EVM"
+	v1SVM "github.com/airchains-network/decentralized-sequencer/zk/v1SVM"
+	v1Wasm "github.com/airchains-network/decentralized-sequencer/zk/v1WASM"
 	"github.com/spf13/cobra"
 )
 
@@ -16,3 +23,23 @@ func runProverCommand(cmd *cobra.Command, _ []string) {
 		cmd.Println("Unable to display help:", err)
 	}
 }
+
+// circuitKeyChecks maps a normalized station type to the check of its circuit keys.
+var circuitKeyChecks = map[string]func(podSize int) error{
+	blocksync.StationTypeEVM:  v1.CheckKeys,
+	blocksync.StationTypeWASM: v1Wasm.CheckKeys,
+	blocksync.StationTypeSVM:  v1SVM.CheckKeys,
+}
+
+// checkCircuitKeys returns an error unless the circuit keys of stationType were generated
+// for pods of podSize transactions.
+func checkCircuitKeys(stationType string, podSize int) error {
+	if err := keys.ValidatePodSize(podSize); err != nil {
+		return err
+	}
+	check, ok := circuitKeyChecks[blocksync.NormalizeStationType(stationType)]
+	if !ok {
+		return fmt.Errorf("no circuit for station type %q", stationType)
+	}
+	return check(podSize)
+}
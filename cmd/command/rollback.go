@@ -3,7 +3,7 @@ package command
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/airchains-network/decentralized-sequencer/config"
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
 	logger "github.com/airchains-network/decentralized-sequencer/log"
 	"github.com/airchains-network/decentralized-sequencer/node/shared"
 	"github.com/airchains-network/decentralized-sequencer/p2p"
@@ -30,6 +30,7 @@ func runRollbackCommand(_ *cobra.Command, _ []string) {
 	batchDB := shared.Node.NodeConnections.GetPodsDatabaseConnection()
 	staticDBConnection := shared.Node.NodeConnections.GetStaticDatabaseConnection()
 	stateConnection := shared.Node.NodeConnections.GetStateDatabaseConnection()
+	txnDBConnection := shared.Node.NodeConnections.GetTxnDatabaseConnection()
 
 	podStateData, err := p2p.GetPodStateFromDatabase()
 	if err != nil {
@@ -64,7 +65,7 @@ func runRollbackCommand(_ *cobra.Command, _ []string) {
 		return
 	}
 
-	err = staticDBConnection.Put([]byte("batchStartIndex"), []byte(strconv.Itoa(config.PODSize*(requiredPodNumberInt))), nil)
+	err = staticDBConnection.Put([]byte("batchStartIndex"), []byte(strconv.Itoa(blocksync.PodLastTxn(txnDBConnection, requiredPodNumberInt))), nil)
 	if err != nil {
 		logger.Log.Error("Error in updating batchStartIndex in static db")
 		return
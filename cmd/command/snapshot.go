@@ -0,0 +1,103 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/airchains-network/decentralized-sequencer/config"
+	logger "github.com/airchains-network/decentralized-sequencer/log"
+	"github.com/airchains-network/decentralized-sequencer/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var SnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Export the node databases into an archive or restore them from one",
+	Run:   runSnapshotCommand,
+}
+
+var SnapshotExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write every database, the pod state and a manifest into a single archive",
+	Run:   runSnapshotExportCommand,
+}
+
+var SnapshotImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Validate an archive and restore it into a fresh home directory",
+	Run:   runSnapshotImportCommand,
+}
+
+func runSnapshotCommand(cmd *cobra.Command, _ []string) {
+	if err := cmd.Help(); err != nil {
+		cmd.Println("Unable to display help:", err)
+	}
+}
+
+// snapshotHome returns the --home flag, or ~/.tracks when it is not set.
+func snapshotHome(cmd *cobra.Command) (string, error) {
+	home, err := cmd.Flags().GetString("home")
+	if err != nil {
+		return "", fmt.Errorf("failed to get flag 'home': %w", err)
+	}
+	if home != "" {
+		return home, nil
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userHome, config.DefaultTracksDir), nil
+}
+
+func runSnapshotExportCommand(cmd *cobra.Command, _ []string) {
+	home, err := snapshotHome(cmd)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return
+	}
+	out, err := cmd.Flags().GetString("output")
+	if err != nil {
+		logger.Log.Error("Failed to get flag 'output': " + err.Error())
+		return
+	}
+	conf, err := blocksync.LoadConfig()
+	if err != nil || conf.Station == nil {
+		logger.Log.Error("Failed to load the station config, run init first")
+		return
+	}
+
+	manifest, err := snapshot.Export(home, conf.Station.StationType, out)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Snapshot export failed: %s", err.Error()))
+		os.Exit(1)
+	}
+	logger.Log.Info(fmt.Sprintf("Exported %s: block %d, %d transactions, pod %d, checksum %s", out, manifest.LatestBlock, manifest.TxnCount, manifest.PodNumber, manifest.Checksum))
+}
+
+func runSnapshotImportCommand(cmd *cobra.Command, _ []string) {
+	home, err := snapshotHome(cmd)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return
+	}
+	archive, err := cmd.Flags().GetString("archive")
+	if err != nil {
+		logger.Log.Error("Failed to get flag 'archive': " + err.Error())
+		return
+	}
+	// A track initialised before the import must index the same station type.
+	var stationType string
+	if conf, err := blocksync.LoadConfig(); err == nil && conf.Station != nil {
+		stationType = conf.Station.StationType
+	}
+
+	manifest, err := snapshot.Import(archive, home, stationType)
+	if err != nil {
+		logger.Log.Error(fmt.Sprintf("Snapshot import failed: %s", err.Error()))
+		os.Exit(1)
+	}
+	logger.Log.Info(fmt.Sprintf("Imported %s into %s: block %d, %d transactions, pod %d", archive, snapshot.DataDir(home), manifest.LatestBlock, manifest.TxnCount, manifest.PodNumber))
+}
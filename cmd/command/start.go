@@ -2,7 +2,10 @@ package command
 
 import (
 	"errors"
+	"fmt"
 	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/airchains-network/decentralized-sequencer/config"
+	"github.com/airchains-network/decentralized-sequencer/junction"
 	logger "github.com/airchains-network/decentralized-sequencer/log"
 	"github.com/airchains-network/decentralized-sequencer/node"
 	"github.com/airchains-network/decentralized-sequencer/node/shared"
@@ -15,6 +18,15 @@ func runSequencerCommand(_ *cobra.Command, _ []string) {
 		logger.Log.Error("Error in initiating sequencer nodes due to the above error")
 		return
 	}
+	// Pods of another size, or proofs made with keys of another pod size, would never
+	// match the pods of the other tracks of the station.
+	if station := shared.Node.Config.Station; station != nil {
+		if err := checkStationPodSize(station); err != nil {
+			logger.Log.Error(err.Error())
+			logger.Log.Error("Set podSize to the pod size of the station and run tracks prover for it before starting")
+			return
+		}
+	}
 	node.Start()
 }
 
@@ -30,6 +42,21 @@ func initSequencer() error {
 	}
 	logger.Log.Info("Database Initialized")
 
+	var stationType string
+	if config.Station != nil {
+		stationType = config.Station.StationType
+	}
+	if err := blocksync.MigrateDatabases(blocksync.LocalDatabases(stationType)); err != nil {
+		return err
+	}
+	if config.Station != nil {
+		policy := blocksync.InclusionPolicyFromConfig(config.Station)
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		logger.Log.Info("Pod inclusion policy: " + policy.String())
+	}
+
 	if config.Junction.StationId == "" {
 		return errors.New("create station before stating sequencer")
 	}
@@ -42,6 +69,24 @@ func initSequencer() error {
 	return nil
 }
 
+// checkStationPodSize returns an error unless the podSize of station, and the circuit
+// keys, match the pod size recorded in the station genesis.
+func checkStationPodSize(station *config.StationConfig) error {
+	genesis, err := junction.ReadGenesisJson()
+	if err != nil {
+		return err
+	}
+	// Stations created before the pod size was configurable use the legacy size.
+	podSize := genesis.ExtraArg.PodSize
+	if podSize == 0 {
+		podSize = config.PODSize
+	}
+	if station.StationPodSize() != podSize {
+		return fmt.Errorf("podSize %d of the config differs from the pod size %d of station %s", station.StationPodSize(), podSize, genesis.StationId)
+	}
+	return checkCircuitKeys(station.StationType, podSize)
+}
+
 var StationCmd = &cobra.Command{
 	Use:   "start",
 	Short: "start the sequencer nodes",
@@ -1,27 +1,55 @@
 package zkpCmd
 
 import (
+	"github.com/airchains-network/decentralized-sequencer/config"
+	logs "github.com/airchains-network/decentralized-sequencer/log"
+	"github.com/airchains-network/decentralized-sequencer/node/shared"
+	"github.com/airchains-network/decentralized-sequencer/zk/keys"
 	v1 "github.com/airchains-network/decentralized-sequencer/zk/v1EVM"
+	v1SVM "github.com/airchains-network/decentralized-sequencer/zk/v1SVM"
 	v1Wasm "github.com/airchains-network/decentralized-sequencer/zk/v1WASM"
 	"github.com/spf13/cobra"
+	"strconv"
 )
 
-func runV1ZKPCommand(_ *cobra.Command, _ []string) {
-	v1.CreateVkPkNew()
+// podSize returns the pod size to generate keys for: the podSize flag, or the pod size
+// of the station configuration when it is not set.
+func podSize(cmd *cobra.Command) (int, error) {
+	size, _ := cmd.Flags().GetInt("podSize")
+	if size == 0 {
+		size = config.PODSize
+		if conf, err := shared.LoadConfig(); err == nil {
+			size = conf.Station.StationPodSize()
+		}
+	}
+	return size, keys.ValidatePodSize(size)
 }
 
-func runV1WasmZKPCommand(_ *cobra.Command, _ []string) {
-	v1Wasm.CreateVkPkWasm()
-
+// runWithPodSize returns a command that generates the keys of a circuit for the pod size.
+func runWithPodSize(create func(podSize int)) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, _ []string) {
+		size, err := podSize(cmd)
+		if err != nil {
+			logs.Log.Error(err.Error())
+			return
+		}
+		logs.Log.Info("Generating keys for pods of " + strconv.Itoa(size) + " transactions")
+		create(size)
+	}
 }
 
 var V1ZKP = &cobra.Command{
 	Use:   "v1EVM",
 	Short: "Initialize the EVM Version 1  Zero Knowledge Prover",
-	Run:   runV1ZKPCommand,
+	Run:   runWithPodSize(v1.CreateVkPkNew),
 }
 var V1ZKPWasm = &cobra.Command{
 	Use:   "v1WASM",
 	Short: "Initialize the Wasm Version 1  Zero Knowledge Prover",
-	Run:   runV1WasmZKPCommand,
+	Run:   runWithPodSize(v1Wasm.CreateVkPkWasm),
+}
+var V1ZKPSVM = &cobra.Command{
+	Use:   "v1SVM",
+	Short: "Initialize the SVM Version 1  Zero Knowledge Prover",
+	Run:   runWithPodSize(v1SVM.CreateVkPkSVM),
 }
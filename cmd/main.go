@@ -1,12 +1,15 @@
 package main
 
 import (
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
 	"github.com/airchains-network/decentralized-sequencer/cmd/command"
 	"github.com/airchains-network/decentralized-sequencer/cmd/command/keys"
 	"github.com/airchains-network/decentralized-sequencer/cmd/command/zkpCmd"
+	"github.com/airchains-network/decentralized-sequencer/config"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/spf13/cobra"
 	"os"
+	"strings"
 )
 
 func main() {
@@ -21,11 +24,18 @@ func main() {
 	rootCmd.AddCommand(command.ProverGenCMD)
 	rootCmd.AddCommand(command.CreateStation)
 	rootCmd.AddCommand(command.Rollback)
+	rootCmd.AddCommand(command.BlocksyncCmd)
+	rootCmd.AddCommand(command.SnapshotCmd)
 
 	command.KeyGenCmd.AddCommand(keys.JunctionKeyGenCmd)
 	command.KeyGenCmd.AddCommand(keys.JunctionKeyImportCmd)
 	command.ProverGenCMD.AddCommand(zkpCmd.V1ZKP)
 	command.ProverGenCMD.AddCommand(zkpCmd.V1ZKPWasm)
+	command.ProverGenCMD.AddCommand(zkpCmd.V1ZKPSVM)
+	command.BlocksyncCmd.AddCommand(command.BackfillCmd)
+	command.BlocksyncCmd.AddCommand(command.VerifyCmd)
+	command.SnapshotCmd.AddCommand(command.SnapshotExportCmd)
+	command.SnapshotCmd.AddCommand(command.SnapshotImportCmd)
 
 	keys.JunctionKeyGenCmd.Flags().String("accountName", "", "Account Name")
 	keys.JunctionKeyGenCmd.Flags().String("accountPath", "", "Account Path")
@@ -40,12 +50,13 @@ func main() {
 	keys.JunctionKeyImportCmd.MarkFlagRequired("mnemonic")
 
 	command.InitCmd.Flags().String("moniker", "", "Moniker for the Tracks")
-	command.InitCmd.Flags().String("stationType", "", "Station Type for the Tracks (evm | cosmwasm | svm)")
+	command.InitCmd.Flags().String("stationType", "", "Station Type for the Tracks ("+strings.Join(blocksync.SupportedStationTypes(), " | ")+")")
 	command.InitCmd.Flags().String("daType", "mock", "DA Type for the Tracks (avail | celestia | eigen | mock)")
 	command.InitCmd.Flags().String("daRpc", "", "DA RPC for the Tracks")
 	command.InitCmd.Flags().String("daKey", "", "DA Key for the Tracks")
-	command.InitCmd.Flags().String("stationRpc", "", "Station RPC for the Tracks")
-	command.InitCmd.Flags().String("stationAPI", "", "Station API for the Tracks")
+	command.InitCmd.Flags().String("stationRpc", "", "Station RPC for the Tracks, comma separated for failover endpoints")
+	command.InitCmd.Flags().String("stationAPI", "", "Station API for the Tracks, comma separated for failover endpoints")
+	command.InitCmd.Flags().String("stationWS", "", "Station websocket endpoint for new block subscriptions (optional)")
 	command.InitCmd.MarkFlagRequired("moniker")
 	command.InitCmd.MarkFlagRequired("daRpc")
 	command.InitCmd.MarkFlagRequired("daKey")
@@ -53,12 +64,32 @@ func main() {
 	command.InitCmd.MarkFlagRequired("stationRpc")
 	command.InitCmd.MarkFlagRequired("stationAPI")
 
+	command.InitCmd.Flags().Int("podSize", config.PODSize, "Transactions per pod of the station")
+
+	zkpCmd.V1ZKP.Flags().Int("podSize", 0, "Transactions per pod, 0 for the podSize of the station config")
+	zkpCmd.V1ZKPWasm.Flags().Int("podSize", 0, "Transactions per pod, 0 for the podSize of the station config")
+	zkpCmd.V1ZKPSVM.Flags().Int("podSize", 0, "Transactions per pod, 0 for the podSize of the station config")
+
+	command.BackfillCmd.Flags().Int("from", 0, "First block to backfill")
+	command.BackfillCmd.Flags().Int("to", -1, "Last block to backfill, -1 for the latest indexed block")
+	command.BackfillCmd.MarkFlagRequired("from")
+	command.VerifyCmd.Flags().Int("from", 0, "First block to verify")
+	command.VerifyCmd.Flags().Int("to", -1, "Last block to verify, -1 for the latest indexed block")
+
+	command.SnapshotExportCmd.Flags().String("output", "", "Path of the archive to write")
+	command.SnapshotExportCmd.Flags().String("home", "", "Tracks home directory (default ~/.tracks)")
+	command.SnapshotExportCmd.MarkFlagRequired("output")
+	command.SnapshotImportCmd.Flags().String("archive", "", "Path of the archive to restore")
+	command.SnapshotImportCmd.Flags().String("home", "", "Tracks home directory to restore into (default ~/.tracks)")
+	command.SnapshotImportCmd.MarkFlagRequired("archive")
+
 	command.CreateStation.Flags().String("info", "", "Station information")
 	command.CreateStation.Flags().String("accountName", "", "Station Account Name")
 	command.CreateStation.Flags().String("accountPath", "", "Station Account Path")
 	command.CreateStation.Flags().String("jsonRPC", "", "Station JSON RPC")
 	command.CreateStation.Flags().StringSlice("tracks", []string{}, "tracks array for this station")
 	command.CreateStation.Flags().StringSlice("bootstrapNode", []string{}, "Bootstrap Node for the Tracks")
+	command.CreateStation.Flags().Int("podSize", 0, "Transactions per pod, 0 for the podSize of the station config")
 
 	command.CreateStation.MarkFlagRequired("info")
 	command.CreateStation.MarkFlagRequired("accountName")
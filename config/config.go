@@ -9,7 +9,9 @@ import (
 )
 
 const (
-	PODSize                       = 25 // P0D Size
+	PODSize                       = 25   // Default pod size, and the size of pods built before it was configurable
+	MaxPODSize                    = 1000 // Largest pod size circuits are compiled for
+	DefaultFinalityConfirmations  = 12   // Blocks on top of a block before its transactions go into pods
 	defaultMoniker                = "tracks"
 	DefaultTracksDir              = ".tracks"
 	DefaultConfigDir              = "config"
@@ -228,17 +230,91 @@ func DefaultDAConfig() *DAConfig {
 }
 
 type StationConfig struct {
-	StationType string
-	StationRPC  string
-	StationAPI  string
+	StationType        string
+	StationRPC         string // Comma separated, later endpoints are failovers
+	StationAPI         string // Comma separated, later endpoints are failovers
+	StationWS          string // Optional websocket endpoint for new block subscriptions
+	IndexerConcurrency int    // Number of blocks fetched from the station in parallel
+	// StationRPCTimeout bounds a single request to a station endpoint, StationRPCRetries
+	// is the number of retries of a failed request (-1 disables them) and
+	// StationRPCRateLimit caps the requests per second sent to each endpoint (0 is
+	// unlimited).
+	StationRPCTimeout   time.Duration
+	StationRPCRetries   int
+	StationRPCRateLimit float64
+	// Finality decides when an indexed block may be included in a pod: "confirmations"
+	// (FinalityConfirmations blocks on top), "tag" (EVM FinalityTag block) or
+	// "commitment" (SVM blocks fetched at FinalityCommitment).
+	Finality              string
+	FinalityConfirmations int
+	FinalityTag           string // finalized or safe
+	FinalityCommitment    string // finalized or confirmed
+	// Pruning deletes the indexed blocks and transactions of verified pods: "nothing",
+	// "keep-recent" (all but the PruningKeepRecent latest verified pods) or
+	// "everything-verified". The pruner runs every PruningInterval.
+	Pruning           string
+	PruningKeepRecent int
+	PruningInterval   time.Duration
+	// The inclusion policy decides which indexed transactions go into pods. Every track
+	// of a station must run the same policy.
+	InclusionValueTransfersOnly bool
+	InclusionSuccessfulOnly     bool
+	InclusionAllowContracts     []string // Only these contracts may be called or deployed
+	InclusionDenyContracts      []string
+	// StateTracker keeps the balances and nonces of the station accounts locally, from
+	// the StateTrackerGenesis file on, and pod builders read them instead of querying
+	// an archive node. StateTrackerCheckRate is the share of the accounts of each block
+	// that are cross-checked against the station; differences are only logged (0 disables
+	// the checks).
+	StateTracker          bool
+	StateTrackerGenesis   string
+	StateTrackerCheckRate float64
+	// MaxPodInterval seals a pod that is not full once the finalized station block is
+	// this far past the block of its first transaction, so pods of a quiet station are
+	// not held back until enough transactions arrive. 0 waits for full pods. Every track
+	// of a station must use the same value.
+	MaxPodInterval time.Duration
+	// PodSize is the number of transactions in a pod, chosen when the station is created.
+	// The circuit keys are generated for it, so every track of a station must use the
+	// same value. 0 is PODSize.
+	PodSize int
+}
+
+// StationPodSize returns the pod size of the station.
+func (c *StationConfig) StationPodSize() int {
+	if c == nil || c.PodSize == 0 {
+		return PODSize
+	}
+	return c.PodSize
 }
 
 // DefaultStationConfig returns a default configuration for the station.
 func DefaultStationConfig() *StationConfig {
 	return &StationConfig{
-		StationType: "",
-		StationRPC:  "",
-		StationAPI:  "",
+		StationType:                 "",
+		StationRPC:                  "",
+		StationAPI:                  "",
+		StationWS:                   "",
+		StationRPCTimeout:           15 * time.Second,
+		StationRPCRetries:           3,
+		StationRPCRateLimit:         0,
+		IndexerConcurrency:          8,
+		Finality:                    "confirmations",
+		FinalityConfirmations:       DefaultFinalityConfirmations,
+		FinalityTag:                 "finalized",
+		FinalityCommitment:          "finalized",
+		Pruning:                     "nothing",
+		PruningKeepRecent:           100,
+		PruningInterval:             10 * time.Minute,
+		InclusionValueTransfersOnly: false,
+		InclusionSuccessfulOnly:     false,
+		InclusionAllowContracts:     []string{},
+		InclusionDenyContracts:      []string{},
+		StateTracker:                false,
+		StateTrackerGenesis:         "",
+		StateTrackerCheckRate:       0.05,
+		MaxPodInterval:              0,
+		PodSize:                     PODSize,
 	}
 }
 
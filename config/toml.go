@@ -154,8 +154,29 @@ rpc_servers = [{{ range .StateSync.RPCServers }} "{{ . }}", {{ end }}]
 temp_dir = "{{ .StateSync.TempDir }}"
 
 [station]
+finality = "{{ .Station.Finality }}"
+finalityCommitment = "{{ .Station.FinalityCommitment }}"
+finalityConfirmations = {{ .Station.FinalityConfirmations }}
+finalityTag = "{{ .Station.FinalityTag }}"
+inclusionAllowContracts = [{{ range .Station.InclusionAllowContracts }} "{{ . }}", {{ end }}]
+inclusionDenyContracts = [{{ range .Station.InclusionDenyContracts }} "{{ . }}", {{ end }}]
+inclusionSuccessfulOnly = {{ .Station.InclusionSuccessfulOnly }}
+inclusionValueTransfersOnly = {{ .Station.InclusionValueTransfersOnly }}
+indexerConcurrency = {{ .Station.IndexerConcurrency }}
+maxPodInterval = "{{ .Station.MaxPodInterval }}"
+podSize = {{ .Station.PodSize }}
+pruning = "{{ .Station.Pruning }}"
+pruningInterval = "{{ .Station.PruningInterval }}"
+pruningKeepRecent = {{ .Station.PruningKeepRecent }}
+stateTracker = {{ .Station.StateTracker }}
+stateTrackerCheckRate = {{ .Station.StateTrackerCheckRate }}
+stateTrackerGenesis = "{{ .Station.StateTrackerGenesis }}"
 stationAPI = "{{ .Station.StationAPI }}"
 stationRPC = "{{ .Station.StationRPC }}"
+stationRPCRateLimit = {{ .Station.StationRPCRateLimit }}
+stationRPCRetries = {{ .Station.StationRPCRetries }}
+stationRPCTimeout = "{{ .Station.StationRPCTimeout }}"
 stationType = "{{ .Station.StationType }}"
+stationWS = "{{ .Station.StationWS }}"
 
 `
@@ -41,7 +41,7 @@ func MockDA(mdb *leveldb.DB, daData []byte, batchNumber int) (string, error) {
 		return "", fmt.Errorf("error putting data into mock db: %v", dbErr)
 	}
 
-	_ = fmt.Sprintf("da_id : %d, commitment : %s", dbName, hashString)
+	_ = fmt.Sprintf("da_id : %s, commitment : %s", dbName, hashString)
 
 	return dbName, nil
 }
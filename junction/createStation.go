@@ -33,7 +33,7 @@ type JunctionConfig struct {
 	Tracks        []string
 }
 
-func CreateStation(extraArg junctionTypes.StationArg, stationId string, stationInfo types.StationInfo, accountName, accountPath, jsonRPC string, verificationKey groth16.VerifyingKey, addressPrefix string, tracks []string, bootstrapNode []string) bool {
+func CreateStation(extraArg types.StationArg, stationId string, stationInfo types.StationInfo, accountName, accountPath, jsonRPC string, verificationKey groth16.VerifyingKey, addressPrefix string, tracks []string, bootstrapNode []string) bool {
 
 	verificationKeyByte, err := json.Marshal(verificationKey)
 	if err != nil {
@@ -273,6 +273,9 @@ func CreateStation(extraArg junctionTypes.StationArg, stationId string, stationI
 	conf.Junction.AccountPath = accountPath
 	conf.Junction.AccountName = accountName
 	conf.Junction.Tracks = tracks
+	if conf.Station != nil {
+		conf.Station.PodSize = extraArg.PodSize
+	}
 
 	// Marshal the struct to TOML
 	f, err := os.Create(ConfigFilePath)
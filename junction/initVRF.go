@@ -167,8 +167,9 @@ func InitVRF() (success bool, addr string) {
 			}
 		} else {
 			// update transaction hash in current pod
-			currentPodState.VRFInitiationTxHash = txRes.TxHash
-			shared.SetPodState(currentPodState)
+			shared.UpdatePodState(podNumber, func(state *shared.PodState) {
+				state.VRFInitiationTxHash = txRes.TxHash
+			})
 			log.Info().Str("module", "junction").Str("txHash", txRes.TxHash).Msg("VRF Initiated Successfully")
 			return true, newTempAddr
 		}
@@ -7,7 +7,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/airchains-network/decentralized-sequencer/config"
-	junctionTypes "github.com/airchains-network/decentralized-sequencer/junction/types"
 	logs "github.com/airchains-network/decentralized-sequencer/log"
 	"github.com/airchains-network/decentralized-sequencer/node/shared"
 	"github.com/airchains-network/decentralized-sequencer/types"
@@ -20,7 +19,7 @@ import (
 	"path/filepath"
 )
 
-func CreateGenesisJson(stationInfo types.StationInfo, verificationKey groth16.VerifyingKey, stationId string, tracks []string, tracksVotingPower []uint64, txHash string, transactionTime string, extraArg junctionTypes.StationArg, creator string) (success bool) {
+func CreateGenesisJson(stationInfo types.StationInfo, verificationKey groth16.VerifyingKey, stationId string, tracks []string, tracksVotingPower []uint64, txHash string, transactionTime string, extraArg types.StationArg, creator string) (success bool) {
 
 	genesisData := types.GenesisDataType{
 		StationId:          stationId,
@@ -314,3 +313,20 @@ func GetJunctionDetails() (JsonRPC, StationId, AccountPath, AccountName, Address
 	// Return the StationId from the unmarshaled data
 	return JsonRPC, StationId, AccountPath, AccountName, AddressPrefix, Tracks, nil
 }
+
+// ReadGenesisJson reads the station genesis file written by CreateGenesisJson.
+func ReadGenesisJson() (types.GenesisDataType, error) {
+	var genesisData types.GenesisDataType
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return genesisData, fmt.Errorf("error in getting home dir path: %w", err)
+	}
+	jsonBytes, err := os.ReadFile(filepath.Join(homeDir, config.DefaultGenesisFilePath))
+	if err != nil {
+		return genesisData, fmt.Errorf("error reading the station genesis: %w", err)
+	}
+	if err := json.Unmarshal(jsonBytes, &genesisData); err != nil {
+		return genesisData, fmt.Errorf("error decoding the station genesis: %w", err)
+	}
+	return genesisData, nil
+}
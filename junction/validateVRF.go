@@ -121,8 +121,9 @@ func ValidateVRF(addr string) bool {
 			}
 		} else {
 			// update VRN verified hash
-			currentPodState.VRFValidationTxHash = txRes.TxHash
-			shared.SetPodState(currentPodState)
+			shared.UpdatePodState(podNumber, func(state *shared.PodState) {
+				state.VRFValidationTxHash = txRes.TxHash
+			})
 			log.Info().Str("module", "junction").Str("txHash", txRes.TxHash).Msg("VRF Validated Tx Success")
 			return true
 		}
@@ -118,9 +118,9 @@ func VerifyCurrentPod() (success bool) {
 			time.Sleep(10 * time.Second)
 			//return false
 		} else {
-			VerifyPodTxHash := txRes.TxHash
-			currentPodState.VerifyPodTxHash = VerifyPodTxHash
-			shared.SetPodState(currentPodState)
+			shared.UpdatePodState(podNumber, func(state *shared.PodState) {
+				state.VerifyPodTxHash = txRes.TxHash
+			})
 			log.Info().Str("module", "junction").Str("txHash", txRes.TxHash).Msg("Pod Verification Tx Success")
 			return true
 		}
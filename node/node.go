@@ -8,7 +8,6 @@ import (
 	"github.com/airchains-network/decentralized-sequencer/node/shared"
 	"github.com/airchains-network/decentralized-sequencer/p2p"
 	"github.com/airchains-network/decentralized-sequencer/rpc"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/syndtr/goleveldb/leveldb"
 	"sync"
 	"time"
@@ -49,13 +48,6 @@ func beginDBIndexingOperations(wg *sync.WaitGroup) {
 	txnDB := connection.GetTxnDatabaseConnection()
 	shared.CheckAndInitializeDBCounters(staticDB)
 	latestBlock := shared.GetLatestBlock(blockDB)
-	baseConfig, err := shared.LoadConfig()
-
-	client, err := ethclient.Dial(baseConfig.Station.StationRPC) // viper.GetString("station.stationRPC"))
-	if err != nil {
-		logs.Log.Error("Error in connecting to the network")
-		return
-	}
 	initializeCounter(staticDB, "batchCount")
 	initializeCounter(staticDB, "batchStartIndex")
 
@@ -66,7 +58,7 @@ func beginDBIndexingOperations(wg *sync.WaitGroup) {
 	//wgnm.Add(1)
 	wgnm.Add(3)
 
-	go blocksync.StartIndexer(wgnm, client, ctx, blockDB, txnDB, latestBlock)
+	go blocksync.StartIndexer(wgnm, ctx, blockDB, txnDB, latestBlock)
 	go p2p.BatchGeneration(wgnm)
 	go rpc.StartRPC(wgnm)
 	wgnm.Wait()
@@ -21,13 +21,6 @@ import (
 var (
 	Node *NodeS
 	mu   sync.Mutex
-
-	// txStates
-	TxStatePreInit   = "PreInit"
-	TxStateInitVRF   = "InitVRF"
-	TxStateVerifyVRF = "VerifyVRF"
-	TxStateSubmitPod = "InitPod"
-	TxStateVerifyPod = "VerifyPod"
 )
 
 type Votes struct {
@@ -37,7 +30,7 @@ type Votes struct {
 }
 type PodState struct {
 	LatestPodHeight     uint64
-	LatestTxState       string // InitVRF / VerifyVRF / InitPod / VerifyPod
+	LatestTxState       PodPhase
 	LatestPodHash       []byte
 	PreviousPodHash     []byte
 	LatestPodProof      []byte
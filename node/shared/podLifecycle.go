@@ -0,0 +1,293 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	logs "github.com/airchains-network/decentralized-sequencer/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"sync"
+	"time"
+)
+
+// PodPhase is the step of the pod lifecycle the latest pod of a track is in. Phases are
+// stored in the pod state, so their values must not change.
+type PodPhase string
+
+const (
+	TxStatePreInit   PodPhase = "PreInit"
+	TxStateInitVRF   PodPhase = "InitVRF"
+	TxStateVerifyVRF PodPhase = "VerifyVRF"
+	TxStateSubmitPod PodPhase = "InitPod"
+	TxStateVerifyPod PodPhase = "VerifyPod"
+)
+
+// PodEvent is something that happened to the latest pod and may move it to another phase.
+type PodEvent string
+
+const (
+	EventPodBuilt     PodEvent = "PodBuilt"
+	EventVRFInitiated PodEvent = "VRFInitiated"
+	EventVRFValidated PodEvent = "VRFValidated"
+	EventPodSubmitted PodEvent = "PodSubmitted"
+	EventPodVerified  PodEvent = "PodVerified"
+)
+
+// PodTransition moves a pod in phase From to phase To when Event happens.
+type PodTransition struct {
+	From  PodPhase
+	Event PodEvent
+	To    PodPhase
+}
+
+// PodTransitions are the legal transitions of the pod lifecycle. A track that is not
+// taking part in a step learns about the verification of the pod from gossip or from
+// junction, so a pod may be verified from any phase after it was built.
+var PodTransitions = []PodTransition{
+	{From: TxStatePreInit, Event: EventPodBuilt, To: TxStateInitVRF},
+	{From: TxStateInitVRF, Event: EventVRFInitiated, To: TxStateVerifyVRF},
+	{From: TxStateVerifyVRF, Event: EventVRFValidated, To: TxStateSubmitPod},
+	{From: TxStateSubmitPod, Event: EventPodSubmitted, To: TxStateVerifyPod},
+	{From: TxStateInitVRF, Event: EventPodVerified, To: TxStatePreInit},
+	{From: TxStateVerifyVRF, Event: EventPodVerified, To: TxStatePreInit},
+	{From: TxStateSubmitPod, Event: EventPodVerified, To: TxStatePreInit},
+	{From: TxStateVerifyPod, Event: EventPodVerified, To: TxStatePreInit},
+}
+
+// ErrIllegalPodTransition is returned by Fire for an event that has no transition from
+// the current phase.
+var ErrIllegalPodTransition = errors.New("illegal pod transition")
+
+// PodGuard decides whether a transition may happen to state. A guard returning an error
+// rejects the transition. Guards run without holding the pod state, so they may query
+// junction; state is a copy.
+type PodGuard func(state *PodState) error
+
+// PodAction runs when a pod enters or leaves a phase.
+type PodAction func(state *PodState) error
+
+// PodJournalEntry records one transition of a pod.
+type PodJournalEntry struct {
+	Pod   uint64
+	Seq   int
+	From  PodPhase
+	Event PodEvent
+	To    PodPhase
+	Time  time.Time
+}
+
+// podJournalPrefix returns the key prefix of the journal entries of pod.
+func podJournalPrefix(pod uint64) string {
+	return fmt.Sprintf("podjournal-%d-", pod)
+}
+
+// PodJournal returns the recorded transitions of pod, oldest first.
+func PodJournal(db *leveldb.DB, pod uint64) ([]PodJournalEntry, error) {
+	iter := db.NewIterator(util.BytesPrefix([]byte(podJournalPrefix(pod))), nil)
+	defer iter.Release()
+	var journal []PodJournalEntry
+	for iter.Next() {
+		var entry PodJournalEntry
+		if err := json.Unmarshal(iter.Value(), &entry); err != nil {
+			return nil, fmt.Errorf("error unmarshalling journal entry %s: %w", iter.Key(), err)
+		}
+		journal = append(journal, entry)
+	}
+	return journal, iter.Error()
+}
+
+// podStateMu serializes the changes of the pod state of the node: the transitions of
+// the lifecycle, UpdatePodState and ReplacePodState. Without it a transition working on
+// a copy of the state would overwrite a change made in the meantime.
+var podStateMu sync.Mutex
+
+// UpdatePodState applies update to a copy of the state of pod and makes it the pod state
+// of the node. It does nothing, and returns false, once pod is no longer the latest pod.
+// It must not be called from an action of the lifecycle.
+func UpdatePodState(pod uint64, update func(state *PodState)) bool {
+	podStateMu.Lock()
+	defer podStateMu.Unlock()
+	state := *GetPodState()
+	if state.LatestPodHeight != pod {
+		return false
+	}
+	update(&state)
+	SetPodState(&state)
+	return true
+}
+
+// ReplacePodState makes state the pod state of the node, for a new pod. It must not be
+// called from an action of the lifecycle.
+func ReplacePodState(state *PodState) {
+	podStateMu.Lock()
+	defer podStateMu.Unlock()
+	SetPodState(state)
+}
+
+// PodLifecycle is the state machine of the latest pod. It reads and replaces the pod
+// state of the node, and stores the pod state and a journal entry of every transition
+// in db together.
+type PodLifecycle struct {
+	db          *leveldb.DB
+	transitions map[PodPhase]map[PodEvent]PodPhase
+	guards      map[PodEvent][]PodGuard
+	onEnter     map[PodPhase][]PodAction
+	onExit      map[PodPhase][]PodAction
+}
+
+// NewPodLifecycle returns a lifecycle with PodTransitions, persisting to the state db.
+func NewPodLifecycle(db *leveldb.DB) *PodLifecycle {
+	l := &PodLifecycle{
+		db:          db,
+		transitions: make(map[PodPhase]map[PodEvent]PodPhase),
+		guards:      make(map[PodEvent][]PodGuard),
+		onEnter:     make(map[PodPhase][]PodAction),
+		onExit:      make(map[PodPhase][]PodAction),
+	}
+	for _, t := range PodTransitions {
+		if l.transitions[t.From] == nil {
+			l.transitions[t.From] = make(map[PodEvent]PodPhase)
+		}
+		l.transitions[t.From][t.Event] = t.To
+	}
+	return l
+}
+
+// Guard adds a guard checked before every transition on event.
+func (l *PodLifecycle) Guard(event PodEvent, guard PodGuard) {
+	l.guards[event] = append(l.guards[event], guard)
+}
+
+// OnEnter adds an action run after a pod entered phase. The transition is already
+// stored when it runs, and it runs again by Resume, so it must be idempotent.
+func (l *PodLifecycle) OnEnter(phase PodPhase, action PodAction) {
+	l.onEnter[phase] = append(l.onEnter[phase], action)
+}
+
+// OnExit adds an action run before a pod leaves phase. An error cancels the transition.
+func (l *PodLifecycle) OnExit(phase PodPhase, action PodAction) {
+	l.onExit[phase] = append(l.onExit[phase], action)
+}
+
+// Phase returns the phase of the latest pod. A state without one has not built a pod.
+func (l *PodLifecycle) Phase() PodPhase {
+	return phaseOf(GetPodState())
+}
+
+func phaseOf(state *PodState) PodPhase {
+	if state.LatestTxState == "" {
+		return TxStatePreInit
+	}
+	return state.LatestTxState
+}
+
+// Can reports whether event has a transition from the current phase.
+func (l *PodLifecycle) Can(event PodEvent) bool {
+	podStateMu.Lock()
+	defer podStateMu.Unlock()
+	_, ok := l.transitions[l.Phase()][event]
+	return ok
+}
+
+// Fire moves the latest pod along the transition of event. Illegal transitions and
+// transitions rejected by a guard or an exit action are logged and leave the pod as it
+// is, as does a pod that moved on while the guards ran.
+func (l *PodLifecycle) Fire(event PodEvent) error {
+	podStateMu.Lock()
+	checked := *GetPodState()
+	podStateMu.Unlock()
+	from := phaseOf(&checked)
+	if _, ok := l.transitions[from][event]; !ok {
+		logs.Log.Warn(fmt.Sprintf("Rejected %s of pod %d in phase %s", event, checked.LatestPodHeight, from))
+		return fmt.Errorf("%w: %s of pod %d in phase %s", ErrIllegalPodTransition, event, checked.LatestPodHeight, from)
+	}
+	for _, guard := range l.guards[event] {
+		if err := guard(&checked); err != nil {
+			logs.Log.Warn(fmt.Sprintf("Rejected %s of pod %d in phase %s: %s", event, checked.LatestPodHeight, from, err.Error()))
+			return fmt.Errorf("%s of pod %d rejected: %w", event, checked.LatestPodHeight, err)
+		}
+	}
+
+	podStateMu.Lock()
+	defer podStateMu.Unlock()
+	state := *GetPodState()
+	if state.LatestPodHeight != checked.LatestPodHeight || phaseOf(&state) != from {
+		logs.Log.Warn(fmt.Sprintf("Rejected %s of pod %d in phase %s: the pod moved on to %d in phase %s", event, checked.LatestPodHeight, from, state.LatestPodHeight, phaseOf(&state)))
+		return fmt.Errorf("%w: %s of pod %d in phase %s, the pod moved on", ErrIllegalPodTransition, event, checked.LatestPodHeight, from)
+	}
+	to := l.transitions[from][event]
+	for _, action := range l.onExit[from] {
+		if err := action(&state); err != nil {
+			logs.Log.Warn(fmt.Sprintf("Failed to leave phase %s of pod %d: %s", from, state.LatestPodHeight, err.Error()))
+			return fmt.Errorf("leaving phase %s of pod %d: %w", from, state.LatestPodHeight, err)
+		}
+	}
+
+	state.LatestTxState = to
+	if err := l.store(&state, from, event); err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in storing %s of pod %d: %s", event, state.LatestPodHeight, err.Error()))
+		return err
+	}
+	SetPodState(&state)
+	logs.Log.Info(fmt.Sprintf("Pod %d moved from %s to %s on %s", state.LatestPodHeight, from, to, event))
+
+	l.enter(to)
+	return nil
+}
+
+// store writes state and the journal entry of its transition in one batch.
+func (l *PodLifecycle) store(state *PodState, from PodPhase, event PodEvent) error {
+	journal, err := PodJournal(l.db, state.LatestPodHeight)
+	if err != nil {
+		return err
+	}
+	entry := PodJournalEntry{
+		Pod:   state.LatestPodHeight,
+		Seq:   len(journal) + 1,
+		From:  from,
+		Event: event,
+		To:    state.LatestTxState,
+		Time:  time.Now().UTC(),
+	}
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put([]byte("podState"), stateBytes)
+	batch.Put([]byte(fmt.Sprintf("%s%04d", podJournalPrefix(entry.Pod), entry.Seq)), entryBytes)
+	return l.db.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+// enter runs the entry actions of phase, logging their errors.
+func (l *PodLifecycle) enter(phase PodPhase) {
+	for _, action := range l.onEnter[phase] {
+		if err := action(GetPodState()); err != nil {
+			logs.Log.Error(fmt.Sprintf("Error in entering phase %s of pod %d: %s", phase, GetPodState().LatestPodHeight, err.Error()))
+		}
+	}
+}
+
+// Resume runs the entry actions of the current phase again when the journal shows the
+// latest pod entered it, in case the node stopped before they finished.
+func (l *PodLifecycle) Resume() error {
+	podStateMu.Lock()
+	defer podStateMu.Unlock()
+	state := GetPodState()
+	journal, err := PodJournal(l.db, state.LatestPodHeight)
+	if err != nil {
+		return err
+	}
+	if len(journal) == 0 || journal[len(journal)-1].To != phaseOf(state) {
+		return nil
+	}
+	l.enter(phaseOf(state))
+	return nil
+}
@@ -0,0 +1,200 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+// newTestLifecycle returns a lifecycle on an in-memory state db, with the node pod state
+// set to pod in phase.
+func newTestLifecycle(t *testing.T, pod uint64, phase PodPhase) (*PodLifecycle, *leveldb.DB) {
+	t.Helper()
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	Node = &NodeS{podState: &PodState{LatestPodHeight: pod, LatestTxState: phase}}
+	t.Cleanup(func() { Node = nil })
+	return NewPodLifecycle(db), db
+}
+
+func TestPodLifecycleFire(t *testing.T) {
+	lifecycle, db := newTestLifecycle(t, 3, TxStatePreInit)
+	var entered, exited []PodPhase
+	lifecycle.OnEnter(TxStatePreInit, func(state *PodState) error {
+		entered = append(entered, state.LatestTxState)
+		return nil
+	})
+	lifecycle.OnExit(TxStateVerifyPod, func(state *PodState) error {
+		exited = append(exited, state.LatestTxState)
+		return nil
+	})
+
+	events := []PodEvent{EventPodBuilt, EventVRFInitiated, EventVRFValidated, EventPodSubmitted, EventPodVerified}
+	for _, event := range events {
+		if err := lifecycle.Fire(event); err != nil {
+			t.Fatalf("Fire(%s) error = %v", event, err)
+		}
+	}
+	if lifecycle.Phase() != TxStatePreInit {
+		t.Errorf("phase = %s, want %s", lifecycle.Phase(), TxStatePreInit)
+	}
+	if len(entered) != 1 || len(exited) != 1 || exited[0] != TxStateVerifyPod {
+		t.Errorf("entered %v, exited %v", entered, exited)
+	}
+
+	journal, err := PodJournal(db, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journal) != len(events) {
+		t.Fatalf("journal = %+v, want one entry per event", journal)
+	}
+	for i, entry := range journal {
+		if entry.Pod != 3 || entry.Seq != i+1 || entry.Event != events[i] {
+			t.Errorf("journal[%d] = %+v", i, entry)
+		}
+	}
+	if journal[0].From != TxStatePreInit || journal[4].From != TxStateVerifyPod || journal[4].To != TxStatePreInit {
+		t.Errorf("journal = %+v", journal)
+	}
+
+	stored, err := db.Get([]byte("podState"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state PodState
+	if err := json.Unmarshal(stored, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.LatestTxState != TxStatePreInit || state.LatestPodHeight != 3 {
+		t.Errorf("stored pod state = %+v", state)
+	}
+}
+
+func TestPodLifecycleRejects(t *testing.T) {
+	lifecycle, db := newTestLifecycle(t, 1, TxStateInitVRF)
+	if err := lifecycle.Fire(EventPodSubmitted); !errors.Is(err, ErrIllegalPodTransition) {
+		t.Errorf("Fire(%s) in %s error = %v, want an illegal transition", EventPodSubmitted, TxStateInitVRF, err)
+	}
+	if lifecycle.Can(EventPodSubmitted) || !lifecycle.Can(EventVRFInitiated) {
+		t.Error("Can() does not follow the declared transitions")
+	}
+
+	lifecycle.Guard(EventVRFInitiated, func(*PodState) error { return errors.New("not on junction") })
+	if err := lifecycle.Fire(EventVRFInitiated); err == nil {
+		t.Error("Fire() passed a failing guard")
+	}
+	lifecycle.OnExit(TxStateInitVRF, func(*PodState) error { return errors.New("busy") })
+	if err := lifecycle.Fire(EventPodVerified); err == nil {
+		t.Error("Fire() passed a failing exit action")
+	}
+
+	if lifecycle.Phase() != TxStateInitVRF {
+		t.Errorf("phase = %s after rejected transitions", lifecycle.Phase())
+	}
+	if journal, _ := PodJournal(db, 1); len(journal) != 0 {
+		t.Errorf("journal = %+v, want rejected transitions left out", journal)
+	}
+}
+
+func TestPodLifecycleResume(t *testing.T) {
+	lifecycle, _ := newTestLifecycle(t, 2, TxStateVerifyPod)
+	entered := 0
+	lifecycle.OnEnter(TxStatePreInit, func(*PodState) error {
+		entered++
+		return nil
+	})
+
+	// Nothing to resume before the pod entered the phase through a transition.
+	if err := lifecycle.Resume(); err != nil || entered != 0 {
+		t.Fatalf("Resume() = %v, entered %d times", err, entered)
+	}
+	if err := lifecycle.Fire(EventPodVerified); err != nil {
+		t.Fatal(err)
+	}
+	if err := lifecycle.Resume(); err != nil || entered != 2 {
+		t.Errorf("Resume() = %v, entered %d times, want the entry action run again", err, entered)
+	}
+}
+
+func TestPodJournalOfOtherPods(t *testing.T) {
+	lifecycle, db := newTestLifecycle(t, 1, TxStatePreInit)
+	if err := lifecycle.Fire(EventPodBuilt); err != nil {
+		t.Fatal(err)
+	}
+	GetPodState().LatestPodHeight = 10
+	if err := lifecycle.Fire(EventPodVerified); err != nil {
+		t.Fatal(err)
+	}
+	if journal, _ := PodJournal(db, 1); len(journal) != 1 || journal[0].Event != EventPodBuilt {
+		t.Errorf("journal of pod 1 = %+v, want its own entry only", journal)
+	}
+}
+
+func TestUpdatePodStateDuringFire(t *testing.T) {
+	lifecycle, db := newTestLifecycle(t, 3, TxStateInitVRF)
+	// A gossip handler records a tx hash while a guard of the transition runs.
+	updated := make(chan bool)
+	lifecycle.Guard(EventVRFInitiated, func(state *PodState) error {
+		go func() {
+			updated <- UpdatePodState(3, func(state *PodState) { state.VRFInitiationTxHash = "hash" })
+		}()
+		return nil
+	})
+	if err := lifecycle.Fire(EventVRFInitiated); err != nil {
+		t.Fatal(err)
+	}
+	if !<-updated {
+		t.Fatal("UpdatePodState() = false, want true")
+	}
+	if state := GetPodState(); state.VRFInitiationTxHash != "hash" || state.LatestTxState != TxStateVerifyVRF {
+		t.Errorf("pod state = %+v, want the tx hash and the new phase", state)
+	}
+
+	// The transition that follows stores the hash as well.
+	if err := lifecycle.Fire(EventVRFValidated); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := db.Get([]byte("podState"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state PodState
+	if err := json.Unmarshal(stored, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.VRFInitiationTxHash != "hash" {
+		t.Errorf("stored pod state = %+v, want the tx hash", state)
+	}
+
+	if UpdatePodState(2, func(state *PodState) { state.InitPodTxHash = "stale" }) {
+		t.Error("UpdatePodState() of an earlier pod = true, want false")
+	}
+	if GetPodState().InitPodTxHash != "" {
+		t.Errorf("pod state = %+v, want no change for an earlier pod", GetPodState())
+	}
+}
+
+func TestPodLifecycleGuardRunsUnlocked(t *testing.T) {
+	lifecycle, _ := newTestLifecycle(t, 3, TxStateInitVRF)
+	// A slow junction query does not block the pod state, and the pod may move on.
+	lifecycle.Guard(EventVRFInitiated, func(state *PodState) error {
+		if !lifecycle.Can(EventVRFInitiated) {
+			t.Error("Can() from a guard = false, want true")
+		}
+		ReplacePodState(&PodState{LatestPodHeight: 4, LatestTxState: TxStateInitVRF})
+		return nil
+	})
+	if err := lifecycle.Fire(EventVRFInitiated); !errors.Is(err, ErrIllegalPodTransition) {
+		t.Errorf("Fire() error = %v, want ErrIllegalPodTransition for a pod that moved on", err)
+	}
+	if state := GetPodState(); state.LatestPodHeight != 4 || state.LatestTxState != TxStateInitVRF {
+		t.Errorf("pod state = %+v, want the new pod left as it is", state)
+	}
+}
@@ -3,10 +3,6 @@ package p2p
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/airchains-network/decentralized-sequencer/da/avail"
-	"github.com/airchains-network/decentralized-sequencer/da/celestia"
-	"github.com/airchains-network/decentralized-sequencer/da/eigen"
-	mock "github.com/airchains-network/decentralized-sequencer/da/mockda"
 	"github.com/airchains-network/decentralized-sequencer/junction"
 	junctionTypes "github.com/airchains-network/decentralized-sequencer/junction/types"
 	logs "github.com/airchains-network/decentralized-sequencer/log"
@@ -18,7 +14,6 @@ import (
 	"github.com/rs/zerolog/log"
 	"math/rand"
 	"os"
-	"strconv"
 	"time"
 )
 
@@ -132,10 +127,10 @@ func (h *VRFInitiatedMessageHandler) HandleVRFInitiatedMessage() {
 	}
 
 	// all nodes: update vrn init hash
-	currentPodState := shared.GetPodState()
-	VrfInitTxHash := h.message.VrfInitTxHash
-	currentPodState.VRFInitiationTxHash = VrfInitTxHash
-	shared.SetPodState(currentPodState)
+	shared.UpdatePodState(h.message.PodNumber, func(state *shared.PodState) {
+		state.VRFInitiationTxHash = h.message.VrfInitTxHash
+	})
+	postPodEvent(h.message.PodNumber, shared.EventVRFInitiated)
 
 	// selected node
 	if h.message.SelectedTrackAddress == accountDetails.MyAddress {
@@ -231,6 +226,8 @@ func processVerifiedVRF(VRFInitiatedMsg *VRFInitiatedMsgData, ad *AccountDetails
 
 	if SelectedTrackAddress == ad.MyAddress {
 		VRNValidatedMsgHandler(VRFVerifiedMsgByte)
+	} else {
+		postPodEvent(uint64(PodNumber), shared.EventVRFValidated)
 	}
 }
 
@@ -256,10 +253,10 @@ func VRNValidatedMsgHandler(dataByte []byte) {
 	}
 
 	// all nodes: update txHash of vrn validated
-	VRFValidationTxHash := VRNVerifiedMsg.VRFVerifiedTxHash
-	currentPodState := shared.GetPodState()
-	currentPodState.VRFValidationTxHash = VRFValidationTxHash
-	shared.SetPodState(currentPodState)
+	shared.UpdatePodState(VRNVerifiedMsg.PodNumber, func(state *shared.PodState) {
+		state.VRFValidationTxHash = VRNVerifiedMsg.VRFVerifiedTxHash
+	})
+	postPodEvent(VRNVerifiedMsg.PodNumber, shared.EventVRFValidated)
 
 	// check if this node is selected to submit pod & da
 	_, _, accountPath, accountName, addressPrefix, tracks, err := junction.GetJunctionDetails()
@@ -275,140 +272,8 @@ func VRNValidatedMsgHandler(dataByte []byte) {
 	// now check for this pod number, who is the selected track
 	if VRNVerifiedMsg.SelectedTrackAddress == myAddress {
 		// submit data to DA
-		DaData := shared.GetPodState().Batch.TransactionHash
-		daDataByte := []byte{}
-		for _, str := range DaData {
-			daDataByte = append(daDataByte, []byte(str)...)
-		}
-
-		PodNumber := int(shared.GetPodState().LatestPodHeight)
-		connection := shared.Node.NodeConnections
-		DaBatchSaver := connection.DataAvailabilityDatabaseConnection
-
-		baseConfig, err := shared.LoadConfig()
-		if err != nil {
-			fmt.Println("Error loading configuration")
-		}
-		Datype := baseConfig.DA.DaType
-		if Datype == "mock" {
-			mdb := connection.MockDatabaseConnection
-			daCheck, daCheckErr := mock.MockDA(mdb, daDataByte, PodNumber)
-			if daCheckErr != nil {
-				logs.Log.Error("Error in submitting data to DA")
-				return
-			}
-
-			da := types.DAStruct{
-				DAKey:             daCheck,
-				DAClientName:      "mock-da",
-				BatchNumber:       strconv.Itoa(PodNumber),
-				PreviousStateHash: string(shared.GetPodState().PreviousPodHash),
-				CurrentStateHash:  string(shared.GetPodState().TracksAppHash),
-			}
-
-			daStoreKey := fmt.Sprintf("da-%d", PodNumber)
-			daStoreData, daStoreDataErr := json.Marshal(da)
-			if daStoreDataErr != nil {
-				logs.Log.Warn(fmt.Sprintf("Error in marshaling DA pointer : %s", daStoreDataErr.Error()))
-			}
-
-			storeErr := DaBatchSaver.Put([]byte(daStoreKey), daStoreData, nil)
-			if storeErr != nil {
-				logs.Log.Warn(fmt.Sprintf("Error in saving DA pointer in pod database : %s", storeErr.Error()))
-			}
-
-			log.Info().Str("module", "p2p").Msg("Data Saved in DA")
-
-		} else if Datype == "avail" {
-			daCheck, daCheckErr := avail.Avail(daDataByte, baseConfig.DA.DaRPC)
-			if daCheckErr != nil {
-				logs.Log.Warn("Error in submitting data to DA")
-				return
-			}
-
-			da := types.DAStruct{
-				DAKey:             daCheck,
-				DAClientName:      "avail-da",
-				BatchNumber:       strconv.Itoa(PodNumber),
-				PreviousStateHash: string(shared.GetPodState().PreviousPodHash),
-				CurrentStateHash:  string(shared.GetPodState().TracksAppHash),
-			}
-
-			daStoreKey := fmt.Sprintf("da-%d", PodNumber)
-			daStoreData, daStoreDataErr := json.Marshal(da)
-			if daStoreDataErr != nil {
-				logs.Log.Warn(fmt.Sprintf("Error in marshaling DA pointer : %s", daStoreDataErr.Error()))
-			}
-
-			storeErr := DaBatchSaver.Put([]byte(daStoreKey), daStoreData, nil)
-			if storeErr != nil {
-				logs.Log.Warn(fmt.Sprintf("Error in saving DA pointer in pod database : %s", storeErr.Error()))
-			}
-
-			log.Info().Str("module", "p2p").Msg("Data Saved in DA")
-
-		} else if Datype == "celestia" {
-			daCheck, daCheckErr := celestia.Celestia(daDataByte, baseConfig.DA.DaRPC, baseConfig.DA.DaRPC)
-
-			if daCheckErr != nil {
-				logs.Log.Warn("Error in submitting data to DA")
-				return
-			}
-
-			da := types.DAStruct{
-				DAKey:             daCheck,
-				DAClientName:      "celestia-da",
-				BatchNumber:       strconv.Itoa(PodNumber),
-				PreviousStateHash: string(shared.GetPodState().PreviousPodHash),
-				CurrentStateHash:  string(shared.GetPodState().TracksAppHash),
-			}
-
-			daStoreKey := fmt.Sprintf("da-%d", PodNumber)
-			daStoreData, daStoreDataErr := json.Marshal(da)
-			if daStoreDataErr != nil {
-				logs.Log.Warn(fmt.Sprintf("Error in marshaling DA pointer : %s", daStoreDataErr.Error()))
-			}
-
-			storeErr := DaBatchSaver.Put([]byte(daStoreKey), daStoreData, nil)
-			if storeErr != nil {
-				logs.Log.Warn(fmt.Sprintf("Error in saving DA pointer in pod database : %s", storeErr.Error()))
-			}
-
-			log.Info().Str("module", "p2p").Msg("Data Saved in DA")
-
-		} else if Datype == "eigen" {
-			daCheck, daCheckErr := eigen.Eigen(daDataByte,
-				baseConfig.DA.DaRPC, baseConfig.DA.DaRPC,
-			)
-
-			if daCheckErr != nil {
-				logs.Log.Warn("Error in submitting data to DA")
-				return
-			}
-
-			da := types.DAStruct{
-				DAKey:             daCheck,
-				DAClientName:      "eigen-da",
-				BatchNumber:       strconv.Itoa(PodNumber),
-				PreviousStateHash: string(shared.GetPodState().PreviousPodHash),
-				CurrentStateHash:  string(shared.GetPodState().TracksAppHash),
-			}
-
-			daStoreKey := fmt.Sprintf("da-%d", PodNumber)
-			daStoreData, daStoreDataErr := json.Marshal(da)
-			if daStoreDataErr != nil {
-				logs.Log.Warn(fmt.Sprintf("Error in marshaling DA pointer : %s", daStoreDataErr.Error()))
-			}
-
-			storeErr := DaBatchSaver.Put([]byte(daStoreKey), daStoreData, nil)
-			if storeErr != nil {
-				logs.Log.Warn(fmt.Sprintf("Error in saving DA pointer in pod database : %s", storeErr.Error()))
-			}
-
-			log.Info().Str("module", "p2p").Msg("Data Saved in DA")
-
-		} else {
-			logs.Log.Error("Unknown layer. Please use 'avail' or 'celestia' as argument.")
+		if err := submitPodToDA(shared.GetPodState()); err != nil {
+			logs.Log.Error("Error in submitting data to DA: " + err.Error())
 			return
 		}
 
@@ -451,6 +316,7 @@ func VRNValidatedMsgHandler(dataByte []byte) {
 			return
 		}
 		BroadcastMessage(CTX, Node, gossipMsgByte)
+		postPodEvent(podNumber, shared.EventPodSubmitted)
 	}
 	return
 }
@@ -497,10 +363,10 @@ func (h *PodSubmittedMessageHandler) processPodSubmission() {
 	}
 
 	// all nodes: update initPodTxHash
-	currentPodState := shared.GetPodState()
-	InitPodTxHash := h.message.InitPodTxHash
-	currentPodState.InitPodTxHash = InitPodTxHash
-	shared.SetPodState(currentPodState)
+	shared.UpdatePodState(h.message.PodNumber, func(state *shared.PodState) {
+		state.InitPodTxHash = h.message.InitPodTxHash
+	})
+	postPodEvent(h.message.PodNumber, shared.EventPodSubmitted)
 
 	if h.message.SelectedTrackAddress == myAddress {
 		h.verifyAndBroadcastPod()
@@ -548,9 +414,8 @@ func (h *PodSubmittedMessageHandler) broadcastPodVerifiedMessage() {
 		logs.Log.Error(LogMarshalGossipMsg)
 		return
 	}
-	saveVerifiedPOD()
 	BroadcastMessage(CTX, Node, gossipMsgByte)
-	GenerateUnverifiedPods()
+	postPodEvent(podNumber, shared.EventPodVerified)
 }
 
 // NewPodVerifiedMessageHandler takes in a byte slice and returns a new instance of PodVerifiedMessageHandler
@@ -581,10 +446,9 @@ func (h *PodVerifiedMessageHandler) HandlePodMessage() {
 	}
 
 	// update pod verified
-	podState := shared.GetPodState()
-	VerifyPodTxHash := shared.GetPodState().VerifyPodTxHash
-	podState.VerifyPodTxHash = VerifyPodTxHash
-	shared.SetPodState(podState)
+	shared.UpdatePodState(h.message.PodNumber, func(state *shared.PodState) {
+		state.VerifyPodTxHash = h.message.PodVerifiedTxHash
+	})
 
 	logs.Log.Warn(LogPodMatchSuccess)
 	h.handleVerificationResult()
@@ -601,9 +465,7 @@ func (h *PodVerifiedMessageHandler) handleVerificationResult() {
 
 	if h.message.VerificationResult {
 		logs.Log.Info(LogPodSave)
-		saveVerifiedPOD() // save the latest pod details and make next pod
-		logs.Log.Info(LogPodGenNext)
-		GenerateUnverifiedPods() // generate next pod
+		postPodEvent(h.message.PodNumber, shared.EventPodVerified)
 	} else {
 		logs.Log.Error(LogPodFail)
 	}
@@ -2,13 +2,14 @@ package p2p
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
 	"github.com/airchains-network/decentralized-sequencer/da/avail"
 	"github.com/airchains-network/decentralized-sequencer/da/celestia"
 	"github.com/airchains-network/decentralized-sequencer/da/eigen"
 	mock "github.com/airchains-network/decentralized-sequencer/da/mockda"
 	"github.com/airchains-network/decentralized-sequencer/junction"
-	junctionTypes "github.com/airchains-network/decentralized-sequencer/junction/types"
 	logs "github.com/airchains-network/decentralized-sequencer/log"
 	"github.com/airchains-network/decentralized-sequencer/node/shared"
 	"github.com/airchains-network/decentralized-sequencer/types"
@@ -23,14 +24,188 @@ import (
 	"time"
 )
 
-func BatchGeneration(wg *sync.WaitGroup) {
-	defer wg.Done()
-	GenerateUnverifiedPods()
+const (
+	// podRetryInterval is the wait before a failed step of the pod lifecycle is tried again.
+	podRetryInterval = 10 * time.Second
+	// podPollInterval is the wait between checks of junction for the verification of a
+	// pod this track is waiting on.
+	podPollInterval = 30 * time.Second
+)
+
+// postedPodEvent is an event of a pod learnt outside of the lifecycle loop.
+type postedPodEvent struct {
+	pod   uint64
+	event shared.PodEvent
 }
 
-func GenerateUnverifiedPods() {
+// podEvents carries the events posted by the gossip handlers to the lifecycle loop.
+var podEvents = make(chan postedPodEvent, 64)
+
+// postPodEvent hands event of pod to the lifecycle loop. The gossip handlers must not
+// wait on the loop, so the event is dropped when the loop is behind; the loop learns
+// what it missed from junction.
+func postPodEvent(pod uint64, event shared.PodEvent) {
+	select {
+	case podEvents <- postedPodEvent{pod: pod, event: event}:
+	default:
+		logs.Log.Warn(fmt.Sprintf("Dropped %s of pod %d, the pod lifecycle is behind", event, pod))
+	}
+}
+
+func BatchGeneration(wg *sync.WaitGroup) {
+	defer wg.Done()
 	zerolog.TimeFieldFormat = time.RFC3339
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+
+	baseCfg, err := shared.LoadConfig()
+	if err != nil {
+		log.Error().Str("module", "p2p").Msg("Error in loading config")
+		return
+	}
+	buildPod, ok := blocksync.StationPodBuilder(baseCfg.Station.StationType)
+	if !ok {
+		log.Error().Str("module", "p2p").Msg("No pod builder available for station type " + baseCfg.Station.StationType)
+		return
+	}
+	runPodLifecycle(newPodLifecycle(), buildPod)
+}
+
+// newPodLifecycle returns the pod lifecycle of the node with the guards and actions of
+// this track.
+func newPodLifecycle() *shared.PodLifecycle {
+	lifecycle := shared.NewPodLifecycle(shared.Node.NodeConnections.GetStateDatabaseConnection())
+	lifecycle.Guard(shared.EventVRFValidated, func(state *shared.PodState) error {
+		vrfRecord := junction.QueryVRF()
+		if vrfRecord == nil {
+			return errors.New("VRF record is nil")
+		}
+		if !vrfRecord.IsVerified {
+			return errors.New("verification of VRF is failed, need Voting for correct VRN")
+		}
+		return nil
+	})
+	lifecycle.Guard(shared.EventPodVerified, func(state *shared.PodState) error {
+		podData := junction.QueryPod(state.LatestPodHeight)
+		if podData == nil || !podData.IsVerified {
+			return fmt.Errorf("pod %d is not verified on junction", state.LatestPodHeight)
+		}
+		return nil
+	})
+	lifecycle.OnEnter(shared.TxStatePreInit, saveVerifiedPodOnce)
+	return lifecycle
+}
+
+// saveVerifiedPodOnce saves the verified pod of state unless it was saved already.
+func saveVerifiedPodOnce(state *shared.PodState) error {
+	staticDB := shared.Node.NodeConnections.GetStaticDatabaseConnection()
+	rawBatchCount, err := GetValueOrDefault(staticDB, []byte(BatchCountKey), []byte("0"))
+	if err != nil {
+		return err
+	}
+	batchCount, _ := strconv.Atoi(strings.TrimSpace(string(rawBatchCount)))
+	if uint64(batchCount) >= state.LatestPodHeight {
+		return nil
+	}
+	saveVerifiedPOD()
+	return nil
+}
+
+// runPodLifecycle drives the pod lifecycle: it takes the step of the current phase that
+// falls to this track, then waits for an event posted by the gossip handlers or for the
+// step to be due again.
+func runPodLifecycle(lifecycle *shared.PodLifecycle, buildPod blocksync.PodBuilder) {
+	if err := lifecycle.Resume(); err != nil {
+		logs.Log.Error("Error in resuming pod lifecycle: " + err.Error())
+	}
+
+	// pending holds events that arrived before the phase they belong to.
+	var pending []postedPodEvent
+	for {
+		wait := stepPod(lifecycle, buildPod)
+		pending = firePendingPodEvents(lifecycle, pending)
+		if wait == 0 {
+			continue
+		}
+
+		select {
+		case posted := <-podEvents:
+			pending = firePodEvent(lifecycle, pending, posted)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// firePodEvent fires a posted event of the latest pod. Events of a later pod, and events
+// the current phase has no transition for yet, are kept in pending.
+func firePodEvent(lifecycle *shared.PodLifecycle, pending []postedPodEvent, posted postedPodEvent) []postedPodEvent {
+	height := shared.GetPodState().LatestPodHeight
+	if posted.pod < height {
+		logs.Log.Debug(fmt.Sprintf("Ignoring %s of pod %d, latest pod is %d", posted.event, posted.pod, height))
+		return pending
+	}
+	if posted.pod > height {
+		return append(pending, posted)
+	}
+	if err := lifecycle.Fire(posted.event); errors.Is(err, shared.ErrIllegalPodTransition) {
+		return append(pending, posted)
+	}
+	return pending
+}
+
+// firePendingPodEvents fires the pending events that became legal and drops those of
+// pods that are done.
+func firePendingPodEvents(lifecycle *shared.PodLifecycle, pending []postedPodEvent) []postedPodEvent {
+	for fired := true; fired; {
+		fired = false
+		height := shared.GetPodState().LatestPodHeight
+		kept := pending[:0]
+		for _, posted := range pending {
+			switch {
+			case posted.pod < height:
+			case posted.pod == height && !fired && lifecycle.Can(posted.event):
+				if err := lifecycle.Fire(posted.event); err != nil {
+					logs.Log.Warn(fmt.Sprintf("Dropped pending %s of pod %d: %s", posted.event, posted.pod, err.Error()))
+				}
+				fired = true
+			default:
+				kept = append(kept, posted)
+			}
+		}
+		pending = kept
+	}
+	return pending
+}
+
+// stepPod takes the step of the current phase that falls to this track. It returns how
+// long to wait before stepping again, zero when the pod moved on.
+func stepPod(lifecycle *shared.PodLifecycle, buildPod blocksync.PodBuilder) time.Duration {
+	phase := lifecycle.Phase()
+	if phase == shared.TxStatePreInit {
+		return buildNextPod(lifecycle, buildPod)
+	}
+	state := shared.GetPodState()
+	if !isPodMaster(state) {
+		return pollPodVerified(lifecycle, state)
+	}
+	if len(getAllPeers(Node)) == 1 {
+		return stepSinglePod(lifecycle, state)
+	}
+	if phase == shared.TxStateInitVRF {
+		return initiateVRF(lifecycle, state)
+	}
+	return pollPodVerified(lifecycle, state)
+}
+
+// isPodMaster reports whether this track is the master of the pod of state.
+func isPodMaster(state *shared.PodState) bool {
+	selectedMaster := MasterTracksSelection(Node, string(state.MasterTrackAppHash))
+	decodedMaster, err := peer.Decode(selectedMaster)
+	CheckErrorAndExit(err, "Error in decoding master", 0)
+	return decodedMaster == Node.ID()
+}
+
+// buildNextPod builds the pod following the last verified one and moves it to InitVRF.
+func buildNextPod(lifecycle *shared.PodLifecycle, buildPod blocksync.PodBuilder) time.Duration {
 	log.Info().
 		Str("module", "p2p").
 		Msg("Generating New unverified pods")
@@ -45,27 +220,7 @@ func GenerateUnverifiedPods() {
 	rawCurrentPodNumber, err := GetValueOrDefault(staticDBConnection, []byte(BatchCountKey), []byte("0"))
 	CheckErrorAndExit(err, "Error in getting currentPodNumber from static db", 0)
 
-	//previousStateData
-	podStateData, err := GetPodStateFromDatabase()
-	CheckErrorAndExit(err, "Error in getting previous station data", 0)
-
-	var (
-		previousTrackAppHash []byte
-		trackAppHash         []byte
-		witness              []byte
-		uZKP                 []byte
-		MRH                  []byte
-		batchInput           *types.BatchStruct
-		txState              string
-		batchNumber          int
-	)
-
 	currentPodNumber, _ := strconv.Atoi(strings.TrimSpace(string(rawCurrentPodNumber)))
-	txState = podStateData.LatestTxState
-	if txState == "" {
-		txState = shared.TxStatePreInit
-	}
-
 	if currentPodNumber == 0 {
 		currentPodNumber = 1
 	}
@@ -76,369 +231,216 @@ func GenerateUnverifiedPods() {
 			currentPodNumber++
 		}
 	}
+	log.Info().Str("module", "p2p").Msg(fmt.Sprintf("Processing Pod Number: %d", currentPodNumber))
 
-	batchNumber = currentPodNumber
-	log.Info().Str("module", "p2p").Msg(fmt.Sprintf("Processing Pod Number: %d", batchNumber))
-	if podStateData.LatestTxState == shared.TxStatePreInit {
-		txState = shared.TxStateInitVRF
-		previousTrackAppHash = podStateData.TracksAppHash
-		if previousTrackAppHash == nil {
-			previousTrackAppHash = []byte("nil")
-		}
+	// the master of the new pod is selected with the app hash of the previous one
+	previousTrackAppHash := shared.GetPodState().TracksAppHash
+	if previousTrackAppHash == nil {
+		previousTrackAppHash = []byte("nil")
+	}
 
-		baseCfg, err := shared.LoadConfig()
-		if err != nil {
-			log.Error().Str("module", "p2p").Msg("Error in loading config")
-		}
-		stationVariant := baseCfg.Station.StationType
-		stationVariantLowerCase := strings.ToLower(stationVariant)
-
-		if stationVariantLowerCase == "evm" {
-			witness, uZKP, MRH, batchInput, err = createEVMPOD(txnDBConnection, rawConfirmedTransactionIndex, rawCurrentPodNumber)
-			CheckErrorAndExit(err, "Error in creating POD", 0)
-		} else if stationVariantLowerCase == "wasm" {
-			witness, uZKP, MRH, batchInput, err = createWasmPOD(txnDBConnection, rawConfirmedTransactionIndex, rawCurrentPodNumber)
-			CheckErrorAndExit(err, "Error in creating POD", 0)
-		}
+	witness, uZKP, MRH, batchInput, err := buildPod(txnDBConnection, rawConfirmedTransactionIndex, rawCurrentPodNumber)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in creating pod %d : %s", currentPodNumber, err.Error()))
+		return podRetryInterval
+	}
 
-		trackAppHash = generatePodHash(witness, uZKP, MRH, rawCurrentPodNumber)
-		updateNewPodState(trackAppHash, witness, uZKP, MRH, uint64(batchNumber), batchInput, txState)
-	} else {
-		trackAppHash = podStateData.TracksAppHash
-		witness = podStateData.LatestPublicWitness
-		uZKP = podStateData.LatestPodProof
-		MRH = podStateData.LatestPodHash
-		pMRH := podStateData.PreviousPodHash
-		batchInput = podStateData.Batch
+	trackAppHash := generatePodHash(witness, uZKP, MRH, rawCurrentPodNumber)
+	podState := newPodState(trackAppHash, witness, uZKP, MRH, previousTrackAppHash, uint64(currentPodNumber), batchInput)
+	if isPodMaster(podState) {
+		podState.Votes[Node.ID().String()] = shared.Votes{
+			PeerID: Node.ID().String(),
+			Vote:   true,
+		}
+	}
+	shared.ReplacePodState(podState)
 
-		storeNewPodState(trackAppHash, witness, uZKP, pMRH, MRH, uint64(batchNumber), batchInput, txState)
+	if err := lifecycle.Fire(shared.EventPodBuilt); err != nil {
+		return podRetryInterval
 	}
+	return 0
+}
 
-	selectedMaster := MasterTracksSelection(Node, string(previousTrackAppHash))
-	decodedMaster, err := peer.Decode(selectedMaster)
-	CheckErrorAndExit(err, "Error in decoding master", 0)
+// stepSinglePod takes the step of the current phase when this track is the only one of
+// the station, and so takes every step itself.
+func stepSinglePod(lifecycle *shared.PodLifecycle, state *shared.PodState) time.Duration {
+	var event shared.PodEvent
+	switch lifecycle.Phase() {
+	case shared.TxStateInitVRF:
+		success, _ := junction.InitVRF()
+		if !success {
+			logs.Log.Error("Failed to Init VRF")
+			return podRetryInterval
+		}
+		event = shared.EventVRFInitiated
 
-	if decodedMaster == Node.ID() {
-		podState := shared.GetPodState()
-		currentVotes := podState.Votes
-		currentVotes[decodedMaster.String()] = shared.Votes{
-			PeerID: decodedMaster.String(),
-			Vote:   true,
+	case shared.TxStateVerifyVRF:
+		addr, err := junction.GetAddress()
+		if err != nil {
+			logs.Log.Error("Error in getting address")
+			return podRetryInterval
 		}
-		podState.Votes = currentVotes
-		shared.SetPodState(podState)
-		Peers := getAllPeers(Node)
-		peerCount := len(Peers)
-		if peerCount == 1 {
-			DaData := shared.GetPodState().Batch.TransactionHash
-			var daDataByte []byte
-			for _, str := range DaData {
-				daDataByte = append(daDataByte, []byte(str)...)
-			}
-			ZkProof := shared.GetPodState().LatestPodProof
-			PodNumber := int(shared.GetPodState().LatestPodHeight)
+		if !junction.ValidateVRF(addr) {
+			logs.Log.Error("Failed to Validate VRF")
+			return podRetryInterval
+		}
+		event = shared.EventVRFValidated
 
-			finalizeDA := types.FinalizeDA{
-				CompressedHash: DaData,
-				Proof:          ZkProof,
-				PodNumber:      PodNumber,
-			}
-			_, err := json.Marshal(finalizeDA)
-			if err != nil {
-				logs.Log.Error("Error marshaling da data: " + err.Error())
-				return
-			}
+	case shared.TxStateSubmitPod:
+		if err := submitPodToDA(state); err != nil {
+			logs.Log.Error("Error in submitting data to DA: " + err.Error())
+			logs.Log.Debug(fmt.Sprintf("Retrying DA after %s", podRetryInterval))
+			return podRetryInterval
+		}
+		if !junction.SubmitCurrentPod() {
+			logs.Log.Error("Failed to submit pod")
+			return podRetryInterval
+		}
+		event = shared.EventPodSubmitted
 
-			addr, err := junction.GetAddress()
-			if err != nil {
-				logs.Log.Error("Error in getting address")
-				return
-			}
+	case shared.TxStateVerifyPod:
+		if !junction.VerifyCurrentPod() {
+			logs.Log.Error("Failed to Transact Verify pod")
+			return podRetryInterval
+		}
+		event = shared.EventPodVerified
+	}
 
-			if shared.GetPodState().LatestTxState == shared.TxStateInitVRF {
-				success, _ := junction.InitVRF()
-				if !success {
-					logs.Log.Error("Failed to Init VRF")
-					return
-				}
-				updateTxState(shared.TxStateVerifyVRF)
-			} else {
-				log.Debug().Str("module", "p2p").Msg("VRF is already initiated, moving to next step")
-			}
+	if err := lifecycle.Fire(event); err != nil {
+		return podRetryInterval
+	}
+	return 0
+}
 
-			//os.Exit(0)
+// initiateVRF initiates the VRF of the pod of state as its master, and asks a random
+// other track to validate it.
+func initiateVRF(lifecycle *shared.PodLifecycle, state *shared.PodState) time.Duration {
+	success, addr := junction.InitVRF()
+	if !success {
+		logs.Log.Error("Failed to Init VRF")
+		return podRetryInterval
+	}
+	logs.Log.Info("VRF initiated")
 
-			if shared.GetPodState().LatestTxState == shared.TxStateVerifyVRF {
-				success := junction.ValidateVRF(addr)
-				if !success {
-					logs.Log.Error("Failed to Validate VRF")
-					return
-				}
-				updateTxState(shared.TxStateSubmitPod)
-
-				// check if VRF is successfully validated
-				var vrfRecord *junctionTypes.VrfRecord
-				vrfRecord = junction.QueryVRF()
-				if vrfRecord == nil {
-					logs.Log.Error("VRF record is nil")
-					return
-				}
-				if !vrfRecord.IsVerified {
-					logs.Log.Error("Verification of VRF is failed, need Voting for correct VRN")
-					return
-				}
-			} else {
-				log.Debug().Str("module", "p2p").Msg("VRF is already validated, moving to next step")
-			}
-			//os.Exit(0)
+	accountDetails, err := getAccountDetails()
+	if err != nil {
+		logs.Log.Error(err.Error())
+		return podRetryInterval
+	}
 
-			if shared.GetPodState().LatestTxState == shared.TxStateSubmitPod {
+	// choose one verifiable random node to verify the VRF
+	var filteredTracks []string
+	for _, track := range accountDetails.Tracks {
+		if track != accountDetails.MyAddress {
+			filteredTracks = append(filteredTracks, track)
+		}
+	}
+	selectedTrackAddress := filteredTracks[rand.Intn(len(filteredTracks))]
+	logs.Log.Info("Selected random address: " + selectedTrackAddress)
+
+	VRFInitiatedMsg := VRFInitiatedMsgData{
+		PodNumber:            state.LatestPodHeight,
+		SelectedTrackAddress: selectedTrackAddress,
+		VrfInitTxHash:        shared.GetPodState().VRFInitiationTxHash,
+		VrfInitiatorAddress:  addr,
+	}
+	VRFInitiatedMsgByte, err := json.Marshal(VRFInitiatedMsg)
+	if err != nil {
+		logs.Log.Error("Error in Marshaling ProofVote Result")
+		return podRetryInterval
+	}
+	gossipMsg := types.GossipData{
+		Type: "vrfInitiated",
+		Data: VRFInitiatedMsgByte,
+	}
+	gossipMsgByte, err := json.Marshal(gossipMsg)
+	if err != nil {
+		logs.Log.Error("Error marshaling gossip message")
+		return podRetryInterval
+	}
+	BroadcastMessage(CTX, Node, gossipMsgByte)
 
-				DaBatchSaver := connection.DataAvailabilityDatabaseConnection
-				baseConfig, err := shared.LoadConfig()
-				if err != nil {
-					fmt.Println("Error loading configuration")
-				}
-				Datype := baseConfig.DA.DaType
-				var (
-					daCheck    string
-					daCheckErr error
-				)
-
-				if Datype == "mock" {
-					mdb := connection.MockDatabaseConnection
-
-					for {
-						daCheck, daCheckErr = mock.MockDA(mdb, daDataByte, PodNumber)
-						if daCheckErr != nil {
-							logs.Log.Error("Error in submitting data to DA")
-							logs.Log.Debug("Retrying Mock DA after 10 seconds")
-							time.Sleep(10 * time.Second)
-						} else {
-							break
-						}
-					}
-
-					da := types.DAStruct{
-						DAKey:             daCheck,
-						DAClientName:      "mock-da",
-						BatchNumber:       strconv.Itoa(PodNumber),
-						PreviousStateHash: string(shared.GetPodState().PreviousPodHash),
-						CurrentStateHash:  string(shared.GetPodState().TracksAppHash),
-					}
-
-					daStoreKey := fmt.Sprintf("da-%d", PodNumber)
-					daStoreData, daStoreDataErr := json.Marshal(da)
-					if daStoreDataErr != nil {
-						logs.Log.Debug(fmt.Sprintf("Error in marshaling DA pointer : %s", daStoreDataErr.Error()))
-					}
-
-					storeErr := DaBatchSaver.Put([]byte(daStoreKey), daStoreData, nil)
-					if storeErr != nil {
-						logs.Log.Debug(fmt.Sprintf("Error in saving DA pointer in pod database : %s", storeErr.Error()))
-					}
-
-					log.Info().Str("module", "p2p").Msg("Data Saved in DA")
-
-				} else if Datype == "avail" {
-
-					for {
-						daCheck, daCheckErr = avail.Avail(daDataByte, baseConfig.DA.DaRPC)
-						if daCheckErr != nil {
-							logs.Log.Debug("Error in submitting data to DA " + daCheckErr.Error())
-							logs.Log.Debug("Retrying Avail DA after 10 seconds")
-							time.Sleep(10 * time.Second)
-						} else {
-							break
-						}
-					}
-
-					da := types.DAStruct{
-						DAKey:             daCheck,
-						DAClientName:      "avail-da",
-						BatchNumber:       strconv.Itoa(PodNumber),
-						PreviousStateHash: string(shared.GetPodState().PreviousPodHash),
-						CurrentStateHash:  string(shared.GetPodState().TracksAppHash),
-					}
-
-					daStoreKey := fmt.Sprintf("da-%d", PodNumber)
-					daStoreData, daStoreDataErr := json.Marshal(da)
-					if daStoreDataErr != nil {
-						logs.Log.Debug(fmt.Sprintf("Error in marshaling DA pointer : %s", daStoreDataErr.Error()))
-						return
-					}
-
-					storeErr := DaBatchSaver.Put([]byte(daStoreKey), daStoreData, nil)
-					if storeErr != nil {
-						logs.Log.Debug(fmt.Sprintf("Error in saving DA pointer in pod database : %s", storeErr.Error()))
-						return
-					}
-
-					log.Info().Str("module", "p2p").Msg("Data Saved in DA")
-
-				} else if Datype == "celestia" {
-					for {
-						daCheck, daCheckErr = celestia.Celestia(daDataByte, baseConfig.DA.DaRPC, baseConfig.DA.DaRPC)
-						if daCheckErr != nil {
-							logs.Log.Debug("Error in submitting data to DA " + daCheckErr.Error())
-							logs.Log.Debug("Retrying Celestia DA after 10 seconds")
-							time.Sleep(10 * time.Second)
-						} else {
-							break
-						}
-					}
-
-					da := types.DAStruct{
-						DAKey:             daCheck,
-						DAClientName:      "celestia-da",
-						BatchNumber:       strconv.Itoa(PodNumber),
-						PreviousStateHash: string(shared.GetPodState().PreviousPodHash),
-						CurrentStateHash:  string(shared.GetPodState().TracksAppHash),
-					}
-
-					daStoreKey := fmt.Sprintf("da-%d", PodNumber)
-					daStoreData, daStoreDataErr := json.Marshal(da)
-					if daStoreDataErr != nil {
-						logs.Log.Debug(fmt.Sprintf("Error in marshaling DA pointer : %s", daStoreDataErr.Error()))
-						return
-					}
-
-					storeErr := DaBatchSaver.Put([]byte(daStoreKey), daStoreData, nil)
-					if storeErr != nil {
-						logs.Log.Debug(fmt.Sprintf("Error in saving DA pointer in pod database : %s", storeErr.Error()))
-						return
-					}
-
-					log.Info().Str("module", "p2p").Msg("Data Saved in DA")
-
-				} else if Datype == "eigen" {
-
-					for {
-						daCheck, daCheckErr = eigen.Eigen(daDataByte, baseConfig.DA.DaRPC, baseConfig.DA.DaRPC)
-						if daCheckErr != nil {
-							logs.Log.Debug("Error in submitting data to DA " + daCheckErr.Error())
-							logs.Log.Debug("Retrying Eigen DA after 10 seconds")
-							time.Sleep(10 * time.Second)
-						} else {
-							break
-						}
-					}
-
-					da := types.DAStruct{
-						DAKey:             daCheck,
-						DAClientName:      "eigen-da",
-						BatchNumber:       strconv.Itoa(PodNumber),
-						PreviousStateHash: string(shared.GetPodState().PreviousPodHash),
-						CurrentStateHash:  string(shared.GetPodState().TracksAppHash),
-					}
-
-					daStoreKey := fmt.Sprintf("da-%d", PodNumber)
-					daStoreData, daStoreDataErr := json.Marshal(da)
-					if daStoreDataErr != nil {
-						logs.Log.Debug(fmt.Sprintf("Error in marshaling DA pointer : %s", daStoreDataErr.Error()))
-						return
-					}
-
-					storeErr := DaBatchSaver.Put([]byte(daStoreKey), daStoreData, nil)
-					if storeErr != nil {
-						logs.Log.Debug(fmt.Sprintf("Error in saving DA pointer in pod database : %s", storeErr.Error()))
-						return
-					}
-
-					log.Info().Str("module", "p2p").Msg("Data Saved in DA")
-
-				} else {
-					logs.Log.Error("Unknown layer. Please use 'avail' or 'celestia' as argument.")
-					return
-				}
+	if err := lifecycle.Fire(shared.EventVRFInitiated); err != nil {
+		return podRetryInterval
+	}
+	return 0
+}
 
-				// submit pod
-				success := junction.SubmitCurrentPod()
-				if !success {
-					logs.Log.Error("Failed to submit pod")
-					return
-				}
-				updateTxState(shared.TxStateVerifyPod)
-			} else {
-				log.Warn().Str("module", "p2p").Msg("Pod already submitted, moving to next step")
-			}
-			//os.Exit(0)
-
-			// verify pod
-			if shared.GetPodState().LatestTxState == shared.TxStateVerifyPod {
-				success := junction.VerifyCurrentPod()
-				if !success {
-					logs.Log.Error("Failed to Transact Verify pod")
-					return
-				}
-				updateTxState(shared.TxStatePreInit)
-			} else {
-				log.Error().Str("module", "p2p").Msg("Database Error. LatestTxState should equal to TxStatePreInit at this point")
-				log.Error().Str("module", "p2p").Msg("LatestTxState: " + shared.GetPodState().LatestTxState)
-				return // stop sequencer, there is some error
-			}
+// pollPodVerified moves the pod of state to PreInit once junction has it verified, for
+// tracks that missed the podVerified gossip.
+func pollPodVerified(lifecycle *shared.PodLifecycle, state *shared.PodState) time.Duration {
+	podData := junction.QueryPod(state.LatestPodHeight)
+	if podData == nil || !podData.IsVerified {
+		return podPollInterval
+	}
+	if err := lifecycle.Fire(shared.EventPodVerified); err != nil {
+		return podRetryInterval
+	}
+	return 0
+}
 
-			saveVerifiedPOD()        // save data to database
-			GenerateUnverifiedPods() // generate next pod
-		} else {
-			PodNumber := int(shared.GetPodState().LatestPodHeight)
-			success, addr := junction.InitVRF()
-			if !success {
-				logs.Log.Error("Failed to Init VRF")
-				return
-			}
-			logs.Log.Info("VRF initiated")
+// submitPodToDA submits the transaction hashes of the pod of state to the configured DA
+// layer and stores the DA pointer of the pod. A pod with a stored pointer is not
+// submitted again.
+func submitPodToDA(state *shared.PodState) error {
+	connection := shared.Node.NodeConnections
+	DaBatchSaver := connection.GetDataAvailabilityDatabaseConnection()
+	PodNumber := int(state.LatestPodHeight)
+	daStoreKey := fmt.Sprintf("da-%d", PodNumber)
+	if stored, _ := DaBatchSaver.Has([]byte(daStoreKey), nil); stored {
+		log.Debug().Str("module", "p2p").Msg("Pod already submitted to DA, moving to next step")
+		return nil
+	}
 
-			// get own address
-			_, _, accountPath, accountName, addressPrefix, tracks, err := junction.GetJunctionDetails()
-			if err != nil {
-				logs.Log.Error("can not get junctionDetails.json data: " + err.Error())
-				return
-			}
-			myAddress, err := junction.CheckIfAccountExists(accountName, accountPath, addressPrefix)
-			if err != nil {
-				logs.Log.Error("Can not get junction wallet address")
-				return
-			}
+	var daDataByte []byte
+	for _, str := range state.Batch.TransactionHash {
+		daDataByte = append(daDataByte, []byte(str)...)
+	}
 
-			// choose one verifiable random node to verify the VRF
-			// Filter out the peer with own Id
-			var filteredTracks []string
-			for _, track := range tracks {
-				if track != myAddress {
-					filteredTracks = append(filteredTracks, track)
-				}
-			}
-			// Select a random peer from the filtered list
-			selectedTrackAddress := filteredTracks[rand.Intn(len(filteredTracks))]
-			fmt.Println("Selected random address:", selectedTrackAddress)
-
-			// get txHash of vrfInit
-			VrfInitTxHash := shared.GetPodState().VRFInitiationTxHash
-			// send verify VRF message to selected node
-			VRFInitiatedMsg := VRFInitiatedMsgData{
-				PodNumber:            uint64(PodNumber),
-				SelectedTrackAddress: selectedTrackAddress,
-				VrfInitTxHash:        VrfInitTxHash,
-				VrfInitiatorAddress:  addr,
-			}
+	baseConfig, err := shared.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+	var (
+		daCheck    string
+		daClient   string
+		daCheckErr error
+	)
+	switch baseConfig.DA.DaType {
+	case Mock:
+		daCheck, daCheckErr = mock.MockDA(connection.MockDatabaseConnection, daDataByte, PodNumber)
+		daClient = "mock-da"
+	case Avail:
+		daCheck, daCheckErr = avail.Avail(daDataByte, baseConfig.DA.DaRPC)
+		daClient = "avail-da"
+	case Celestia:
+		daCheck, daCheckErr = celestia.Celestia(daDataByte, baseConfig.DA.DaRPC, baseConfig.DA.DaRPC)
+		daClient = "celestia-da"
+	case Eigen:
+		daCheck, daCheckErr = eigen.Eigen(daDataByte, baseConfig.DA.DaRPC, baseConfig.DA.DaRPC)
+		daClient = "eigen-da"
+	default:
+		return errors.New("unknown layer. Please use 'avail' or 'celestia' as argument")
+	}
+	if daCheckErr != nil {
+		return daCheckErr
+	}
 
-			VRFInitiatedMsgByte, err := json.Marshal(VRFInitiatedMsg)
-			if err != nil {
-				logs.Log.Error("Error in Marshaling ProofVote Result")
-				return
-			}
-			gossipMsg := types.GossipData{
-				Type: "vrfInitiated",
-				Data: VRFInitiatedMsgByte,
-			}
-			gossipMsgByte, err := json.Marshal(gossipMsg)
-			if err != nil {
-				logs.Log.Error("Error marshaling gossip message")
-				return
-			}
-			BroadcastMessage(CTX, Node, gossipMsgByte)
-		}
+	da := types.DAStruct{
+		DAKey:             daCheck,
+		DAClientName:      daClient,
+		BatchNumber:       strconv.Itoa(PodNumber),
+		PreviousStateHash: string(state.PreviousPodHash),
+		CurrentStateHash:  string(state.TracksAppHash),
+	}
+	daStoreData, err := json.Marshal(da)
+	if err != nil {
+		return fmt.Errorf("error in marshaling DA pointer: %w", err)
+	}
+	if err := DaBatchSaver.Put([]byte(daStoreKey), daStoreData, nil); err != nil {
+		return fmt.Errorf("error in saving DA pointer in pod database: %w", err)
 	}
 
+	log.Info().Str("module", "p2p").Msg("Data Saved in DA")
+	return nil
 }
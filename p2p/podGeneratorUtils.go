@@ -4,19 +4,24 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
 	"github.com/airchains-network/decentralized-sequencer/config"
 	logs "github.com/airchains-network/decentralized-sequencer/log"
 	"github.com/airchains-network/decentralized-sequencer/node/shared"
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
 	"github.com/airchains-network/decentralized-sequencer/types"
 	utilis "github.com/airchains-network/decentralized-sequencer/utils"
 	v1 "github.com/airchains-network/decentralized-sequencer/zk/v1EVM"
+	v1SVM "github.com/airchains-network/decentralized-sequencer/zk/v1SVM"
 	v1Wasm "github.com/airchains-network/decentralized-sequencer/zk/v1WASM"
 	"github.com/rs/zerolog/log"
 	"github.com/syndtr/goleveldb/leveldb"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -29,6 +34,12 @@ const (
 	BatchStartIndexKey = "batchStartIndex"
 )
 
+func init() {
+	blocksync.RegisterPodBuilder(blocksync.StationTypeEVM, createEVMPOD)
+	blocksync.RegisterPodBuilder(blocksync.StationTypeWASM, createWasmPOD)
+	blocksync.RegisterPodBuilder(blocksync.StationTypeSVM, createSVMPOD)
+}
+
 func CheckErrorAndExit(err error, message string, exitCode int) {
 	if err != nil {
 		log.Log().Str("module", "p2p").Msg("error: " + message)
@@ -51,6 +62,11 @@ func createEVMPOD(ldt *leveldb.DB, batchStartIndex []byte, limit []byte) (witnes
 	if err != nil {
 		return
 	}
+	stationRPC, err := stationclient.Shared(baseConfig.Station.StationRPC, blocksync.StationClientOptions(baseConfig.Station))
+	if err != nil {
+		return
+	}
+	policy := blocksync.InclusionPolicyFromConfig(baseConfig.Station)
 	limitInt, _ := strconv.Atoi(strings.TrimSpace(string(limit)))
 
 	batchStartIndexInt, _ := strconv.Atoi(strings.TrimSpace(string(batchStartIndex)))
@@ -66,50 +82,79 @@ func createEVMPOD(ldt *leveldb.DB, batchStartIndex []byte, limit []byte) (witnes
 	var Messages []string
 	var TransactionNonces []string
 	var AccountNonces []string
-
-	for i := batchStartIndexInt; i < (config.PODSize * (limitInt + 1)); i++ {
-
-		findKey := fmt.Sprintf("txns-%d", i+1)
-		txData, err := ldt.Get([]byte(findKey), nil)
+	var excluded []blocksync.TxnExclusion
+	var included []types.TransactionStruct
+	var tracked []blocksync.AccountStates
+
+	podSize := baseConfig.Station.StationPodSize()
+	firstSeq, lastSeq := sealPodTxns(ldt, batchStartIndexInt+1, limitInt+1, podSize, baseConfig.Station.MaxPodInterval)
+	for seq := firstSeq; seq <= lastSeq; seq++ {
+		txData, err := ldt.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
 		if err != nil {
-			i--
-			time.Sleep(1 * time.Second)
-			continue
+			return nil, nil, nil, nil, fmt.Errorf("error reading transaction %d: %w", seq, err)
 		}
 		var tx types.TransactionStruct
-		err = json.Unmarshal(txData, &tx)
-		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in unmarshalling tx data : %s", err.Error()))
-			os.Exit(0)
+		if err := json.Unmarshal(txData, &tx); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("error unmarshalling transaction %d: %w", seq, err)
 		}
-
-		senderBalancesCheck, err := utilis.GetBalance(tx.From, tx.BlockNumber-1, baseConfig.Station.StationRPC)
-		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in getting sender balance : %s", err.Error()))
-			os.Exit(0)
+		candidate := blocksync.EVMPodCandidate(seq, tx)
+		if reason := policy.Exclude(candidate); reason != "" {
+			excluded = append(excluded, candidate.Exclusion(limitInt+1, reason))
+			continue
 		}
-
-		receiverBalancesCheck, err := utilis.GetBalance(tx.To, tx.BlockNumber-1, baseConfig.Station.StationRPC)
-		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in getting reciver balance : %s", err.Error()))
-			os.Exit(0)
+		included = append(included, tx)
+		if baseConfig.Station.StateTracker {
+			states, err := trackedAccountStates(ldt, seq)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			tracked = append(tracked, states)
 		}
+	}
 
-		accountNonceCheck, err := utilis.GetAccountNonce(context.Background(), tx.Hash, tx.BlockNumber, baseConfig.Station.StationRPC)
+	if err := recordPodExclusions(ldt, firstSeq, lastSeq, excluded); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	// The station is asked for the state the tracker does not have.
+	var lookup []types.TransactionStruct
+	for j, tx := range included {
+		if !baseConfig.Station.StateTracker || tracked[j].Of(tx.From).Untrusted || tracked[j].Of(tx.To).Untrusted {
+			lookup = append(lookup, tx)
+		}
+	}
+	var balances, nonces map[utilis.EVMAccountAt]string
+	if len(lookup) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), evmStateTimeout)
+		balances, nonces, err = fetchEVMPodState(ctx, lookup, stationRPC)
+		cancel()
 		if err != nil {
-			logs.Log.Error(fmt.Sprintf("Error in getting account nonce : %s", err.Error()))
-			os.Exit(0)
+			return nil, nil, nil, nil, fmt.Errorf("error looking up account state of pod %d: %w", limitInt+1, err)
+		}
+	}
+	for j, tx := range included {
+		senderBalance := balances[evmPodAccount(tx.From, tx)]
+		receiverBalance := balances[evmPodAccount(tx.To, tx)]
+		accountNonce := nonces[evmPodAccount(tx.From, tx)]
+		if baseConfig.Station.StateTracker {
+			sender, receiver := tracked[j].Of(tx.From), tracked[j].Of(tx.To)
+			if !sender.Untrusted {
+				senderBalance = sender.Balance("")
+			}
+			if !receiver.Untrusted {
+				receiverBalance = receiver.Balance("")
+			}
+			accountNonce = fmt.Sprintf("0x%x", sender.Nonce)
 		}
-
 		From = append(From, tx.From)
 		To = append(To, tx.To)
 		Amounts = append(Amounts, tx.Value)
 		TransactionHash = append(TransactionHash, tx.Hash)
-		SenderBalances = append(SenderBalances, senderBalancesCheck)
-		ReceiverBalances = append(ReceiverBalances, receiverBalancesCheck)
+		SenderBalances = append(SenderBalances, senderBalance)
+		ReceiverBalances = append(ReceiverBalances, receiverBalance)
 		Messages = append(Messages, tx.Input)
 		TransactionNonces = append(TransactionNonces, tx.Nonce)
-		AccountNonces = append(AccountNonces, accountNonceCheck)
+		AccountNonces = append(AccountNonces, accountNonce)
 	}
 
 	batch.From = From
@@ -121,7 +166,10 @@ func createEVMPOD(ldt *leveldb.DB, batchStartIndex []byte, limit []byte) (witnes
 	batch.Messages = Messages
 	batch.TransactionNonces = TransactionNonces
 	batch.AccountNonces = AccountNonces
-	witnessVector, currentStatusHash, proofByte, pkErr := v1.GenerateProof(batch, limitInt+1)
+	batch.TxnCount = len(From)
+	batch.FirstTxnSeq = firstSeq
+	batch.LastTxnSeq = lastSeq
+	witnessVector, currentStatusHash, proofByte, pkErr := v1.GenerateProof(batch, limitInt+1, podSize)
 	if pkErr != nil {
 		logs.Log.Error(fmt.Sprintf("Error in generating proof : %s", pkErr.Error()))
 		return nil, nil, nil, nil, pkErr
@@ -131,23 +179,195 @@ func createEVMPOD(ldt *leveldb.DB, batchStartIndex []byte, limit []byte) (witnes
 	// marshal witnessVector
 	witnessVectorByte, err := json.Marshal(witnessVector)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in marshalling witness vector : %s", err.Error()))
+		return nil, nil, nil, nil, fmt.Errorf("error marshalling witness vector: %w", err)
 	}
 
 	// string to []byte currentStatusHash
 	currentStatusHashByte, err := json.Marshal(currentStatusHash)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in marshalling current status hash : %s", err.Error()))
-		os.Exit(0)
+		return nil, nil, nil, nil, fmt.Errorf("error marshalling current status hash: %w", err)
 	}
 
 	return witnessVectorByte, proofByte, currentStatusHashByte, &batch, nil
 }
+
+// evmStates caches the account state looked up for EVM pods.
+var (
+	evmStatesOnce sync.Once
+	evmStates     *utilis.EVMStateCache
+)
+
+const (
+	evmStateRetryBase = time.Second
+	evmStateRetryMax  = 30 * time.Second
+	// evmStateTimeout bounds the lookups of one pod; the pod is built again later.
+	evmStateTimeout = 10 * time.Minute
+)
+
+// evmPodAccount is address before the block of tx, where the balances and account
+// nonces of a pod are read. Accounts of the genesis block are read at the genesis block.
+func evmPodAccount(address string, tx types.TransactionStruct) utilis.EVMAccountAt {
+	block := tx.BlockNumber
+	if block > 0 {
+		block--
+	}
+	return utilis.EVMAccountAt{Address: address, Block: block}
+}
+
+// fetchEVMPodState looks up the balances of the senders and recipients of txs and the
+// nonces of the senders in as few batch requests as possible. Failed lookups are
+// retried with backoff until the station answers, ctx is done, or the station answers
+// with a permanent error, such as a node that pruned the state of the blocks.
+func fetchEVMPodState(ctx context.Context, txs []types.TransactionStruct, stationRPC *stationclient.Client) (balances, nonces map[utilis.EVMAccountAt]string, err error) {
+	evmStatesOnce.Do(func() {
+		evmStates = utilis.NewEVMStateCache(stationRPC)
+	})
+	var balancesOf, noncesOf []utilis.EVMAccountAt
+	for _, tx := range txs {
+		balancesOf = append(balancesOf, evmPodAccount(tx.From, tx), evmPodAccount(tx.To, tx))
+		noncesOf = append(noncesOf, evmPodAccount(tx.From, tx))
+	}
+
+	wait := evmStateRetryBase
+	for {
+		balances, nonces, err = evmStates.Fetch(ctx, balancesOf, noncesOf)
+		if err == nil || stationclient.IsPermanent(err) || ctx.Err() != nil {
+			return balances, nonces, err
+		}
+		log.Warn().Str("module", "p2p").Err(err).Msg(fmt.Sprintf("Failed to look up account state of the pod, retrying in %s", wait))
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, evmStateRetryMax)
+	}
+}
+
+// trackedAccountStates waits until the state tracker applied transaction seq and
+// returns the state of its accounts before it. It fails when the tracker is stuck.
+func trackedAccountStates(ldt *leveldb.DB, seq int) (blocksync.AccountStates, error) {
+	for {
+		states, err := blocksync.TxnAccountStates(ldt, seq)
+		if err == nil {
+			return states, nil
+		}
+		if !errors.Is(err, leveldb.ErrNotFound) {
+			return nil, fmt.Errorf("error reading account states of transaction %d: %w", seq, err)
+		}
+		if err := blocksync.StateTrackerError(ldt); err != nil {
+			return nil, fmt.Errorf("state tracker failed before transaction %d: %w", seq, err)
+		}
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// sealPodTxns waits until the pod of podSize transactions starting at transaction first
+// can be sealed and returns the range of transactions it covers.
+func sealPodTxns(ldt *leveldb.DB, first, podNumber, podSize int, maxInterval time.Duration) (int, int) {
+	for {
+		if last, ok := blocksync.SealPod(ldt, first, podSize, maxInterval); ok {
+			if count := last - first + 1; count < podSize {
+				log.Info().Str("module", "p2p").Str("Pod Number", strconv.Itoa(podNumber)).Msg(fmt.Sprintf("Sealing pod with %d of %d transactions after %s", count, podSize, maxInterval))
+			}
+			return first, last
+		}
+		// Transactions of tentative blocks wait until the block is final.
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// recordPodExclusions stores why the excluded transactions of a pod were left out.
+func recordPodExclusions(ldt *leveldb.DB, firstSeq, lastSeq int, excluded []blocksync.TxnExclusion) error {
+	if err := blocksync.RecordPodExclusions(ldt, firstSeq, lastSeq, excluded); err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in recording excluded transactions : %s", err.Error()))
+		return err
+	}
+	for _, exclusion := range excluded {
+		log.Info().Str("module", "p2p").Int("seq", exclusion.Seq).Str("hash", exclusion.Hash).Str("reason", exclusion.Reason).Msg("Transaction excluded from pod")
+	}
+	return nil
+}
+
+// podTxn is a transaction of a pod together with its pod entries.
+type podTxn[T, E any] struct {
+	seq     int
+	hash    string
+	txn     T
+	entries []E
+}
+
+// fillPod reads the transactions firstSeq to lastSeq of pod and returns those the policy
+// includes with their entries, the transactions left out, and the last transaction of the
+// pod. The pod ends before the first transaction whose entries no longer fit in it, so
+// that transaction starts the next pod. Only a transaction with more entries than a whole
+// pod is left out as pod-full.
+func fillPod[T, E any](ldt *leveldb.DB, firstSeq, lastSeq, pod, podSize int, policy blocksync.InclusionPolicy, describe func(seq int, txn T) (blocksync.PodCandidate, []E)) ([]podTxn[T, E], int, []blocksync.TxnExclusion, error) {
+	var txns []podTxn[T, E]
+	var excluded []blocksync.TxnExclusion
+	used := 0
+	for seq := firstSeq; seq <= lastSeq; seq++ {
+		txData, err := ldt.Get([]byte(fmt.Sprintf("txns-%d", seq)), nil)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("error reading transaction %d: %w", seq, err)
+		}
+		var txn T
+		if err := json.Unmarshal(txData, &txn); err != nil {
+			return nil, 0, nil, fmt.Errorf("error unmarshalling transaction %d: %w", seq, err)
+		}
+		candidate, entries := describe(seq, txn)
+		reason := policy.Exclude(candidate)
+		if reason == "" && len(entries) > podSize {
+			reason = blocksync.ExcludedPodFull
+		}
+		if reason != "" {
+			excluded = append(excluded, candidate.Exclusion(pod, reason))
+			continue
+		}
+		if used+len(entries) > podSize {
+			return txns, seq - 1, excluded, nil
+		}
+		if len(candidate.Messages) > 0 {
+			excluded = append(excluded, candidate.Exclusion(pod, blocksync.ExcludedMessages))
+		}
+		txns = append(txns, podTxn[T, E]{seq: seq, hash: candidate.Hash, txn: txn, entries: entries})
+		used += len(entries)
+	}
+	return txns, lastSeq, excluded, nil
+}
+
+// wasmEntryBalances returns the balances of the sender and recipient of entry, in its
+// denomination, before block height.
+func wasmEntryBalances(entry blocksync.WasmPodEntry, height string, stationAPI *stationclient.Client) (string, string, error) {
+	balanceOf := func(address string) (string, error) {
+		return utilis.AccountDenomBalanceCheck(address, entry.Denom, height, stationAPI)
+	}
+	if entry.CW20 {
+		balanceOf = func(address string) (string, error) {
+			return utilis.CW20BalanceCheck(entry.Denom, address, height, stationAPI)
+		}
+	}
+	sender, err := balanceOf(entry.From)
+	if err != nil {
+		return "", "", err
+	}
+	receiver, err := balanceOf(entry.To)
+	if err != nil {
+		return "", "", err
+	}
+	return sender, receiver, nil
+}
+
 func createWasmPOD(ldt *leveldb.DB, batchStartIndex []byte, limit []byte) (witness []byte, unverifiedProof []byte, MRH []byte, podData *types.BatchStruct, err error) {
 	baseConfig, err := shared.LoadConfig()
 	if err != nil {
 		return
 	}
+	stationAPI, err := stationclient.Shared(baseConfig.Station.StationAPI, blocksync.StationClientOptions(baseConfig.Station))
+	if err != nil {
+		return
+	}
+	policy := blocksync.InclusionPolicyFromConfig(baseConfig.Station)
 	limitInt, _ := strconv.Atoi(strings.TrimSpace(string(limit)))
 	batchStartIndexInt, _ := strconv.Atoi(strings.TrimSpace(string(batchStartIndex)))
 
@@ -163,38 +383,53 @@ func createWasmPOD(ldt *leveldb.DB, batchStartIndex []byte, limit []byte) (witne
 	var TransactionNonces []string
 	var AccountNonces []string
 
-	for i := batchStartIndexInt; i < (config.PODSize * (limitInt + 1)); i++ {
-		findKey := fmt.Sprintf("txns-%d", i+1)
-		txData, err := ldt.Get([]byte(findKey), nil)
-
-		if err != nil {
-			i--
-			time.Sleep(1 * time.Second)
-			continue
+	podSize := baseConfig.Station.StationPodSize()
+	firstSeq, lastSeq := sealPodTxns(ldt, batchStartIndexInt+1, limitInt+1, podSize, baseConfig.Station.MaxPodInterval)
+	txns, lastSeq, excluded, err := fillPod(ldt, firstSeq, lastSeq, limitInt+1, podSize, policy, blocksync.WasmPodCandidate)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	for _, included := range txns {
+		txn := included.txn
+		transactionHashCheck := utilis.TXHashCheck(txn.TxResponse.TxHash)
+		var tracked blocksync.AccountStates
+		if baseConfig.Station.StateTracker {
+			if tracked, err = trackedAccountStates(ldt, included.seq); err != nil {
+				return nil, nil, nil, nil, err
+			}
 		}
-
-		var txn types.BatchTransaction
-		err = json.Unmarshal(txData, &txn)
-		if err != nil {
-			logs.Log.Info(fmt.Sprintf("Error in unmarshalling tx data : %s", err.Error()))
+		for _, entry := range included.entries {
+			var senderBalancesCheck, receiverBalancesCheck, accountNoncesCheck string
+			if tracked != nil {
+				accountNoncesCheck = strconv.FormatUint(tracked.Of(entry.From).Nonce, 10)
+			} else if accountNoncesCheck, err = utilis.AccountNounceCheck(entry.From, stationAPI); err != nil {
+				return nil, nil, nil, nil, err
+			}
+			if tracked != nil && !entry.CW20 && !tracked.Of(entry.From).Untrusted && !tracked.Of(entry.To).Untrusted {
+				senderBalancesCheck, receiverBalancesCheck = tracked.Of(entry.From).Balance(entry.Denom), tracked.Of(entry.To).Balance(entry.Denom)
+			} else {
+				// cw20 balances live in contract storage and are not tracked, and the
+				// balances of untrusted accounts are unknown.
+				senderBalancesCheck, receiverBalancesCheck, err = wasmEntryBalances(entry, txn.TxResponse.Height, stationAPI)
+				if err != nil {
+					return nil, nil, nil, nil, err
+				}
+			}
+
+			From = append(From, utilis.Bech32Decoder(entry.From))
+			To = append(To, utilis.Bech32Decoder(entry.To))
+			Amounts = append(Amounts, entry.Amount)
+			SenderBalances = append(SenderBalances, senderBalancesCheck)
+			ReceiverBalances = append(ReceiverBalances, receiverBalancesCheck)
+			TransactionHash = append(TransactionHash, transactionHashCheck)
+			Messages = append(Messages, fmt.Sprint(txn.Tx.Body.Messages[entry.Message]))
+			TransactionNonces = append(TransactionNonces, "0")
+			AccountNonces = append(AccountNonces, accountNoncesCheck)
 		}
-		fromCheck := utilis.Bech32Decoder(txn.Tx.Body.Messages[0].FromAddress)
-		toCheck := utilis.Bech32Decoder(txn.Tx.Body.Messages[0].ToAddress)
-		transactionHashCheck := utilis.TXHashCheck(txn.TxResponse.TxHash)
-
-		senderBalancesCheck := utilis.AccountBalanceCheck(txn.Tx.Body.Messages[0].FromAddress, txn.TxResponse.Height, baseConfig.Station.StationAPI)
-		receiverBalancesCheck := utilis.AccountBalanceCheck(txn.Tx.Body.Messages[0].ToAddress, txn.TxResponse.Height, baseConfig.Station.StationAPI)
-		accountNoncesCheck := utilis.AccountNounceCheck(txn.Tx.Body.Messages[0].FromAddress, baseConfig.Station.StationAPI)
+	}
 
-		From = append(From, fromCheck)
-		To = append(To, toCheck)
-		Amounts = append(Amounts, txn.Tx.Body.Messages[0].Amount[0].Amount)
-		SenderBalances = append(SenderBalances, senderBalancesCheck)
-		ReceiverBalances = append(ReceiverBalances, receiverBalancesCheck)
-		TransactionHash = append(TransactionHash, transactionHashCheck)
-		Messages = append(Messages, fmt.Sprint(txn.Tx.Body.Messages[0]))
-		TransactionNonces = append(TransactionNonces, "0")
-		AccountNonces = append(AccountNonces, accountNoncesCheck)
+	if err := recordPodExclusions(ldt, firstSeq, lastSeq, excluded); err != nil {
+		return nil, nil, nil, nil, err
 	}
 
 	batch.From = From
@@ -206,29 +441,121 @@ func createWasmPOD(ldt *leveldb.DB, batchStartIndex []byte, limit []byte) (witne
 	batch.Messages = Messages
 	batch.TransactionNonces = TransactionNonces
 	batch.AccountNonces = AccountNonces
+	batch.TxnCount = len(From)
+	batch.FirstTxnSeq = firstSeq
+	batch.LastTxnSeq = lastSeq
 
 	// add prover here
-	witnessVector, currentStatusHash, proofByte, pkErr := v1Wasm.GenerateProof(batch, limitInt+1)
+	witnessVector, currentStatusHash, proofByte, pkErr := v1Wasm.GenerateProof(batch, limitInt+1, podSize)
 	if pkErr != nil {
-		logs.Log.Error("Error in generating proof : %s" + pkErr.Error())
-		os.Exit(0)
+		logs.Log.Error(fmt.Sprintf("Error in generating proof : %s", pkErr.Error()))
+		return nil, nil, nil, nil, pkErr
 	}
+	log.Info().Str("module", "p2p").Str("Pod Number", strconv.Itoa(limitInt+1)).Msg("Successfully generated  Unverified proof")
 
 	witnessVectorByte, err := json.Marshal(witnessVector)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in marshalling witness vector : %s", err.Error()))
+		return nil, nil, nil, nil, fmt.Errorf("error marshalling witness vector: %w", err)
 	}
 
 	// string to []byte currentStatusHash
 	currentStatusHashByte, err := json.Marshal(currentStatusHash)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error in marshalling current status hash : %s", err.Error()))
-		os.Exit(0)
+		return nil, nil, nil, nil, fmt.Errorf("error marshalling current status hash: %w", err)
 	}
 
 	return witnessVectorByte, proofByte, currentStatusHashByte, &batch, nil
 
 }
+
+// createSVMPOD builds a pod from the system program transfers of the stored solana
+// transactions. The balances come from the transaction meta, so the station is not queried.
+func createSVMPOD(ldt *leveldb.DB, batchStartIndex []byte, limit []byte) (witness []byte, unverifiedProof []byte, MRH []byte, podData *types.BatchStruct, err error) {
+	baseConfig, err := shared.LoadConfig()
+	if err != nil {
+		return
+	}
+	policy := blocksync.InclusionPolicyFromConfig(baseConfig.Station)
+	limitInt, _ := strconv.Atoi(strings.TrimSpace(string(limit)))
+	batchStartIndexInt, _ := strconv.Atoi(strings.TrimSpace(string(batchStartIndex)))
+
+	var batch types.BatchStruct
+
+	var From []string
+	var To []string
+	var Amounts []string
+	var TransactionHash []string
+	var SenderBalances []string
+	var ReceiverBalances []string
+	var SenderPostBalances []string
+	var ReceiverPostBalances []string
+	var Messages []string
+	var TransactionNonces []string
+	var AccountNonces []string
+
+	podSize := baseConfig.Station.StationPodSize()
+	firstSeq, lastSeq := sealPodTxns(ldt, batchStartIndexInt+1, limitInt+1, podSize, baseConfig.Station.MaxPodInterval)
+	txns, lastSeq, excluded, err := fillPod(ldt, firstSeq, lastSeq, limitInt+1, podSize, policy, blocksync.SVMPodCandidate)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	for _, included := range txns {
+		transactionHash := utilis.Base58Decoder(included.hash)
+		for _, entry := range included.entries {
+			From = append(From, utilis.Base58Decoder(entry.From))
+			To = append(To, utilis.Base58Decoder(entry.To))
+			Amounts = append(Amounts, strconv.FormatUint(entry.Lamports, 10))
+			TransactionHash = append(TransactionHash, transactionHash)
+			SenderBalances = append(SenderBalances, strconv.FormatUint(entry.FromPreBalance, 10))
+			ReceiverBalances = append(ReceiverBalances, strconv.FormatUint(entry.ToPreBalance, 10))
+			SenderPostBalances = append(SenderPostBalances, strconv.FormatUint(entry.FromPostBalance, 10))
+			ReceiverPostBalances = append(ReceiverPostBalances, strconv.FormatUint(entry.ToPostBalance, 10))
+			Messages = append(Messages, entry.Type)
+			// Solana transactions carry a recent blockhash instead of a nonce.
+			TransactionNonces = append(TransactionNonces, "0")
+			AccountNonces = append(AccountNonces, "0")
+		}
+	}
+
+	if err := recordPodExclusions(ldt, firstSeq, lastSeq, excluded); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	batch.From = From
+	batch.To = To
+	batch.Amounts = Amounts
+	batch.TransactionHash = TransactionHash
+	batch.SenderBalances = SenderBalances
+	batch.ReceiverBalances = ReceiverBalances
+	batch.SenderPostBalances = SenderPostBalances
+	batch.ReceiverPostBalances = ReceiverPostBalances
+	batch.Messages = Messages
+	batch.TransactionNonces = TransactionNonces
+	batch.AccountNonces = AccountNonces
+	batch.TxnCount = len(From)
+	batch.FirstTxnSeq = firstSeq
+	batch.LastTxnSeq = lastSeq
+
+	witnessVector, currentStatusHash, proofByte, pkErr := v1SVM.GenerateProof(batch, limitInt+1, podSize)
+	if pkErr != nil {
+		logs.Log.Error(fmt.Sprintf("Error in generating proof : %s", pkErr.Error()))
+		return nil, nil, nil, nil, pkErr
+	}
+	log.Info().Str("module", "p2p").Str("Pod Number", strconv.Itoa(limitInt+1)).Msg("Successfully generated  Unverified proof")
+
+	witnessVectorByte, err := json.Marshal(witnessVector)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("error marshalling witness vector: %w", err)
+	}
+
+	currentStatusHashByte, err := json.Marshal(currentStatusHash)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("error marshalling current status hash: %w", err)
+	}
+
+	return witnessVectorByte, proofByte, currentStatusHashByte, &batch, nil
+}
+
 func saveVerifiedPOD() {
 
 	podState := shared.GetPodState()
@@ -237,9 +564,15 @@ func saveVerifiedPOD() {
 	currentPodNumber := podState.LatestPodHeight
 	currentPodNumberInt := int(currentPodNumber)
 
+	// Pods built before their range was recorded hold config.PODSize transactions.
+	firstSeq, lastSeq := config.PODSize*(currentPodNumberInt-1)+1, config.PODSize*currentPodNumberInt
+	if podState.Batch != nil && podState.Batch.LastTxnSeq > 0 {
+		firstSeq, lastSeq = podState.Batch.FirstTxnSeq, podState.Batch.LastTxnSeq
+	}
+
 	lds := shared.Node.NodeConnections.GetStaticDatabaseConnection()
 
-	err := lds.Put([]byte("batchStartIndex"), []byte(strconv.Itoa(config.PODSize*(currentPodNumberInt))), nil)
+	err := lds.Put([]byte("batchStartIndex"), []byte(strconv.Itoa(lastSeq)), nil)
 	if err != nil {
 		logs.Log.Error(fmt.Sprintf("Error in updating batchStartIndex in static db : %s", err.Error()))
 		os.Exit(0)
@@ -263,6 +596,12 @@ func saveVerifiedPOD() {
 	if err != nil {
 		panic("Failed to update pod data: " + err.Error())
 	}
+	txnDB := shared.Node.NodeConnections.GetTxnDatabaseConnection()
+	err = blocksync.IndexPodTxns(txnDB, currentPodNumberInt, firstSeq, lastSeq)
+	if err != nil {
+		logs.Log.Error(fmt.Sprintf("Error in indexing transactions of pod %d : %s", currentPodNumberInt, err.Error()))
+	}
+
 	podState.MasterTrackAppHash = nil
 	shared.SetPodState(podState)
 
@@ -276,29 +615,14 @@ func generatePodHash(Witness, uZKP, MRH []byte, podNumber []byte) []byte {
 	hash.Write(podNumber)
 	return hash.Sum(nil)
 }
-func storeNewPodState(CombinedPodHash, Witness, uZKP, previousMRH, MRH []byte, podNumber uint64, batchInput *types.BatchStruct, txState string) {
-	var podState *shared.PodState
-	votes := make(map[string]shared.Votes)
-	podState = &shared.PodState{
-		LatestPodHeight:     podNumber,
-		LatestTxState:       txState,
-		LatestPodHash:       MRH,
-		PreviousPodHash:     previousMRH,
-		LatestPodProof:      uZKP,
-		LatestPublicWitness: Witness,
-		Votes:               votes,
-		TracksAppHash:       CombinedPodHash,
-		Batch:               batchInput,
-	}
-	shared.SetPodState(podState)
-	updatePodStateInDatabase(podState)
-}
-func updateNewPodState(CombinedPodHash, Witness, uZKP, MRH []byte, podNumber uint64, batchInput *types.BatchStruct, txState string) {
-	var podState *shared.PodState
+
+// newPodState returns the state of a newly built pod, in PreInit until the pod built event.
+// masterTrackAppHash is the input of the master selection of the pod.
+func newPodState(CombinedPodHash, Witness, uZKP, MRH, masterTrackAppHash []byte, podNumber uint64, batchInput *types.BatchStruct) *shared.PodState {
 	votes := make(map[string]shared.Votes)
-	podState = &shared.PodState{
+	return &shared.PodState{
 		LatestPodHeight:     podNumber,
-		LatestTxState:       txState,
+		LatestTxState:       shared.TxStatePreInit,
 		LatestPodHash:       MRH,
 		PreviousPodHash:     shared.GetPodState().LatestPodHash,
 		LatestPodProof:      uZKP,
@@ -306,28 +630,7 @@ func updateNewPodState(CombinedPodHash, Witness, uZKP, MRH []byte, podNumber uin
 		Votes:               votes,
 		TracksAppHash:       CombinedPodHash,
 		Batch:               batchInput,
-	}
-	shared.SetPodState(podState)
-	updatePodStateInDatabase(podState)
-}
-func updateTxState(txState string) {
-	podState := shared.GetPodState()
-	podState.LatestTxState = txState
-	shared.SetPodState(podState)
-	updatePodStateInDatabase(podState)
-}
-func updatePodStateInDatabase(podState *shared.PodState) {
-	stateConnection := shared.Node.NodeConnections.GetStateDatabaseConnection()
-
-	podStateByte, err := json.Marshal(podState)
-	if err != nil {
-		logs.Log.Error(err.Error())
-		os.Exit(0)
-	}
-
-	err = stateConnection.Put([]byte("podState"), podStateByte, nil)
-	if err != nil {
-		logs.Log.Error(err.Error())
+		MasterTrackAppHash:  masterTrackAppHash,
 	}
 }
 func GetPodStateFromDatabase() (*types.PodState, error) {
@@ -0,0 +1,113 @@
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+// testMultiSend returns a stored cosmos transaction with a multi-send to outputs recipients.
+func testMultiSend(hash string, outputs int) types.BatchTransaction {
+	msg := types.Message{Type: "/cosmos.bank.v1beta1.MsgMultiSend", Inputs: []types.BatchMultiSendIO{{Address: "wasm1a", Coins: []types.BatchAmount{{Denom: "stake", Amount: fmt.Sprint(outputs)}}}}}
+	for i := 0; i < outputs; i++ {
+		msg.Outputs = append(msg.Outputs, types.BatchMultiSendIO{Address: fmt.Sprintf("wasm1b%d", i), Coins: []types.BatchAmount{{Denom: "stake", Amount: "1"}}})
+	}
+	var txn types.BatchTransaction
+	txn.Tx.Body.Messages = []types.Message{msg}
+	txn.TxResponse.TxHash = hash
+	return txn
+}
+
+func TestFillPodStartsNextPodWithOverflowingTransaction(t *testing.T) {
+	ldt, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ldt.Close()
+	for seq, txn := range []types.BatchTransaction{testMultiSend("A", 2), testMultiSend("B", 3), testMultiSend("C", 5), testMultiSend("D", 1)} {
+		data, err := json.Marshal(txn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ldt.Put([]byte(fmt.Sprintf("txns-%d", seq+1)), data, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The multi-send of B does not fit next to A, so the first pod ends before it.
+	txns, last, excluded, err := fillPod(ldt, 1, 4, 1, 4, blocksync.InclusionPolicy{}, blocksync.WasmPodCandidate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 1 || txns[0].seq != 1 || last != 1 || len(excluded) != 0 {
+		t.Fatalf("first pod = %+v ending at %d, excluded %+v; want A only", txns, last, excluded)
+	}
+
+	// B starts the second pod. C has more entries than a whole pod and is left out.
+	txns, last, excluded, err = fillPod(ldt, last+1, 4, 2, 4, blocksync.InclusionPolicy{}, blocksync.WasmPodCandidate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 2 || txns[0].seq != 2 || len(txns[0].entries) != 3 || txns[1].seq != 4 || last != 4 {
+		t.Fatalf("second pod = %+v ending at %d, want B and D", txns, last)
+	}
+	if len(excluded) != 1 || excluded[0].Seq != 3 || excluded[0].Reason != blocksync.ExcludedPodFull {
+		t.Errorf("excluded = %+v, want C as pod-full", excluded)
+	}
+}
+
+func TestEVMPodAccountOfGenesisBlock(t *testing.T) {
+	if at := evmPodAccount("0xa", types.TransactionStruct{BlockNumber: 0}); at.Block != 0 {
+		t.Errorf("evmPodAccount() of block 0 = %d, want 0", at.Block)
+	}
+	if at := evmPodAccount("0xa", types.TransactionStruct{BlockNumber: 7}); at.Block != 6 {
+		t.Errorf("evmPodAccount() of block 7 = %d, want 6", at.Block)
+	}
+}
+
+func TestFetchEVMPodStateStopsOnPermanentError(t *testing.T) {
+	var requests atomic.Int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var batch []struct {
+			ID int `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&batch)
+		var answers []string
+		for _, request := range batch {
+			answers = append(answers, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-32000,"message":"missing trie node"}}`, request.ID))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(answers, ","))
+	}))
+	defer node.Close()
+	stationRPC, err := stationclient.New([]string{node.URL}, stationclient.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	evmStatesOnce, evmStates = sync.Once{}, nil
+	t.Cleanup(func() { evmStatesOnce, evmStates = sync.Once{}, nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	txs := []types.TransactionStruct{{From: "0x0000000000000000000000000000000000000001", To: "0x0000000000000000000000000000000000000001", BlockNumber: 3}}
+	_, _, err = fetchEVMPodState(ctx, txs, stationRPC)
+	if !stationclient.IsPermanent(err) {
+		t.Fatalf("fetchEVMPodState() error = %v, want the permanent error", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("sent %d requests, want 1", requests.Load())
+	}
+}
@@ -0,0 +1,70 @@
+package p2p
+
+import (
+	"testing"
+
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/airchains-network/decentralized-sequencer/node/shared"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+func TestPodEventsOutOfOrder(t *testing.T) {
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	shared.Node = &shared.NodeS{}
+	defer func() { shared.Node = nil }()
+	shared.SetPodState(&shared.PodState{LatestPodHeight: 4, LatestTxState: shared.TxStateInitVRF})
+	lifecycle := shared.NewPodLifecycle(db)
+
+	// The podSubmitted gossip overtook the VRF messages, and a late message of pod 3 arrived.
+	var pending []postedPodEvent
+	pending = firePodEvent(lifecycle, pending, postedPodEvent{pod: 4, event: shared.EventPodSubmitted})
+	pending = firePodEvent(lifecycle, pending, postedPodEvent{pod: 3, event: shared.EventPodVerified})
+	pending = firePodEvent(lifecycle, pending, postedPodEvent{pod: 5, event: shared.EventVRFInitiated})
+	if len(pending) != 2 || lifecycle.Phase() != shared.TxStateInitVRF {
+		t.Fatalf("pending = %+v in %s, want the events of pods 4 and 5 kept", pending, lifecycle.Phase())
+	}
+
+	pending = firePodEvent(lifecycle, pending, postedPodEvent{pod: 4, event: shared.EventVRFInitiated})
+	pending = firePodEvent(lifecycle, pending, postedPodEvent{pod: 4, event: shared.EventVRFValidated})
+	pending = firePendingPodEvents(lifecycle, pending)
+	if lifecycle.Phase() != shared.TxStateVerifyPod {
+		t.Errorf("phase = %s, want the pending submission fired", lifecycle.Phase())
+	}
+	if len(pending) != 1 || pending[0].pod != 5 {
+		t.Errorf("pending = %+v, want the event of pod 5 only", pending)
+	}
+
+	// Once the pod is done, events of it are dropped.
+	shared.GetPodState().LatestPodHeight = 6
+	if pending = firePendingPodEvents(lifecycle, pending); len(pending) != 0 {
+		t.Errorf("pending = %+v, want events of done pods dropped", pending)
+	}
+}
+
+func TestPostPodEventDoesNotBlock(t *testing.T) {
+	defer func() {
+		for len(podEvents) > 0 {
+			<-podEvents
+		}
+	}()
+	// The lifecycle loop is not running, so the channel fills up.
+	for i := 0; i < cap(podEvents)+1; i++ {
+		postPodEvent(uint64(i), shared.EventPodVerified)
+	}
+	if len(podEvents) != cap(podEvents) {
+		t.Errorf("%d events queued, want %d", len(podEvents), cap(podEvents))
+	}
+}
+
+func TestEveryStationTypeBuildsPods(t *testing.T) {
+	for _, stationType := range blocksync.SupportedStationTypes() {
+		if _, ok := blocksync.StationPodBuilder(stationType); !ok {
+			t.Errorf("no pod builder registered for station type %s", stationType)
+		}
+	}
+}
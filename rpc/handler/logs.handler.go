@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/airchains-network/decentralized-sequencer/node/shared"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// HandleGetLogs answers tracks_getLogs from the locally indexed EVM receipts. Params[0]
+// is a filter object: {"address": "0x..", "topic0": "0x..", "fromBlock": 1, "toBlock": 10}.
+func HandleGetLogs(c *gin.Context, Params []interface{}) {
+	Log := logrus.New()
+	if len(Params) == 0 {
+		respondWithError(c, Log, 5, "Missing log filter", 400)
+		return
+	}
+	filterBytes, err := json.Marshal(Params[0])
+	if err != nil {
+		respondWithError(c, Log, 5, "Invalid log filter", 400)
+		return
+	}
+	var filter blocksync.EVMLogFilter
+	if err := json.Unmarshal(filterBytes, &filter); err != nil {
+		respondWithError(c, Log, 5, "Invalid log filter", 400)
+		return
+	}
+
+	txnDB := shared.Node.NodeConnections.GetTxnDatabaseConnection()
+	logs, err := blocksync.GetEVMLogs(txnDB, filter)
+	if err != nil {
+		Log.Error("Failed to get logs: ", err)
+		respondWithError(c, Log, 6, err.Error(), 400)
+		return
+	}
+	if logs == nil {
+		logs = []types.LogStruct{}
+	}
+	respondWithSuccess(c, Log, logs, "success")
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"github.com/airchains-network/decentralized-sequencer/node/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// HandleGetPodHistory answers tracks_getPodHistory. Params[0] is the pod number. It returns
+// the lifecycle transitions of the pod recorded by this track, oldest first.
+func HandleGetPodHistory(c *gin.Context, Params []interface{}) {
+	Log := logrus.New()
+	if len(Params) == 0 {
+		respondWithError(c, Log, 5, "Missing pod number", 400)
+		return
+	}
+	podNumber, ok := Params[0].(float64)
+	if !ok || podNumber < 1 {
+		respondWithError(c, Log, 5, "Pod number must be a positive number", 400)
+		return
+	}
+
+	stateDB := shared.Node.NodeConnections.GetStateDatabaseConnection()
+	journal, err := shared.PodJournal(stateDB, uint64(podNumber))
+	if err != nil {
+		Log.Error("Failed to read pod history: ", err)
+		respondWithError(c, Log, 6, "Failed to read pod history", 500)
+		return
+	}
+	if len(journal) == 0 {
+		respondWithError(c, Log, 7, "No history for pod", 404)
+		return
+	}
+	respondWithSuccess(c, Log, journal, "success")
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/airchains-network/decentralized-sequencer/node/shared"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// HandleGetPodByTxHash answers tracks_getPodByTxHash. Params[0] is the transaction hash.
+// PodNumber is null while the transaction is not part of a verified pod, and Exclusion is
+// set when the inclusion policy left the transaction out of its pod.
+func HandleGetPodByTxHash(c *gin.Context, Params []interface{}) {
+	Log := logrus.New()
+	if len(Params) == 0 {
+		respondWithError(c, Log, 5, "Missing transaction hash", 400)
+		return
+	}
+	hash, ok := Params[0].(string)
+	if !ok {
+		respondWithError(c, Log, 5, "Transaction hash must be a string", 400)
+		return
+	}
+
+	txnDB := shared.Node.NodeConnections.GetTxnDatabaseConnection()
+	seq, err := blocksync.GetTxnSeqByHash(txnDB, hash)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		respondWithError(c, Log, 7, "Transaction not found", 404)
+		return
+	}
+	if err != nil {
+		Log.Error("Failed to look up transaction hash: ", err)
+		respondWithError(c, Log, 6, "Failed to look up transaction hash", 500)
+		return
+	}
+
+	var responseData struct {
+		TransactionSeq int
+		PodNumber      *int
+		Exclusion      *blocksync.TxnExclusion
+	}
+	responseData.TransactionSeq = seq
+	podNumber, err := blocksync.GetTxnPod(txnDB, seq)
+	if err == nil {
+		responseData.PodNumber = &podNumber
+	} else if !errors.Is(err, leveldb.ErrNotFound) {
+		Log.Error("Failed to look up pod of transaction: ", err)
+		respondWithError(c, Log, 6, "Failed to look up pod of transaction", 500)
+		return
+	}
+
+	exclusion, err := blocksync.GetTxnExclusion(txnDB, seq)
+	if err == nil {
+		responseData.Exclusion = &exclusion
+	} else if !errors.Is(err, leveldb.ErrNotFound) {
+		Log.Error("Failed to look up exclusion of transaction: ", err)
+		respondWithError(c, Log, 6, "Failed to look up exclusion of transaction", 500)
+		return
+	}
+
+	respondWithSuccess(c, Log, responseData, "success")
+}
@@ -0,0 +1,523 @@
+// Package snapshot exports the LevelDB stores of a track into a single archive and
+// restores such an archive into a fresh home directory.
+//
+// An archive is a gzipped tar holding one leveldb/<store>.kv file per store, the pod
+// state as podState.json and, last, manifest.json. A store file is the sequence of its
+// entries, each written as the uvarint length of the key, the key, the uvarint length
+// of the value and the value.
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// FormatVersion is the archive layout written by Export.
+const FormatVersion = 1
+
+const (
+	manifestName = "manifest.json"
+	podStateName = "podState.json"
+	storeDir     = "leveldb"
+	// importBatchSize bounds the entries written to a store in one batch on import.
+	importBatchSize = 1000
+)
+
+// Manifest describes the content of an archive.
+type Manifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	SchemaVersion int       `json:"schemaVersion"`
+	StationType   string    `json:"stationType"`
+	// LatestBlock is the last indexed station block, -1 if there is none.
+	LatestBlock     int    `json:"latestBlock"`
+	LatestBlockHash string `json:"latestBlockHash"`
+	TxnCount        int    `json:"txnCount"`
+	// PodNumber is the pod the track was processing and VerifiedPods the number of pods
+	// verified on the junction.
+	PodNumber     uint64          `json:"podNumber"`
+	VerifiedPods  int             `json:"verifiedPods"`
+	TracksAppHash string          `json:"tracksAppHash"`
+	Stores        []StoreManifest `json:"stores"`
+	// Checksum is the SHA-256 of the store checksums, in store order.
+	Checksum string `json:"checksum"`
+}
+
+// StoreManifest describes one LevelDB store of an archive.
+type StoreManifest struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"`
+	SHA256  string `json:"sha256"`
+}
+
+// DataDir returns the directory holding the LevelDB stores of a tracks home directory.
+func DataDir(home string) string {
+	return filepath.Join(home, "data", "leveldb")
+}
+
+// Export writes every store of home, which must not be in use by a running node, into
+// an archive at out and returns its manifest.
+func Export(home, stationType, out string) (*Manifest, error) {
+	dataDir := DataDir(home)
+	stores := make(map[string]*leveldb.DB, len(blocksync.DatabaseNames))
+	defer func() {
+		for _, db := range stores {
+			db.Close()
+		}
+	}()
+	for _, name := range blocksync.DatabaseNames {
+		db, err := leveldb.OpenFile(filepath.Join(dataDir, name), &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the %s store, stop the node before exporting: %w", name, err)
+		}
+		stores[name] = db
+	}
+	if err := blocksync.CheckSchemaVersion(stores["static"]); err != nil {
+		return nil, fmt.Errorf("cannot export the databases: %w", err)
+	}
+
+	manifest, podState, err := describeStores(stores, stationType)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeArchive(file, stores, manifest, podState); err != nil {
+		file.Close()
+		os.Remove(out)
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(out)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// describeStores fills the heights and pod state of a manifest from the stores.
+func describeStores(stores map[string]*leveldb.DB, stationType string) (*Manifest, []byte, error) {
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		SchemaVersion: blocksync.ReadSchemaVersion(stores["static"]),
+		StationType:   blocksync.NormalizeStationType(stationType),
+		LatestBlock:   blocksync.LatestIndexedBlock(stores["blocks"]),
+		TxnCount:      readCounter(stores["tx"], "txnCount"),
+		VerifiedPods:  readCounter(stores["static"], "batchCount"),
+	}
+	if manifest.LatestBlock >= 0 {
+		blockHash, err := blocksync.StoredBlockHash(stores["blocks"], stationType, manifest.LatestBlock)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the latest block: %w", err)
+		}
+		manifest.LatestBlockHash = blockHash
+	}
+
+	podState, err := stores["state"].Get([]byte("podState"), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the pod state: %w", err)
+	}
+	var state types.PodState
+	if err := json.Unmarshal(podState, &state); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode the pod state: %w", err)
+	}
+	manifest.PodNumber = state.LatestPodHeight
+	manifest.TracksAppHash = hex.EncodeToString(state.TracksAppHash)
+	return manifest, podState, nil
+}
+
+func writeArchive(w io.Writer, stores map[string]*leveldb.DB, manifest *Manifest, podState []byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, name := range blocksync.DatabaseNames {
+		store, err := writeStore(tw, name, stores[name])
+		if err != nil {
+			return fmt.Errorf("failed to export the %s store: %w", name, err)
+		}
+		manifest.Stores = append(manifest.Stores, store)
+	}
+	manifest.Checksum = storesChecksum(manifest.Stores)
+
+	if err := writeFile(tw, podStateName, podState); err != nil {
+		return err
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(tw, manifestName, manifestJSON); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeStore dumps db into a temporary file, as tar needs the size up front, and adds it
+// to the archive.
+func writeStore(tw *tar.Writer, name string, db *leveldb.DB) (StoreManifest, error) {
+	store := StoreManifest{Name: name}
+	tmp, err := os.CreateTemp("", "tracks-snapshot-"+name)
+	if err != nil {
+		return store, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	snap, err := db.GetSnapshot()
+	if err != nil {
+		return store, err
+	}
+	defer snap.Release()
+
+	digest := sha256.New()
+	buf := bufio.NewWriter(io.MultiWriter(tmp, digest))
+	iter := snap.NewIterator(nil, nil)
+	for iter.Next() {
+		if err := writeRecord(buf, iter.Key(), iter.Value()); err != nil {
+			iter.Release()
+			return store, err
+		}
+		store.Entries++
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return store, err
+	}
+	if err := buf.Flush(); err != nil {
+		return store, err
+	}
+	store.SHA256 = hex.EncodeToString(digest.Sum(nil))
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return store, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return store, err
+	}
+	header := &tar.Header{Name: path.Join(storeDir, name+".kv"), Mode: 0600, Size: size, ModTime: time.Now()}
+	if err := tw.WriteHeader(header); err != nil {
+		return store, err
+	}
+	_, err = io.Copy(tw, tmp)
+	return store, err
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func writeRecord(w io.Writer, key, value []byte) error {
+	var length [binary.MaxVarintLen64]byte
+	for _, field := range [][]byte{key, value} {
+		n := binary.PutUvarint(length[:], uint64(len(field)))
+		if _, err := w.Write(length[:n]); err != nil {
+			return err
+		}
+		if _, err := w.Write(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func storesChecksum(stores []StoreManifest) string {
+	digest := sha256.New()
+	for _, store := range stores {
+		fmt.Fprintf(digest, "%s %d %s\n", store.Name, store.Entries, store.SHA256)
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// Import restores the archive at archive into home. The stores are written next to the
+// data directory and only moved into place once the archive checks out, so a failed
+// import leaves home as it was. home must not hold any store yet. When stationType is
+// set, the archive must come from a track of that station type.
+func Import(archive, home, stationType string) (*Manifest, error) {
+	dataDir := DataDir(home)
+	for _, name := range blocksync.DatabaseNames {
+		if _, err := os.Stat(filepath.Join(dataDir, name)); err == nil {
+			return nil, fmt.Errorf("%s already holds a %s store, import into a fresh home directory", dataDir, name)
+		}
+	}
+
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dataDir), 0700); err != nil {
+		return nil, err
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dataDir), "leveldb-import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, podState, err := restoreArchive(file, staging)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkManifest(manifest, podState, staging, stationType); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	for _, name := range blocksync.DatabaseNames {
+		if err := os.Rename(filepath.Join(staging, name), filepath.Join(dataDir, name)); err != nil {
+			return nil, fmt.Errorf("failed to move the %s store into place: %w", name, err)
+		}
+	}
+	return manifest, nil
+}
+
+// restoreArchive writes every store of the archive under dir and returns the manifest
+// and the pod state once the stores match the manifest.
+func restoreArchive(r io.Reader, dir string) (*Manifest, []byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a snapshot archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var manifest *Manifest
+	var podState []byte
+	restored := make(map[string]StoreManifest)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the archive: %w", err)
+		}
+		switch {
+		case header.Name == manifestName:
+			manifest = new(Manifest)
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode the manifest: %w", err)
+			}
+		case header.Name == podStateName:
+			if podState, err = io.ReadAll(tr); err != nil {
+				return nil, nil, err
+			}
+		case path.Dir(header.Name) == storeDir && strings.HasSuffix(header.Name, ".kv"):
+			name := strings.TrimSuffix(path.Base(header.Name), ".kv")
+			if !isStoreName(name) {
+				return nil, nil, fmt.Errorf("unknown store %q in the archive", name)
+			}
+			store, err := restoreStore(tr, header.Size, filepath.Join(dir, name))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to restore the %s store: %w", name, err)
+			}
+			store.Name = name
+			restored[name] = store
+		default:
+			return nil, nil, fmt.Errorf("unexpected file %q in the archive", header.Name)
+		}
+	}
+	if manifest == nil {
+		return nil, nil, errors.New("the archive has no manifest")
+	}
+	if podState == nil {
+		return nil, nil, errors.New("the archive has no pod state")
+	}
+
+	if manifest.FormatVersion != FormatVersion {
+		return nil, nil, fmt.Errorf("archive format version %d is not supported, expected %d", manifest.FormatVersion, FormatVersion)
+	}
+	if len(manifest.Stores) != len(blocksync.DatabaseNames) {
+		return nil, nil, fmt.Errorf("the manifest lists %d stores, expected %d", len(manifest.Stores), len(blocksync.DatabaseNames))
+	}
+	for i, want := range manifest.Stores {
+		got, ok := restored[want.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("the %s store is missing from the archive", want.Name)
+		}
+		if got.Entries != want.Entries || got.SHA256 != want.SHA256 {
+			return nil, nil, fmt.Errorf("the %s store does not match the manifest: %d entries with checksum %s, expected %d with %s", want.Name, got.Entries, got.SHA256, want.Entries, want.SHA256)
+		}
+		manifest.Stores[i] = got
+	}
+	if checksum := storesChecksum(manifest.Stores); checksum != manifest.Checksum {
+		return nil, nil, fmt.Errorf("archive checksum %s does not match the manifest checksum %s", checksum, manifest.Checksum)
+	}
+	return manifest, podState, nil
+}
+
+func isStoreName(name string) bool {
+	for _, store := range blocksync.DatabaseNames {
+		if store == name {
+			return true
+		}
+	}
+	return false
+}
+
+func restoreStore(r io.Reader, size int64, dir string) (StoreManifest, error) {
+	var store StoreManifest
+	db, err := leveldb.OpenFile(dir, &opt.Options{ErrorIfExist: true})
+	if err != nil {
+		return store, err
+	}
+	defer db.Close()
+
+	digest := sha256.New()
+	reader := &storeReader{r: bufio.NewReader(io.TeeReader(r, digest)), left: size}
+	batch := new(leveldb.Batch)
+	for {
+		key, value, err := readRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return store, err
+		}
+		batch.Put(key, value)
+		store.Entries++
+		if batch.Len() >= importBatchSize {
+			if err := db.Write(batch, nil); err != nil {
+				return store, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := db.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return store, err
+	}
+	store.SHA256 = hex.EncodeToString(digest.Sum(nil))
+	return store, nil
+}
+
+// storeReader reads the entries of a store file of the archive, and counts the bytes
+// left in it to bound the fields before they are allocated.
+type storeReader struct {
+	r    *bufio.Reader
+	left int64
+}
+
+func (s *storeReader) ReadByte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err == nil {
+		s.left--
+	}
+	return b, err
+}
+
+// readRecord returns the next entry of a store file, or io.EOF at its end.
+func readRecord(r *storeReader) ([]byte, []byte, error) {
+	key, err := readField(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	value, err := readField(r)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return key, value, err
+}
+
+func readField(r *storeReader) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	// The checksum is only known at the end of the file, a corrupt length must not
+	// allocate more than the file holds.
+	if length > uint64(r.left) {
+		return nil, fmt.Errorf("field of %d bytes is longer than the %d bytes left in the store file", length, r.left)
+	}
+	field := make([]byte, length)
+	if _, err := io.ReadFull(r.r, field); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	r.left -= int64(length)
+	return field, nil
+}
+
+// checkManifest compares the restored stores in dir with the heights and pod state of
+// the manifest and with podState.json.
+func checkManifest(manifest *Manifest, podState []byte, dir, stationType string) error {
+	if manifest.SchemaVersion > blocksync.SchemaVersion() {
+		return fmt.Errorf("the archive has schema version %d, newer than version %d supported by this binary", manifest.SchemaVersion, blocksync.SchemaVersion())
+	}
+	if stationType != "" && blocksync.NormalizeStationType(stationType) != manifest.StationType {
+		return fmt.Errorf("the archive is from a %s station, this track is configured for %s", manifest.StationType, blocksync.NormalizeStationType(stationType))
+	}
+
+	stores := make(map[string]*leveldb.DB, len(blocksync.DatabaseNames))
+	defer func() {
+		for _, db := range stores {
+			db.Close()
+		}
+	}()
+	for _, name := range blocksync.DatabaseNames {
+		db, err := leveldb.OpenFile(filepath.Join(dir, name), &opt.Options{ReadOnly: true, ErrorIfMissing: true})
+		if err != nil {
+			return fmt.Errorf("failed to open the restored %s store: %w", name, err)
+		}
+		stores[name] = db
+	}
+	restored, restoredPodState, err := describeStores(stores, manifest.StationType)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(restoredPodState, podState) {
+		return errors.New("the restored pod state does not match podState.json")
+	}
+	if restored.LatestBlock != manifest.LatestBlock || !strings.EqualFold(restored.LatestBlockHash, manifest.LatestBlockHash) {
+		return fmt.Errorf("restored latest block %d (%s) does not match the manifest block %d (%s)", restored.LatestBlock, restored.LatestBlockHash, manifest.LatestBlock, manifest.LatestBlockHash)
+	}
+	if restored.TxnCount != manifest.TxnCount || restored.VerifiedPods != manifest.VerifiedPods || restored.PodNumber != manifest.PodNumber || restored.TracksAppHash != manifest.TracksAppHash {
+		return fmt.Errorf("restored stores hold %d transactions and pod %d, the manifest %d transactions and pod %d", restored.TxnCount, restored.PodNumber, manifest.TxnCount, manifest.PodNumber)
+	}
+	return nil
+}
+
+// readCounter reads a decimal counter, returning 0 when it is missing.
+func readCounter(db *leveldb.DB, key string) int {
+	value, err := db.Get([]byte(key), nil)
+	if err != nil {
+		return 0
+	}
+	count, _ := strconv.Atoi(strings.TrimSpace(string(value)))
+	return count
+}
@@ -0,0 +1,212 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// testEntries are written into the stores of a test home, by store.
+var testEntries = map[string]map[string]string{
+	"blocks": {"blockCount": "2", "Block0": `{"hash":"0x00"}`, "Block1": `{"hash":"0xAB"}`},
+	"tx":     {"txnCount": "2", "txns-1": `{"hash":"0x01"}`, "txns-2": `{"hash":"0x02"}`},
+	"static": {"batchCount": "1", "batchStartIndex": "25", "schemaVersion": strconv.Itoa(blocksync.SchemaVersion())},
+	"state":  {"podState": `{"LatestPodHeight":2,"LatestTxState":"PreInit","TracksAppHash":"AQI="}`},
+	"da":     {"da-1": `{"DAKey":"key"}`},
+}
+
+func newTestHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	for _, name := range blocksync.DatabaseNames {
+		db, err := leveldb.OpenFile(filepath.Join(DataDir(home), name), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range testEntries[name] {
+			if err := db.Put([]byte(key), []byte(value), nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		db.Close()
+	}
+	return home
+}
+
+func readStore(t *testing.T, home, name string) map[string]string {
+	t.Helper()
+	db, err := leveldb.OpenFile(filepath.Join(DataDir(home), name), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	entries := make(map[string]string)
+	iter := db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		entries[string(iter.Key())] = string(iter.Value())
+	}
+	return entries
+}
+
+// rewriteArchive copies the archive at src to dst, passing every file through edit.
+func rewriteArchive(t *testing.T, src, dst string, edit func(name string, data []byte) []byte) {
+	t.Helper()
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	gzOut := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gzOut)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		data = edit(header.Name, data)
+		header.Size = int64(len(data))
+		tw.WriteHeader(header)
+		tw.Write(data)
+	}
+	tw.Close()
+	gzOut.Close()
+	if err := os.WriteFile(dst, out.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	home := newTestHome(t)
+	archive := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+	manifest, err := Export(home, "evm", archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.LatestBlock != 1 || manifest.LatestBlockHash != "0xAB" || manifest.TxnCount != 2 || manifest.PodNumber != 2 || manifest.VerifiedPods != 1 || manifest.SchemaVersion != blocksync.SchemaVersion() {
+		t.Errorf("Export() manifest = %+v", manifest)
+	}
+	if len(manifest.Stores) != len(blocksync.DatabaseNames) || manifest.Checksum == "" {
+		t.Errorf("Export() manifest stores = %+v, checksum %q", manifest.Stores, manifest.Checksum)
+	}
+
+	restored := t.TempDir()
+	imported, err := Import(archive, restored, "EVM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Checksum != manifest.Checksum {
+		t.Errorf("Import() checksum = %s, want %s", imported.Checksum, manifest.Checksum)
+	}
+	for _, name := range blocksync.DatabaseNames {
+		want := testEntries[name]
+		got := readStore(t, restored, name)
+		if len(got) != len(want) {
+			t.Errorf("%s store has %d entries, want %d", name, len(got), len(want))
+		}
+		for key, value := range want {
+			if got[key] != value {
+				t.Errorf("%s store %s = %q, want %q", name, key, got[key], value)
+			}
+		}
+	}
+
+	if _, err := Import(archive, restored, ""); err == nil || !strings.Contains(err.Error(), "fresh home") {
+		t.Errorf("Import() into a home with stores = %v, want a refusal", err)
+	}
+}
+
+func TestImportRejectsBadArchives(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+	if _, err := Export(newTestHome(t), "evm", archive); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		edit        func(name string, data []byte) []byte
+		stationType string
+		want        string
+	}{
+		{
+			name: "corrupted store",
+			edit: func(name string, data []byte) []byte {
+				if name == "leveldb/tx.kv" {
+					return bytes.Replace(data, []byte("0x01"), []byte("0x99"), 1)
+				}
+				return data
+			},
+			want: "does not match the manifest",
+		},
+		{
+			name: "oversized field",
+			edit: func(name string, data []byte) []byte {
+				if name == "leveldb/tx.kv" {
+					return append(binary.AppendUvarint(nil, 1<<62), data...)
+				}
+				return data
+			},
+			want: "longer than",
+		},
+		{
+			name: "edited pod state",
+			edit: func(name string, data []byte) []byte {
+				if name == podStateName {
+					return bytes.Replace(data, []byte(`"LatestPodHeight":2`), []byte(`"LatestPodHeight":9`), 1)
+				}
+				return data
+			},
+			want: "pod state",
+		},
+		{
+			name: "newer schema",
+			edit: func(name string, data []byte) []byte {
+				if name == manifestName {
+					return bytes.Replace(data, []byte(fmt.Sprintf(`"schemaVersion": %d`, blocksync.SchemaVersion())), []byte(`"schemaVersion": 999`), 1)
+				}
+				return data
+			},
+			want: "newer",
+		},
+		{
+			name:        "other station type",
+			edit:        func(name string, data []byte) []byte { return data },
+			stationType: "wasm",
+			want:        "evm station",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited := filepath.Join(t.TempDir(), "edited.tar.gz")
+			rewriteArchive(t, archive, edited, tt.edit)
+			home := t.TempDir()
+			if _, err := Import(edited, home, tt.stationType); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Import() = %v, want an error about %q", err, tt.want)
+			}
+			if entries, _ := os.ReadDir(DataDir(home)); len(entries) != 0 {
+				t.Errorf("a failed import left %d stores behind", len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+// Package stationclient talks to the RPC and API endpoints of a station. A Client holds
+// one or more endpoints of the same station, sends each request to the first healthy
+// one, fails over to the next when it is down and retries with exponential backoff, so a
+// track keeps running while a single provider is unavailable.
+package stationclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultTimeout        = 15 * time.Second
+	DefaultRetries        = 3
+	DefaultBackoffBase    = 500 * time.Millisecond
+	DefaultBackoffMax     = 30 * time.Second
+	DefaultHealthInterval = 10 * time.Second
+)
+
+// Options configures a Client. Zero values use the defaults above.
+type Options struct {
+	// Timeout bounds a single request to one endpoint.
+	Timeout time.Duration
+	// Retries is the number of times a failed request is sent again, to the next healthy
+	// endpoint when there is one. A negative value disables retries.
+	Retries int
+	// RateLimit is the number of requests per second sent to each endpoint. Zero
+	// disables the limit.
+	RateLimit float64
+	// BackoffBase and BackoffMax bound the exponential wait before a retry when no
+	// endpoint is healthy, and how long a failed endpoint is skipped.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// HealthInterval is how often endpoints marked down are probed.
+	HealthInterval time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	switch {
+	case o.Retries == 0:
+		o.Retries = DefaultRetries
+	case o.Retries < 0:
+		o.Retries = 0
+	}
+	if o.BackoffBase <= 0 {
+		o.BackoffBase = DefaultBackoffBase
+	}
+	if o.BackoffMax <= 0 {
+		o.BackoffMax = DefaultBackoffMax
+	}
+	if o.HealthInterval <= 0 {
+		o.HealthInterval = DefaultHealthInterval
+	}
+	return o
+}
+
+// ParseEndpoints splits a comma separated endpoint list from sequencer.toml. Trailing
+// slashes are removed so paths can be appended.
+func ParseEndpoints(spec string) []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(spec, ",") {
+		endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+		if endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// StatusError is returned for a response with a status other than 200 OK.
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %d %s: %s", e.Endpoint, e.StatusCode, http.StatusText(e.StatusCode), bytes.TrimSpace(e.Body))
+}
+
+// retryable reports whether another endpoint, or the same one later, may serve the
+// request.
+func (e *StatusError) retryable() bool {
+	return e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error returned to Do as an answer of a working endpoint, such as
+// a JSON-RPC error. Do returns it without retrying, and IsPermanent reports it.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or an error it wraps, was marked by Permanent, so
+// sending the request again would get the same answer.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// PermanentRPCCode reports whether a JSON-RPC error with code answers the request
+// itself: a reverted call, or a server error such as a missing trie node. Other codes,
+// such as a rate limit, may be served by another endpoint.
+func PermanentRPCCode(code int) bool {
+	return code == 3 || code == -32000
+}
+
+// EndpointStatus is a point-in-time view of one endpoint.
+type EndpointStatus struct {
+	URL       string
+	Healthy   bool
+	Failures  int
+	LastError string
+}
+
+type endpoint struct {
+	url     string
+	limiter *limiter
+
+	mu        sync.Mutex
+	failures  int
+	downUntil time.Time
+	lastErr   error
+}
+
+// Client sends requests to the endpoints of one station. It is safe for concurrent use.
+type Client struct {
+	endpoints []*endpoint
+	http      *http.Client
+	opts      Options
+}
+
+// New returns a client for endpoints, which are tried in the given order.
+func New(endpoints []string, opts Options) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no station endpoint configured")
+	}
+	opts = opts.withDefaults()
+	c := &Client{http: &http.Client{}, opts: opts}
+	for _, url := range endpoints {
+		c.endpoints = append(c.endpoints, &endpoint{url: url, limiter: newLimiter(opts.RateLimit)})
+	}
+	return c, nil
+}
+
+var (
+	sharedMu      sync.Mutex
+	sharedClients = make(map[string]*Client)
+)
+
+// Shared returns the process wide client for the comma separated endpoints in spec, so
+// the indexer and the pod generator see the same endpoint health. The client is created,
+// and starts probing failed endpoints, on first use; later calls ignore opts.
+func Shared(spec string, opts Options) (*Client, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if c, ok := sharedClients[spec]; ok {
+		return c, nil
+	}
+	c, err := New(ParseEndpoints(spec), opts)
+	if err != nil {
+		return nil, err
+	}
+	c.StartHealthChecks(context.Background())
+	sharedClients[spec] = c
+	return c, nil
+}
+
+// HTTPClient returns the http.Client used for requests. Per request timeouts come from
+// the context Do passes to its callback.
+func (c *Client) HTTPClient() *http.Client {
+	return c.http
+}
+
+// Endpoints returns the configured endpoint URLs in order.
+func (c *Client) Endpoints() []string {
+	urls := make([]string, len(c.endpoints))
+	for i, ep := range c.endpoints {
+		urls[i] = ep.url
+	}
+	return urls
+}
+
+// Status returns the health of every endpoint in order. An endpoint stays unhealthy
+// from a failure until a request or probe to it succeeds.
+func (c *Client) Status() []EndpointStatus {
+	statuses := make([]EndpointStatus, len(c.endpoints))
+	for i, ep := range c.endpoints {
+		ep.mu.Lock()
+		statuses[i] = EndpointStatus{URL: ep.url, Healthy: ep.failures == 0, Failures: ep.failures}
+		if ep.lastErr != nil {
+			statuses[i].LastError = ep.lastErr.Error()
+		}
+		ep.mu.Unlock()
+	}
+	return statuses
+}
+
+// Do calls call with the URL of the first healthy endpoint and a context bounded by
+// the request timeout. A failed call marks the endpoint down and is retried, right away
+// on the next healthy endpoint or after a backoff when none is left.
+func (c *Client) Do(ctx context.Context, call func(ctx context.Context, endpoint string) error) error {
+	var lastErr error
+	for attempt := 0; attempt <= c.opts.Retries; attempt++ {
+		ep, healthy := c.pick()
+		if !healthy && attempt > 0 {
+			if err := sleep(ctx, c.backoff(attempt)); err != nil {
+				return err
+			}
+		}
+		if err := ep.limiter.wait(ctx); err != nil {
+			return err
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+		err := call(reqCtx, ep.url)
+		cancel()
+		switch {
+		case err == nil:
+			c.markUp(ep)
+			return nil
+		case IsPermanent(err):
+			c.markUp(ep)
+			return err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		}
+		c.markDown(ep, err)
+		lastErr = err
+	}
+	return fmt.Errorf("station request failed after %d attempts: %w", c.opts.Retries+1, lastErr)
+}
+
+// Get sends a GET request for path and returns the body of the 200 OK response.
+func (c *Client) Get(ctx context.Context, path string) ([]byte, error) {
+	return c.send(ctx, http.MethodGet, path, "", nil)
+}
+
+// Post sends body to path and returns the body of the 200 OK response.
+func (c *Client) Post(ctx context.Context, path string, contentType string, body []byte) ([]byte, error) {
+	return c.send(ctx, http.MethodPost, path, contentType, body)
+}
+
+func (c *Client) send(ctx context.Context, method, path, contentType string, body []byte) ([]byte, error) {
+	var data []byte
+	err := c.Do(ctx, func(ctx context.Context, endpoint string) error {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			return Permanent(err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			statusErr := &StatusError{Endpoint: endpoint, StatusCode: resp.StatusCode, Body: respBody}
+			if statusErr.retryable() {
+				return statusErr
+			}
+			return Permanent(statusErr)
+		}
+		data = respBody
+		return nil
+	})
+	return data, err
+}
+
+// pick returns the first healthy endpoint, or the one that comes back soonest when all
+// of them are down.
+func (c *Client) pick() (*endpoint, bool) {
+	now := time.Now()
+	var soonest *endpoint
+	var soonestUntil time.Time
+	for _, ep := range c.endpoints {
+		ep.mu.Lock()
+		downUntil := ep.downUntil
+		ep.mu.Unlock()
+		if !downUntil.After(now) {
+			return ep, true
+		}
+		if soonest == nil || downUntil.Before(soonestUntil) {
+			soonest, soonestUntil = ep, downUntil
+		}
+	}
+	return soonest, false
+}
+
+// backoff returns the wait before the attempt-th retry, or before an endpoint that
+// failed attempt times in a row is used again.
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.opts.BackoffBase
+	for i := 1; i < attempt && wait < c.opts.BackoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, c.opts.BackoffMax)
+}
+
+func (c *Client) markDown(ep *endpoint, err error) {
+	ep.mu.Lock()
+	ep.failures++
+	ep.downUntil = time.Now().Add(c.backoff(ep.failures))
+	ep.lastErr = err
+	failures := ep.failures
+	ep.mu.Unlock()
+	if failures == 1 {
+		log.Warn().Str("module", "stationclient").Err(err).Msg(fmt.Sprintf("Station endpoint %s is down", ep.url))
+	}
+}
+
+func (c *Client) markUp(ep *endpoint) {
+	ep.mu.Lock()
+	failures := ep.failures
+	ep.failures = 0
+	ep.downUntil = time.Time{}
+	ep.lastErr = nil
+	ep.mu.Unlock()
+	if failures > 0 {
+		log.Info().Str("module", "stationclient").Msg(fmt.Sprintf("Station endpoint %s recovered", ep.url))
+	}
+}
+
+// StartHealthChecks probes the endpoints that are down every HealthInterval until ctx
+// is cancelled, so they are used again as soon as they recover instead of after their
+// backoff.
+func (c *Client) StartHealthChecks(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.opts.HealthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.checkHealth(ctx)
+			}
+		}
+	}()
+}
+
+func (c *Client) checkHealth(ctx context.Context) {
+	for _, ep := range c.endpoints {
+		ep.mu.Lock()
+		down := ep.failures > 0
+		ep.mu.Unlock()
+		if !down {
+			continue
+		}
+		if err := c.probe(ctx, ep.url); err != nil {
+			c.markDown(ep, err)
+			continue
+		}
+		c.markUp(ep)
+	}
+}
+
+// probe reports whether endpoint answers HTTP requests. Any response other than a
+// gateway error counts: RPC servers answer a bare GET with all kinds of statuses.
+func (c *Client) probe(ctx context.Context, endpoint string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &StatusError{Endpoint: endpoint, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// limiter spaces requests to one endpoint evenly to stay under its rate limit.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(perSecond float64) *limiter {
+	if perSecond <= 0 {
+		return &limiter{}
+	}
+	return &limiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	return sleep(ctx, wait)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,213 @@
+package stationclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer answers every request with the next status in statuses, then with 200
+// and body.
+type countingServer struct {
+	*httptest.Server
+	hits     atomic.Int32
+	statuses []int
+	body     string
+	delay    time.Duration
+}
+
+func newCountingServer(t *testing.T, body string, statuses ...int) *countingServer {
+	t.Helper()
+	s := &countingServer{statuses: statuses, body: body}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit := int(s.hits.Add(1))
+		time.Sleep(s.delay)
+		if hit <= len(s.statuses) {
+			http.Error(w, "failing", s.statuses[hit-1])
+			return
+		}
+		fmt.Fprint(w, s.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testOptions() Options {
+	return Options{BackoffBase: 10 * time.Millisecond, BackoffMax: 50 * time.Millisecond}
+}
+
+func newTestClient(t *testing.T, opts Options, endpoints ...string) *Client {
+	t.Helper()
+	c, err := New(endpoints, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestParseEndpoints(t *testing.T) {
+	got := ParseEndpoints(" http://a:26657/, http://b:26657 ,,")
+	want := []string{"http://a:26657", "http://b:26657"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseEndpoints() = %q, want %q", got, want)
+	}
+	if _, err := New(ParseEndpoints(""), Options{}); err == nil {
+		t.Error("New() accepted an empty endpoint list")
+	}
+}
+
+func TestClientFailsOver(t *testing.T) {
+	primary := newCountingServer(t, "primary", http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	fallback := newCountingServer(t, "fallback")
+	c := newTestClient(t, Options{BackoffBase: time.Minute}, primary.URL, fallback.URL)
+
+	for i := 0; i < 2; i++ {
+		body, err := c.Get(context.Background(), "/status")
+		if err != nil || string(body) != "fallback" {
+			t.Fatalf("Get() = %q, %v, want the fallback answer", body, err)
+		}
+	}
+	// The primary is skipped until its cooldown expires.
+	if hits := primary.hits.Load(); hits != 1 {
+		t.Errorf("primary was hit %d times, want 1", hits)
+	}
+	status := c.Status()
+	if status[0].Healthy || status[0].Failures != 1 || status[0].LastError == "" || !status[1].Healthy {
+		t.Errorf("Status() = %+v, want the primary down", status)
+	}
+}
+
+func TestClientRetriesWithBackoff(t *testing.T) {
+	flaky := newCountingServer(t, "ok", http.StatusBadGateway, http.StatusTooManyRequests)
+	c := newTestClient(t, testOptions(), flaky.URL)
+	if body, err := c.Get(context.Background(), "/"); err != nil || string(body) != "ok" {
+		t.Fatalf("Get() = %q, %v, want ok after two retries", body, err)
+	}
+	if status := c.Status(); !status[0].Healthy {
+		t.Errorf("Status() = %+v, want the endpoint healthy after a success", status)
+	}
+
+	down := newCountingServer(t, "", http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	c = newTestClient(t, Options{Retries: 2, BackoffBase: 10 * time.Millisecond}, down.URL)
+	_, err := c.Get(context.Background(), "/")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Get() error = %v, want the last status error", err)
+	}
+	if hits := down.hits.Load(); hits != 3 {
+		t.Errorf("endpoint was hit %d times, want 3", hits)
+	}
+}
+
+func TestClientDoesNotRetryAnswers(t *testing.T) {
+	primary := newCountingServer(t, "", http.StatusNotFound)
+	fallback := newCountingServer(t, "fallback")
+	c := newTestClient(t, testOptions(), primary.URL, fallback.URL)
+
+	_, err := c.Get(context.Background(), "/missing")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Get() error = %v, want 404", err)
+	}
+	if fallback.hits.Load() != 0 || !c.Status()[0].Healthy {
+		t.Error("a 404 answer was treated as an endpoint failure")
+	}
+
+	permanent := errors.New("method not found")
+	calls := 0
+	err = c.Do(context.Background(), func(ctx context.Context, endpoint string) error {
+		calls++
+		return Permanent(permanent)
+	})
+	if !errors.Is(err, permanent) || !IsPermanent(err) || calls != 1 {
+		t.Errorf("Do() = %v after %d calls, want the permanent error after 1", err, calls)
+	}
+}
+
+func TestClientTimesOutSlowEndpoint(t *testing.T) {
+	slow := newCountingServer(t, "slow")
+	slow.delay = time.Second
+	fast := newCountingServer(t, "fast")
+	c := newTestClient(t, Options{Timeout: 50 * time.Millisecond, BackoffBase: time.Minute}, slow.URL, fast.URL)
+
+	start := time.Now()
+	body, err := c.Get(context.Background(), "/")
+	if err != nil || string(body) != "fast" {
+		t.Fatalf("Get() = %q, %v, want the fast endpoint", body, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Get() took %v, want the slow endpoint cut off at the timeout", elapsed)
+	}
+}
+
+func TestClientHealthCheckRestoresEndpoint(t *testing.T) {
+	primary := newCountingServer(t, "primary", http.StatusServiceUnavailable)
+	fallback := newCountingServer(t, "fallback")
+	opts := Options{BackoffBase: time.Minute, HealthInterval: 20 * time.Millisecond}
+	c := newTestClient(t, opts, primary.URL, fallback.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.StartHealthChecks(ctx)
+
+	if body, _ := c.Get(ctx, "/"); string(body) != "fallback" {
+		t.Fatalf("Get() = %q, want the fallback while the primary is down", body)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !c.Status()[0].Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("health check did not restore the primary")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if body, _ := c.Get(ctx, "/"); string(body) != "primary" {
+		t.Errorf("Get() = %q, want the restored primary", body)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	server := newCountingServer(t, "ok")
+	c := newTestClient(t, Options{RateLimit: 50}, server.URL)
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err := c.Get(context.Background(), "/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Six requests at 50 per second are spread over at least 100ms.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("6 requests took %v, want at least 100ms at 50 requests per second", elapsed)
+	}
+}
+
+func TestSharedClient(t *testing.T) {
+	server := newCountingServer(t, "ok")
+	a, err := Shared(server.URL+", "+server.URL+"/fallback", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Shared(server.URL+", "+server.URL+"/fallback", Options{Retries: 7})
+	if err != nil || a != b {
+		t.Errorf("Shared() returned a new client for the same endpoints")
+	}
+	if got := a.Endpoints(); len(got) != 2 {
+		t.Errorf("Endpoints() = %q, want 2 endpoints", got)
+	}
+}
+
+func TestClientWithoutRetries(t *testing.T) {
+	down := newCountingServer(t, "", http.StatusInternalServerError, http.StatusInternalServerError)
+	c := newTestClient(t, Options{Retries: -1, BackoffBase: 10 * time.Millisecond}, down.URL)
+	if _, err := c.Get(context.Background(), "/"); err == nil {
+		t.Fatal("Get() succeeded, want the status error")
+	}
+	if hits := down.hits.Load(); hits != 1 {
+		t.Errorf("endpoint was hit %d times, want 1", hits)
+	}
+}
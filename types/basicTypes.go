@@ -45,4 +45,51 @@ type TransactionStruct struct {
 	Type             string `json:"type"`
 	V                string `json:"v"`
 	Value            string `json:"value"`
+	// ChainID is empty for legacy transactions signed without replay protection.
+	ChainID string `json:"chainId,omitempty"`
+	// AccessList is set from type 1 (EIP-2930) transactions on.
+	AccessList []AccessTupleStruct `json:"accessList,omitempty"`
+	// MaxFeePerGas and MaxPriorityFeePerGas are set from type 2 (EIP-1559) on.
+	MaxFeePerGas         string `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas,omitempty"`
+	// MaxFeePerBlobGas and BlobVersionedHashes are set for type 3 (EIP-4844). Set code
+	// transactions (type 4, EIP-7702) are stored without their authorization lists.
+	MaxFeePerBlobGas    string   `json:"maxFeePerBlobGas,omitempty"`
+	BlobVersionedHashes []string `json:"blobVersionedHashes,omitempty"`
+	// Receipt is nil for transactions indexed before receipts were stored.
+	Receipt *ReceiptStruct `json:"receipt,omitempty"`
+	// Coinbase and BaseFeePerGas of the block, which split the fee between the block
+	// producer and the burn. Empty for transactions indexed before they were stored, and
+	// BaseFeePerGas before London.
+	Coinbase      string `json:"coinbase,omitempty"`
+	BaseFeePerGas string `json:"baseFeePerGas,omitempty"`
+}
+
+type AccessTupleStruct struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+type ReceiptStruct struct {
+	Status            uint64      `json:"status"`
+	CumulativeGasUsed string      `json:"cumulativeGasUsed"`
+	GasUsed           string      `json:"gasUsed"`
+	EffectiveGasPrice string      `json:"effectiveGasPrice"`
+	ContractAddress   string      `json:"contractAddress"`
+	LogsBloom         string      `json:"logsBloom"`
+	Logs              []LogStruct `json:"logs"`
+	// BlobGasUsed and BlobGasPrice are set for blob transactions.
+	BlobGasUsed  string `json:"blobGasUsed,omitempty"`
+	BlobGasPrice string `json:"blobGasPrice,omitempty"`
+}
+
+type LogStruct struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      uint64   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex uint     `json:"transactionIndex"`
+	LogIndex         uint     `json:"logIndex"`
 }
@@ -9,6 +9,13 @@ type StationInfo struct {
 	//DaType      string `json:"daType"`
 }
 
+// StationArg is the extra argument a station is created with on junction.
+type StationArg struct {
+	types.StationArg
+	// PodSize is the number of transactions in a pod of the station.
+	PodSize int `json:"podSize,omitempty"`
+}
+
 type GenesisDataType struct {
 	StationId          string
 	Creator            string
@@ -17,6 +24,6 @@ type GenesisDataType struct {
 	Tracks             []string
 	TracksVotingPowers []uint64
 	VerificationKey    interface{}
-	ExtraArg           types.StationArg
+	ExtraArg           StationArg
 	StationInfo        StationInfo
 }
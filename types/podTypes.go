@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type BatchStruct struct {
 	From              []string
 	To                []string
@@ -10,6 +12,15 @@ type BatchStruct struct {
 	Messages          []string
 	TransactionNonces []string
 	AccountNonces     []string
+	// SenderPostBalances and ReceiverPostBalances are only set by stations that report
+	// the balances after each transaction.
+	SenderPostBalances   []string `json:",omitempty"`
+	ReceiverPostBalances []string `json:",omitempty"`
+	// TxnCount is the number of entries above; the circuits pad the rest of the pod with
+	// zero entries. FirstTxnSeq and LastTxnSeq are the stored transactions the pod covers.
+	TxnCount    int
+	FirstTxnSeq int `json:",omitempty"`
+	LastTxnSeq  int `json:",omitempty"`
 }
 
 type Votes struct {
@@ -27,4 +38,10 @@ type PodState struct {
 	TracksAppHash       []byte
 	Batch               *BatchStruct
 	MasterTrackAppHash  []byte
+	Timestamp           *time.Time `json:"timestamp,omitempty"`
+
+	VRFInitiationTxHash string
+	VRFValidationTxHash string
+	InitPodTxHash       string
+	VerifyPodTxHash     string
 }
@@ -5,6 +5,7 @@ type Params struct {
 	MaxSupportedTransactionVersion int    `json:"maxSupportedTransactionVersion"`
 	TransactionDetails             string `json:"transactionDetails"`
 	Rewards                        bool   `json:"rewards"`
+	Commitment                     string `json:"commitment,omitempty"`
 }
 
 type PayloadStruct struct {
@@ -51,9 +52,9 @@ type SVMTransactionStruct struct {
 		Fee                  int           `json:"fee"`
 		InnerInstructions    []interface{} `json:"innerInstructions"`
 		LogMessages          []string      `json:"logMessages"`
-		PostBalances         []interface{} `json:"postBalances"`
+		PostBalances         []uint64      `json:"postBalances"`
 		PostTokenBalances    []interface{} `json:"postTokenBalances"`
-		PreBalances          []interface{} `json:"preBalances"`
+		PreBalances          []uint64      `json:"preBalances"`
 		PreTokenBalances     []interface{} `json:"preTokenBalances"`
 		Rewards              interface{}   `json:"rewards"`
 		Status               struct {
@@ -71,6 +72,10 @@ type SVMTransactionStruct struct {
 			Instructions []struct {
 				Parsed struct {
 					Info struct {
+						// Source, Destination and Lamports are set for system program transfers.
+						Source          string `json:"source,omitempty"`
+						Destination     string `json:"destination,omitempty"`
+						Lamports        uint64 `json:"lamports,omitempty"`
 						VoteAccount     string `json:"voteAccount"`
 						VoteAuthority   string `json:"voteAuthority"`
 						VoteStateUpdate struct {
@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type BatchTransaction struct {
 	Tx         Tx         `json:"tx"`
@@ -31,6 +34,20 @@ type Message struct {
 	FromAddress string        `json:"from_address"`
 	ToAddress   string        `json:"to_address"`
 	Amount      []BatchAmount `json:"amount"`
+	// Inputs and Outputs are set on a MsgMultiSend.
+	Inputs  []BatchMultiSendIO `json:"inputs,omitempty"`
+	Outputs []BatchMultiSendIO `json:"outputs,omitempty"`
+	// Sender, Contract, Msg and Funds are set on a MsgExecuteContract. Msg is the
+	// contract message as a JSON object, or base64 encoded by older stations.
+	Sender   string          `json:"sender,omitempty"`
+	Contract string          `json:"contract,omitempty"`
+	Msg      json.RawMessage `json:"msg,omitempty"`
+	Funds    []BatchAmount   `json:"funds,omitempty"`
+}
+
+type BatchMultiSendIO struct {
+	Address string        `json:"address"`
+	Coins   []BatchAmount `json:"coins"`
 }
 
 type BatchAmount struct {
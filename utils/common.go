@@ -2,27 +2,27 @@ package utils
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	logs "github.com/airchains-network/decentralized-sequencer/log"
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/btcsuite/btcd/btcutil/base58"
 	"github.com/btcsuite/btcd/btcutil/bech32"
 	"github.com/cosmos/cosmos-sdk/crypto/hd"
 	"github.com/cosmos/cosmos-sdk/crypto/keyring"
 	sdktypes "github.com/cosmos/cosmos-sdk/types"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
-	"io"
 	"math/big"
 	"math/rand"
-	"net/http"
+	"net/url"
 	"os"
 	"strconv"
-	"strings"
 )
 
-func GetBalance(address string, blockNumber uint64, stationRPC string) (string, error) {
+func GetBalance(address string, blockNumber uint64, stationRPC *stationclient.Client) (string, error) {
 	payload := fmt.Sprintf(`{
 		"jsonrpc": "2.0",
 		"method": "eth_getBalance",
@@ -30,21 +30,10 @@ func GetBalance(address string, blockNumber uint64, stationRPC string) (string,
 		"id": 1
 	}`, address, strconv.FormatUint(blockNumber, 16))
 
-	resp, err := http.Post(stationRPC, "application/json", strings.NewReader(payload))
+	body, err := stationRPC.Post(context.Background(), "", "application/json", []byte(payload))
 	if err != nil {
 		return "", fmt.Errorf("http post error: %w", err)
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-
-		}
-	}(resp.Body)
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("error reading response body: %w", err)
-	}
 
 	var jsonResponse map[string]interface{}
 	err = json.Unmarshal(body, &jsonResponse)
@@ -67,22 +56,34 @@ func GetBalance(address string, blockNumber uint64, stationRPC string) (string,
 	}
 }
 
-func GetAccountNonce(ctx context.Context, address string, blockNumber uint64, stationRPC string) (string, error) {
-	client, err := rpc.Dial(stationRPC)
+func GetAccountNonce(ctx context.Context, address string, blockNumber uint64, stationRPC *stationclient.Client) (string, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "eth_getTransactionCount",
+		"params":  []interface{}{common.HexToAddress(address), "0x" + strconv.FormatUint(blockNumber, 16)},
+		"id":      1,
+	})
 	if err != nil {
-		return "0", fmt.Errorf("error dialing RPC: %w", err)
+		return "0", err
 	}
 
-	accountAddress := common.HexToAddress(address)
-	formattedBlockNumber := "0x" + strconv.FormatUint(blockNumber, 16)
-	var result string
-	err = client.CallContext(ctx, &result, "eth_getTransactionCount", accountAddress, formattedBlockNumber)
-
+	body, err := stationRPC.Post(ctx, "", "application/json", payload)
 	if err != nil {
 		return "0", fmt.Errorf("error getting transaction count: %w", err)
 	}
 
-	return result, nil
+	var response struct {
+		Result string          `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "0", fmt.Errorf("error unmarshalling JSON response: %w", err)
+	}
+	if len(response.Error) > 0 {
+		return "0", fmt.Errorf("error getting transaction count: %s", response.Error)
+	}
+
+	return response.Result, nil
 }
 
 func ToString(value interface{}) string {
@@ -251,6 +252,18 @@ func Bech32Decoder(value string) string {
 	return decodedBigInt.String()
 }
 
+// Base58Decoder returns the decimal value of a base58 encoded solana account or
+// signature.
+func Base58Decoder(value string) string {
+	bytes := base58.Decode(value)
+	if len(bytes) == 0 && value != "" {
+		logs.Log.Error(fmt.Sprintf("Error decoding Base58 value: %s", value))
+	}
+
+	decodedBigInt := new(big.Int).SetBytes(bytes)
+	return decodedBigInt.String()
+}
+
 func TXHashCheck(value string) string {
 	byteSlice, err := hex.DecodeString(value)
 	if err != nil {
@@ -261,22 +274,21 @@ func TXHashCheck(value string) string {
 	return decodedBigInt.String()
 }
 
-func AccountBalanceCheck(walletAddress string, blockHeight string, JsonAPI string) string {
-
+// AccountBalanceCheck returns the balance of walletAddress in its first denomination
+// before block blockHeight.
+func AccountBalanceCheck(walletAddress string, blockHeight string, stationAPI *stationclient.Client) (string, error) {
 	height, err := strconv.Atoi(blockHeight)
 	if err != nil {
-		logs.Log.Error(fmt.Sprintf("Error converting block height to integer: %v", err))
+		return "", fmt.Errorf("malformed block height %q: %w", blockHeight, err)
 	}
 
-	res, resErr := http.Get(
-		fmt.Sprintf(
-			"%s/cosmos/bank/v1beta1/balances/%s?height=%d", JsonAPI, walletAddress, height-1,
-		),
+	res, err := stationAPI.Get(
+		context.Background(),
+		fmt.Sprintf("/cosmos/bank/v1beta1/balances/%s?height=%d", walletAddress, height-1),
 	)
-	if resErr != nil {
-		logs.Log.Error(fmt.Sprintf("Error making HTTP request: %v", resErr))
+	if err != nil {
+		return "", fmt.Errorf("error querying balances of %s: %w", walletAddress, err)
 	}
-	defer res.Body.Close()
 
 	var accountBalance struct {
 		Balances []struct {
@@ -288,25 +300,89 @@ func AccountBalanceCheck(walletAddress string, blockHeight string, JsonAPI strin
 			Total   string `json:"total"`
 		} `json:"pagination"`
 	}
+	if err := json.Unmarshal(res, &accountBalance); err != nil {
+		return "", fmt.Errorf("error decoding balances of %s: %w", walletAddress, err)
+	}
+	if len(accountBalance.Balances) == 0 {
+		return "0", nil
+	}
+	return accountBalance.Balances[0].Amount, nil
+}
+
+// AccountDenomBalanceCheck returns the balance of walletAddress in denom before block
+// blockHeight.
+func AccountDenomBalanceCheck(walletAddress string, denom string, blockHeight string, stationAPI *stationclient.Client) (string, error) {
+	height, err := strconv.Atoi(blockHeight)
+	if err != nil {
+		return "", fmt.Errorf("malformed block height %q: %w", blockHeight, err)
+	}
 
-	decodeError := json.NewDecoder(res.Body).Decode(&accountBalance)
-	if decodeError != nil {
-		logs.Log.Error(fmt.Sprintf("Error decoding JSON response: %v", decodeError))
+	res, err := stationAPI.Get(
+		context.Background(),
+		fmt.Sprintf("/cosmos/bank/v1beta1/balances/%s/by_denom?denom=%s&height=%d", walletAddress, url.QueryEscape(denom), height-1),
+	)
+	if err != nil {
+		return "", fmt.Errorf("error querying %s balance of %s: %w", denom, walletAddress, err)
+	}
+
+	var accountBalance struct {
+		Balance *struct {
+			Denom  string `json:"denom"`
+			Amount string `json:"amount"`
+		} `json:"balance"`
+	}
+	if err := json.Unmarshal(res, &accountBalance); err != nil {
+		return "", fmt.Errorf("error decoding %s balance of %s: %w", denom, walletAddress, err)
+	}
+	if accountBalance.Balance == nil {
+		return "0", nil
+	}
+	return accountBalance.Balance.Amount, nil
+}
+
+// CW20BalanceCheck returns the cw20 token balance of walletAddress in contract before
+// block blockHeight.
+func CW20BalanceCheck(contract string, walletAddress string, blockHeight string, stationAPI *stationclient.Client) (string, error) {
+	height, err := strconv.Atoi(blockHeight)
+	if err != nil {
+		return "", fmt.Errorf("malformed block height %q: %w", blockHeight, err)
+	}
+
+	query, err := json.Marshal(map[string]map[string]string{"balance": {"address": walletAddress}})
+	if err != nil {
+		return "", err
+	}
+	res, err := stationAPI.Get(
+		context.Background(),
+		fmt.Sprintf("/cosmwasm/wasm/v1/contract/%s/smart/%s?height=%d", contract, base64.URLEncoding.EncodeToString(query), height-1),
+	)
+	if err != nil {
+		return "", fmt.Errorf("error querying %s balance of %s: %w", contract, walletAddress, err)
 	}
 
-	return accountBalance.Balances[0].Amount
+	var tokenBalance struct {
+		Data struct {
+			Balance string `json:"balance"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(res, &tokenBalance); err != nil {
+		return "", fmt.Errorf("error decoding %s balance of %s: %w", contract, walletAddress, err)
+	}
+	if tokenBalance.Data.Balance == "" {
+		return "0", nil
+	}
+	return tokenBalance.Data.Balance, nil
 }
 
-func AccountNounceCheck(walletAddress string, JsonAPI string) string {
-	res, resErr := http.Get(
-		fmt.Sprintf(
-			"%s/cosmos/auth/v1beta1/accounts/%s", JsonAPI, walletAddress,
-		),
+// AccountNounceCheck returns the current account sequence of walletAddress.
+func AccountNounceCheck(walletAddress string, stationAPI *stationclient.Client) (string, error) {
+	res, err := stationAPI.Get(
+		context.Background(),
+		fmt.Sprintf("/cosmos/auth/v1beta1/accounts/%s", walletAddress),
 	)
-	if resErr != nil {
-		logs.Log.Error(fmt.Sprintf("Error making HTTP request: %v", resErr))
+	if err != nil {
+		return "", fmt.Errorf("error querying account %s: %w", walletAddress, err)
 	}
-	defer res.Body.Close()
 
 	var accountNounce struct {
 		Account struct {
@@ -320,11 +396,8 @@ func AccountNounceCheck(walletAddress string, JsonAPI string) string {
 			Sequence      string `json:"sequence"`
 		} `json:"account"`
 	}
-
-	decodeError := json.NewDecoder(res.Body).Decode(&accountNounce)
-	if decodeError != nil {
-		logs.Log.Error(fmt.Sprintf("Error decoding JSON response: %v", decodeError))
+	if err := json.Unmarshal(res, &accountNounce); err != nil {
+		return "", fmt.Errorf("error decoding account %s: %w", walletAddress, err)
 	}
-
-	return accountNounce.Account.Sequence
+	return accountNounce.Account.Sequence, nil
 }
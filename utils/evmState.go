@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// evmStateBatchSize is the number of calls sent in one JSON-RPC batch request.
+	evmStateBatchSize = 100
+	// evmStateCacheSize bounds the number of lookups kept by an EVMStateCache.
+	evmStateCacheSize = 10000
+)
+
+// EVMAccountAt identifies an account at a block.
+type EVMAccountAt struct {
+	Address string
+	Block   uint64
+}
+
+// evmStateKey is one cached lookup: a balance or nonce of an account at a block.
+type evmStateKey struct {
+	method string
+	at     EVMAccountAt
+}
+
+// EVMStateCache looks up the balances and nonces of station accounts at past blocks.
+// Lookups are de-duplicated and sent as JSON-RPC batch requests, and their results are
+// kept, as the state of a final block does not change. It is safe for concurrent use.
+type EVMStateCache struct {
+	station   *stationclient.Client
+	batchSize int
+	limit     int
+
+	mu      sync.Mutex
+	entries map[evmStateKey]string
+	order   []evmStateKey
+	// noBatches is set once the station turned a batch request down.
+	noBatches bool
+}
+
+// NewEVMStateCache returns an empty cache looking up missing state on station.
+func NewEVMStateCache(station *stationclient.Client) *EVMStateCache {
+	return &EVMStateCache{
+		station:   station,
+		batchSize: evmStateBatchSize,
+		limit:     evmStateCacheSize,
+		entries:   make(map[evmStateKey]string),
+	}
+}
+
+// Fetch returns the balance of every account of balancesOf and the nonce of every
+// account of noncesOf, keyed as given. Balances are decimal and nonces are the hex
+// quantities returned by the station. Results of a failed Fetch that did arrive are
+// kept, so a retry only asks for the rest.
+func (c *EVMStateCache) Fetch(ctx context.Context, balancesOf, noncesOf []EVMAccountAt) (balances, nonces map[EVMAccountAt]string, err error) {
+	var keys []evmStateKey
+	for _, at := range balancesOf {
+		keys = append(keys, evmStateKey{method: "eth_getBalance", at: at})
+	}
+	for _, at := range noncesOf {
+		keys = append(keys, evmStateKey{method: "eth_getTransactionCount", at: at})
+	}
+
+	missing := c.missing(keys)
+	for start := 0; start < len(missing); start += c.batchSize {
+		results, err := c.call(ctx, missing[start:min(start+c.batchSize, len(missing))])
+		c.store(results)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	balances = make(map[EVMAccountAt]string, len(balancesOf))
+	nonces = make(map[EVMAccountAt]string, len(noncesOf))
+	for _, key := range keys {
+		value, ok := c.entries[normalizeEVMStateKey(key)]
+		if !ok {
+			// Evicted by a concurrent Fetch; the caller retries.
+			return nil, nil, fmt.Errorf("%s of %s at block %d was evicted", key.method, key.at.Address, key.at.Block)
+		}
+		if key.method == "eth_getBalance" {
+			balances[key.at] = value
+		} else {
+			nonces[key.at] = value
+		}
+	}
+	return balances, nonces, nil
+}
+
+// missing returns the distinct normalized keys that are not cached yet.
+func (c *EVMStateCache) missing(keys []evmStateKey) []evmStateKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := make(map[evmStateKey]bool)
+	var missing []evmStateKey
+	for _, key := range keys {
+		key = normalizeEVMStateKey(key)
+		if _, ok := c.entries[key]; ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		missing = append(missing, key)
+	}
+	return missing
+}
+
+// store caches results, dropping the oldest entries beyond the cache limit.
+func (c *EVMStateCache) store(results map[evmStateKey]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, value := range results {
+		if _, ok := c.entries[key]; !ok {
+			c.order = append(c.order, key)
+		}
+		c.entries[key] = value
+	}
+	for len(c.order) > c.limit {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+func normalizeEVMStateKey(key evmStateKey) evmStateKey {
+	key.at.Address = strings.ToLower(common.HexToAddress(key.at.Address).Hex())
+	return key
+}
+
+type evmStateRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type evmStateResponse struct {
+	ID     int             `json:"id"`
+	Result string          `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+// call looks keys up in one batch request, or one request per key when the station
+// does not accept batches. It returns the results that arrived, and an error naming the
+// first lookup that failed.
+func (c *EVMStateCache) call(ctx context.Context, keys []evmStateKey) (map[evmStateKey]string, error) {
+	requests := make([]evmStateRequest, len(keys))
+	for i, key := range keys {
+		requests[i] = evmStateRequest{
+			JSONRPC: "2.0",
+			ID:      i,
+			Method:  key.method,
+			Params:  []interface{}{key.at.Address, "0x" + strconv.FormatUint(key.at.Block, 16)},
+		}
+	}
+
+	c.mu.Lock()
+	noBatches := c.noBatches
+	c.mu.Unlock()
+	var responses []evmStateResponse
+	if noBatches || len(requests) == 1 {
+		for _, request := range requests {
+			response, err := c.post(ctx, request)
+			if err != nil {
+				results, _ := evmStateResults(keys, responses)
+				return results, fmt.Errorf("%s of %s at block %d: %w", keys[request.ID].method, keys[request.ID].at.Address, keys[request.ID].at.Block, err)
+			}
+			responses = append(responses, response)
+		}
+	} else {
+		payload, err := json.Marshal(requests)
+		if err != nil {
+			return nil, err
+		}
+		body, err := c.station.Post(ctx, "", "application/json", payload)
+		if err != nil {
+			return nil, fmt.Errorf("error sending batch request: %w", err)
+		}
+		if err := json.Unmarshal(body, &responses); err != nil {
+			// A station without batch support answers with a single error object.
+			c.mu.Lock()
+			c.noBatches = true
+			c.mu.Unlock()
+			return c.call(ctx, keys)
+		}
+	}
+	return evmStateResults(keys, responses)
+}
+
+// post sends a single request.
+func (c *EVMStateCache) post(ctx context.Context, request evmStateRequest) (evmStateResponse, error) {
+	var response evmStateResponse
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return response, err
+	}
+	body, err := c.station.Post(ctx, "", "application/json", payload)
+	if err != nil {
+		return response, err
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return response, fmt.Errorf("error unmarshalling JSON response: %w", err)
+	}
+	response.ID = request.ID
+	return response, nil
+}
+
+// evmStateResults matches responses to keys by id.
+func evmStateResults(keys []evmStateKey, responses []evmStateResponse) (map[evmStateKey]string, error) {
+	results := make(map[evmStateKey]string, len(responses))
+	answered := make(map[int]bool, len(responses))
+	var firstErr error
+	for _, response := range responses {
+		if response.ID < 0 || response.ID >= len(keys) {
+			continue
+		}
+		key := keys[response.ID]
+		answered[response.ID] = true
+		value, err := evmStateValue(key.method, response)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s of %s at block %d: %w", key.method, key.at.Address, key.at.Block, err)
+			}
+			continue
+		}
+		results[key] = value
+	}
+	if firstErr == nil && len(answered) < len(keys) {
+		firstErr = fmt.Errorf("station answered %d of %d lookups", len(answered), len(keys))
+	}
+	return results, firstErr
+}
+
+// evmNodeError turns the error object of a response into an error, marked permanent
+// when the node answered the lookup itself, such as a missing trie node of a pruned block.
+func evmNodeError(raw json.RawMessage) error {
+	err := fmt.Errorf("error from Ethereum node: %s", raw)
+	var rpcErr struct {
+		Code int `json:"code"`
+	}
+	if json.Unmarshal(raw, &rpcErr) == nil && stationclient.PermanentRPCCode(rpcErr.Code) {
+		return stationclient.Permanent(err)
+	}
+	return err
+}
+
+func evmStateValue(method string, response evmStateResponse) (string, error) {
+	if len(response.Error) > 0 && string(response.Error) != "null" {
+		return "", evmNodeError(response.Error)
+	}
+	if !strings.HasPrefix(response.Result, "0x") {
+		return "", fmt.Errorf("malformed result %q", response.Result)
+	}
+	if method == "eth_getTransactionCount" {
+		return response.Result, nil
+	}
+	balance, ok := new(big.Int).SetString(response.Result[2:], 16)
+	if !ok {
+		return "", fmt.Errorf("failed to parse balance %q", response.Result)
+	}
+	return balance.String(), nil
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/airchains-network/decentralized-sequencer/stationclient"
+)
+
+// stubEVMStateNode answers eth_getBalance with the block number times 1000 and
+// eth_getTransactionCount with the block number. Addresses in failing get an error once.
+type stubEVMStateNode struct {
+	*httptest.Server
+	noBatches bool
+
+	mu       sync.Mutex
+	requests int
+	calls    map[string]int
+	failing  map[string]bool
+}
+
+func newStubEVMStateNode(t *testing.T) *stubEVMStateNode {
+	t.Helper()
+	n := &stubEVMStateNode{calls: make(map[string]int), failing: make(map[string]bool)}
+	n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		n.requests++
+		if strings.HasPrefix(string(body), "[") {
+			if n.noBatches {
+				fmt.Fprint(w, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch requests are not supported"}}`)
+				return
+			}
+			var requests []evmStateRequest
+			json.Unmarshal(body, &requests)
+			var responses []map[string]interface{}
+			for i := len(requests) - 1; i >= 0; i-- {
+				responses = append(responses, n.answer(requests[i]))
+			}
+			json.NewEncoder(w).Encode(responses)
+			return
+		}
+		var request evmStateRequest
+		json.Unmarshal(body, &request)
+		json.NewEncoder(w).Encode(n.answer(request))
+	}))
+	t.Cleanup(n.Close)
+	return n
+}
+
+func (n *stubEVMStateNode) answer(request evmStateRequest) map[string]interface{} {
+	address, block := request.Params[0].(string), request.Params[1].(string)
+	n.calls[request.Method+" "+address+" "+block]++
+	response := map[string]interface{}{"jsonrpc": "2.0", "id": request.ID}
+	if n.failing[address] {
+		delete(n.failing, address)
+		response["error"] = map[string]interface{}{"code": -32005, "message": "rate limited"}
+		return response
+	}
+	var number uint64
+	fmt.Sscanf(block, "0x%x", &number)
+	if request.Method == "eth_getBalance" {
+		response["result"] = fmt.Sprintf("0x%x", number*1000)
+	} else {
+		response["result"] = fmt.Sprintf("0x%x", number)
+	}
+	return response
+}
+
+func (n *stubEVMStateNode) stats() (int, map[string]int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	calls := make(map[string]int, len(n.calls))
+	for call, count := range n.calls {
+		calls[call] = count
+	}
+	return n.requests, calls
+}
+
+func newTestEVMStateCache(t *testing.T, node *stubEVMStateNode) *EVMStateCache {
+	t.Helper()
+	station, err := stationclient.New([]string{node.URL}, stationclient.Options{BackoffBase: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewEVMStateCache(station)
+}
+
+const (
+	testSender   = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+	testReceiver = "0xe7f1725e7734ce288f8367e1bb143e90bb3f0512"
+)
+
+func TestEVMStateCacheBatchesAndDeduplicates(t *testing.T) {
+	node := newStubEVMStateNode(t)
+	cache := newTestEVMStateCache(t, node)
+
+	// Two transfers of the same sender in block 8, the second written in lower case.
+	balancesOf := []EVMAccountAt{
+		{Address: testSender, Block: 7}, {Address: testReceiver, Block: 7},
+		{Address: strings.ToLower(testSender), Block: 7}, {Address: testReceiver, Block: 7},
+	}
+	noncesOf := []EVMAccountAt{{Address: testSender, Block: 7}, {Address: strings.ToLower(testSender), Block: 7}}
+	balances, nonces, err := cache.Fetch(context.Background(), balancesOf, noncesOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances[balancesOf[0]] != "7000" || balances[balancesOf[2]] != "7000" || balances[balancesOf[1]] != "7000" {
+		t.Errorf("balances = %v", balances)
+	}
+	if nonces[noncesOf[0]] != "0x7" || nonces[noncesOf[1]] != "0x7" {
+		t.Errorf("nonces = %v", nonces)
+	}
+	requests, calls := node.stats()
+	if requests != 1 {
+		t.Errorf("sent %d requests, want a single batch", requests)
+	}
+	if len(calls) != 3 {
+		t.Errorf("station calls = %v, want one per distinct lookup", calls)
+	}
+
+	if _, _, err := cache.Fetch(context.Background(), balancesOf, noncesOf); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := node.stats(); again != requests {
+		t.Errorf("cached lookups sent %d more requests", again-requests)
+	}
+}
+
+func TestEVMStateCacheRetriesFailedLookups(t *testing.T) {
+	node := newStubEVMStateNode(t)
+	node.failing[strings.ToLower(testReceiver)] = true
+	cache := newTestEVMStateCache(t, node)
+
+	balancesOf := []EVMAccountAt{{Address: testSender, Block: 3}, {Address: testReceiver, Block: 3}}
+	if _, _, err := cache.Fetch(context.Background(), balancesOf, nil); err == nil || !strings.Contains(err.Error(), "rate limited") || stationclient.IsPermanent(err) {
+		t.Fatalf("Fetch() error = %v, want the retryable rate limit error", err)
+	}
+	balances, _, err := cache.Fetch(context.Background(), balancesOf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances[balancesOf[1]] != "3000" {
+		t.Errorf("balances = %v", balances)
+	}
+	if _, calls := node.stats(); calls["eth_getBalance "+strings.ToLower(testSender)+" 0x3"] != 1 {
+		t.Errorf("the lookup that succeeded was sent again: %v", calls)
+	}
+}
+
+func TestEVMNodeErrorMarksMissingStatePermanent(t *testing.T) {
+	err := evmNodeError(json.RawMessage(`{"code":-32000,"message":"missing trie node 5e1f (path )"}`))
+	if !stationclient.IsPermanent(err) {
+		t.Errorf("evmNodeError() = %v, want a permanent error", err)
+	}
+}
+
+func TestEVMStateCacheWithoutBatchSupport(t *testing.T) {
+	node := newStubEVMStateNode(t)
+	node.noBatches = true
+	cache := newTestEVMStateCache(t, node)
+
+	balancesOf := []EVMAccountAt{{Address: testSender, Block: 2}, {Address: testReceiver, Block: 2}}
+	balances, _, err := cache.Fetch(context.Background(), balancesOf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balances[balancesOf[0]] != "2000" || balances[balancesOf[1]] != "2000" {
+		t.Errorf("balances = %v", balances)
+	}
+}
@@ -0,0 +1,111 @@
+// Package keys names the proving and verification key files of the pod circuits, which
+// are generated for one circuit and pod size each, and checks that keys fit a circuit.
+package keys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/airchains-network/decentralized-sequencer/config"
+	logs "github.com/airchains-network/decentralized-sequencer/log"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/schema"
+)
+
+// ErrMismatch is returned by Check for keys generated for another circuit or pod size.
+var ErrMismatch = errors.New("circuit keys do not match the pod size")
+
+var tVariable = reflect.ValueOf(struct{ A frontend.Variable }{}).FieldByName("A").Type()
+
+func configDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, config.DefaultTracksDir, config.DefaultConfigDir)
+}
+
+// ProvingKeyFile returns the path of the proving key of circuit for pods of podSize
+// transactions.
+func ProvingKeyFile(circuit string, podSize int) string {
+	return filepath.Join(configDir(), fmt.Sprintf("provingKey-%s-%d.txt", circuit, podSize))
+}
+
+// VerificationKeyFile returns the path of the verification key of circuit for pods of
+// podSize transactions.
+func VerificationKeyFile(circuit string, podSize int) string {
+	return filepath.Join(configDir(), fmt.Sprintf("verificationKey-%s-%d.json", circuit, podSize))
+}
+
+// ValidatePodSize returns an error for a pod size no circuit can be compiled for.
+func ValidatePodSize(podSize int) error {
+	if podSize < 1 || podSize > config.MaxPODSize {
+		return fmt.Errorf("pod size %d is not between 1 and %d", podSize, config.MaxPODSize)
+	}
+	return nil
+}
+
+// Check returns an error unless vk verifies proofs of circuit, which must be sized for
+// podSize. Every circuit has public inputs per transaction, so keys of another pod size
+// expect a public witness of another length. Keys of other circuits are told apart by
+// their file names.
+func Check(vk groth16.VerifyingKey, circuit frontend.Circuit, podSize int) error {
+	s, err := schema.New(circuit, tVariable)
+	if err != nil {
+		return fmt.Errorf("failed to parse the circuit: %w", err)
+	}
+	if vk.NbPublicWitness() != s.NbPublic {
+		return fmt.Errorf("%w: the verification key takes %d public inputs, pods of %d transactions have %d", ErrMismatch, vk.NbPublicWitness(), podSize, s.NbPublic)
+	}
+	return nil
+}
+
+// Create generates and saves the proving and verification keys of circuit, sized for pods
+// of podSize transactions, under the key files of name unless both exist already.
+func Create(name string, podSize int, circuit frontend.Circuit) {
+	provingKeyFile := ProvingKeyFile(name, podSize)
+	verificationKeyFile := VerificationKeyFile(name, podSize)
+
+	_, err1 := os.Stat(provingKeyFile)
+	_, err2 := os.Stat(verificationKeyFile)
+	if !os.IsNotExist(err1) && !os.IsNotExist(err2) {
+		logs.Log.Info("Both Proving key and Verification key already exist. No action needed.")
+		return
+	}
+
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, circuit)
+	if err != nil {
+		logs.Log.Error("Unable to compile the circuit" + err.Error())
+		return
+	}
+	provingKey, verificationKey, err := groth16.Setup(ccs)
+	if err != nil {
+		logs.Log.Error("Unable to generate the keys" + err.Error())
+		return
+	}
+
+	// Save Proving Key
+	pkFile, err := os.Create(provingKeyFile)
+	if err != nil {
+		logs.Log.Error("Unable to create Proving Key file" + err.Error())
+		return
+	}
+	_, err = provingKey.WriteTo(pkFile)
+	pkFile.Close()
+	if err != nil {
+		logs.Log.Error("Unable to write Proving Key" + err.Error())
+		return
+	}
+
+	// Save Verification Key
+	file, _ := json.MarshalIndent(verificationKey, "", " ")
+	err = os.WriteFile(verificationKeyFile, file, 0644)
+	if err != nil {
+		logs.Log.Error("Unable to write Verification Key to file" + err.Error())
+	}
+	logs.Log.Info("Proving key and Verification key generated and saved successfully\n")
+}
@@ -0,0 +1,59 @@
+package keys
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+type rowsCircuit struct {
+	Rows  []frontend.Variable `gnark:",public"`
+	Count frontend.Variable   `gnark:",public"`
+}
+
+func (c *rowsCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(c.Count, len(c.Rows))
+	return nil
+}
+
+func newRowsCircuit(podSize int) *rowsCircuit {
+	return &rowsCircuit{Rows: make([]frontend.Variable, podSize)}
+}
+
+func TestCheck(t *testing.T) {
+	ccs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, newRowsCircuit(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(vk, newRowsCircuit(3), 3); err != nil {
+		t.Errorf("Check() of the keys of the circuit = %v", err)
+	}
+	if err := Check(vk, newRowsCircuit(4), 4); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Check() of another pod size = %v, want a mismatch", err)
+	}
+}
+
+func TestValidatePodSize(t *testing.T) {
+	for size, valid := range map[int]bool{0: false, 1: true, 25: true, 1000: true, 1001: false} {
+		if err := ValidatePodSize(size); (err == nil) != valid {
+			t.Errorf("ValidatePodSize(%d) = %v", size, err)
+		}
+	}
+}
+
+func TestKeyFilesPerPodSize(t *testing.T) {
+	if ProvingKeyFile("v1EVM", 25) == ProvingKeyFile("v1EVM", 50) || VerificationKeyFile("v1EVM", 25) == VerificationKeyFile("v1EVM", 50) {
+		t.Error("pod sizes share key files")
+	}
+	if ProvingKeyFile("v1EVM", 25) == ProvingKeyFile("v1SVM", 25) || VerificationKeyFile("v1EVM", 25) == VerificationKeyFile("v1SVM", 25) {
+		t.Error("circuits share key files")
+	}
+}
@@ -0,0 +1,29 @@
+// Package padding constrains the rows pod circuits pad a pod with when it holds fewer
+// transactions than the circuit has rows.
+package padding
+
+import "github.com/consensys/gnark/frontend"
+
+// Rows returns, for each of the n rows of a circuit, 1 when the row is one of the first
+// count rows and 0 when it is padding. It asserts that count is at most n.
+func Rows(api frontend.API, count frontend.Variable, n int) []frontend.Variable {
+	rows := make([]frontend.Variable, n)
+	// ended sums IsZero(count - j) over j <= i, so it turns 1 at row count and stays there.
+	ended := frontend.Variable(0)
+	for i := 0; i < n; i++ {
+		ended = api.Add(ended, api.IsZero(api.Sub(count, i)))
+		rows[i] = api.Sub(1, ended)
+	}
+	ended = api.Add(ended, api.IsZero(api.Sub(count, n)))
+	api.AssertIsEqual(ended, 1)
+	return rows
+}
+
+// AssertZero asserts that every one of fields is zero unless row is 1, so a padding row
+// cannot carry a transaction.
+func AssertZero(api frontend.API, row frontend.Variable, fields ...frontend.Variable) {
+	padding := api.Sub(1, row)
+	for _, field := range fields {
+		api.AssertIsEqual(api.Mul(padding, field), 0)
+	}
+}
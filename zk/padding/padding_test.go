@@ -0,0 +1,48 @@
+package padding
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test"
+)
+
+type rowsCircuit struct {
+	Count  frontend.Variable
+	Values [3]frontend.Variable
+}
+
+func (c *rowsCircuit) Define(api frontend.API) error {
+	rows := Rows(api, c.Count, len(c.Values))
+	for i, value := range c.Values {
+		AssertZero(api, rows[i], value)
+	}
+	return nil
+}
+
+func TestRows(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		count  int
+		values [3]int
+		solved bool
+	}{
+		{"full", 3, [3]int{1, 2, 3}, true},
+		{"padded", 1, [3]int{7, 0, 0}, true},
+		{"empty", 0, [3]int{0, 0, 0}, true},
+		{"value in padding", 1, [3]int{7, 0, 4}, false},
+		{"count above rows", 4, [3]int{1, 2, 3}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assignment := rowsCircuit{Count: tt.count}
+			for i, value := range tt.values {
+				assignment.Values[i] = value
+			}
+			err := test.IsSolved(&rowsCircuit{}, &assignment, ecc.BLS12_381.ScalarField())
+			if solved := err == nil; solved != tt.solved {
+				t.Errorf("solved = %t, want %t: %v", solved, tt.solved, err)
+			}
+		})
+	}
+}
@@ -2,57 +2,27 @@ package v1EVM
 
 import (
 	"encoding/json"
+	"fmt"
 	logs "github.com/airchains-network/decentralized-sequencer/log"
+	"github.com/airchains-network/decentralized-sequencer/zk/keys"
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/groth16"
 	"os"
 )
 
-// CreateVkPkNew generates and saves a new Proving Key and Verification Key if either file doesn't exist
-func CreateVkPkNew() {
-	homeDir, _ := os.UserHomeDir()
-	provingKeyFile := homeDir + "/.tracks/config/provingKey.txt"
-	verificationKeyFile := homeDir + "/.tracks/config/verificationKey.json"
+// CircuitName names the key files of the circuit. Change it with the constraints, so
+// keys of the previous circuit are never used.
+const CircuitName = "v1EVM"
 
-	_, err1 := os.Stat(provingKeyFile)
-	_, err2 := os.Stat(verificationKeyFile)
-
-	// If either file doesn't exist, generate and save new keys
-	if os.IsNotExist(err1) || os.IsNotExist(err2) {
-		provingKey, verificationKey, err := GenerateVerificationKey()
-		if err != nil {
-			return
-		}
-
-		// Save Proving Key
-		pkFile, err := os.Create(provingKeyFile)
-		if err != nil {
-			logs.Log.Error("Unable to create Proving Key file" + err.Error())
-			return
-		}
-		_, err = provingKey.WriteTo(pkFile)
-		pkFile.Close()
-		if err != nil {
-			logs.Log.Error("Unable to write Proving Key" + err.Error())
-			return
-		}
-
-		// Save Verification Key
-		file, _ := json.MarshalIndent(verificationKey, "", " ")
-		err = os.WriteFile(verificationKeyFile, file, 0644)
-		if err != nil {
-			logs.Log.Error("Unable to write Verification Key to file" + err.Error())
-		}
-		logs.Log.Info("Proving key and Verification key generated and saved successfully\n")
-	} else {
-		logs.Log.Info("Both Proving key and Verification key already exist. No action needed.")
-	}
+// CreateVkPkNew generates and saves a new Proving Key and Verification Key for pods of podSize
+// transactions if either file doesn't exist
+func CreateVkPkNew(podSize int) {
+	keys.Create(CircuitName, podSize, NewCircuit(podSize))
 }
 
-func GetVkPk() (groth16.ProvingKey, groth16.VerifyingKey, error) {
-	homeDir, _ := os.UserHomeDir()
-	provingKeyFile := homeDir + "/.tracks/config/provingKey.txt"
-	verificationKeyFile := homeDir + "/.tracks/config/verificationKey.json"
+func GetVkPk(podSize int) (groth16.ProvingKey, groth16.VerifyingKey, error) {
+	provingKeyFile := keys.ProvingKeyFile(CircuitName, podSize)
+	verificationKeyFile := keys.VerificationKeyFile(CircuitName, podSize)
 
 	// Read Proving Key
 	pk, err := ReadProvingKeyFromFile2(provingKeyFile)
@@ -99,3 +69,13 @@ func ReadVerificationKeyFromFile(filename string) (groth16.VerifyingKey, error)
 
 	return vk, nil
 }
+
+// CheckKeys returns an error unless the verification key of podSize fits the circuit
+// for pods of podSize transactions.
+func CheckKeys(podSize int) error {
+	vk, err := ReadVerificationKeyFromFile(keys.VerificationKeyFile(CircuitName, podSize))
+	if err != nil {
+		return fmt.Errorf("no circuit keys for pods of %d transactions, run tracks prover: %w", podSize, err)
+	}
+	return keys.Check(vk, NewCircuit(podSize), podSize)
+}
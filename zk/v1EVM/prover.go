@@ -6,8 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/airchains-network/decentralized-sequencer/blocksync"
-	"github.com/airchains-network/decentralized-sequencer/config"
 	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/airchains-network/decentralized-sequencer/zk/keys"
+	"github.com/airchains-network/decentralized-sequencer/zk/padding"
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/constraint"
@@ -18,13 +19,28 @@ import (
 	"strconv"
 )
 
+// MyCircuit has one row per transaction of a pod. Use NewCircuit to size it.
 type MyCircuit struct {
-	To              [config.PODSize]frontend.Variable `gnark:",public"`
-	From            [config.PODSize]frontend.Variable `gnark:",public"`
-	Amount          [config.PODSize]frontend.Variable `gnark:",public"`
-	TransactionHash [config.PODSize]frontend.Variable `gnark:",public"`
-	FromBalances    [config.PODSize]frontend.Variable `gnark:",public"`
-	ToBalances      [config.PODSize]frontend.Variable `gnark:",public"`
+	To              []frontend.Variable `gnark:",public"`
+	From            []frontend.Variable `gnark:",public"`
+	Amount          []frontend.Variable `gnark:",public"`
+	TransactionHash []frontend.Variable `gnark:",public"`
+	FromBalances    []frontend.Variable `gnark:",public"`
+	ToBalances      []frontend.Variable `gnark:",public"`
+	// TxnCount is the number of transactions in the pod; the rows after them are padding.
+	TxnCount frontend.Variable `gnark:",public"`
+}
+
+// NewCircuit returns a circuit for pods of podSize transactions.
+func NewCircuit(podSize int) *MyCircuit {
+	return &MyCircuit{
+		To:              make([]frontend.Variable, podSize),
+		From:            make([]frontend.Variable, podSize),
+		Amount:          make([]frontend.Variable, podSize),
+		TransactionHash: make([]frontend.Variable, podSize),
+		FromBalances:    make([]frontend.Variable, podSize),
+		ToBalances:      make([]frontend.Variable, podSize),
+	}
 }
 
 type TransactionSecond struct {
@@ -80,7 +96,9 @@ func GetMerkleRootSecond(transactions []TransactionSecond) string {
 }
 
 func (circuit *MyCircuit) Define(api frontend.API) error {
-	for i := 0; i < config.PODSize; i++ {
+	rows := padding.Rows(api, circuit.TxnCount, len(circuit.To))
+	for i := range circuit.To {
+		padding.AssertZero(api, rows[i], circuit.To[i], circuit.From[i], circuit.Amount[i], circuit.TransactionHash[i], circuit.FromBalances[i], circuit.ToBalances[i])
 		api.AssertIsLessOrEqual(circuit.Amount[i], circuit.FromBalances[i]) //TODO  Here is one error1
 
 		api.Sub(circuit.FromBalances[i], circuit.Amount[i])
@@ -96,43 +114,23 @@ func (circuit *MyCircuit) Define(api frontend.API) error {
 	return nil
 }
 
-func ComputeCCS() constraint.ConstraintSystem {
-	var circuit MyCircuit
-	ccs, _ := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &circuit)
+func ComputeCCS(podSize int) constraint.ConstraintSystem {
+	ccs, _ := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, NewCircuit(podSize))
 
 	return ccs
 }
 
-func GenerateVerificationKey() (groth16.ProvingKey, groth16.VerifyingKey, error) {
-	ccs := ComputeCCS()
+func GenerateVerificationKey(podSize int) (groth16.ProvingKey, groth16.VerifyingKey, error) {
+	ccs := ComputeCCS(podSize)
 	pk, vk, error := groth16.Setup(ccs)
 	return pk, vk, error
 }
 
-func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []byte, error) {
-	ccs := ComputeCCS()
+func GenerateProof(inputData types.BatchStruct, batchNum int, podSize int) (any, string, []byte, error) {
+	ccs := ComputeCCS(podSize)
 	log.Info().Str("batchNum", strconv.Itoa(batchNum)).Msg("Generating proof")
-	var transactions []TransactionSecond
-
-	for i := 0; i < config.PODSize; i++ {
-
-		transaction := TransactionSecond{
-			To:              inputData.To[i],
-			From:            inputData.From[i],
-			Amount:          inputData.Amounts[i],
-			FromBalances:    inputData.SenderBalances[i],
-			ToBalances:      inputData.ReceiverBalances[i],
-			TransactionHash: inputData.TransactionHash[i],
-		}
-		transactions = append(transactions, transaction)
-	}
-
-	currentStatusHash := GetMerkleRootSecond(transactions)
 
-	//pk, err := ReadProvingKeyFromFile("provingKey.txt")
-	homeDir, _ := os.UserHomeDir()
-	provingKeyFile := homeDir + "/.tracks/config/provingKey.txt"
-	pk, err := ReadProvingKeyFromFile(provingKeyFile)
+	pk, err := ReadProvingKeyFromFile(keys.ProvingKeyFile(CircuitName, podSize))
 
 	if err != nil {
 		fmt.Println("Error reading proving key:", err)
@@ -165,8 +163,11 @@ func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []by
 		return nil, "", nil, fmt.Errorf("input data is not correct")
 	}
 
-	if inputValueLength < config.PODSize {
-		leftOver := config.PODSize - inputValueLength
+	if inputValueLength > podSize {
+		return nil, "", nil, fmt.Errorf("input data holds %d transactions, more than the pod size", inputValueLength)
+	}
+	if inputValueLength < podSize {
+		leftOver := podSize - inputValueLength
 		for i := 0; i < leftOver; i++ {
 			inputData.From = append(inputData.From, "0")
 			inputData.To = append(inputData.To, "0")
@@ -180,16 +181,28 @@ func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []by
 		}
 	}
 
-	inputs := MyCircuit{
-		To:              [config.PODSize]frontend.Variable{},
-		From:            [config.PODSize]frontend.Variable{},
-		Amount:          [config.PODSize]frontend.Variable{},
-		TransactionHash: [config.PODSize]frontend.Variable{},
-		FromBalances:    [config.PODSize]frontend.Variable{},
-		ToBalances:      [config.PODSize]frontend.Variable{},
+	// The merkle root is taken over the padded batch, as pods may hold fewer transactions.
+	var transactions []TransactionSecond
+
+	for i := 0; i < podSize; i++ {
+
+		transaction := TransactionSecond{
+			To:              inputData.To[i],
+			From:            inputData.From[i],
+			Amount:          inputData.Amounts[i],
+			FromBalances:    inputData.SenderBalances[i],
+			ToBalances:      inputData.ReceiverBalances[i],
+			TransactionHash: inputData.TransactionHash[i],
+		}
+		transactions = append(transactions, transaction)
 	}
 
-	for i := 0; i < config.PODSize; i++ {
+	currentStatusHash := GetMerkleRootSecond(transactions)
+
+	inputs := NewCircuit(podSize)
+	inputs.TxnCount = inputValueLength
+
+	for i := 0; i < podSize; i++ {
 		inputs.To[i] = frontend.Variable(inputData.To[i])
 		inputs.From[i] = frontend.Variable(inputData.From[i])
 		inputs.Amount[i] = frontend.Variable(inputData.Amounts[i])
@@ -198,7 +211,7 @@ func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []by
 		inputs.ToBalances[i] = frontend.Variable(inputData.ReceiverBalances[i])
 	}
 
-	witness, err := frontend.NewWitness(&inputs, ecc.BLS12_381.ScalarField())
+	witness, err := frontend.NewWitness(inputs, ecc.BLS12_381.ScalarField())
 	if err != nil {
 		fmt.Printf("Error creating a witness: %v\n", err)
 		return nil, "", nil, err
@@ -0,0 +1,81 @@
+package v1SVM
+
+import (
+	"encoding/json"
+	"fmt"
+	logs "github.com/airchains-network/decentralized-sequencer/log"
+	"github.com/airchains-network/decentralized-sequencer/zk/keys"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"os"
+)
+
+// CircuitName names the key files of the circuit. Change it with the constraints, so
+// keys of the previous circuit are never used.
+const CircuitName = "v1SVMr2"
+
+// CreateVkPkSVM generates and saves a new Proving Key and Verification Key for the SVM circuit
+// for pods of podSize transactions if either file doesn't exist
+func CreateVkPkSVM(podSize int) {
+	keys.Create(CircuitName, podSize, NewCircuit(podSize))
+}
+
+func GetVkPk(podSize int) (groth16.ProvingKey, groth16.VerifyingKey, error) {
+	provingKeyFile := keys.ProvingKeyFile(CircuitName, podSize)
+	verificationKeyFile := keys.VerificationKeyFile(CircuitName, podSize)
+
+	// Read Proving Key
+	pk, err := ReadProvingKeyFromFile2(provingKeyFile)
+	if err != nil {
+		logs.Log.Error("Failed to read Proving Key")
+		return nil, nil, err
+	}
+
+	vk, err := ReadVerificationKeyFromFile(verificationKeyFile)
+	if err != nil {
+		logs.Log.Error("Failed to read Verification Key")
+		return nil, nil, err
+	}
+
+	return pk, vk, nil
+}
+
+func ReadProvingKeyFromFile2(filename string) (groth16.ProvingKey, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pk := groth16.NewProvingKey(ecc.BLS12_381)
+	_, err = pk.ReadFrom(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return pk, nil
+}
+func ReadVerificationKeyFromFile(filename string) (groth16.VerifyingKey, error) {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	vk := groth16.NewVerifyingKey(ecc.BLS12_381)
+	err = json.Unmarshal(file, vk)
+	if err != nil {
+		return nil, err
+	}
+
+	return vk, nil
+}
+
+// CheckKeys returns an error unless the verification key of podSize fits the circuit
+// for pods of podSize transactions.
+func CheckKeys(podSize int) error {
+	vk, err := ReadVerificationKeyFromFile(keys.VerificationKeyFile(CircuitName, podSize))
+	if err != nil {
+		return fmt.Errorf("no circuit keys for pods of %d transactions, run tracks prover: %w", podSize, err)
+	}
+	return keys.Check(vk, NewCircuit(podSize), podSize)
+}
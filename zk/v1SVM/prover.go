@@ -0,0 +1,239 @@
+package v1SVM
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/airchains-network/decentralized-sequencer/blocksync"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/airchains-network/decentralized-sequencer/zk/keys"
+	"github.com/airchains-network/decentralized-sequencer/zk/padding"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/rs/zerolog/log"
+	"strconv"
+)
+
+// MyCircuit proves the system program transfers of a pod. The balances are the lamports
+// of the accounts right before and after each transfer. Use NewCircuit to size it.
+type MyCircuit struct {
+	To               []frontend.Variable `gnark:",public"`
+	From             []frontend.Variable `gnark:",public"`
+	Amount           []frontend.Variable `gnark:",public"`
+	TransactionHash  []frontend.Variable `gnark:",public"`
+	FromBalances     []frontend.Variable `gnark:",public"`
+	ToBalances       []frontend.Variable `gnark:",public"`
+	FromPostBalances []frontend.Variable `gnark:",public"`
+	ToPostBalances   []frontend.Variable `gnark:",public"`
+	// TxnCount is the number of transfers in the pod; the rows after them are padding.
+	TxnCount frontend.Variable `gnark:",public"`
+}
+
+// NewCircuit returns a circuit for pods of podSize transfers.
+func NewCircuit(podSize int) *MyCircuit {
+	return &MyCircuit{
+		To:               make([]frontend.Variable, podSize),
+		From:             make([]frontend.Variable, podSize),
+		Amount:           make([]frontend.Variable, podSize),
+		TransactionHash:  make([]frontend.Variable, podSize),
+		FromBalances:     make([]frontend.Variable, podSize),
+		ToBalances:       make([]frontend.Variable, podSize),
+		FromPostBalances: make([]frontend.Variable, podSize),
+		ToPostBalances:   make([]frontend.Variable, podSize),
+	}
+}
+
+type Transaction struct {
+	To               string
+	From             string
+	Amount           string
+	FromBalances     string
+	ToBalances       string
+	FromPostBalances string
+	ToPostBalances   string
+	TransactionHash  string
+}
+
+func getTransactionHash(tx Transaction) string {
+	h := sha256.New()
+	for _, field := range []string{tx.To, tx.From, tx.Amount, tx.FromBalances, tx.ToBalances, tx.FromPostBalances, tx.ToPostBalances, tx.TransactionHash} {
+		fieldHash := sha256.Sum256([]byte(field))
+		h.Write(fieldHash[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func GetMerkleRoot(transactions []Transaction) string {
+	var merkleTree []string
+
+	for _, tx := range transactions {
+		merkleTree = append(merkleTree, getTransactionHash(tx))
+	}
+
+	for len(merkleTree) > 1 {
+		var tempTree []string
+		for i := 0; i < len(merkleTree); i += 2 {
+			if i+1 == len(merkleTree) {
+				tempTree = append(tempTree, merkleTree[i])
+			} else {
+				combinedHash := merkleTree[i] + merkleTree[i+1]
+				h := sha256.New()
+				h.Write([]byte(combinedHash))
+				tempTree = append(tempTree, hex.EncodeToString(h.Sum(nil)))
+			}
+		}
+		merkleTree = tempTree
+	}
+
+	return merkleTree[0]
+}
+
+func (circuit *MyCircuit) Define(api frontend.API) error {
+	rows := padding.Rows(api, circuit.TxnCount, len(circuit.To))
+	for i := range circuit.To {
+		padding.AssertZero(api, rows[i], circuit.To[i], circuit.From[i], circuit.Amount[i], circuit.TransactionHash[i],
+			circuit.FromBalances[i], circuit.ToBalances[i], circuit.FromPostBalances[i], circuit.ToPostBalances[i])
+		// A transfer can not move more lamports than the sender held before the transaction.
+		api.AssertIsLessOrEqual(circuit.Amount[i], circuit.FromBalances[i])
+
+		// The transfer moves exactly Amount from the sender to the recipient.
+		api.AssertIsEqual(circuit.FromPostBalances[i], api.Sub(circuit.FromBalances[i], circuit.Amount[i]))
+		api.AssertIsEqual(circuit.ToPostBalances[i], api.Add(circuit.ToBalances[i], circuit.Amount[i]))
+	}
+
+	return nil
+}
+
+func ComputeCCS(podSize int) constraint.ConstraintSystem {
+	ccs, _ := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, NewCircuit(podSize))
+
+	return ccs
+}
+
+func GenerateVerificationKey(podSize int) (groth16.ProvingKey, groth16.VerifyingKey, error) {
+	ccs := ComputeCCS(podSize)
+	pk, vk, error := groth16.Setup(ccs)
+	return pk, vk, error
+}
+
+// padBatch checks that every field of inputData holds one value per transfer and pads
+// them with zero transfers up to podSize. TxnCount is set to the number of transfers
+// before padding.
+func padBatch(inputData types.BatchStruct, podSize int) (types.BatchStruct, error) {
+	fields := []*[]string{
+		&inputData.From,
+		&inputData.To,
+		&inputData.Amounts,
+		&inputData.TransactionHash,
+		&inputData.SenderBalances,
+		&inputData.ReceiverBalances,
+		&inputData.SenderPostBalances,
+		&inputData.ReceiverPostBalances,
+		&inputData.Messages,
+		&inputData.TransactionNonces,
+		&inputData.AccountNonces,
+	}
+	inputValueLength := len(inputData.From)
+	for _, field := range fields {
+		if len(*field) != inputValueLength {
+			return inputData, fmt.Errorf("input data is not correct")
+		}
+	}
+	if inputValueLength > podSize {
+		return inputData, fmt.Errorf("input data holds %d transfers, more than the pod size", inputValueLength)
+	}
+	for _, field := range fields {
+		for i := inputValueLength; i < podSize; i++ {
+			*field = append(*field, "0")
+		}
+	}
+	inputData.TxnCount = inputValueLength
+	return inputData, nil
+}
+
+// Assignment returns the circuit inputs for the padded batch inputData, together with
+// the merkle root of its transfers. The circuit has one row per entry of the batch.
+func Assignment(inputData types.BatchStruct) (*MyCircuit, string) {
+	var transactions []Transaction
+	inputs := NewCircuit(len(inputData.From))
+	for i := range inputData.From {
+		transactions = append(transactions, Transaction{
+			To:               inputData.To[i],
+			From:             inputData.From[i],
+			Amount:           inputData.Amounts[i],
+			FromBalances:     inputData.SenderBalances[i],
+			ToBalances:       inputData.ReceiverBalances[i],
+			FromPostBalances: inputData.SenderPostBalances[i],
+			ToPostBalances:   inputData.ReceiverPostBalances[i],
+			TransactionHash:  inputData.TransactionHash[i],
+		})
+
+		inputs.To[i] = frontend.Variable(inputData.To[i])
+		inputs.From[i] = frontend.Variable(inputData.From[i])
+		inputs.Amount[i] = frontend.Variable(inputData.Amounts[i])
+		inputs.TransactionHash[i] = frontend.Variable(inputData.TransactionHash[i])
+		inputs.FromBalances[i] = frontend.Variable(inputData.SenderBalances[i])
+		inputs.ToBalances[i] = frontend.Variable(inputData.ReceiverBalances[i])
+		inputs.FromPostBalances[i] = frontend.Variable(inputData.SenderPostBalances[i])
+		inputs.ToPostBalances[i] = frontend.Variable(inputData.ReceiverPostBalances[i])
+	}
+	inputs.TxnCount = inputData.TxnCount
+	return inputs, GetMerkleRoot(transactions)
+}
+
+func GenerateProof(inputData types.BatchStruct, batchNum int, podSize int) (any, string, []byte, error) {
+	ccs := ComputeCCS(podSize)
+	log.Info().Str("batchNum", strconv.Itoa(batchNum)).Msg("Generating proof")
+
+	pk, err := ReadProvingKeyFromFile2(keys.ProvingKeyFile(CircuitName, podSize))
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error reading proving key: %w", err)
+	}
+
+	inputData, err = padBatch(inputData, podSize)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	inputs, currentStatusHash := Assignment(inputData)
+
+	witness, err := frontend.NewWitness(inputs, ecc.BLS12_381.ScalarField())
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error creating a witness: %w", err)
+	}
+
+	witnessVector := witness.Vector()
+
+	publicWitness, _ := witness.Public()
+
+	publicWitnessDb := blocksync.GetPublicWitnessDbInstance()
+	publicWitnessDbKey := fmt.Sprintf("public_witness_%d", batchNum)
+	publicWitnessDbValue, err := json.Marshal(publicWitness)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error marshalling public witness: %w", err)
+	}
+	err = publicWitnessDb.Put([]byte(publicWitnessDbKey), publicWitnessDbValue, nil)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error saving public witness: %w", err)
+	}
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error generating proof: %w", err)
+	}
+
+	proofDb := blocksync.GetProofDbInstance()
+	proofDbKey := fmt.Sprintf("proof_%d", batchNum)
+	proofDbValue, err := json.Marshal(proof)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error marshalling proof: %w", err)
+	}
+	err = proofDb.Put([]byte(proofDbKey), proofDbValue, nil)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error saving proof: %w", err)
+	}
+
+	return witnessVector, currentStatusHash, proofDbValue, nil
+}
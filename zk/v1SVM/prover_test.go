@@ -0,0 +1,122 @@
+package v1SVM
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/airchains-network/decentralized-sequencer/config"
+	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+)
+
+// testBatch returns a pod with a transfer of amount from an account holding 1000 to
+// one holding 10.
+func testBatch(amount string) types.BatchStruct {
+	lamports, _ := strconv.Atoi(amount)
+	return types.BatchStruct{
+		From:                 []string{"1"},
+		To:                   []string{"2"},
+		Amounts:              []string{amount},
+		TransactionHash:      []string{"3"},
+		SenderBalances:       []string{"1000"},
+		ReceiverBalances:     []string{"10"},
+		SenderPostBalances:   []string{strconv.Itoa(1000 - lamports)},
+		ReceiverPostBalances: []string{strconv.Itoa(10 + lamports)},
+		Messages:             []string{"transfer"},
+		TransactionNonces:    []string{"0"},
+		AccountNonces:        []string{"0"},
+	}
+}
+
+func TestCircuit(t *testing.T) {
+	for _, tt := range []struct {
+		amount string
+		solved bool
+	}{
+		{amount: "400", solved: true},
+		{amount: "1000", solved: true},
+		{amount: "1001", solved: false},
+	} {
+		batch, err := padBatch(testBatch(tt.amount), config.PODSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(batch.ReceiverPostBalances) != config.PODSize {
+			t.Fatalf("padded batch holds %d transfers, want %d", len(batch.ReceiverPostBalances), config.PODSize)
+		}
+		assignment, root := Assignment(batch)
+		if root == "" {
+			t.Error("empty merkle root")
+		}
+		err = test.IsSolved(NewCircuit(config.PODSize), assignment, ecc.BLS12_381.ScalarField())
+		if solved := err == nil; solved != tt.solved {
+			t.Errorf("transfer of %s solved = %t, want %t: %v", tt.amount, solved, tt.solved, err)
+		}
+	}
+}
+
+func TestCircuitRejectsWrongPostBalances(t *testing.T) {
+	for _, edit := range []func(*types.BatchStruct){
+		func(b *types.BatchStruct) { b.SenderPostBalances[0] = "1000" },
+		func(b *types.BatchStruct) { b.ReceiverPostBalances[0] = "400" },
+	} {
+		batch := testBatch("400")
+		edit(&batch)
+		batch, err := padBatch(batch, config.PODSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assignment, _ := Assignment(batch)
+		if err := test.IsSolved(NewCircuit(config.PODSize), assignment, ecc.BLS12_381.ScalarField()); err == nil {
+			t.Errorf("solved a transfer with post balances %s, %s", batch.SenderPostBalances[0], batch.ReceiverPostBalances[0])
+		}
+	}
+}
+
+func TestPadBatchRejectsMissingPostBalances(t *testing.T) {
+	batch := testBatch("1")
+	batch.SenderPostBalances = nil
+	if _, err := padBatch(batch, config.PODSize); err == nil {
+		t.Error("padBatch accepted a batch without post balances")
+	}
+}
+
+func TestCircuitRejectsFilledPadding(t *testing.T) {
+	batch, err := padBatch(testBatch("400"), config.PODSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.TxnCount != 1 {
+		t.Fatalf("TxnCount = %d, want the transfers before padding", batch.TxnCount)
+	}
+	assignment, _ := Assignment(batch)
+	assignment.Amount[config.PODSize-1] = "5"
+	assignment.FromBalances[config.PODSize-1] = "10"
+	if err := test.IsSolved(NewCircuit(config.PODSize), assignment, ecc.BLS12_381.ScalarField()); err == nil {
+		t.Error("solved a pod with a transfer in a padding row")
+	}
+
+	assignment, _ = Assignment(batch)
+	assignment.TxnCount = config.PODSize + 1
+	if err := test.IsSolved(NewCircuit(config.PODSize), assignment, ecc.BLS12_381.ScalarField()); err == nil {
+		t.Error("solved a pod with more transfers than rows")
+	}
+}
+
+func TestCircuitOfPodSize(t *testing.T) {
+	batch, err := padBatch(testBatch("400"), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assignment, _ := Assignment(batch)
+	if len(assignment.To) != 4 {
+		t.Fatalf("assignment has %d rows, want 4", len(assignment.To))
+	}
+	if err := test.IsSolved(NewCircuit(4), assignment, ecc.BLS12_381.ScalarField()); err != nil {
+		t.Errorf("pod of 4 transfers not solved: %v", err)
+	}
+	if _, err := padBatch(testBatch("400"), 0); err == nil {
+		t.Error("padBatch accepted a batch larger than the pod size")
+	}
+}
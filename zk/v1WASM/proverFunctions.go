@@ -6,8 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/airchains-network/decentralized-sequencer/blocksync"
-	"github.com/airchains-network/decentralized-sequencer/config"
 	"github.com/airchains-network/decentralized-sequencer/types"
+	"github.com/airchains-network/decentralized-sequencer/zk/keys"
+	"github.com/airchains-network/decentralized-sequencer/zk/padding"
 	"math/rand"
 	"os"
 	"time"
@@ -25,16 +26,34 @@ import (
 	"github.com/consensys/gnark/std/signature/eddsa"
 )
 
+// MyCircuit has one row per transaction of a pod. Use NewCircuit to size it.
 type MyCircuit struct {
-	To              [config.PODSize]frontend.Variable `gnark:",public"`
-	From            [config.PODSize]frontend.Variable `gnark:",public"`
-	Amount          [config.PODSize]frontend.Variable `gnark:",public"`
-	TransactionHash [config.PODSize]frontend.Variable `gnark:",public"`
-	FromBalances    [config.PODSize]frontend.Variable `gnark:",public"`
-	ToBalances      [config.PODSize]frontend.Variable `gnark:",public"`
-	Messages        [config.PODSize]frontend.Variable `gnark:",public"`
-	PublicKeys      [config.PODSize]eddsa.PublicKey   `gnark:",public"`
-	Signatures      [config.PODSize]eddsa.Signature   `gnark:",public"`
+	To              []frontend.Variable `gnark:",public"`
+	From            []frontend.Variable `gnark:",public"`
+	Amount          []frontend.Variable `gnark:",public"`
+	TransactionHash []frontend.Variable `gnark:",public"`
+	FromBalances    []frontend.Variable `gnark:",public"`
+	ToBalances      []frontend.Variable `gnark:",public"`
+	Messages        []frontend.Variable `gnark:",public"`
+	PublicKeys      []eddsa.PublicKey   `gnark:",public"`
+	Signatures      []eddsa.Signature   `gnark:",public"`
+	// TxnCount is the number of transactions in the pod; the rows after them are padding.
+	TxnCount frontend.Variable `gnark:",public"`
+}
+
+// NewCircuit returns a circuit for pods of podSize transactions.
+func NewCircuit(podSize int) *MyCircuit {
+	return &MyCircuit{
+		To:              make([]frontend.Variable, podSize),
+		From:            make([]frontend.Variable, podSize),
+		Amount:          make([]frontend.Variable, podSize),
+		TransactionHash: make([]frontend.Variable, podSize),
+		FromBalances:    make([]frontend.Variable, podSize),
+		ToBalances:      make([]frontend.Variable, podSize),
+		Messages:        make([]frontend.Variable, podSize),
+		PublicKeys:      make([]eddsa.PublicKey, podSize),
+		Signatures:      make([]eddsa.Signature, podSize),
+	}
 }
 
 func getTransactionHash(tx types.GetTransactionStruct) string {
@@ -69,7 +88,7 @@ func GetMerkleRootCheck(transactions []types.GetTransactionStruct) string {
 	return merkleTree[0]
 }
 
-func GetMerkleRoot(api frontend.API, leaves [config.PODSize]frontend.Variable) frontend.Variable {
+func GetMerkleRoot(api frontend.API, leaves []frontend.Variable) frontend.Variable {
 
 	if len(leaves) == 0 {
 		return nil
@@ -103,8 +122,10 @@ func GetMerkleRoot(api frontend.API, leaves [config.PODSize]frontend.Variable) f
 }
 
 func (circuit *MyCircuit) Define(api frontend.API) error {
-	var leaves [config.PODSize]frontend.Variable
-	for i := 0; i < config.PODSize; i++ {
+	leaves := make([]frontend.Variable, len(circuit.To))
+	rows := padding.Rows(api, circuit.TxnCount, len(circuit.To))
+	for i := range circuit.To {
+		padding.AssertZero(api, rows[i], circuit.To[i], circuit.From[i], circuit.Amount[i], circuit.TransactionHash[i], circuit.FromBalances[i], circuit.ToBalances[i])
 
 		//Signature Verification
 		curve, err := twistededwards.NewEdCurve(api, tedwards.ID(ecc.BLS12_381))
@@ -152,15 +173,14 @@ func (circuit *MyCircuit) Define(api frontend.API) error {
 	return nil
 }
 
-func ComputeCCS() constraint.ConstraintSystem {
-	var circuit MyCircuit
-	ccs, _ := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &circuit)
+func ComputeCCS(podSize int) constraint.ConstraintSystem {
+	ccs, _ := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, NewCircuit(podSize))
 
 	return ccs
 }
 
-func GenerateVerificationKey() (groth16.ProvingKey, groth16.VerifyingKey, error) {
-	ccs := ComputeCCS()
+func GenerateVerificationKey(podSize int) (groth16.ProvingKey, groth16.VerifyingKey, error) {
+	ccs := ComputeCCS(podSize)
 	// groth16 zkSNARK: Setup
 	pk, vk, error := groth16.Setup(ccs)
 	return pk, vk, error
@@ -169,24 +189,9 @@ func GenerateVerificationKey() (groth16.ProvingKey, groth16.VerifyingKey, error)
 // GenerateProof generates a proof for the given input data
 // and returns the proof and the error
 // batchDbCount is the number of batches in the database and it will be passed as batchNum here
-func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []byte, error) {
-	ccs := ComputeCCS()
-	var transactions []types.GetTransactionStruct
-	for i := 0; i < config.PODSize; i++ {
-		transaction := types.GetTransactionStruct{
-			To:              inputData.To[i],
-			From:            inputData.From[i],
-			Amount:          inputData.Amounts[i],
-			FromBalances:    inputData.SenderBalances[i],
-			ToBalances:      inputData.ReceiverBalances[i],
-			TransactionHash: inputData.TransactionHash[i],
-		}
-		transactions = append(transactions, transaction)
-	}
-	currentStatusHash := GetMerkleRootCheck(transactions)
-	homeDir, _ := os.UserHomeDir()
-	provingKeyFile := homeDir + "/.tracks/config/provingKey.txt"
-	pk, err := ReadProvingKeyFromFile(provingKeyFile)
+func GenerateProof(inputData types.BatchStruct, batchNum int, podSize int) (any, string, []byte, error) {
+	ccs := ComputeCCS(podSize)
+	pk, err := ReadProvingKeyFromFile(keys.ProvingKeyFile(CircuitName, podSize))
 	if err != nil {
 		fmt.Println("Error reading proving key:", err)
 		return nil, "", nil, err
@@ -227,8 +232,11 @@ func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []by
 		return nil, "", nil, fmt.Errorf("input data is not correct")
 	}
 
-	if inputValueLength < config.PODSize {
-		leftOver := config.PODSize - inputValueLength
+	if inputValueLength > podSize {
+		return nil, "", nil, fmt.Errorf("input data holds %d transactions, more than the pod size", inputValueLength)
+	}
+	if inputValueLength < podSize {
+		leftOver := podSize - inputValueLength
 		for i := 0; i < leftOver; i++ {
 			inputData.From = append(inputData.From, "0")
 			inputData.To = append(inputData.To, "0")
@@ -242,19 +250,25 @@ func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []by
 		}
 	}
 
-	inputs := MyCircuit{
-		To:              [config.PODSize]frontend.Variable{},
-		From:            [config.PODSize]frontend.Variable{},
-		Amount:          [config.PODSize]frontend.Variable{},
-		TransactionHash: [config.PODSize]frontend.Variable{},
-		FromBalances:    [config.PODSize]frontend.Variable{},
-		ToBalances:      [config.PODSize]frontend.Variable{},
-		Signatures:      [config.PODSize]eddsa.Signature{},
-		PublicKeys:      [config.PODSize]eddsa.PublicKey{},
-		Messages:        [config.PODSize]frontend.Variable{},
+	// The merkle root is taken over the padded batch, as pods may hold fewer transactions.
+	var transactions []types.GetTransactionStruct
+	for i := 0; i < podSize; i++ {
+		transaction := types.GetTransactionStruct{
+			To:              inputData.To[i],
+			From:            inputData.From[i],
+			Amount:          inputData.Amounts[i],
+			FromBalances:    inputData.SenderBalances[i],
+			ToBalances:      inputData.ReceiverBalances[i],
+			TransactionHash: inputData.TransactionHash[i],
+		}
+		transactions = append(transactions, transaction)
 	}
+	currentStatusHash := GetMerkleRootCheck(transactions)
+
+	inputs := NewCircuit(podSize)
+	inputs.TxnCount = inputValueLength
 
-	for i := 0; i < config.PODSize; i++ {
+	for i := 0; i < podSize; i++ {
 		inputs.To[i] = frontend.Variable(inputData.To[i])
 		inputs.From[i] = frontend.Variable(inputData.From[i])
 		inputs.Amount[i] = frontend.Variable(inputData.Amounts[i])
@@ -285,7 +299,7 @@ func GenerateProof(inputData types.BatchStruct, batchNum int) (any, string, []by
 	}
 
 	// witness definition
-	witness, err := frontend.NewWitness(&inputs, ecc.BLS12_381.ScalarField())
+	witness, err := frontend.NewWitness(inputs, ecc.BLS12_381.ScalarField())
 	if err != nil {
 		fmt.Printf("Error creating a witness: %v\n", err)
 		return nil, "", nil, err